@@ -0,0 +1,50 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/streamingfast/substreams/client"
+	"github.com/streamingfast/substreams/reqctx"
+	"github.com/streamingfast/substreams/service"
+)
+
+func TestTier1App_ExecutionTraceDir(t *testing.T) {
+	for _, traceDir := range []string{"", t.TempDir()} {
+		app := NewTier1(zap.NewNop(), &Tier1Config{ExecutionTraceDir: traceDir}, &Tier1Modules{})
+
+		opts, err := app.serviceOptions()
+		require.NoError(t, err)
+
+		svc, err := service.NewTier1(
+			zap.NewNop(),
+			dstore.NewMockStore(nil),
+			nil,
+			nil,
+			dstore.NewMockStore(nil),
+			"",
+			1,
+			1000,
+			"sf.substreams.v1.test.Block",
+			client.NewSubstreamsClientConfig("localhost:9000", "", client.None, false, true),
+			reqctx.Tier2RequestParameters{},
+			opts...,
+		)
+		require.NoError(t, err)
+		assert.Equal(t, traceDir, svc.TestRuntimeConfig().ExecutionTraceDir)
+	}
+}
+
+func TestTier2App_ExecutionTraceDir(t *testing.T) {
+	for _, traceDir := range []string{"", t.TempDir()} {
+		app := NewTier2(zap.NewNop(), &Tier2Config{ExecutionTraceDir: traceDir}, &Tier2Modules{})
+
+		svc, err := service.NewTier2(zap.NewNop(), app.serviceOptions()...)
+		require.NoError(t, err)
+		assert.Equal(t, traceDir, svc.TestRuntimeConfig().ExecutionTraceDir)
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/streamingfast/dstore"
 	"github.com/streamingfast/shutter"
 	"github.com/streamingfast/substreams/client"
+	"github.com/streamingfast/substreams/manifest"
 	"github.com/streamingfast/substreams/metrics"
 	"github.com/streamingfast/substreams/service"
 	"github.com/streamingfast/substreams/wasm"
@@ -55,7 +56,10 @@ type Tier1Config struct {
 
 	WASMExtensions wasm.WASMExtensioner
 
-	Tracing bool
+	Tracing           bool
+	ExecutionTraceDir string // if set, module executions are recorded in this local directory, for replay with `substreams tools replay-trace`
+
+	TrustedPackageSigners []string // hex-encoded ed25519 public keys, if set only modules signed by one of them are run (see `substreams pack --sign-key`)
 }
 
 type Tier1App struct {
@@ -150,13 +154,9 @@ func (a *Tier1App) Run() error {
 		a.config.SubrequestsInsecure,
 		a.config.SubrequestsPlaintext,
 	)
-	var opts []service.Option
-	if a.config.WASMExtensions != nil {
-		opts = append(opts, service.WithWASMExtensioner(a.config.WASMExtensions))
-	}
-
-	if a.config.Tracing {
-		opts = append(opts, service.WithModuleExecutionTracing())
+	opts, err := a.serviceOptions()
+	if err != nil {
+		return err
 	}
 
 	var wasmModules map[string]string
@@ -220,6 +220,32 @@ func (a *Tier1App) Run() error {
 	return nil
 }
 
+// serviceOptions returns the options of the tier1 service derived from the app config.
+func (a *Tier1App) serviceOptions() ([]service.Option, error) {
+	var opts []service.Option
+	if a.config.WASMExtensions != nil {
+		opts = append(opts, service.WithWASMExtensioner(a.config.WASMExtensions))
+	}
+
+	if a.config.Tracing {
+		opts = append(opts, service.WithModuleExecutionTracing())
+	}
+
+	if a.config.ExecutionTraceDir != "" {
+		opts = append(opts, service.WithExecutionTraceDir(a.config.ExecutionTraceDir))
+	}
+
+	if len(a.config.TrustedPackageSigners) != 0 {
+		keys, err := manifest.ParsePublicKeys(a.config.TrustedPackageSigners)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted package signers: %w", err)
+		}
+		opts = append(opts, service.WithTrustedPackageSigners(keys))
+	}
+
+	return opts, nil
+}
+
 func (a *Tier1App) HealthCheck(ctx context.Context) (bool, interface{}, error) {
 	return a.IsReady(ctx), nil, nil
 }
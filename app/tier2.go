@@ -25,7 +25,8 @@ type Tier2Config struct {
 	MaximumConcurrentRequests uint64
 	WASMExtensions            wasm.WASMExtensioner
 
-	Tracing bool
+	Tracing           bool
+	ExecutionTraceDir string // if set, module executions are recorded in this local directory, for replay with `substreams tools replay-trace`
 }
 
 type Tier2App struct {
@@ -59,27 +60,9 @@ func (a *Tier2App) Run() error {
 		return fmt.Errorf("invalid app config: %w", err)
 	}
 
-	var opts []service.Option
-	//for _, opt := range a.config.PipelineOptions {
-	//	opts = append(opts, service.WithPipelineOptions(opt))
-	//}
-
-	if a.config.Tracing {
-		opts = append(opts, service.WithModuleExecutionTracing())
-	}
-
-	if a.config.MaximumConcurrentRequests > 0 {
-		opts = append(opts, service.WithMaxConcurrentRequests(a.config.MaximumConcurrentRequests))
-	}
-	opts = append(opts, service.WithReadinessFunc(a.setReadiness))
-
-	if a.config.WASMExtensions != nil {
-		opts = append(opts, service.WithWASMExtensioner(a.config.WASMExtensions))
-	}
-
 	svc, err := service.NewTier2(
 		a.logger,
-		opts...,
+		a.serviceOptions()...,
 	)
 	if err != nil {
 		return err
@@ -105,6 +88,33 @@ func (a *Tier2App) Run() error {
 	return nil
 }
 
+// serviceOptions returns the options of the tier2 service derived from the app config.
+func (a *Tier2App) serviceOptions() []service.Option {
+	var opts []service.Option
+	//for _, opt := range a.config.PipelineOptions {
+	//	opts = append(opts, service.WithPipelineOptions(opt))
+	//}
+
+	if a.config.Tracing {
+		opts = append(opts, service.WithModuleExecutionTracing())
+	}
+
+	if a.config.ExecutionTraceDir != "" {
+		opts = append(opts, service.WithExecutionTraceDir(a.config.ExecutionTraceDir))
+	}
+
+	if a.config.MaximumConcurrentRequests > 0 {
+		opts = append(opts, service.WithMaxConcurrentRequests(a.config.MaximumConcurrentRequests))
+	}
+	opts = append(opts, service.WithReadinessFunc(a.setReadiness))
+
+	if a.config.WASMExtensions != nil {
+		opts = append(opts, service.WithWASMExtensioner(a.config.WASMExtensions))
+	}
+
+	return opts
+}
+
 func (a *Tier2App) HealthCheck(ctx context.Context) (bool, interface{}, error) {
 	return a.IsReady(ctx), nil, nil
 }
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/streamingfast/cli"
+
+	"github.com/streamingfast/substreams/manifest"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <old_package> <new_package>",
+	Short: "Compare the modules of two packages and report which module caches are invalidated",
+	Long: cli.Dedent(`
+		Compare the modules of two packages (local manifests or .spkg files, local or remote) and report the
+		added, removed and renamed modules, what changed in the other ones (binary, inputs, initial block,
+		params, block filter, output type), which module hashes changed, either directly or because of one of
+		their ancestors, and the output types that cannot be decoded anymore with the new proto definitions.
+
+		A module whose hash changed does not reuse the cached outputs and states of the old package.
+	`),
+	RunE:         runDiff,
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+}
+
+func init() {
+	diffCmd.Flags().Bool("json", false, "Output as JSON")
+	rootCmd.AddCommand(diffCmd)
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	oldPkg, oldGraph, err := readDiffPackage(args[0])
+	if err != nil {
+		return err
+	}
+	newPkg, newGraph, err := readDiffPackage(args[1])
+	if err != nil {
+		return err
+	}
+
+	diff, err := manifest.DiffPackages(oldPkg, oldGraph, newPkg, newGraph)
+	if err != nil {
+		return fmt.Errorf("diffing packages: %w", err)
+	}
+
+	if mustGetBool(cmd, "json") {
+		res, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(res))
+		return nil
+	}
+
+	if diff.IsEmpty() {
+		fmt.Println("No module changes, all module caches are kept")
+		return nil
+	}
+
+	for _, name := range diff.Added {
+		fmt.Printf("+ %s (added)\n", name)
+	}
+	for _, name := range diff.Removed {
+		fmt.Printf("- %s (removed)\n", name)
+	}
+	for _, rename := range diff.Renamed {
+		fmt.Printf("~ %s -> %s (renamed)\n", rename.From, rename.To)
+	}
+
+	invalidated := 0
+	for _, mod := range diff.Changed {
+		switch mod.HashChange {
+		case manifest.HashChangeDirect:
+			invalidated++
+			fmt.Printf("* %s: hash changed %s -> %s\n", mod.Name, mod.OldHash, mod.NewHash)
+		case manifest.HashChangeInherited:
+			invalidated++
+			fmt.Printf("* %s: hash changed %s -> %s, inherited from %s\n", mod.Name, mod.OldHash, mod.NewHash, strings.Join(mod.ChangedAncestors, ", "))
+		default:
+			fmt.Printf("* %s: hash unchanged %s\n", mod.Name, mod.OldHash)
+		}
+		for _, change := range mod.Changes {
+			fmt.Printf("    %s\n", change)
+		}
+		if mod.OutputBreak != "" {
+			fmt.Printf("    output type breaking change: %s\n", mod.OutputBreak)
+		}
+	}
+
+	fmt.Println("")
+	fmt.Printf("%d module(s) added, %d removed, %d renamed, %d changed, %d module cache(s) invalidated\n", len(diff.Added), len(diff.Removed), len(diff.Renamed), len(diff.Changed), invalidated)
+	return nil
+}
+
+func readDiffPackage(input string) (*pbsubstreams.Package, *manifest.ModuleGraph, error) {
+	reader, err := manifest.NewReader(input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("manifest reader %q: %w", input, err)
+	}
+
+	pkg, graph, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("read manifest %q: %w", input, err)
+	}
+	return pkg, graph, nil
+}
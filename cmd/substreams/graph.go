@@ -1,28 +1,35 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/spf13/cobra"
 	"github.com/streamingfast/cli"
 
+	"github.com/streamingfast/substreams/info"
 	"github.com/streamingfast/substreams/manifest"
 )
 
 var graphCmd = &cobra.Command{
 	Use:   "graph [<manifest_file>]",
-	Short: "Generate mermaid-js graph document",
+	Short: "Generate the graph of the modules, as a mermaid-js, Graphviz DOT, SVG or JSON document",
 	RunE:  runManifestGraph,
 	Long: cli.Dedent(`
 		Generate mermaid-js graph document. The manifest is optional as it will try to find a file named
 		'substreams.yaml' in current working directory if nothing entered. You may enter a directory that contains a
 		'substreams.yaml' file in place of '<manifest_file>', or a link to a remote .spkg file, using urls gs://, http(s)://, ipfs://, etc.'.
+
+		Use '--format' to print the graph in another format than mermaid-js: 'dot' (Graphviz), 'svg' (rendered
+		locally, without any external service) or 'json'. Those formats annotate each module with its kind,
+		initial block, module hash and execution stage, and each store input with its mode (get or deltas).
 	`),
 	Args:         cobra.RangeArgs(0, 1),
 	SilenceUsage: true,
 }
 
 func init() {
+	graphCmd.Flags().String("format", "mermaid", "Output format, one of 'mermaid', 'dot', 'svg' or 'json'")
 	rootCmd.AddCommand(graphCmd)
 }
 
@@ -37,11 +44,35 @@ func runManifestGraph(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("manifest reader: %w", err)
 	}
 
-	pkg, _, err := manifestReader.Read()
+	pkg, graph, err := manifestReader.Read()
 	if err != nil {
 		return fmt.Errorf("read manifest %q: %w", manifestPath, err)
 	}
 
+	format := mustGetString(cmd, "format")
+	if format != "mermaid" {
+		graphInfo, err := info.Graph(pkg, graph)
+		if err != nil {
+			return fmt.Errorf("building graph: %w", err)
+		}
+
+		switch format {
+		case "dot":
+			fmt.Print(graphInfo.DOT())
+		case "svg":
+			fmt.Print(graphInfo.SVG())
+		case "json":
+			res, err := json.MarshalIndent(graphInfo, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(res))
+		default:
+			return fmt.Errorf("invalid format %q, must be one of 'mermaid', 'dot', 'svg' or 'json'", format)
+		}
+		return nil
+	}
+
 	manifest.PrintMermaid(pkg.Modules)
 
 	fmt.Println("")
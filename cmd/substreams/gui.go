@@ -23,6 +23,7 @@ func init() {
 	guiCmd.Flags().String("substreams-api-key-envvar", "SUBSTREAMS_API_KEY", "Name of variable containing Substreams Api Key")
 	guiCmd.Flags().StringP("substreams-endpoint", "e", "", "Substreams gRPC endpoint. If empty, will be replaced by the SUBSTREAMS_ENDPOINT_{network_name} environment variable, where `network_name` is determined from the substreams manifest. Some network names have default endpoints.")
 	guiCmd.Flags().String("network", "", "Specify the network to use for params and initialBlocks, overriding the 'network' field in the substreams package")
+	guiCmd.Flags().StringArray("overlay", nil, "Deep-merge the overlay 'substreams.<name>.yaml' found next to the manifest into it, can be repeated. The overlay of --network is applied automatically when it exists")
 	guiCmd.Flags().Bool("insecure", false, "Skip certificate validation on GRPC connection")
 	guiCmd.Flags().Bool("plaintext", false, "Establish GRPC connection in plaintext")
 	guiCmd.Flags().StringSliceP("header", "H", nil, "Additional headers to be sent in the substreams request")
@@ -92,6 +93,7 @@ func runGui(cmd *cobra.Command, args []string) error {
 	readerOptions := []manifest.Option{
 		manifest.WithOverrideOutputModule(outputModule),
 		manifest.WithOverrideNetwork(network),
+		manifest.WithOverlays(sflags.MustGetStringArray(cmd, "overlay")...),
 		manifest.WithParams(params),
 	}
 	if sflags.MustGetBool(cmd, "skip-package-validation") {
@@ -83,6 +83,22 @@ func runInfo(cmd *cobra.Command, args []string) error {
 		for _, input := range mod.Inputs {
 			fmt.Printf("Input: %s: %s\n", input.Type, input.Name)
 		}
+		if schema := mod.ParamsSchema; schema != nil {
+			fmt.Println("Params schema:")
+			fmt.Println("  Type:", schema.Type)
+			if schema.Regex != "" {
+				fmt.Println("  Regex:", schema.Regex)
+			}
+			if len(schema.Enum) != 0 {
+				fmt.Println("  Enum:", strings.Join(schema.Enum, ", "))
+			}
+			if schema.Default != "" {
+				fmt.Printf("  Default: %q\n", schema.Default)
+			}
+			if schema.Doc != "" {
+				fmt.Println("  Doc:", strings.Replace(schema.Doc, "\n", "\n    ", -1))
+			}
+		}
 
 		switch mod.Kind {
 		case "map":
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"crypto/ed25519"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,6 +11,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/streamingfast/cli"
 	"github.com/streamingfast/substreams/manifest"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
 	"go.uber.org/zap"
 	"google.golang.org/protobuf/proto"
 )
@@ -20,6 +23,22 @@ var packCmd = &cobra.Command{
 		Build an .spkg out of a .yaml manifest. The manifest is optional as it will try to find a file named
 		'substreams.yaml' in current working directory if nothing entered. You may enter a directory that contains a
 		'substreams.yaml' file in place of '<manifest_file>', or a link to a remote .spkg file, using urls gs://, http(s)://, ipfs://, etc.'.
+
+		When the manifest has 'imports', a 'substreams.lock' file is written next to it, pinning the resolved URL,
+		the SHA-256 and the module hashes of each imported package. Reading the manifest afterwards fails if any
+		import drifted from the lock file, use '--update-lock' to accept the changes and refresh it.
+
+		Use '--sign-key' to attach an ed25519 signature to the package, over its canonical bytes and over its
+		binaries. Readers can then require it with '--trusted-signers', and servers with an allow-list of signers.
+
+		Use '--overlay <name>' to merge 'substreams.<name>.yaml' into the manifest before packing it, for
+		example to build the package of a given network. Each combination of overlays has its own lock file
+		('substreams.<name>.lock').
+
+		Use '--check-compat-with <old.spkg>' to fail when the output type of a module breaks compatibility with
+		the same module of a previously released package: field numbers reused, field types or labels changed,
+		fields or enum values removed without reserving their number, required fields removed, types moved to
+		another package, etc. Sinks decoding the outputs with the old proto definitions would break.
 	`),
 	RunE:         runPack,
 	Args:         cobra.RangeArgs(0, 1),
@@ -36,6 +55,14 @@ func init() {
 		replaced by "-") and "<version>" is "package.version" value. You can use "{version}" which resolves
 		to "package.version".
 	`))
+	packCmd.Flags().String("sign-key", "", cli.FlagDescription(`
+		Path to an ed25519 private key used to sign the package, either a PKCS #8 PEM file (generated with
+		"openssl genpkey -algorithm ed25519 -out key.pem") or the hex-encoded 32 bytes seed
+	`))
+	packCmd.Flags().Bool("prune-proto-files", false, "Only keep the proto files needed to decode module inputs and outputs, store values and the sink configuration")
+	packCmd.Flags().StringArray("overlay", nil, "Deep-merge the overlay 'substreams.<name>.yaml' found next to the manifest into it before packing, can be repeated")
+	packCmd.Flags().String("check-compat-with", "", "Path or URL of a previous .spkg, fail if the output types of the modules are not backward-compatible with it")
+	packCmd.Flags().Bool("update-lock", false, "Accept imports that drifted from the 'substreams.lock' file and refresh it")
 	//packCmd.Flags().StringArrayP("config", "c", []string{}, cli.FlagDescription(`path to a configuration file that contains overrides for the manifest`))
 }
 
@@ -45,7 +72,17 @@ func runPack(cmd *cobra.Command, args []string) error {
 		manifestPath = args[0]
 	}
 
-	manifestReader, err := manifest.NewReader(manifestPath)
+	readerOptions := []manifest.Option{
+		manifest.WithOverlays(mustGetStringArray(cmd, "overlay")...),
+	}
+	if mustGetBool(cmd, "update-lock") {
+		readerOptions = append(readerOptions, manifest.SkipLockVerificationReader())
+	}
+	if mustGetBool(cmd, "prune-proto-files") {
+		readerOptions = append(readerOptions, manifest.WithProtoFilesPruning())
+	}
+
+	manifestReader, err := manifest.NewReader(manifestPath, readerOptions...)
 	if err != nil {
 		return fmt.Errorf("manifest reader: %w", err)
 	}
@@ -59,6 +96,12 @@ func runPack(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("reading manifest %q: %w", manifestPath, err)
 	}
 
+	if oldPkgPath := mustGetString(cmd, "check-compat-with"); oldPkgPath != "" {
+		if err := checkOutputCompatibility(oldPkgPath, pkg); err != nil {
+			return err
+		}
+	}
+
 	originalOutputFile := maybeGetString(cmd, "output-file")
 	resolvedOutputFile := resolveOutputFile(originalOutputFile, map[string]string{
 		"manifestDir":     filepath.Dir(manifestPath),
@@ -73,6 +116,17 @@ func runPack(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("create output directories: %w", err)
 	}
 
+	if signKeyPath := mustGetString(cmd, "sign-key"); signKeyPath != "" {
+		key, err := manifest.ReadSigningKey(signKeyPath)
+		if err != nil {
+			return err
+		}
+		if err := manifest.SignPackage(pkg, key); err != nil {
+			return fmt.Errorf("signing package: %w", err)
+		}
+		fmt.Printf("Signed package with public key %s\n", hex.EncodeToString(key.Public().(ed25519.PublicKey)))
+	}
+
 	cnt, err := proto.Marshal(pkg)
 	if err != nil {
 		return fmt.Errorf("marshalling package: %w", err)
@@ -85,6 +139,47 @@ func runPack(cmd *cobra.Command, args []string) error {
 
 	fmt.Printf("Successfully wrote %q.\n", resolvedOutputFile)
 
+	if err := writeLockfile(manifestReader); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func checkOutputCompatibility(oldPkgPath string, pkg *pbsubstreams.Package) error {
+	oldReader, err := manifest.NewReader(oldPkgPath)
+	if err != nil {
+		return fmt.Errorf("manifest reader %q: %w", oldPkgPath, err)
+	}
+	oldPkg, _, err := oldReader.Read()
+	if err != nil {
+		return fmt.Errorf("reading package %q: %w", oldPkgPath, err)
+	}
+
+	issues := manifest.CheckOutputCompatibility(oldPkg, pkg)
+	if len(issues) == 0 {
+		fmt.Printf("Module output types are backward-compatible with %q.\n", oldPkgPath)
+		return nil
+	}
+
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	return fmt.Errorf("%d breaking change(s) in module output types compared to %q", len(issues), oldPkgPath)
+}
+
+func writeLockfile(manifestReader *manifest.Reader) error {
+	lock := manifestReader.Lockfile()
+	lockPath := manifestReader.LockfilePath()
+	if len(lock.Imports) == 0 && !cli.FileExists(lockPath) {
+		return nil
+	}
+
+	if err := lock.Write(lockPath); err != nil {
+		return err
+	}
+
+	fmt.Printf("Successfully wrote %q.\n", lockPath)
 	return nil
 }
 
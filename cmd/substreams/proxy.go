@@ -48,6 +48,7 @@ func (cs *ConnectServer) Blocks(
 		OutputModule:                        req.Msg.OutputModule,
 		Modules:                             req.Msg.Modules,
 		DebugInitialStoreSnapshotForModules: req.Msg.DebugInitialStoreSnapshotForModules,
+		PackageSignatures:                   req.Msg.PackageSignatures,
 	}
 
 	if cs.Manifest != "" {
@@ -61,6 +62,7 @@ func (cs *ConnectServer) Blocks(
 			return fmt.Errorf("read manifest %q: %w", cs.Manifest, err)
 		}
 		newReq.Modules = pkg.Modules
+		newReq.PackageSignatures = pkg.Signatures
 	}
 
 	if cs.StartBlock != 0 {
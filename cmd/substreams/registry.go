@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/streamingfast/cli"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/streamingfast/substreams/manifest"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+var registryCmd = &cobra.Command{
+	Use:   "registry",
+	Short: "Manage a package registry used to resolve '<package>@<version>' imports",
+}
+
+var registryPublishCmd = &cobra.Command{
+	Use:   "publish <spkg>...",
+	Short: "Upload packages to a registry and add them to its index",
+	Long: cli.Dedent(`
+		Uploads each '.spkg' file to the registry given by '--registry-url' (any dstore URL, such as a local
+		directory, gs://, s3:// or az://) under '<name>/<name>-<version>.spkg', and adds it to the registry's
+		'index.json'.
+
+		Manifests can then import the package with '<name>@<version constraint>', for example
+		'ethereum-common@^0.3', resolving to the highest published version matching the constraint.
+		Publishing is not safe to run concurrently against the same registry.
+	`),
+	Example: cli.Dedent(`
+		substreams registry publish --registry-url gs://my-bucket/registry ethereum-common-v0.3.1.spkg
+	`),
+	RunE:         runRegistryPublish,
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+}
+
+func init() {
+	registryPublishCmd.Flags().Bool("overwrite", false, "Replace an already published version of a package")
+
+	registryCmd.AddCommand(registryPublishCmd)
+	rootCmd.AddCommand(registryCmd)
+}
+
+func runRegistryPublish(cmd *cobra.Command, args []string) error {
+	registryURL := manifest.RegistryURL
+	if registryURL == "" {
+		return fmt.Errorf("no registry specified, use --registry-url")
+	}
+
+	for _, path := range args {
+		cnt, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading package: %w", err)
+		}
+
+		pkg := &pbsubstreams.Package{}
+		if err := proto.Unmarshal(cnt, pkg); err != nil {
+			return fmt.Errorf("unable to unmarshal package %q: %w", path, err)
+		}
+
+		entry, err := manifest.PublishPackage(cmd.Context(), registryURL, pkg, cnt, mustGetBool(cmd, "overwrite"))
+		if err != nil {
+			return fmt.Errorf("publishing %q: %w", path, err)
+		}
+
+		fmt.Printf("Published %s %s to %s/%s\n", pkg.PackageMeta[0].Name, entry.Version, registryURL, entry.URL)
+	}
+
+	return nil
+}
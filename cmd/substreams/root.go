@@ -22,4 +22,5 @@ func init() {
 	// From https://thegraph.com/docs/en/operating-graph-node/
 	rootCmd.PersistentFlags().String("ipfs-url", "https://ipfs.network.thegraph.com", "IPFS endpoint to resolve substreams-based subgraphs as manifest")
 	rootCmd.PersistentFlags().Duration("ipfs-timeout", time.Second*10, "IPFS timeout when resolving substreams-based subgraphs as manifest")
+	rootCmd.PersistentFlags().String("registry-url", "", "Package registry (any dstore URL, local directory or HTTP(S) base URL holding an 'index.json') used to resolve '<package>@<version>' imports and to publish packages")
 }
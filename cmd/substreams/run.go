@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 
@@ -23,6 +24,7 @@ func init() {
 	runCmd.Flags().String("substreams-api-token-envvar", "SUBSTREAMS_API_TOKEN", "name of variable containing Substreams Authentication token")
 	runCmd.Flags().String("substreams-api-key-envvar", "SUBSTREAMS_API_KEY", "Name of variable containing Substreams Api Key")
 	runCmd.Flags().String("network", "", "Specify the network to use for params and initialBlocks, overriding the 'network' field in the substreams package")
+	runCmd.Flags().StringArray("overlay", nil, "Deep-merge the overlay 'substreams.<name>.yaml' found next to the manifest into it, can be repeated. The overlay of --network is applied automatically when it exists")
 	runCmd.Flags().StringP("start-block", "s", "", "Start block to stream from. If empty, will be replaced by initialBlock of the first module you are streaming. If negative, will be resolved by the server relative to the chain head")
 	runCmd.Flags().StringP("cursor", "c", "", "Cursor to stream from. Leave blank for no cursor")
 	runCmd.Flags().StringP("stop-block", "t", "0", "Stop block to end stream at, exclusively. If the start-block is positive, a '+' prefix can indicate 'relative to start-block'")
@@ -35,9 +37,13 @@ func init() {
 	runCmd.Flags().StringSliceP("header", "H", nil, "Additional headers to be sent in the substreams request")
 	runCmd.Flags().Bool("production-mode", false, "Enable Production Mode, with high-speed parallel processing")
 	runCmd.Flags().Bool("skip-package-validation", false, "Do not perform any validation when reading substreams package")
+	runCmd.Flags().StringSlice("trusted-signers", nil, "Hex-encoded ed25519 public keys, refuse to run a package that is not signed by one of them (see 'substreams pack --sign-key')")
 	runCmd.Flags().StringArrayP("params", "p", nil, "Set a params for parameterizable modules. Can be specified multiple times. Ex: -p module1=valA -p module2=valX&valY")
 	runCmd.Flags().String("test-file", "", "runs a test file")
 	runCmd.Flags().Bool("test-verbose", false, "print out all the results")
+	runCmd.Flags().String("test-junit-output", "", "When running a test file, also write the results as a JUnit XML report to this path")
+	runCmd.Flags().String("golden-dir", "", "Compare the decoded output of each printed module at each block with the golden files '<golden-dir>/<module>/<block>.json', failing on mismatch")
+	runCmd.Flags().Bool("update-golden", false, "Write (or overwrite) the golden files in --golden-dir with the received outputs instead of comparing them")
 	rootCmd.AddCommand(runCmd)
 }
 
@@ -83,11 +89,19 @@ func runRun(cmd *cobra.Command, args []string) error {
 	readerOptions := []manifest.Option{
 		manifest.WithOverrideOutputModule(outputModule),
 		manifest.WithOverrideNetwork(network),
+		manifest.WithOverlays(sflags.MustGetStringArray(cmd, "overlay")...),
 		manifest.WithParams(params),
 	}
 	if sflags.MustGetBool(cmd, "skip-package-validation") {
 		readerOptions = append(readerOptions, manifest.SkipPackageValidationReader())
 	}
+	trustedSigners, err := readTrustedSignersFlag(cmd, "trusted-signers")
+	if err != nil {
+		return err
+	}
+	if trustedSigners != nil {
+		readerOptions = append(readerOptions, trustedSigners)
+	}
 
 	manifestReader, err := manifest.NewReader(manifestPath, readerOptions...)
 	if err != nil {
@@ -171,6 +185,7 @@ func runRun(cmd *cobra.Command, args []string) error {
 		OutputModule:                        outputModule,
 		ProductionMode:                      productionMode,
 		DebugInitialStoreSnapshotForModules: debugModulesInitialSnapshot,
+		PackageSignatures:                   pkg.Signatures,
 	}
 
 	if err := req.Validate(); err != nil {
@@ -187,6 +202,24 @@ func runRun(cmd *cobra.Command, args []string) error {
 	}
 	defer ui.CleanUpTerminal()
 
+	var golden *tui.Golden
+	if goldenDir := mustGetString(cmd, "golden-dir"); goldenDir != "" {
+		golden = tui.NewGolden(goldenDir, mustGetBool(cmd, "update-golden"))
+		ui.SetGolden(golden)
+	} else if mustGetBool(cmd, "update-golden") {
+		return fmt.Errorf("--update-golden requires --golden-dir")
+	}
+	// the golden files are reported however the stream ends, failing the run on mismatches
+	reportGolden := func(err error) error {
+		if golden == nil {
+			return err
+		}
+		if reportErr := golden.Report(); reportErr != nil && err == nil {
+			return reportErr
+		}
+		return err
+	}
+
 	streamCtx, cancel := context.WithCancel(ctx)
 	ui.OnTerminated(func(err error) {
 		if err != nil {
@@ -216,7 +249,7 @@ func runRun(cmd *cobra.Command, args []string) error {
 	ui.Connecting()
 	cli, err := ssClient.Blocks(streamCtx, req, callOpts...)
 	if err != nil && streamCtx.Err() != context.Canceled {
-		return fmt.Errorf("call sf.substreams.rpc.v2.Stream/Blocks: %w", err)
+		return reportGolden(fmt.Errorf("call sf.substreams.rpc.v2.Stream/Blocks: %w", err))
 	}
 	ui.Connected()
 
@@ -224,6 +257,11 @@ func runRun(cmd *cobra.Command, args []string) error {
 		resp, err := cli.Recv()
 		if resp != nil {
 			if err := ui.IncomingMessage(ctx, resp, testRunner); err != nil {
+				var goldenErr *tui.GoldenError
+				if errors.As(err, &goldenErr) {
+					ui.Cancel()
+					return reportGolden(err)
+				}
 				fmt.Printf("RETURN HANDLER ERROR: %s\n", err)
 			}
 		}
@@ -232,8 +270,11 @@ func runRun(cmd *cobra.Command, args []string) error {
 				ui.Cancel()
 				fmt.Println("Total Read Bytes (server-side consumption):", ui.TotalReadBytes)
 				fmt.Println("all done")
+				if err := reportGolden(nil); err != nil {
+					return err
+				}
 				if testRunner != nil {
-					testRunner.LogResults()
+					return reportTestResults(cmd, testRunner, nil)
 				}
 
 				return nil
@@ -242,10 +283,36 @@ func runRun(cmd *cobra.Command, args []string) error {
 			// Special handling if interrupted the context ourselves, no error
 			if streamCtx.Err() == context.Canceled {
 				ui.Cancel()
-				return nil
+				return reportGolden(nil)
 			}
 
+			if testRunner != nil {
+				ui.Cancel()
+				return reportGolden(reportTestResults(cmd, testRunner, err))
+			}
+
+			return reportGolden(err)
+		}
+	}
+}
+
+// reportTestResults prints the results of the test file and writes the JUnit report if requested. It
+// returns an error if a test failed, or if the stream failed while no test expected it to.
+func reportTestResults(cmd *cobra.Command, testRunner *test.Runner, streamErr error) error {
+	testRunner.StreamEnded(streamErr)
+	testRunner.LogResults()
+
+	if junitPath := mustGetString(cmd, "test-junit-output"); junitPath != "" {
+		if err := testRunner.WriteJUnit(junitPath); err != nil {
 			return err
 		}
 	}
+
+	if streamErr != nil && !testRunner.ErrorExpected() {
+		return streamErr
+	}
+	if testRunner.Failed() {
+		return fmt.Errorf("test file: some tests failed")
+	}
+	return nil
 }
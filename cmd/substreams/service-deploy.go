@@ -12,6 +12,7 @@ import (
 	"github.com/streamingfast/substreams/manifest"
 	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
 	"github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1/pbsinksvcconnect"
+	"github.com/streamingfast/substreams/sink-server/resources"
 )
 
 func init() {
@@ -19,7 +20,10 @@ func init() {
 	deployCmd.Flags().StringArray("deployment-params", []string{}, "Extra parameters to pass to the deployment endpoint")
 	deployCmd.Flags().StringArrayP("params", "p", []string{}, "Parameters to pass to the substreams (ex: module2=key1=valX&key2=valY)")
 	deployCmd.Flags().StringP("network", "n", "", "Network to deploy to (overrides the 'network' field in the manifest)")
+	deployCmd.Flags().StringArray("overlay", nil, "Deep-merge the overlay 'substreams.<name>.yaml' found next to the manifest into it, can be repeated. The overlay of --network is applied automatically when it exists")
 	deployCmd.Flags().Bool("prod", false, "Enable production mode (default: false)")
+	deployCmd.Flags().String("size", "", "Size tier of the deployment (ex: small, medium, large), limiting the resources of its services. Shortcut for '--deployment-params SF_SIZE=<size>', the server default is used if empty")
+	deployCmd.Flags().StringSlice("trusted-signers", nil, "Hex-encoded ed25519 public keys, refuse to deploy a package that is not signed by one of them (see 'substreams pack --sign-key')")
 }
 
 var deployCmd = &cobra.Command{
@@ -48,8 +52,16 @@ func deployE(cmd *cobra.Command, args []string) error {
 	network := sflags.MustGetString(cmd, "network")
 	readerOptions := []manifest.Option{
 		manifest.WithOverrideNetwork(network),
+		manifest.WithOverlays(sflags.MustGetStringArray(cmd, "overlay")...),
 		manifest.WithParams(params),
 	}
+	trustedSigners, err := readTrustedSignersFlag(cmd, "trusted-signers")
+	if err != nil {
+		return err
+	}
+	if trustedSigners != nil {
+		readerOptions = append(readerOptions, trustedSigners)
+	}
 
 	reader, err := manifest.NewReader(file, readerOptions...)
 	if err != nil {
@@ -77,6 +89,9 @@ func deployE(cmd *cobra.Command, args []string) error {
 		}
 		paramsMap[parts[0]] = parts[1]
 	}
+	if size := sflags.MustGetString(cmd, "size"); size != "" {
+		paramsMap[resources.SizeParameter] = size
+	}
 
 	deployParams := []*pbsinksvc.Parameter{}
 	for k, v := range paramsMap {
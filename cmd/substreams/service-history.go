@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/spf13/cobra"
+	cli "github.com/streamingfast/cli"
+	"github.com/streamingfast/cli/sflags"
+	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
+	"github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1/pbsinksvcconnect"
+	server "github.com/streamingfast/substreams/sink-server"
+)
+
+func init() {
+	serviceCmd.AddCommand(historyCmd)
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history [deployment-id]",
+	Short: "Get the history of a deployed substreams sink",
+	Long: cli.Dedent(`
+        Sends a "History" request to a server. By default, it will talk to a local "substreams alpha service serve" instance.
+        It returns who created the deployment, with which package and parameters, and every request and status change since then,
+        including for removed deployments.
+        If deploymentID is not set or is incomplete, the CLI will try to guess among the existing deployments (unless --strict is set).
+		`),
+	RunE:         historyE,
+	Args:         cobra.RangeArgs(0, 1),
+	SilenceUsage: true,
+}
+
+func historyE(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	var id string
+	if len(args) == 1 {
+		id = args[0]
+	}
+
+	cli := pbsinksvcconnect.NewProviderClient(http.DefaultClient, sflags.MustGetString(cmd, "endpoint"))
+	if len(id) < server.DeploymentIDLength {
+		if sflags.MustGetBool(cmd, "strict") {
+			return fmt.Errorf("invalid ID provided: %q and '--strict' is set", id)
+		}
+		matching, err := fuzzyMatchDeployment(ctx, id, cli, cmd, fuzzyMatchPreferredStatusOrder)
+		if err != nil {
+			return err
+		}
+		id = matching.Id
+	}
+
+	req := connect.NewRequest(&pbsinksvc.HistoryRequest{
+		DeploymentId: id,
+	})
+	if err := addHeaders(cmd, req); err != nil {
+		return err
+	}
+
+	resp, err := cli.History(ctx, req)
+	if err != nil {
+		return interceptConnectionError(err)
+	}
+
+	dep := resp.Msg.Deployment
+	fmt.Printf("History of deployment %q:\n", id)
+	if dep.PackageInfo != nil {
+		fmt.Printf("  Name: %s (%s)\n  Output module: %s (%s)\n", dep.PackageInfo.Name, dep.PackageInfo.Version, dep.PackageInfo.OutputModuleName, dep.PackageInfo.OutputModuleHash)
+	}
+	fmt.Printf("  Owner: %s\n  Created: %s\n  Package hash: %s\n  Status: %v\n", orDash(dep.Owner), dep.CreatedAt.AsTime().Local().Format(time.RFC3339), orDash(dep.PackageHash), dep.Status)
+	if dep.Removed {
+		fmt.Printf("  Removed: true\n")
+	}
+	if dep.DevelopmentMode {
+		fmt.Printf("  Development mode: true\n")
+	}
+	if len(dep.Parameters) != 0 {
+		var params []string
+		for _, p := range dep.Parameters {
+			params = append(params, p.Key+"="+p.Value)
+		}
+		fmt.Printf("  Parameters: %s\n", strings.Join(params, ", "))
+	}
+
+	fmt.Printf("Events:\n")
+	for _, ev := range resp.Msg.Events {
+		line := fmt.Sprintf("  %s  %-7s %v -> %v", ev.Timestamp.AsTime().Local().Format(time.RFC3339), ev.Action, ev.PreviousStatus, ev.NewStatus)
+		if ev.User != "" {
+			line += " by " + ev.User
+		}
+		if ev.PackageHash != "" {
+			line += " (package " + ev.PackageHash[:min(12, len(ev.PackageHash))] + ")"
+		}
+		if ev.Reason != "" {
+			line += ": " + ev.Reason
+		}
+		fmt.Println(line)
+	}
+
+	return nil
+}
+
+func orDash(in string) string {
+	if in == "" {
+		return "-"
+	}
+	return in
+}
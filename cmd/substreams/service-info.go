@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"connectrpc.com/connect"
 	"github.com/spf13/cobra"
@@ -66,11 +67,35 @@ func serviceInfoE(cmd *cobra.Command, args []string) error {
 		reason = " (" + resp.Msg.Reason + ")"
 	}
 	fmt.Printf("Response for deployment %q:\n  Name: %s (%s)\n  Output module: %s (%s)\n  Status: %v%s\n ", id, resp.Msg.PackageInfo.Name, resp.Msg.PackageInfo.Version, resp.Msg.PackageInfo.OutputModuleName, resp.Msg.PackageInfo.OutputModuleHash, resp.Msg.Status, reason)
-	if resp.Msg.Progress != nil {
-		fmt.Printf("Last processed block: %d\n", resp.Msg.Progress.LastProcessedBlock)
+	printSinkProgress(resp.Msg.Progress)
+	if pending := resp.Msg.PendingUpdate; pending != nil {
+		fmt.Printf("Pending update: %s (%s), started %s, switching over once caught up\n", pending.PackageInfo.GetName(), pending.PackageInfo.GetVersion(), pending.StartedAt.AsTime().Local().Format(time.DateTime))
+		printSinkProgress(pending.Progress)
+	}
+	if previous := resp.Msg.PreviousPackageInfo; previous != nil {
+		fmt.Printf("Previous package: %s (%s), use 'substreams alpha service rollback %s' to switch back to it\n", previous.Name, previous.Version, id)
 	}
 	fmt.Print(resp.Msg.Motd)
 	printServices(resp.Msg.Services)
 
 	return nil
 }
+
+func printSinkProgress(progress *pbsinksvc.SinkProgress) {
+	if progress == nil {
+		return
+	}
+	fmt.Printf("Last processed block: %d\n", progress.LastProcessedBlock)
+	if progress.HeadLagSeconds != 0 {
+		fmt.Printf("  Head lag: %s\n", time.Duration(progress.HeadLagSeconds*float64(time.Second)).Round(time.Second))
+	}
+	if progress.BlocksPerSecond != 0 {
+		fmt.Printf("  Blocks/sec: %.2f\n", progress.BlocksPerSecond)
+	}
+	if progress.LastCursor != "" {
+		fmt.Printf("  Last cursor: %s\n", progress.LastCursor)
+	}
+	if progress.LastError != "" {
+		fmt.Printf("  Last error: %s\n", progress.LastError)
+	}
+}
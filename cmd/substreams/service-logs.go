@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/spf13/cobra"
+	cli "github.com/streamingfast/cli"
+	"github.com/streamingfast/cli/sflags"
+	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
+	"github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1/pbsinksvcconnect"
+	server "github.com/streamingfast/substreams/sink-server"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func init() {
+	serviceCmd.AddCommand(logsCmd)
+	logsCmd.Flags().BoolP("follow", "f", false, "Keep printing the new lines until interrupted")
+	logsCmd.Flags().StringP("service", "s", "", "Only print the logs of this service (ex: 'sink', 'postgres'), all of them by default")
+	logsCmd.Flags().String("since", "", "Only print the lines logged since this time, either relative (ex: '10m', '2h') or absolute (RFC3339, ex: '2024-03-01T10:00:00Z')")
+	logsCmd.Flags().BoolP("timestamps", "t", false, "Print the time at which each line was logged, when known")
+}
+
+var logsCmd = &cobra.Command{
+	Use:   "logs [deployment-id]",
+	Short: "Print the logs of the services of a deployed substreams sink",
+	Long: cli.Dedent(`
+        Sends a "Logs" request to a server. By default, it will talk to a local "substreams alpha service serve" instance.
+        It prints the logs of all the services of the deployment, or of the one given with --service, and keeps printing
+        the new lines with --follow. Only the user that created the deployment can read its logs.
+        If deploymentID is not set or is incomplete, the CLI will try to guess (unless --strict is set).
+		`),
+	RunE:         logsE,
+	Args:         cobra.RangeArgs(0, 1),
+	SilenceUsage: true,
+}
+
+func logsE(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	var id string
+	if len(args) == 1 {
+		id = args[0]
+	}
+
+	cli := pbsinksvcconnect.NewProviderClient(http.DefaultClient, sflags.MustGetString(cmd, "endpoint"))
+	if len(id) < server.DeploymentIDLength {
+		if sflags.MustGetBool(cmd, "strict") {
+			return fmt.Errorf("invalid ID provided: %q and '--strict' is set", id)
+		}
+		matching, err := fuzzyMatchDeployment(ctx, id, cli, cmd, fuzzyMatchPreferredStatusOrder)
+		if err != nil {
+			return err
+		}
+		id = matching.Id
+	}
+
+	logsReq := &pbsinksvc.LogsRequest{
+		DeploymentId: id,
+		Service:      sflags.MustGetString(cmd, "service"),
+		Follow:       sflags.MustGetBool(cmd, "follow"),
+	}
+	if since := sflags.MustGetString(cmd, "since"); since != "" {
+		t, err := parseSince(since, time.Now())
+		if err != nil {
+			return err
+		}
+		logsReq.Since = timestamppb.New(t)
+	}
+
+	req := connect.NewRequest(logsReq)
+	if err := addHeaders(cmd, req); err != nil {
+		return err
+	}
+
+	stream, err := cli.Logs(ctx, req)
+	if err != nil {
+		return interceptConnectionError(err)
+	}
+	defer stream.Close()
+
+	timestamps := sflags.MustGetBool(cmd, "timestamps")
+	for stream.Receive() {
+		msg := stream.Msg()
+		var prefix string
+		if logsReq.Service == "" {
+			prefix = strings.TrimPrefix(msg.Service, id+"-") + " | "
+		}
+		if timestamps && msg.Timestamp != nil {
+			prefix += msg.Timestamp.AsTime().Local().Format(time.RFC3339) + " "
+		}
+		fmt.Println(prefix + msg.Line)
+	}
+	if err := stream.Err(); err != nil {
+		return interceptConnectionError(err)
+	}
+	return nil
+}
+
+// parseSince reads a duration before `now`, or an RFC3339 time.
+func parseSince(in string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(in); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, in); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q, expecting a duration (ex: '10m') or a RFC3339 time", in)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"connectrpc.com/connect"
+	"github.com/spf13/cobra"
+	cli "github.com/streamingfast/cli"
+	"github.com/streamingfast/cli/sflags"
+	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
+	"github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1/pbsinksvcconnect"
+	server "github.com/streamingfast/substreams/sink-server"
+)
+
+func init() {
+	serviceCmd.AddCommand(rollbackCmd)
+}
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback [deployment-id]",
+	Short: "Cancel the pending blue/green update of a service, or switch back to its previous package",
+	Long: cli.Dedent(`
+        Sends a "Rollback" request to a server. By default, it will talk to a local "substreams alpha service serve" instance.
+        If the deployment has a pending update (from "substreams alpha service update --blue-green"), it is cancelled and its data is dropped.
+        Otherwise, the deployment switches back to the package replaced by its last blue/green update, along with its data.
+        If deploymentID is not set or is incomplete, the CLI will try to guess (unless --strict is set).
+		`),
+	RunE:         rollbackE,
+	Args:         cobra.RangeArgs(0, 1),
+	SilenceUsage: true,
+}
+
+func rollbackE(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	var id string
+	if len(args) == 1 {
+		id = args[0]
+	}
+
+	cli := pbsinksvcconnect.NewProviderClient(http.DefaultClient, sflags.MustGetString(cmd, "endpoint"))
+	if len(id) < server.DeploymentIDLength {
+		if sflags.MustGetBool(cmd, "strict") {
+			return fmt.Errorf("invalid ID provided: %q and '--strict' is set", id)
+		}
+		matching, err := fuzzyMatchDeployment(ctx, id, cli, cmd, fuzzyMatchPreferredStatusOrder)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Found deployment %q (%s-%s) from 'fuzzy search'. Do you really want to roll it back ? (y/n): ", matching.Id, matching.PackageInfo.Name, matching.PackageInfo.Version)
+		if !userConfirm() {
+			return fmt.Errorf("cancelled by user")
+		}
+		id = matching.Id
+	}
+
+	req := connect.NewRequest(&pbsinksvc.RollbackRequest{
+		DeploymentId: id,
+	})
+	if err := addHeaders(cmd, req); err != nil {
+		return err
+	}
+
+	fmt.Printf("Rolling back... (restarting the sink, please wait)\n")
+	resp, err := cli.Rollback(ctx, req)
+	if err != nil {
+		return interceptConnectionError(err)
+	}
+	if resp.Msg.CancelledPendingUpdate {
+		fmt.Printf("Pending update of deployment %q cancelled, still running %s (%s).\n", id, resp.Msg.PackageInfo.GetName(), resp.Msg.PackageInfo.GetVersion())
+		return nil
+	}
+	fmt.Printf("Deployment %q rolled back to %s (%s).\n", id, resp.Msg.PackageInfo.GetName(), resp.Msg.PackageInfo.GetVersion())
+
+	return nil
+}
@@ -5,16 +5,22 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"time"
 
 	"github.com/streamingfast/dauth"
 	dauthnull "github.com/streamingfast/dauth/null"
 	"github.com/streamingfast/substreams/sink-server/docker"
+	"github.com/streamingfast/substreams/sink-server/kubernetes"
+	"github.com/streamingfast/substreams/sink-server/process"
+	"github.com/streamingfast/substreams/sink-server/resources"
+	"github.com/streamingfast/substreams/sink-server/secrets"
 
 	"github.com/spf13/cobra"
 	"github.com/streamingfast/cli"
 	"github.com/streamingfast/cli/sflags"
 	"github.com/streamingfast/derr"
 	server "github.com/streamingfast/substreams/sink-server"
+	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
@@ -25,17 +31,51 @@ func init() {
 	serveCmd.Flags().String("data-dir", "./sink-data", "Store data to this folder")
 	serveCmd.Flags().String("listen-addr", "localhost:8000", "Listen for GRPC connections on this address")
 	serveCmd.Flags().String("cors-host-regex-allow", "^localhost", "Regex to allow CORS origin requests from, defaults to localhost only")
-	serveCmd.Flags().String("engine", "docker", "Engine to use for deployments, defaults to docker")
+	serveCmd.Flags().String("secrets-key-file", "", "File holding the 32 bytes key encrypting the credentials of the docker engine deployments, kept outside of '--data-dir'. The SUBSTREAMS_SECRETS_KEY environment variable can give it as 64 hex characters instead")
+	serveCmd.Flags().String("engine", "docker", "Engine to use for deployments, one of 'docker', 'process' or 'kubernetes', defaults to docker")
+	serveCmd.Flags().String("process-sink-binary", "substreams-sink-sql", "Path of the substreams-sink-sql binary run by the process engine")
+	serveCmd.Flags().String("process-dsn", "", "Database DSN used by the sinks of the process engine, an embedded PostgreSQL is started for each deployment if empty")
+	serveCmd.Flags().String("process-postgres-bin-dir", "", "Directory containing the 'initdb' and 'postgres' binaries of the process engine embedded PostgreSQL, looked up in the PATH if empty")
 	serveCmd.Flags().String("kubernetes-config-path", "", "Path to the kubeconfig file for kubernetes engine. If empty, will use InClusterConfig")
 	serveCmd.Flags().String("kubernetes-namespace", "hosted-substreams-sinks", "Namespace to use for kubernetes engine")
+	serveCmd.Flags().String("kubernetes-storage-size", "10Gi", "Size of the database volume of each deployment of the kubernetes engine")
+	serveCmd.Flags().Int("max-deployments-per-owner", 1, "Maximum number of deployments starting, running, paused or failing at the same time for each owner, 0 for no limit")
+	serveCmd.Flags().String("sizes-config", "", "YAML file defining the size tiers of the deployments (CPU and memory limits of their database, sink and other services), replacing the built-in 'small', 'medium' and 'large' ones")
+	serveCmd.Flags().String("default-size", "medium", "Size tier of the deployments that do not request one with the 'SF_SIZE' deployment parameter, empty to leave them unlimited")
+	serveCmd.Flags().Duration("watch-interval", 30*time.Second, "How often the status and progress of the deployments are checked, to send the events of their changes")
+	serveCmd.Flags().Duration("stall-timeout", 15*time.Minute, "Send a 'stalled' event when a running sink did not process any block for this long, 0 to disable")
+	serveCmd.Flags().StringArray("webhook-url", nil, "URL receiving the events of the deployments as JSON POST requests, signed with the SUBSTREAMS_WEBHOOK_SECRET environment variable when set (can be repeated)")
 	dauthnull.Register()
 }
 
 var serveCmd = &cobra.Command{
 	Use:   "serve",
-	Short: "Serve local service deployments using docker-compose",
+	Short: "Serve service deployments using docker-compose, local processes or kubernetes",
 	Long: cli.Dedent(`
         Listens for "deploy" requests, allowing you to test your deployable units to a local docker-based dev environment.
+        The docker engine runs a single active deployment at a time, as they all publish the same host ports.
+        The credentials it generates for the deployments are encrypted with the key of '--secrets-key-file' or
+        of the SUBSTREAMS_SECRETS_KEY environment variable, or with a key generated in '--data-dir' if none is given.
+
+        Use '--engine=process' to run the sinks as local processes instead, without Docker: the substreams-sink-sql
+        binary (see '--process-sink-binary') is run against the database given by '--process-dsn', or against an
+        embedded PostgreSQL started for each deployment. Processes are restarted when they fail, pausing a deployment
+        suspends its sink process, and their output is written to the 'logs' folder of the deployment.
+
+        Use '--engine=kubernetes' to run each deployment as objects of the '--kubernetes-namespace' namespace: a
+        StatefulSet for the database, a Deployment for the sink and a Deployment with a Service for each frontend.
+        Pausing a deployment scales its sink to 0, stopping it scales everything to 0 while keeping the database volume.
+
+        The services of each deployment are limited in CPU and memory by its size tier, requested with the 'SF_SIZE'
+        deployment parameter (see 'service deploy --size') or '--default-size' otherwise. The process engine does not
+        apply these limits. Each owner can only have '--max-deployments-per-owner' deployments starting, running,
+        paused or failing at the same time: without authentication, this applies to all the deployments of the server.
+
+        The deployments are checked every '--watch-interval': an event is sent when one changes status (ex: RUNNING to
+        FAILING), or when its sink has not processed any block for '--stall-timeout'. The events are streamed to the
+        clients of the 'WatchDeployments' RPC, and posted to each '--webhook-url' with retries. The requests are signed
+        with an HMAC-SHA256 of '<X-Substreams-Timestamp header>.<body>' using the SUBSTREAMS_WEBHOOK_SECRET environment
+        variable, given in the 'X-Substreams-Signature' header as 'sha256=<hex>'.
 	`),
 	PersistentPreRun: func(cmd *cobra.Command, _ []string) {
 		setup(cmd, zapcore.InfoLevel)
@@ -74,12 +114,36 @@ func serveE(cmd *cobra.Command, args []string) error {
 	var err error
 	switch engineType {
 	case "docker":
-		engine, err = docker.NewEngine(dataDir, token, endpoint)
+		secretsKey, err := secrets.LoadKey(os.Getenv("SUBSTREAMS_SECRETS_KEY"), sflags.MustGetString(cmd, "secrets-key-file"))
+		if err != nil {
+			return err
+		}
+		if secretsKey == nil {
+			zlog.Warn("no secrets key given with --secrets-key-file or SUBSTREAMS_SECRETS_KEY, the credentials of the deployments are encrypted with a key stored next to them in the data dir", zap.String("data_dir", dataDir))
+		}
+		engine, err = docker.NewEngine(dataDir, token, endpoint, secretsKey)
+		if err != nil {
+			return err
+		}
+	case "process":
+		engine, err = process.NewEngine(dataDir, token, endpoint, process.Config{
+			SinkBinary:     sflags.MustGetString(cmd, "process-sink-binary"),
+			DSN:            sflags.MustGetString(cmd, "process-dsn"),
+			PostgresBinDir: sflags.MustGetString(cmd, "process-postgres-bin-dir"),
+		})
+		if err != nil {
+			return err
+		}
+	case "kubernetes":
+		engine, err = kubernetes.NewEngine(sflags.MustGetString(cmd, "kubernetes-config-path"), kubernetes.Config{
+			Namespace:   sflags.MustGetString(cmd, "kubernetes-namespace"),
+			StorageSize: sflags.MustGetString(cmd, "kubernetes-storage-size"),
+		}, token, endpoint)
 		if err != nil {
 			return err
 		}
 	default:
-		return fmt.Errorf("unsupported engine: %q", engine)
+		return fmt.Errorf("unsupported engine: %q", engineType)
 	}
 
 	// local service serve does not support auth, so we disable by using null://
@@ -88,7 +152,26 @@ func serveE(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	srv, err := server.New(ctx, engine, dataDir, listenAddr, cors, auth, zlog)
+	limits := server.Limits{
+		MaxDeploymentsPerOwner: sflags.MustGetInt(cmd, "max-deployments-per-owner"),
+		Sizes:                  resources.DefaultSizes,
+		DefaultSize:            sflags.MustGetString(cmd, "default-size"),
+	}
+	if path := sflags.MustGetString(cmd, "sizes-config"); path != "" {
+		limits.Sizes, err = resources.LoadSizes(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	notifications := server.Notifications{
+		CheckInterval: sflags.MustGetDuration(cmd, "watch-interval"),
+		StallTimeout:  sflags.MustGetDuration(cmd, "stall-timeout"),
+		Webhooks:      sflags.MustGetStringArray(cmd, "webhook-url"),
+		WebhookSecret: os.Getenv("SUBSTREAMS_WEBHOOK_SECRET"),
+	}
+
+	srv, err := server.New(ctx, engine, dataDir, listenAddr, cors, auth, limits, notifications, zlog)
 	if err != nil {
 		return fmt.Errorf("initializing server: %w", err)
 	}
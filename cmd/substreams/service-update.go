@@ -17,6 +17,7 @@ import (
 func init() {
 	serviceCmd.AddCommand(updateCmd)
 	updateCmd.Flags().BoolP("reset", "r", false, "Reset the deployment by DELETING ALL ITS DATA")
+	updateCmd.Flags().Bool("blue-green", false, "Deploy the package next to the current one, switching over to it once it has caught up with the chain head (see 'service rollback')")
 }
 
 var updateCmd = &cobra.Command{
@@ -25,6 +26,8 @@ var updateCmd = &cobra.Command{
 	Long: cli.Dedent(`
         Sends a "update" request to a server. By default, it will talk to a local "substreams alpha service serve" instance.
         The substreams must contain a "SinkConfig" section to be deployable.
+        With --blue-green, the current package keeps serving until the new one has caught up with the chain head,
+        use "substreams alpha service info" to follow its progress and "substreams alpha service rollback" to cancel it.
         If deploymentID is not set or is incomplete, the CLI will try to guess (unless --strict is set).
      	`),
 	RunE:         updateE,
@@ -67,11 +70,16 @@ func updateE(cmd *cobra.Command, args []string) error {
 	}
 
 	reset := sflags.MustGetBool(cmd, "reset")
+	blueGreen := sflags.MustGetBool(cmd, "blue-green")
+	if reset && blueGreen {
+		return fmt.Errorf("cannot use --reset with --blue-green")
+	}
 
 	req := connect.NewRequest(&pbsinksvc.UpdateRequest{
 		SubstreamsPackage: pkg,
 		DeploymentId:      id,
 		Reset_:            reset,
+		BlueGreen:         blueGreen,
 	})
 
 	deletingString := ""
@@ -83,11 +91,19 @@ func updateE(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	fmt.Printf("Updating service %q... (restarting services,%s please wait)\n", id, deletingString)
+	if blueGreen {
+		fmt.Printf("Updating service %q... (starting the new sink next to the current one, please wait)\n", id)
+	} else {
+		fmt.Printf("Updating service %q... (restarting services,%s please wait)\n", id, deletingString)
+	}
 	resp, err := cli.Update(ctx, req)
 	if err != nil {
 		return interceptConnectionError(err)
 	}
+	if blueGreen {
+		fmt.Printf("Update of service %q started: the current package keeps serving until the new one has caught up.\nUse 'substreams alpha service info %s' to follow its progress.\n", id, id)
+		return nil
+	}
 
 	reason := ""
 	if resp.Msg.Reason != "" {
@@ -14,6 +14,7 @@ import (
 func setup(cmd *cobra.Command, loglevel zapcore.Level) {
 	setupProfiler()
 	manifest.IPFSURL = mustGetString(cmd, "ipfs-url")
+	manifest.RegistryURL = mustGetString(cmd, "registry-url")
 	logging.InstantiateLoggers(logging.WithLogLevelSwitcherServerAutoStart(), logging.WithDefaultLevel(loglevel))
 }
 
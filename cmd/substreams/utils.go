@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"github.com/spf13/cobra"
+	"github.com/streamingfast/substreams/manifest"
 	"strconv"
 	"strings"
 )
@@ -54,3 +55,21 @@ func readStopBlockFlag(cmd *cobra.Command, startBlock int64, flagName string, wi
 
 	return endBlock, nil
 }
+
+// readTrustedSignersFlag returns the reader option requiring packages to be signed by one of the
+// keys of `flagName`, or nil when the flag is not set.
+func readTrustedSignersFlag(cmd *cobra.Command, flagName string) (manifest.Option, error) {
+	val, err := cmd.Flags().GetStringSlice(flagName)
+	if err != nil {
+		panic(fmt.Sprintf("flags: couldn't find flag %q", flagName))
+	}
+	if len(val) == 0 {
+		return nil, nil
+	}
+
+	keys, err := manifest.ParsePublicKeys(val)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", flagName, err)
+	}
+	return manifest.WithTrustedSigners(keys), nil
+}
@@ -0,0 +1,229 @@
+package info
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/streamingfast/substreams/manifest"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"github.com/streamingfast/substreams/pipeline/outputmodules"
+)
+
+// GraphInfo is the module graph of a package, as rendered by `substreams graph`.
+type GraphInfo struct {
+	Name  string       `json:"name"`
+	Nodes []*GraphNode `json:"nodes"`
+	Edges []*GraphEdge `json:"edges"`
+}
+
+type GraphNode struct {
+	Name         string  `json:"name"`
+	Kind         string  `json:"kind"` // map, store, blockIndex or source
+	InitialBlock *uint64 `json:"initial_block,omitempty"`
+	Hash         string  `json:"hash,omitempty"`
+	Stage        *int    `json:"stage,omitempty"` // index of the execution stage of the module, when it is the output module
+	HasParams    bool    `json:"has_params,omitempty"`
+	Depth        int     `json:"-"`
+}
+
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Mode string `json:"mode,omitempty"` // get or deltas for store inputs, blockFilter for the module a module is filtered on
+}
+
+// Graph returns the module graph of `pkg`, each module annotated with its kind, initial block, module hash
+// and execution stage, and each store input with its mode.
+func Graph(pkg *pbsubstreams.Package, graph *manifest.ModuleGraph) (*GraphInfo, error) {
+	out := &GraphInfo{Name: "Unnamed"}
+	if len(pkg.PackageMeta) != 0 {
+		out.Name = pkg.PackageMeta[0].Name
+	}
+
+	hashes := manifest.NewModuleHashes()
+	nodes := map[string]*GraphNode{}
+	for _, mod := range pkg.Modules.Modules {
+		hash, err := hashes.HashModule(pkg.Modules, mod, graph)
+		if err != nil {
+			return nil, fmt.Errorf("hashing module %q: %w", mod.Name, err)
+		}
+
+		stage, err := moduleStage(pkg.Modules, mod.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		initialBlock := mod.InitialBlock
+		node := &GraphNode{
+			Name:         mod.Name,
+			InitialBlock: &initialBlock,
+			Hash:         hex.EncodeToString(hash),
+			Stage:        &stage,
+		}
+		switch mod.Kind.(type) {
+		case *pbsubstreams.Module_KindMap_:
+			node.Kind = "map"
+		case *pbsubstreams.Module_KindStore_:
+			node.Kind = "store"
+		case *pbsubstreams.Module_KindBlockIndex_:
+			node.Kind = "blockIndex"
+		}
+		nodes[mod.Name] = node
+		out.Nodes = append(out.Nodes, node)
+	}
+
+	for _, mod := range pkg.Modules.Modules {
+		for _, in := range mod.Inputs {
+			switch input := in.Input.(type) {
+			case *pbsubstreams.Module_Input_Source_:
+				if nodes[input.Source.Type] == nil {
+					source := &GraphNode{Name: input.Source.Type, Kind: "source"}
+					nodes[source.Name] = source
+					out.Nodes = append(out.Nodes, source)
+				}
+				out.Edges = append(out.Edges, &GraphEdge{From: input.Source.Type, To: mod.Name})
+			case *pbsubstreams.Module_Input_Map_:
+				out.Edges = append(out.Edges, &GraphEdge{From: input.Map.ModuleName, To: mod.Name})
+			case *pbsubstreams.Module_Input_Store_:
+				mode := "get"
+				if input.Store.Mode == pbsubstreams.Module_Input_Store_DELTAS {
+					mode = "deltas"
+				}
+				out.Edges = append(out.Edges, &GraphEdge{From: input.Store.ModuleName, To: mod.Name, Mode: mode})
+			case *pbsubstreams.Module_Input_Params_:
+				nodes[mod.Name].HasParams = true
+			}
+		}
+		if filter := mod.GetBlockFilter(); filter != nil {
+			out.Edges = append(out.Edges, &GraphEdge{From: filter.Module, To: mod.Name, Mode: "blockFilter"})
+		}
+	}
+
+	computeDepths(out, nodes)
+	return out, nil
+}
+
+// moduleStage returns the index of the execution stage of module `name` when it is the output module.
+func moduleStage(modules *pbsubstreams.Modules, name string) (int, error) {
+	outputGraph, err := outputmodules.NewOutputModuleGraph(name, true, modules)
+	if err != nil {
+		return 0, fmt.Errorf("creating output module graph of %q: %w", name, err)
+	}
+
+	for idx, stage := range outputGraph.StagedUsedModules() {
+		for _, layer := range stage {
+			for _, mod := range layer {
+				if mod.Name == name {
+					return idx, nil
+				}
+			}
+		}
+	}
+	return 0, fmt.Errorf("module %q not found in its execution stages", name)
+}
+
+// computeDepths sets the depth of each node: 0 for sources, one more than the deepest of their inputs for
+// modules.
+func computeDepths(g *GraphInfo, nodes map[string]*GraphNode) {
+	parents := map[string][]string{}
+	for _, edge := range g.Edges {
+		parents[edge.To] = append(parents[edge.To], edge.From)
+	}
+
+	done := map[string]bool{}
+	var depth func(name string) int
+	depth = func(name string) int {
+		node := nodes[name]
+		if done[name] {
+			return node.Depth
+		}
+		done[name] = true // the module graph is acyclic, guards against invalid packages only
+		for _, parent := range parents[name] {
+			if nodes[parent] == nil {
+				continue
+			}
+			if d := depth(parent) + 1; d > node.Depth {
+				node.Depth = d
+			}
+		}
+		return node.Depth
+	}
+	for _, node := range g.Nodes {
+		depth(node.Name)
+	}
+}
+
+func (n *GraphNode) annotations() (out []string) {
+	if n.Kind == "source" {
+		return []string{"source"}
+	}
+
+	kind := n.Kind
+	if n.HasParams {
+		kind += " (params)"
+	}
+	out = append(out, kind)
+	if n.InitialBlock != nil {
+		out = append(out, fmt.Sprintf("initial block: %d", *n.InitialBlock))
+	}
+	if n.Stage != nil {
+		out = append(out, fmt.Sprintf("stage: %d", *n.Stage))
+	}
+	if n.Hash != "" {
+		out = append(out, "hash: "+shortHash(n.Hash))
+	}
+	return out
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+// DOT renders the graph in the Graphviz DOT language.
+func (g *GraphInfo) DOT() string {
+	var str strings.Builder
+	fmt.Fprintf(&str, "digraph %s {\n", dotQuote(g.Name))
+	str.WriteString("  rankdir=TB;\n")
+	str.WriteString("  node [shape=box, style=rounded, fontname=\"Helvetica\"];\n")
+	str.WriteString("  edge [fontname=\"Helvetica\", fontsize=10];\n")
+
+	for _, node := range g.Nodes {
+		label := strings.Join(append([]string{node.Name}, node.annotations()...), "\n")
+		attrs := fmt.Sprintf("label=%s", dotQuote(label))
+		switch node.Kind {
+		case "source":
+			attrs += ", shape=ellipse"
+		case "store":
+			attrs += ", shape=cylinder, style=solid"
+		case "blockIndex":
+			attrs += ", shape=hexagon, style=solid"
+		}
+		fmt.Fprintf(&str, "  %s [%s];\n", dotQuote(node.Name), attrs)
+	}
+
+	for _, edge := range g.Edges {
+		var attrs string
+		switch edge.Mode {
+		case "":
+		case "blockFilter":
+			attrs = fmt.Sprintf(" [label=%s, style=dashed]", dotQuote(edge.Mode))
+		default:
+			attrs = fmt.Sprintf(" [label=%s]", dotQuote(edge.Mode))
+		}
+		fmt.Fprintf(&str, "  %s -> %s%s;\n", dotQuote(edge.From), dotQuote(edge.To), attrs)
+	}
+
+	str.WriteString("}\n")
+	return str.String()
+}
+
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,154 @@
+package info
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+)
+
+const (
+	svgCharWidth   = 7
+	svgLineHeight  = 16
+	svgNodePadding = 10
+	svgHorizGap    = 40
+	svgVertGap     = 60
+	svgMargin      = 20
+)
+
+var svgKindColors = map[string]string{
+	"source":     "#eeeeee",
+	"map":        "#dbeafe",
+	"store":      "#fde68a",
+	"blockIndex": "#d1fae5",
+}
+
+type svgBox struct {
+	node          *GraphNode
+	lines         []string
+	x, y          int
+	width, height int
+}
+
+// SVG renders the graph as a standalone SVG document, laid out offline: nodes are placed in layers by
+// depth, sources at the top, and ordered in each layer by the mean position of their inputs.
+func (g *GraphInfo) SVG() string {
+	layers := map[int][]*svgBox{}
+	boxes := map[string]*svgBox{}
+	maxDepth := 0
+	for _, node := range g.Nodes {
+		box := &svgBox{node: node, lines: append([]string{node.Name}, node.annotations()...)}
+		for _, line := range box.lines {
+			if w := len(line)*svgCharWidth + 2*svgNodePadding; w > box.width {
+				box.width = w
+			}
+		}
+		box.height = len(box.lines)*svgLineHeight + 2*svgNodePadding
+		boxes[node.Name] = box
+		layers[node.Depth] = append(layers[node.Depth], box)
+		if node.Depth > maxDepth {
+			maxDepth = node.Depth
+		}
+	}
+
+	parents := map[string][]string{}
+	for _, edge := range g.Edges {
+		parents[edge.To] = append(parents[edge.To], edge.From)
+	}
+
+	// Order each layer by the barycenter of the inputs in the layers above, then size the layers
+	positions := map[string]float64{}
+	layerWidths := make([]int, maxDepth+1)
+	for depth := 0; depth <= maxDepth; depth++ {
+		layer := layers[depth]
+		if depth > 0 {
+			barycenters := map[string]float64{}
+			for idx, box := range layer {
+				sum, count := 0.0, 0
+				for _, parent := range parents[box.node.Name] {
+					if pos, found := positions[parent]; found {
+						sum += pos
+						count++
+					}
+				}
+				barycenters[box.node.Name] = float64(idx)
+				if count != 0 {
+					barycenters[box.node.Name] = sum / float64(count)
+				}
+			}
+			sort.SliceStable(layer, func(i, j int) bool {
+				return barycenters[layer[i].node.Name] < barycenters[layer[j].node.Name]
+			})
+		}
+		for idx, box := range layer {
+			positions[box.node.Name] = float64(idx) - float64(len(layer)-1)/2
+			layerWidths[depth] += box.width
+		}
+		layerWidths[depth] += (len(layer) - 1) * svgHorizGap
+	}
+
+	totalWidth := 0
+	for _, width := range layerWidths {
+		if width > totalWidth {
+			totalWidth = width
+		}
+	}
+
+	y := svgMargin
+	for depth := 0; depth <= maxDepth; depth++ {
+		x := svgMargin + (totalWidth-layerWidths[depth])/2
+		layerHeight := 0
+		for _, box := range layers[depth] {
+			box.x, box.y = x, y
+			x += box.width + svgHorizGap
+			if box.height > layerHeight {
+				layerHeight = box.height
+			}
+		}
+		y += layerHeight + svgVertGap
+	}
+	totalHeight := y - svgVertGap + svgMargin
+
+	var str strings.Builder
+	fmt.Fprintf(&str, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="Helvetica, Arial, sans-serif" font-size="12">`+"\n", totalWidth+2*svgMargin, totalHeight, totalWidth+2*svgMargin, totalHeight)
+	fmt.Fprintf(&str, "  <title>%s</title>\n", html.EscapeString(g.Name))
+	str.WriteString(`  <defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" orient="auto-start-reverse"><path d="M 0 0 L 10 5 L 0 10 z" fill="#555555"/></marker></defs>` + "\n")
+
+	for _, edge := range g.Edges {
+		from, to := boxes[edge.From], boxes[edge.To]
+		if from == nil || to == nil {
+			continue
+		}
+		x1, y1 := from.x+from.width/2, from.y+from.height
+		x2, y2 := to.x+to.width/2, to.y
+		dash := ""
+		if edge.Mode == "blockFilter" {
+			dash = ` stroke-dasharray="5,3"`
+		}
+		fmt.Fprintf(&str, `  <line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#555555"%s marker-end="url(#arrow)"/>`+"\n", x1, y1, x2, y2, dash)
+		if edge.Mode != "" {
+			fmt.Fprintf(&str, `  <text x="%d" y="%d" font-size="10" fill="#555555" text-anchor="middle">%s</text>`+"\n", (x1+x2)/2, (y1+y2)/2, html.EscapeString(edge.Mode))
+		}
+	}
+
+	for _, node := range g.Nodes {
+		box := boxes[node.Name]
+		radius := 6
+		if node.Kind == "source" {
+			radius = box.height / 2
+		}
+		fmt.Fprintf(&str, `  <g class="%s">`+"\n", html.EscapeString(node.Kind))
+		fmt.Fprintf(&str, `    <rect x="%d" y="%d" width="%d" height="%d" rx="%d" fill="%s" stroke="#333333"/>`+"\n", box.x, box.y, box.width, box.height, radius, svgKindColors[node.Kind])
+		for idx, line := range box.lines {
+			weight := ""
+			if idx == 0 {
+				weight = ` font-weight="bold"`
+			}
+			fmt.Fprintf(&str, `    <text x="%d" y="%d" text-anchor="middle"%s>%s</text>`+"\n", box.x+box.width/2, box.y+svgNodePadding+(idx+1)*svgLineHeight-4, weight, html.EscapeString(line))
+		}
+		str.WriteString("  </g>\n")
+	}
+
+	str.WriteString("</svg>\n")
+	return str.String()
+}
@@ -0,0 +1,102 @@
+package info
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/streamingfast/substreams/manifest"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestGraph(t *testing.T) {
+	source := &pbsubstreams.Module_Input{Input: &pbsubstreams.Module_Input_Source_{Source: &pbsubstreams.Module_Input_Source{Type: "sf.test.Block"}}}
+	pkg := &pbsubstreams.Package{
+		PackageMeta: []*pbsubstreams.PackageMetadata{{Name: "test"}},
+		Modules: &pbsubstreams.Modules{
+			Binaries: []*pbsubstreams.Binary{{Type: "wasm/rust-v1", Content: []byte("code")}},
+			Modules: []*pbsubstreams.Module{
+				{
+					Name:         "map_a",
+					InitialBlock: 10,
+					Kind:         &pbsubstreams.Module_KindMap_{KindMap: &pbsubstreams.Module_KindMap{OutputType: "proto:test.A"}},
+					Inputs: []*pbsubstreams.Module_Input{
+						{Input: &pbsubstreams.Module_Input_Params_{Params: &pbsubstreams.Module_Input_Params{Value: "x"}}},
+						source,
+					},
+				},
+				{
+					Name:         "store_b",
+					InitialBlock: 10,
+					Kind:         &pbsubstreams.Module_KindStore_{KindStore: &pbsubstreams.Module_KindStore{ValueType: "int64", UpdatePolicy: pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD}},
+					Inputs:       []*pbsubstreams.Module_Input{{Input: &pbsubstreams.Module_Input_Map_{Map: &pbsubstreams.Module_Input_Map{ModuleName: "map_a"}}}},
+				},
+				{
+					Name:         "map_c",
+					InitialBlock: 20,
+					Kind:         &pbsubstreams.Module_KindMap_{KindMap: &pbsubstreams.Module_KindMap{OutputType: "proto:test.C"}},
+					Inputs: []*pbsubstreams.Module_Input{
+						source,
+						{Input: &pbsubstreams.Module_Input_Store_{Store: &pbsubstreams.Module_Input_Store{ModuleName: "store_b", Mode: pbsubstreams.Module_Input_Store_DELTAS}}},
+						{Input: &pbsubstreams.Module_Input_Store_{Store: &pbsubstreams.Module_Input_Store{ModuleName: "store_b", Mode: pbsubstreams.Module_Input_Store_GET}}},
+					},
+				},
+			},
+		},
+	}
+	graph, err := manifest.NewModuleGraph(pkg.Modules.Modules)
+	require.NoError(t, err)
+
+	g, err := Graph(pkg, graph)
+	require.NoError(t, err)
+
+	type node struct {
+		name, kind string
+		stage      int
+		depth      int
+	}
+	var nodes []node
+	for _, n := range g.Nodes {
+		stage := -1
+		if n.Stage != nil {
+			stage = *n.Stage
+			assert.Len(t, n.Hash, 40)
+		}
+		nodes = append(nodes, node{n.Name, n.Kind, stage, n.Depth})
+	}
+	assert.Equal(t, []node{
+		{"map_a", "map", 0, 1},
+		{"store_b", "store", 0, 2},
+		{"map_c", "map", 1, 3},
+		{"sf.test.Block", "source", -1, 0},
+	}, nodes)
+	assert.True(t, g.Nodes[0].HasParams)
+	assert.Equal(t, []*GraphEdge{
+		{From: "sf.test.Block", To: "map_a"},
+		{From: "map_a", To: "store_b"},
+		{From: "sf.test.Block", To: "map_c"},
+		{From: "store_b", To: "map_c", Mode: "deltas"},
+		{From: "store_b", To: "map_c", Mode: "get"},
+	}, g.Edges)
+
+	dot := g.DOT()
+	assert.Contains(t, dot, `digraph "test" {`)
+	assert.Contains(t, dot, `"map_c" [label="map_c\nmap\ninitial block: 20\nstage: 1\nhash: `+g.Nodes[2].Hash[:12]+`"];`)
+	assert.Contains(t, dot, `"sf.test.Block" [label="sf.test.Block\nsource", shape=ellipse];`)
+	assert.Contains(t, dot, `"store_b" -> "map_c" [label="deltas"];`)
+
+	svg := g.SVG()
+	decoder := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		_, err := decoder.Token()
+		if err != nil {
+			assert.Equal(t, "EOF", err.Error())
+			break
+		}
+	}
+	assert.Contains(t, svg, `>map (params)</text>`)
+	assert.Equal(t, 5, strings.Count(svg, "<line "))
+}
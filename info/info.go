@@ -69,9 +69,18 @@ type ModulesInfo struct {
 	UpdatePolicy  *string       `json:"update_policy,omitempty"` //for store inputs
 	InitialBlock  uint64        `json:"initial_block"`
 	Documentation *string       `json:"documentation,omitempty"`
+	ParamsSchema  *ParamsSchema `json:"params_schema,omitempty"`
 	Hash          string        `json:"hash"`
 }
 
+type ParamsSchema struct {
+	Type    string   `json:"type"`
+	Regex   string   `json:"regex,omitempty"`
+	Enum    []string `json:"enum,omitempty"`
+	Default string   `json:"default,omitempty"`
+	Doc     string   `json:"doc,omitempty"`
+}
+
 type ModuleInput struct {
 	Type string  `json:"type"`
 	Name string  `json:"name"`
@@ -150,6 +159,15 @@ func Basic(pkg *pbsubstreams.Package, graph *manifest.ModuleGraph) (*BasicInfo,
 			if modMeta != nil && modMeta.Doc != "" {
 				modInfo.Documentation = strPtr(strings.Replace(modMeta.Doc, "\n", "\n  ", -1))
 			}
+			if schema := modMeta.GetParamsSchema(); schema != nil {
+				modInfo.ParamsSchema = &ParamsSchema{
+					Type:    strings.ToLower(strings.TrimPrefix(schema.Type.String(), "TYPE_")),
+					Regex:   schema.Regex,
+					Enum:    schema.EnumValues,
+					Default: schema.DefaultValue,
+					Doc:     schema.Doc,
+				}
+			}
 		}
 
 		inputs := make([]ModuleInput, 0, len(mod.Inputs))
@@ -0,0 +1,285 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Breaking change rules reported by CheckOutputCompatibility, named after their buf equivalents.
+const (
+	RuleOutputSameType                  = "OUTPUT_SAME_TYPE"
+	RulePackageSameName                 = "PACKAGE_SAME_NAME"
+	RuleMessageNoDelete                 = "MESSAGE_NO_DELETE"
+	RuleEnumNoDelete                    = "ENUM_NO_DELETE"
+	RuleFieldNoDeleteUnlessReserved     = "FIELD_NO_DELETE_UNLESS_NUMBER_RESERVED"
+	RuleFieldNoDeleteRequired           = "FIELD_NO_DELETE_REQUIRED"
+	RuleFieldNoAddRequired              = "FIELD_NO_ADD_REQUIRED"
+	RuleFieldNumberReused               = "FIELD_NUMBER_REUSED"
+	RuleFieldSameName                   = "FIELD_SAME_NAME"
+	RuleFieldSameType                   = "FIELD_SAME_TYPE"
+	RuleFieldSameLabel                  = "FIELD_SAME_LABEL"
+	RuleFieldSameOneof                  = "FIELD_SAME_ONEOF"
+	RuleEnumValueNoDeleteUnlessReserved = "ENUM_VALUE_NO_DELETE_UNLESS_NUMBER_RESERVED"
+	RuleEnumValueSameName               = "ENUM_VALUE_SAME_NAME"
+)
+
+// CompatibilityIssue is a change to the output type of a module that breaks the consumers of its
+// outputs, like sinks decoding them with the old proto definitions.
+type CompatibilityIssue struct {
+	Module  string `json:"module"`
+	Rule    string `json:"rule"`
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+func (i *CompatibilityIssue) String() string {
+	return fmt.Sprintf("module %q: %s: %s", i.Module, i.Rule, i.Message)
+}
+
+// CheckOutputCompatibility compares the output type of each module of `oldPkg` with the one of the module
+// of the same name in `newPkg`, using the `proto_files` of each package, and returns the breaking changes
+// found in the output messages and in the messages and enums they reference. Modules missing from
+// `newPkg` and non-proto output types are not checked.
+func CheckOutputCompatibility(oldPkg, newPkg *pbsubstreams.Package) []*CompatibilityIssue {
+	oldTypes := indexPackageProtoTypes(oldPkg)
+	newTypes := indexPackageProtoTypes(newPkg)
+	newModules := modulesByName(newPkg)
+
+	var out []*CompatibilityIssue
+	for _, oldMod := range oldPkg.Modules.GetModules() {
+		newMod, found := newModules[oldMod.Name]
+		if !found {
+			continue
+		}
+		out = append(out, checkModuleOutput(oldMod, newMod, oldTypes, newTypes)...)
+	}
+
+	return out
+}
+
+// checkModuleOutput returns the breaking changes of the output type of `newMod` compared to the one of
+// `oldMod`, which can have another name, using the proto types of their packages.
+func checkModuleOutput(oldMod, newMod *pbsubstreams.Module, oldTypes, newTypes *protoTypes) []*CompatibilityIssue {
+	oldOutput, newOutput := moduleOutputType(oldMod), moduleOutputType(newMod)
+	if !strings.HasPrefix(oldOutput, "proto:") {
+		return nil
+	}
+	if !strings.HasPrefix(newOutput, "proto:") {
+		return []*CompatibilityIssue{{Module: oldMod.Name, Rule: RuleOutputSameType, Type: oldOutput, Message: fmt.Sprintf("output type changed from %q to %q", oldOutput, newOutput)}}
+	}
+
+	checker := &compatChecker{module: oldMod.Name, oldTypes: oldTypes, newTypes: newTypes, seen: map[[2]string]bool{}}
+	oldName, newName := strings.TrimPrefix(oldOutput, "proto:"), strings.TrimPrefix(newOutput, "proto:")
+	if oldName != newName && !checker.checkTypeName(oldName, oldName, newName) {
+		checker.report(RuleOutputSameType, oldName, "output type changed from %q to %q", oldOutput, newOutput)
+	}
+	checker.run(oldName, newName)
+	return checker.issues
+}
+
+type compatChecker struct {
+	module   string
+	oldTypes *protoTypes
+	newTypes *protoTypes
+
+	seen   map[[2]string]bool
+	queue  [][2]string
+	issues []*CompatibilityIssue
+}
+
+func (c *compatChecker) report(rule, typeName, format string, args ...any) {
+	issue := &CompatibilityIssue{Module: c.module, Rule: rule, Type: typeName, Message: fmt.Sprintf(format, args...)}
+	for _, existing := range c.issues {
+		if *existing == *issue {
+			return
+		}
+	}
+	c.issues = append(c.issues, issue)
+}
+
+// checkTypeName reports a package renaming when `oldName` and `newName` only differ by their package, and
+// returns whether it did. `typeName` is the type owning the reference.
+func (c *compatChecker) checkTypeName(typeName, oldName, newName string) bool {
+	oldPackage, oldShort := splitProtoName(oldName)
+	newPackage, newShort := splitProtoName(newName)
+	if oldShort != newShort || oldPackage == newPackage {
+		return false
+	}
+	c.report(RulePackageSameName, typeName, "type %s moved from package %q to %q", oldShort, oldPackage, newPackage)
+	return true
+}
+
+// run compares the old definition of type `oldName` with the new definition of type `newName`, then the
+// types referenced by their fields, breadth-first.
+func (c *compatChecker) run(oldName, newName string) {
+	c.queue = append(c.queue, [2]string{oldName, newName})
+	for len(c.queue) != 0 {
+		pair := c.queue[0]
+		c.queue = c.queue[1:]
+		if c.seen[pair] {
+			continue
+		}
+		c.seen[pair] = true
+		c.compareType(pair[0], pair[1])
+	}
+}
+
+func (c *compatChecker) compareType(oldName, newName string) {
+	if oldEnum, found := c.oldTypes.enums[oldName]; found {
+		newEnum, found := c.newTypes.enums[newName]
+		if !found {
+			c.report(RuleEnumNoDelete, oldName, "enum %s is missing", newName)
+			return
+		}
+		c.compareEnum(oldName, oldEnum, newEnum)
+		return
+	}
+
+	oldMsg, found := c.oldTypes.messages[oldName]
+	if !found {
+		// Not defined in the old package, nothing to compare with
+		return
+	}
+	newMsg, found := c.newTypes.messages[newName]
+	if !found {
+		c.report(RuleMessageNoDelete, oldName, "message %s is missing", newName)
+		return
+	}
+	c.compareMessage(oldName, oldMsg, newMsg)
+}
+
+func (c *compatChecker) compareMessage(name string, oldMsg, newMsg *descriptorpb.DescriptorProto) {
+	newFields := map[int32]*descriptorpb.FieldDescriptorProto{}
+	for _, field := range newMsg.Field {
+		newFields[field.GetNumber()] = field
+	}
+	oldFields := map[int32]*descriptorpb.FieldDescriptorProto{}
+	for _, field := range oldMsg.Field {
+		oldFields[field.GetNumber()] = field
+	}
+
+	for _, oldField := range oldMsg.Field {
+		number := oldField.GetNumber()
+		newField, found := newFields[number]
+		if !found {
+			if oldField.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REQUIRED {
+				c.report(RuleFieldNoDeleteRequired, name, "required field %s.%s (%d) was removed", name, oldField.GetName(), number)
+			} else if !messageReservesNumber(newMsg, number) {
+				c.report(RuleFieldNoDeleteUnlessReserved, name, "field %s.%s (%d) was removed without reserving its number", name, oldField.GetName(), number)
+			}
+			continue
+		}
+
+		sameName := oldField.GetName() == newField.GetName()
+		sameType := oldField.GetType() == newField.GetType()
+		oldTypeName, newTypeName := strings.TrimPrefix(oldField.GetTypeName(), "."), strings.TrimPrefix(newField.GetTypeName(), ".")
+		if sameType && oldTypeName != newTypeName {
+			sameType = c.checkTypeName(name, oldTypeName, newTypeName)
+		}
+
+		switch {
+		case !sameName && !sameType:
+			c.report(RuleFieldNumberReused, name, "field number %d of %s.%s is reused by %s.%s, %s", number, name, oldField.GetName(), name, newField.GetName(), describeProtoField(newField))
+			continue
+		case !sameName:
+			c.report(RuleFieldSameName, name, "field %s.%s (%d) was renamed to %q", name, oldField.GetName(), number, newField.GetName())
+		case !sameType:
+			c.report(RuleFieldSameType, name, "field %s.%s (%d) changed type from %s to %s", name, oldField.GetName(), number, describeProtoFieldType(oldField), describeProtoFieldType(newField))
+			continue
+		}
+
+		if oldField.GetLabel() != newField.GetLabel() {
+			c.report(RuleFieldSameLabel, name, "field %s.%s (%d) changed from %s to %s", name, oldField.GetName(), number, describeProtoFieldLabel(oldField), describeProtoFieldLabel(newField))
+		}
+		if oldOneof, newOneof := fieldOneofName(oldMsg, oldField), fieldOneofName(newMsg, newField); oldOneof != newOneof {
+			c.report(RuleFieldSameOneof, name, "field %s.%s (%d) moved from oneof %q to oneof %q", name, oldField.GetName(), number, oldOneof, newOneof)
+		}
+		if oldTypeName != "" {
+			c.queue = append(c.queue, [2]string{oldTypeName, newTypeName})
+		}
+	}
+
+	for _, newField := range newMsg.Field {
+		number := newField.GetNumber()
+		if _, found := oldFields[number]; found {
+			continue
+		}
+		if messageReservesNumber(oldMsg, number) {
+			c.report(RuleFieldNumberReused, name, "field %s.%s (%d) reuses a number reserved in the old definition", name, newField.GetName(), number)
+		}
+		if newField.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REQUIRED {
+			c.report(RuleFieldNoAddRequired, name, "required field %s.%s (%d) was added", name, newField.GetName(), number)
+		}
+	}
+}
+
+func (c *compatChecker) compareEnum(name string, oldEnum, newEnum *descriptorpb.EnumDescriptorProto) {
+	newValues := map[int32]string{}
+	for _, value := range newEnum.Value {
+		if _, found := newValues[value.GetNumber()]; !found {
+			newValues[value.GetNumber()] = value.GetName()
+		}
+	}
+
+	for _, oldValue := range oldEnum.Value {
+		number := oldValue.GetNumber()
+		newValue, found := newValues[number]
+		switch {
+		case !found && !enumReservesNumber(newEnum, number):
+			c.report(RuleEnumValueNoDeleteUnlessReserved, name, "enum value %s.%s (%d) was removed without reserving its number", name, oldValue.GetName(), number)
+		case found && newValue != oldValue.GetName():
+			c.report(RuleEnumValueSameName, name, "enum value %s.%s (%d) was renamed to %q", name, oldValue.GetName(), number, newValue)
+		}
+	}
+}
+
+func messageReservesNumber(msg *descriptorpb.DescriptorProto, number int32) bool {
+	for _, r := range msg.ReservedRange {
+		// The end of message reserved ranges is exclusive
+		if number >= r.GetStart() && number < r.GetEnd() {
+			return true
+		}
+	}
+	return false
+}
+
+func enumReservesNumber(enum *descriptorpb.EnumDescriptorProto, number int32) bool {
+	for _, r := range enum.ReservedRange {
+		// The end of enum reserved ranges is inclusive
+		if number >= r.GetStart() && number <= r.GetEnd() {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldOneofName returns the name of the real oneof `field` is part of, ignoring the synthetic oneofs of
+// proto3 optional fields.
+func fieldOneofName(msg *descriptorpb.DescriptorProto, field *descriptorpb.FieldDescriptorProto) string {
+	if field.OneofIndex == nil || field.GetProto3Optional() {
+		return ""
+	}
+	return msg.OneofDecl[field.GetOneofIndex()].GetName()
+}
+
+func splitProtoName(name string) (pkg, short string) {
+	idx := strings.LastIndex(name, ".")
+	if idx == -1 {
+		return "", name
+	}
+	return name[:idx], name[idx+1:]
+}
+
+func describeProtoFieldType(field *descriptorpb.FieldDescriptorProto) string {
+	if field.GetTypeName() != "" {
+		return strings.TrimPrefix(field.GetTypeName(), ".")
+	}
+	return strings.ToLower(strings.TrimPrefix(field.GetType().String(), "TYPE_"))
+}
+
+func describeProtoFieldLabel(field *descriptorpb.FieldDescriptorProto) string {
+	return strings.ToLower(strings.TrimPrefix(field.GetLabel().String(), "LABEL_"))
+}
@@ -0,0 +1,121 @@
+package manifest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestCheckOutputCompatibility(t *testing.T) {
+	newPackage := func(content string, outputTypes map[string]string) *pbsubstreams.Package {
+		pkg := &pbsubstreams.Package{
+			ProtoFiles: []*descriptorpb.FileDescriptorProto{parseProtoFiles(t, map[string]string{"test.proto": content}, "test.proto")[0]},
+			Modules:    &pbsubstreams.Modules{},
+		}
+		for _, name := range []string{"map_a", "map_b", "store_c"} {
+			outputType, found := outputTypes[name]
+			if !found {
+				continue
+			}
+			mod := &pbsubstreams.Module{Name: name, Kind: &pbsubstreams.Module_KindMap_{KindMap: &pbsubstreams.Module_KindMap{OutputType: outputType}}}
+			if name == "store_c" {
+				mod.Kind = &pbsubstreams.Module_KindStore_{KindStore: &pbsubstreams.Module_KindStore{ValueType: outputType}}
+			}
+			pkg.Modules.Modules = append(pkg.Modules.Modules, mod)
+		}
+		return pkg
+	}
+
+	tests := []struct {
+		name       string
+		oldProto   string
+		newProto   string
+		oldOutputs map[string]string
+		newOutputs map[string]string
+		expect     []string
+	}{
+		{
+			name:       "compatible",
+			oldProto:   `syntax = "proto3"; package test; message Out { string a = 1; Kind kind = 2; } enum Kind { UNKNOWN = 0; A = 1; }`,
+			newProto:   `syntax = "proto3"; package test; message Out { string a = 1; Kind kind = 2; uint64 b = 3; } enum Kind { UNKNOWN = 0; A = 1; B = 2; } message Unrelated {}`,
+			oldOutputs: map[string]string{"map_a": "proto:test.Out", "store_c": "int64"},
+			newOutputs: map[string]string{"map_a": "proto:test.Out", "store_c": "string"},
+		},
+		{
+			name:       "field changes",
+			oldProto:   `syntax = "proto3"; package test; message Out { string a = 1; uint64 b = 2; string c = 3; string d = 4; repeated string e = 5; int32 f = 6; oneof value { string g = 7; } reserved 10; }`,
+			newProto:   `syntax = "proto3"; package test; message Out { string a = 1; string b = 2; string c2 = 3; bytes d2 = 4; string e = 5; string g = 7; string h = 10; reserved 6; }`,
+			oldOutputs: map[string]string{"map_a": "proto:test.Out"},
+			newOutputs: map[string]string{"map_a": "proto:test.Out"},
+			expect: []string{
+				`module "map_a": FIELD_SAME_TYPE: field test.Out.b (2) changed type from uint64 to string`,
+				`module "map_a": FIELD_SAME_NAME: field test.Out.c (3) was renamed to "c2"`,
+				`module "map_a": FIELD_NUMBER_REUSED: field number 4 of test.Out.d is reused by test.Out.d2, optional bytes`,
+				`module "map_a": FIELD_SAME_LABEL: field test.Out.e (5) changed from repeated to optional`,
+				`module "map_a": FIELD_SAME_ONEOF: field test.Out.g (7) moved from oneof "value" to oneof ""`,
+				`module "map_a": FIELD_NUMBER_REUSED: field test.Out.h (10) reuses a number reserved in the old definition`,
+			},
+		},
+		{
+			name:       "removed fields and enum values",
+			oldProto:   `syntax = "proto3"; package test; message Out { string a = 1; string b = 2; Inner inner = 3; } message Inner { Kind kind = 1; } enum Kind { UNKNOWN = 0; A = 1; B = 2; C = 3; }`,
+			newProto:   `syntax = "proto3"; package test; message Out { string a = 1; Inner inner = 3; } message Inner { Kind kind = 1; } enum Kind { UNKNOWN = 0; A_RENAMED = 1; reserved 3; }`,
+			oldOutputs: map[string]string{"map_a": "proto:test.Out"},
+			newOutputs: map[string]string{"map_a": "proto:test.Out"},
+			expect: []string{
+				`module "map_a": FIELD_NO_DELETE_UNLESS_NUMBER_RESERVED: field test.Out.b (2) was removed without reserving its number`,
+				`module "map_a": ENUM_VALUE_SAME_NAME: enum value test.Kind.A (1) was renamed to "A_RENAMED"`,
+				`module "map_a": ENUM_VALUE_NO_DELETE_UNLESS_NUMBER_RESERVED: enum value test.Kind.B (2) was removed without reserving its number`,
+			},
+		},
+		{
+			name:       "required fields",
+			oldProto:   `syntax = "proto2"; package test; message Out { required string a = 1; optional string b = 2; }`,
+			newProto:   `syntax = "proto2"; package test; message Out { optional string b = 2; required string c = 3; }`,
+			oldOutputs: map[string]string{"map_a": "proto:test.Out"},
+			newOutputs: map[string]string{"map_a": "proto:test.Out"},
+			expect: []string{
+				`module "map_a": FIELD_NO_DELETE_REQUIRED: required field test.Out.a (1) was removed`,
+				`module "map_a": FIELD_NO_ADD_REQUIRED: required field test.Out.c (3) was added`,
+			},
+		},
+		{
+			name:       "renamed package and types",
+			oldProto:   `syntax = "proto3"; package test.v1; message Out { string a = 1; } message Other { string a = 1; }`,
+			newProto:   `syntax = "proto3"; package test.v2; message Out { uint32 a = 1; } message Renamed { string a = 1; }`,
+			oldOutputs: map[string]string{"map_a": "proto:test.v1.Out", "map_b": "proto:test.v1.Other", "store_c": "proto:test.v1.Other"},
+			newOutputs: map[string]string{"map_a": "proto:test.v2.Out", "map_b": "proto:test.v2.Renamed", "store_c": "bytes"},
+			expect: []string{
+				`module "map_a": PACKAGE_SAME_NAME: type Out moved from package "test.v1" to "test.v2"`,
+				`module "map_a": FIELD_SAME_TYPE: field test.v1.Out.a (1) changed type from string to uint32`,
+				`module "map_b": OUTPUT_SAME_TYPE: output type changed from "proto:test.v1.Other" to "proto:test.v2.Renamed"`,
+				`module "store_c": OUTPUT_SAME_TYPE: output type changed from "proto:test.v1.Other" to "bytes"`,
+			},
+		},
+		{
+			name:       "missing types",
+			oldProto:   `syntax = "proto3"; package test; message Out { string a = 1; }`,
+			newProto:   `syntax = "proto3"; package test; message Other { string a = 1; }`,
+			oldOutputs: map[string]string{"map_a": "proto:test.Out", "map_b": "proto:test.Out"},
+			newOutputs: map[string]string{"map_a": "proto:test.Out"},
+			expect: []string{
+				`module "map_a": MESSAGE_NO_DELETE: message test.Out is missing`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			issues := CheckOutputCompatibility(newPackage(test.oldProto, test.oldOutputs), newPackage(test.newProto, test.newOutputs))
+
+			var out []string
+			for _, issue := range issues {
+				out = append(out, issue.String())
+			}
+			assert.Equal(t, test.expect, out)
+		})
+	}
+}
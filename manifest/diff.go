@@ -0,0 +1,338 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+const (
+	// HashChangeDirect is a module hash changing because of a change of the module itself.
+	HashChangeDirect = "direct"
+	// HashChangeInherited is a module hash changing only because the hash of one of its ancestors changed.
+	HashChangeInherited = "inherited"
+)
+
+// PackageDiff is the difference between the modules of two packages, see DiffPackages.
+type PackageDiff struct {
+	Added   []string         `json:"added,omitempty"`
+	Removed []string         `json:"removed,omitempty"`
+	Renamed []*RenamedModule `json:"renamed,omitempty"`
+	// Changed lists the modules found in both packages (including renamed ones) which changed in any way.
+	Changed []*ModuleDiff `json:"changed,omitempty"`
+}
+
+type RenamedModule struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type ModuleDiff struct {
+	Name    string `json:"name"`
+	OldHash string `json:"old_hash"`
+	NewHash string `json:"new_hash"`
+	// HashChange is empty when the module hash did not change, HashChangeDirect or HashChangeInherited otherwise.
+	HashChange string `json:"hash_change,omitempty"`
+	// Changes describes the changes of the module itself.
+	Changes []string `json:"changes,omitempty"`
+	// ChangedAncestors lists the ancestors whose hash changed.
+	ChangedAncestors []string `json:"changed_ancestors,omitempty"`
+	// OutputBreak is set when outputs of the new module cannot be decoded as the old module's, with the reason.
+	OutputBreak string `json:"output_break,omitempty"`
+}
+
+// HashChanged returns whether the module hash changed, invalidating its cached outputs and states.
+func (d *ModuleDiff) HashChanged() bool {
+	return d.HashChange != ""
+}
+
+// IsEmpty returns whether both packages have the same modules with the same hashes and definitions.
+func (d *PackageDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Renamed) == 0 && len(d.Changed) == 0
+}
+
+// DiffPackages compares the modules of `oldPkg` and `newPkg`, as returned with their graph by `Reader.Read`,
+// reporting added, removed and renamed modules, what changed in the other ones, and which module hashes
+// changed (and so which caches are invalidated).
+//
+// A removed module and an added module with the same hash, or with the same definition once renamed
+// ancestors are accounted for, are considered a rename, the module hash not depending on the module name.
+func DiffPackages(oldPkg *pbsubstreams.Package, oldGraph *ModuleGraph, newPkg *pbsubstreams.Package, newGraph *ModuleGraph) (*PackageDiff, error) {
+	oldHashes, err := hashAllModules(oldPkg, oldGraph)
+	if err != nil {
+		return nil, fmt.Errorf("hashing old package modules: %w", err)
+	}
+	newHashes, err := hashAllModules(newPkg, newGraph)
+	if err != nil {
+		return nil, fmt.Errorf("hashing new package modules: %w", err)
+	}
+
+	oldModules := modulesByName(oldPkg)
+	newModules := modulesByName(newPkg)
+
+	out := &PackageDiff{}
+	var removed, added []string
+	for _, mod := range oldPkg.Modules.Modules {
+		if newModules[mod.Name] == nil {
+			removed = append(removed, mod.Name)
+		}
+	}
+	for _, mod := range newPkg.Modules.Modules {
+		if oldModules[mod.Name] == nil {
+			added = append(added, mod.Name)
+		}
+	}
+
+	// newName -> oldName, and oldName -> newName to compare inputs across renames
+	renames := map[string]string{}
+	newNames := map[string]string{}
+	rename := func(matches func(oldName, newName string) bool) {
+		for _, oldName := range removed {
+			if newNames[oldName] != "" {
+				continue
+			}
+			for _, newName := range added {
+				if _, taken := renames[newName]; !taken && matches(oldName, newName) {
+					renames[newName] = oldName
+					newNames[oldName] = newName
+					break
+				}
+			}
+		}
+	}
+	// same hash first, then same definition, for renamed modules whose ancestors changed
+	rename(func(oldName, newName string) bool {
+		return bytes.Equal(oldHashes[oldName], newHashes[newName])
+	})
+	rename(func(oldName, newName string) bool {
+		changes, _ := moduleChanges(oldPkg, oldModules[oldName], newPkg, newModules[newName], newNames)
+		return len(changes) == 0
+	})
+
+	for _, oldName := range removed {
+		if newName := newNames[oldName]; newName != "" {
+			out.Renamed = append(out.Renamed, &RenamedModule{From: oldName, To: newName})
+		} else {
+			out.Removed = append(out.Removed, oldName)
+		}
+	}
+	for _, newName := range added {
+		if _, renamed := renames[newName]; !renamed {
+			out.Added = append(out.Added, newName)
+		}
+	}
+
+	oldTypes := indexPackageProtoTypes(oldPkg)
+	newTypes := indexPackageProtoTypes(newPkg)
+
+	for _, newMod := range newPkg.Modules.Modules {
+		oldName, renamed := renames[newMod.Name]
+		if !renamed {
+			oldName = newMod.Name
+		}
+		oldMod := oldModules[oldName]
+		if oldMod == nil {
+			continue
+		}
+
+		changes, hashedChanges := moduleChanges(oldPkg, oldMod, newPkg, newMod, newNames)
+		diff := &ModuleDiff{
+			Name:    newMod.Name,
+			OldHash: hex.EncodeToString(oldHashes[oldName]),
+			NewHash: hex.EncodeToString(newHashes[newMod.Name]),
+			Changes: changes,
+		}
+
+		var breaks []string
+		for _, issue := range checkModuleOutput(oldMod, newMod, oldTypes, newTypes) {
+			breaks = append(breaks, issue.Message)
+		}
+		diff.OutputBreak = strings.Join(breaks, "; ")
+
+		if !bytes.Equal(oldHashes[oldName], newHashes[newMod.Name]) {
+			ancestors, err := newGraph.AncestorsOf(newMod.Name)
+			if err != nil {
+				return nil, fmt.Errorf("module %q: %w", newMod.Name, err)
+			}
+			for _, ancestor := range ancestors {
+				ancestorOldName, renamed := renames[ancestor.Name]
+				if !renamed {
+					ancestorOldName = ancestor.Name
+				}
+				if !bytes.Equal(oldHashes[ancestorOldName], newHashes[ancestor.Name]) {
+					diff.ChangedAncestors = append(diff.ChangedAncestors, ancestor.Name)
+				}
+			}
+			sort.Strings(diff.ChangedAncestors)
+
+			diff.HashChange = HashChangeInherited
+			if hashedChanges || len(diff.ChangedAncestors) == 0 {
+				diff.HashChange = HashChangeDirect
+			}
+		}
+
+		if diff.HashChanged() || len(diff.Changes) != 0 || diff.OutputBreak != "" {
+			out.Changed = append(out.Changed, diff)
+		}
+	}
+
+	return out, nil
+}
+
+func hashAllModules(pkg *pbsubstreams.Package, graph *ModuleGraph) (map[string]ModuleHash, error) {
+	hashes := NewModuleHashes()
+	out := make(map[string]ModuleHash, len(pkg.Modules.Modules))
+	for _, mod := range pkg.Modules.Modules {
+		hash, err := hashes.HashModule(pkg.Modules, mod, graph)
+		if err != nil {
+			return nil, fmt.Errorf("module %q: %w", mod.Name, err)
+		}
+		out[mod.Name] = hash
+	}
+	return out, nil
+}
+
+func modulesByName(pkg *pbsubstreams.Package) map[string]*pbsubstreams.Module {
+	out := make(map[string]*pbsubstreams.Module, len(pkg.Modules.Modules))
+	for _, mod := range pkg.Modules.Modules {
+		out[mod.Name] = mod
+	}
+	return out
+}
+
+// moduleChanges describes what changed in the definition of a module, `newNames` mapping the old name
+// of renamed modules to their new name. `hashed` is whether any of the changes is part of the module hash.
+func moduleChanges(oldPkg *pbsubstreams.Package, oldMod *pbsubstreams.Module, newPkg *pbsubstreams.Package, newMod *pbsubstreams.Module, newNames map[string]string) (out []string, hashed bool) {
+	// the output type and update policy are not part of the module hash
+	if oldOutput, newOutput := moduleOutputType(oldMod), moduleOutputType(newMod); oldOutput != newOutput {
+		out = append(out, fmt.Sprintf("output type changed from %q to %q", oldOutput, newOutput))
+	}
+	if oldStore, newStore := oldMod.GetKindStore(), newMod.GetKindStore(); oldStore != nil && newStore != nil && oldStore.UpdatePolicy != newStore.UpdatePolicy {
+		out = append(out, fmt.Sprintf("update policy changed from %s to %s", oldStore.UpdatePolicy.Pretty(), newStore.UpdatePolicy.Pretty()))
+	}
+	unhashed := len(out)
+
+	if oldKind, newKind := moduleKindName(oldMod), moduleKindName(newMod); oldKind != newKind {
+		out = append(out, fmt.Sprintf("kind changed from %s to %s", oldKind, newKind))
+	}
+
+	oldBinary := oldPkg.Modules.Binaries[oldMod.BinaryIndex]
+	newBinary := newPkg.Modules.Binaries[newMod.BinaryIndex]
+	if oldBinary.Type != newBinary.Type || !bytes.Equal(oldBinary.Content, newBinary.Content) {
+		out = append(out, "binary changed")
+	}
+	if oldMod.BinaryEntrypoint != newMod.BinaryEntrypoint {
+		out = append(out, fmt.Sprintf("entrypoint changed from %q to %q", oldMod.BinaryEntrypoint, newMod.BinaryEntrypoint))
+	}
+
+	oldInputs := make([]string, len(oldMod.Inputs))
+	for i, input := range oldMod.Inputs {
+		oldInputs[i] = describeInput(input, newNames)
+	}
+	newInputs := make([]string, len(newMod.Inputs))
+	for i, input := range newMod.Inputs {
+		newInputs[i] = describeInput(input, nil)
+	}
+	if strings.Join(oldInputs, ", ") != strings.Join(newInputs, ", ") {
+		out = append(out, fmt.Sprintf("inputs changed from [%s] to [%s]", strings.Join(oldInputs, ", "), strings.Join(newInputs, ", ")))
+	}
+
+	if oldMod.InitialBlock != newMod.InitialBlock {
+		out = append(out, fmt.Sprintf("initial block changed from %d to %d", oldMod.InitialBlock, newMod.InitialBlock))
+	}
+
+	if oldParams, newParams := moduleParamsValue(oldMod), moduleParamsValue(newMod); oldParams != newParams {
+		out = append(out, fmt.Sprintf("params changed from %q to %q", oldParams, newParams))
+	}
+
+	oldFilter, newFilter := oldMod.GetBlockFilter(), newMod.GetBlockFilter()
+	if oldFilter != nil && newNames[oldFilter.Module] != "" {
+		oldFilter = &pbsubstreams.Module_BlockFilter{Module: newNames[oldFilter.Module], Query: oldFilter.Query}
+	}
+	if oldFilter.GetModule() != newFilter.GetModule() || oldFilter.GetQuery() != newFilter.GetQuery() {
+		out = append(out, fmt.Sprintf("block filter changed from %q to %q", describeBlockFilter(oldFilter), describeBlockFilter(newFilter)))
+	}
+
+	return out, len(out) > unhashed
+}
+
+func moduleKindName(mod *pbsubstreams.Module) string {
+	switch mod.Kind.(type) {
+	case *pbsubstreams.Module_KindMap_:
+		return "map"
+	case *pbsubstreams.Module_KindStore_:
+		return "store"
+	case *pbsubstreams.Module_KindBlockIndex_:
+		return "blockIndex"
+	}
+	return "unknown"
+}
+
+func moduleOutputType(mod *pbsubstreams.Module) string {
+	switch kind := mod.Kind.(type) {
+	case *pbsubstreams.Module_KindMap_:
+		return kind.KindMap.OutputType
+	case *pbsubstreams.Module_KindStore_:
+		return kind.KindStore.ValueType
+	case *pbsubstreams.Module_KindBlockIndex_:
+		return kind.KindBlockIndex.OutputType
+	}
+	return ""
+}
+
+func moduleParamsValue(mod *pbsubstreams.Module) string {
+	if len(mod.Inputs) == 0 {
+		return ""
+	}
+	return mod.Inputs[0].GetParams().GetValue()
+}
+
+func describeInput(input *pbsubstreams.Module_Input, renames map[string]string) string {
+	rename := func(name string) string {
+		if renamed, found := renames[name]; found {
+			return renamed
+		}
+		return name
+	}
+
+	switch in := input.Input.(type) {
+	case *pbsubstreams.Module_Input_Source_:
+		return "source: " + in.Source.Type
+	case *pbsubstreams.Module_Input_Map_:
+		return "map: " + rename(in.Map.ModuleName)
+	case *pbsubstreams.Module_Input_Store_:
+		return fmt.Sprintf("store: %s (%s)", rename(in.Store.ModuleName), in.Store.Mode.Pretty())
+	case *pbsubstreams.Module_Input_Params_:
+		return "params"
+	}
+	return "unknown"
+}
+
+func describeBlockFilter(filter *pbsubstreams.Module_BlockFilter) string {
+	if filter == nil {
+		return ""
+	}
+	return filter.Module + " " + filter.Query
+}
+
+type protoTypes struct {
+	messages map[string]*descriptorpb.DescriptorProto
+	enums    map[string]*descriptorpb.EnumDescriptorProto
+}
+
+func indexPackageProtoTypes(pkg *pbsubstreams.Package) *protoTypes {
+	out := &protoTypes{
+		messages: map[string]*descriptorpb.DescriptorProto{},
+		enums:    map[string]*descriptorpb.EnumDescriptorProto{},
+	}
+	for _, file := range pkg.ProtoFiles {
+		indexProtoTypes(file.GetPackage(), file.MessageType, file.EnumType, out.messages, out.enums)
+	}
+	return out
+}
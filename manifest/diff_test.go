@@ -0,0 +1,118 @@
+package manifest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestDiffPackages(t *testing.T) {
+	source := &pbsubstreams.Module_Input{Input: &pbsubstreams.Module_Input_Source_{Source: &pbsubstreams.Module_Input_Source{Type: "sf.test.Block"}}}
+	mapInput := func(name string) *pbsubstreams.Module_Input {
+		return &pbsubstreams.Module_Input{Input: &pbsubstreams.Module_Input_Map_{Map: &pbsubstreams.Module_Input_Map{ModuleName: name}}}
+	}
+	params := func(value string) *pbsubstreams.Module_Input {
+		return &pbsubstreams.Module_Input{Input: &pbsubstreams.Module_Input_Params_{Params: &pbsubstreams.Module_Input_Params{Value: value}}}
+	}
+	mapModule := func(name, entrypoint, outputType string, binaryIndex uint32, initialBlock uint64, inputs ...*pbsubstreams.Module_Input) *pbsubstreams.Module {
+		return &pbsubstreams.Module{
+			Name:             name,
+			BinaryIndex:      binaryIndex,
+			BinaryEntrypoint: entrypoint,
+			InitialBlock:     initialBlock,
+			Kind:             &pbsubstreams.Module_KindMap_{KindMap: &pbsubstreams.Module_KindMap{OutputType: outputType}},
+			Inputs:           inputs,
+		}
+	}
+	newPackage := func(protoFile *descriptorpb.FileDescriptorProto, modules ...*pbsubstreams.Module) (*pbsubstreams.Package, *ModuleGraph) {
+		pkg := &pbsubstreams.Package{
+			ProtoFiles: []*descriptorpb.FileDescriptorProto{protoFile},
+			Modules: &pbsubstreams.Modules{
+				Binaries: []*pbsubstreams.Binary{
+					{Type: "wasm/rust-v1", Content: []byte("v1")},
+					{Type: "wasm/rust-v1", Content: []byte("v2")},
+				},
+				Modules: modules,
+			},
+		}
+		graph, err := NewModuleGraph(modules)
+		require.NoError(t, err)
+		return pkg, graph
+	}
+	parse := func(content string) *descriptorpb.FileDescriptorProto {
+		return parseProtoFiles(t, map[string]string{"test.proto": content}, "test.proto")[0]
+	}
+
+	oldPkg, oldGraph := newPackage(
+		parse(`syntax = "proto3"; package test; message Output { string value = 1; Nested nested = 2; } message Nested { string id = 1; }`),
+		mapModule("map_a", "map_a", "proto:test.Output", 0, 10, source),
+		mapModule("map_b", "map_b", "proto:test.Output", 0, 10, params("min=1"), mapInput("map_a")),
+		mapModule("map_c", "shared", "proto:test.Output", 0, 10, mapInput("map_b")),
+		mapModule("map_unchanged", "map_unchanged", "proto:test.Output", 0, 10, source),
+		mapModule("map_gone", "map_gone", "proto:test.Output", 0, 10, source),
+	)
+	newPkg, newGraph := newPackage(
+		parse(`syntax = "proto3"; package test; message Output { string value = 1; Nested nested = 2; string extra = 3; } message Nested { uint64 id = 1; }`),
+		mapModule("map_a", "map_a", "proto:test.Output", 1, 10, source),
+		mapModule("map_b", "map_b", "proto:test.Output", 0, 20, params("min=2"), mapInput("map_a")),
+		mapModule("map_c_renamed", "shared", "proto:test.Output", 0, 10, mapInput("map_b")),
+		mapModule("map_unchanged", "map_unchanged", "proto:test.Output", 0, 10, source),
+		mapModule("map_renamed", "map_gone", "proto:test.Output", 0, 10, source),
+		mapModule("map_new", "map_new", "proto:test.Output", 0, 10, source),
+	)
+
+	diff, err := DiffPackages(oldPkg, oldGraph, newPkg, newGraph)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"map_new"}, diff.Added)
+	assert.Empty(t, diff.Removed)
+	assert.Equal(t, []*RenamedModule{{From: "map_c", To: "map_c_renamed"}, {From: "map_gone", To: "map_renamed"}}, diff.Renamed)
+
+	changed := map[string]*ModuleDiff{}
+	for _, mod := range diff.Changed {
+		changed[mod.Name] = mod
+	}
+	require.Len(t, changed, 5)
+
+	breakReason := "field test.Nested.id (1) changed type from string to uint64"
+	// the same rules as CheckOutputCompatibility
+	for _, issue := range CheckOutputCompatibility(oldPkg, newPkg) {
+		if issue.Module == "map_a" {
+			assert.Equal(t, breakReason, issue.Message)
+		}
+	}
+
+	assert.Equal(t, HashChangeDirect, changed["map_a"].HashChange)
+	assert.Equal(t, []string{"binary changed"}, changed["map_a"].Changes)
+	assert.Equal(t, breakReason, changed["map_a"].OutputBreak)
+
+	assert.Equal(t, HashChangeDirect, changed["map_b"].HashChange)
+	assert.Equal(t, []string{"initial block changed from 10 to 20", `params changed from "min=1" to "min=2"`}, changed["map_b"].Changes)
+	assert.Equal(t, []string{"map_a"}, changed["map_b"].ChangedAncestors)
+
+	// renamed with a changed ancestor
+	assert.Equal(t, HashChangeInherited, changed["map_c_renamed"].HashChange)
+	assert.Equal(t, []string{"map_a", "map_b"}, changed["map_c_renamed"].ChangedAncestors)
+
+	// unchanged modules are only reported for their output type break
+	assert.False(t, changed["map_unchanged"].HashChanged())
+	assert.Empty(t, changed["map_unchanged"].Changes)
+	assert.Equal(t, breakReason, changed["map_unchanged"].OutputBreak)
+	assert.False(t, changed["map_renamed"].HashChanged())
+	assert.Equal(t, breakReason, changed["map_renamed"].OutputBreak)
+
+	// Only a change of an ancestor
+	oldPkg, oldGraph = newPackage(oldPkg.ProtoFiles[0], mapModule("map_a", "map_a", "proto:test.Output", 0, 10, source), mapModule("map_b", "map_b", "proto:test.Output", 0, 10, mapInput("map_a")))
+	newPkg, newGraph = newPackage(oldPkg.ProtoFiles[0], mapModule("map_a", "map_a", "proto:test.Output", 0, 5, source), mapModule("map_b", "map_b", "proto:test.Output", 0, 10, mapInput("map_a")))
+	diff, err = DiffPackages(oldPkg, oldGraph, newPkg, newGraph)
+	require.NoError(t, err)
+	require.Len(t, diff.Changed, 2)
+	assert.Equal(t, HashChangeInherited, diff.Changed[1].HashChange)
+	assert.Empty(t, diff.Changed[1].Changes)
+	assert.Equal(t, []string{"map_a"}, diff.Changed[1].ChangedAncestors)
+	assert.Empty(t, diff.Changed[1].OutputBreak)
+}
@@ -0,0 +1,195 @@
+package manifest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// LockFileName is the name of the lock file written next to a manifest by `substreams pack`,
+// pinning the content of each of its `imports`.
+const LockFileName = "substreams.lock"
+
+const lockFileVersion = 1
+
+type Lockfile struct {
+	Version int             `yaml:"version"`
+	Imports []*LockedImport `yaml:"imports"`
+}
+
+// LockedImport pins an imported package: where it was fetched from, the SHA-256 of the
+// fetched content and the hash of each of its modules (keyed by unprefixed module name).
+type LockedImport struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	// Version resolved from the registry, for `<package>@<version>` imports
+	Version string            `yaml:"version,omitempty"`
+	SHA256  string            `yaml:"sha256"`
+	Modules map[string]string `yaml:"modules,omitempty"`
+}
+
+func NewLockfile(imports []*LockedImport) *Lockfile {
+	sorted := make([]*LockedImport, len(imports))
+	copy(sorted, imports)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	return &Lockfile{
+		Version: lockFileVersion,
+		Imports: sorted,
+	}
+}
+
+// ReadLockfile reads the lock file at `path`, returning nil without error if it does not exist.
+func ReadLockfile(path string) (*Lockfile, error) {
+	cnt, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading lock file: %w", err)
+	}
+
+	lock := &Lockfile{}
+	decoder := yaml3.NewDecoder(bytes.NewReader(cnt))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(lock); err != nil {
+		return nil, fmt.Errorf("decoding lock file %q: %w", path, err)
+	}
+	if lock.Version != lockFileVersion {
+		return nil, fmt.Errorf("lock file %q: unsupported version %d", path, lock.Version)
+	}
+
+	return lock, nil
+}
+
+func (l *Lockfile) Write(path string) error {
+	cnt, err := yaml3.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("encoding lock file: %w", err)
+	}
+
+	header := "# Generated by `substreams pack`, refresh with `substreams pack --update-lock`. Do not edit.\n"
+	if err := os.WriteFile(path, append([]byte(header), cnt...), 0644); err != nil {
+		return fmt.Errorf("writing lock file: %w", err)
+	}
+	return nil
+}
+
+// Verify returns an error describing every difference between the lock and the imports
+// resolved while reading the manifest.
+func (l *Lockfile) Verify(resolved []*LockedImport) error {
+	locked := map[string]*LockedImport{}
+	for _, imp := range l.Imports {
+		locked[imp.Name] = imp
+	}
+
+	var drifts []string
+	seen := map[string]bool{}
+	for _, imp := range resolved {
+		seen[imp.Name] = true
+
+		lockedImp, found := locked[imp.Name]
+		if !found {
+			drifts = append(drifts, fmt.Sprintf("import %q is not in the lock file", imp.Name))
+			continue
+		}
+		drifts = append(drifts, lockedImp.diff(imp)...)
+	}
+
+	for _, imp := range l.Imports {
+		if !seen[imp.Name] {
+			drifts = append(drifts, fmt.Sprintf("import %q is in the lock file but not in the manifest", imp.Name))
+		}
+	}
+
+	if len(drifts) != 0 {
+		return fmt.Errorf("imports drifted from %s (run `substreams pack --update-lock` to accept the changes):\n  %s", LockFileName, strings.Join(drifts, "\n  "))
+	}
+	return nil
+}
+
+func (l *LockedImport) diff(resolved *LockedImport) (out []string) {
+	if l.Version != resolved.Version {
+		out = append(out, fmt.Sprintf("import %q: version changed from %q to %q", l.Name, l.Version, resolved.Version))
+	}
+	if l.URL != resolved.URL {
+		out = append(out, fmt.Sprintf("import %q: url changed from %q to %q", l.Name, l.URL, resolved.URL))
+	}
+	if l.SHA256 != resolved.SHA256 {
+		out = append(out, fmt.Sprintf("import %q: sha256 changed from %s to %s", l.Name, l.SHA256, resolved.SHA256))
+	}
+
+	var names []string
+	for name := range l.Modules {
+		names = append(names, name)
+	}
+	for name := range resolved.Modules {
+		if _, found := l.Modules[name]; !found {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		lockedHash, inLock := l.Modules[name]
+		resolvedHash, inResolved := resolved.Modules[name]
+		switch {
+		case !inResolved:
+			out = append(out, fmt.Sprintf("import %q: module %q was removed", l.Name, name))
+		case !inLock:
+			out = append(out, fmt.Sprintf("import %q: module %q was added", l.Name, name))
+		case lockedHash != resolvedHash:
+			out = append(out, fmt.Sprintf("import %q: module %q hash changed from %s to %s", l.Name, name, lockedHash, resolvedHash))
+		}
+	}
+	return
+}
+
+// newLockedImport pins an imported package, `content` being the bytes fetched from `url`. It must be
+// called before the modules are prefixed with the import name.
+func newLockedImport(name, url string, content []byte, pkg *pbsubstreams.Package, graph *ModuleGraph) (*LockedImport, error) {
+	out := &LockedImport{
+		Name:    name,
+		URL:     url,
+		SHA256:  sha256Hex(content),
+		Modules: map[string]string{},
+	}
+
+	hashes := NewModuleHashes()
+	for _, mod := range pkg.Modules.Modules {
+		hash, err := hashes.HashModule(pkg.Modules, mod, graph)
+		if err != nil {
+			return nil, fmt.Errorf("hashing module %q: %w", mod.Name, err)
+		}
+		out.Modules[mod.Name] = hex.EncodeToString(hash)
+	}
+
+	return out, nil
+}
+
+func sha256Hex(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// lockURL returns how an import resolved to `importPath` is recorded in the lock file: local
+// paths are kept relative to the manifest directory so the lock file stays portable.
+func lockURL(importPath, workdir string) string {
+	if hasRemotePrefix(importPath) || workdir == "" || !filepath.IsAbs(importPath) {
+		return importPath
+	}
+
+	rel, err := filepath.Rel(workdir, importPath)
+	if err != nil {
+		return importPath
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,105 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestReader_Lockfile(t *testing.T) {
+	dir := t.TempDir()
+
+	spkg1Content, err := os.ReadFile("testdata/spkg1/spkg1-v0.0.0.spkg")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "dep.spkg"), spkg1Content, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "substreams.yaml"), []byte(`
+specVersion: v0.1.0
+package:
+  name: test
+  version: v0.0.0
+
+imports:
+  dep: ./dep.spkg
+`), 0644))
+
+	read := func(opts ...Option) (*Reader, error) {
+		reader, err := NewReader(filepath.Join(dir, "substreams.yaml"), opts...)
+		require.NoError(t, err)
+		_, _, err = reader.Read()
+		return reader, err
+	}
+
+	// No lock file yet, nothing to verify
+	reader, err := read()
+	require.NoError(t, err)
+
+	lock := reader.Lockfile()
+	require.Len(t, lock.Imports, 1)
+	assert.Equal(t, "dep", lock.Imports[0].Name)
+	assert.Equal(t, "dep.spkg", lock.Imports[0].URL)
+	assert.Len(t, lock.Imports[0].SHA256, 64)
+
+	require.Equal(t, filepath.Join(dir, LockFileName), reader.LockfilePath())
+	require.NoError(t, lock.Write(reader.LockfilePath()))
+
+	written, err := ReadLockfile(reader.LockfilePath())
+	require.NoError(t, err)
+	assert.Equal(t, lock.Imports[0].SHA256, written.Imports[0].SHA256)
+
+	_, err = read()
+	require.NoError(t, err)
+
+	// Upstream republishes the same package with different content
+	republished, err := proto.Marshal(&pbsubstreams.Package{
+		Version:     1,
+		Modules:     &pbsubstreams.Modules{},
+		PackageMeta: []*pbsubstreams.PackageMetadata{{Name: "spkg1", Version: "v0.0.0", Doc: "republished"}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "dep.spkg"), republished, 0644))
+
+	_, err = read()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `import "dep": sha256 changed from `+lock.Imports[0].SHA256)
+
+	reader, err = read(SkipLockVerificationReader())
+	require.NoError(t, err)
+	assert.NotEqual(t, lock.Imports[0].SHA256, reader.Lockfile().Imports[0].SHA256)
+}
+
+func TestLockfile_Verify(t *testing.T) {
+	lock := NewLockfile([]*LockedImport{
+		{Name: "removed", URL: "https://example.com/removed.spkg", SHA256: "aa"},
+		{Name: "eth", URL: "https://example.com/eth-v0.1.0.spkg", SHA256: "bb", Modules: map[string]string{
+			"map_blocks": "01",
+			"store_old":  "02",
+			"map_same":   "03",
+		}},
+	})
+
+	assert.NoError(t, lock.Verify(lock.Imports))
+
+	err := lock.Verify([]*LockedImport{
+		{Name: "eth", URL: "https://example.com/eth-v0.1.1.spkg", SHA256: "cc", Modules: map[string]string{
+			"map_blocks": "04",
+			"map_same":   "03",
+			"store_new":  "05",
+		}},
+		{Name: "added", URL: "./added.spkg", SHA256: "dd"},
+	})
+	require.Error(t, err)
+	assert.Equal(t, "imports drifted from substreams.lock (run `substreams pack --update-lock` to accept the changes):\n"+
+		`  import "eth": url changed from "https://example.com/eth-v0.1.0.spkg" to "https://example.com/eth-v0.1.1.spkg"`+"\n"+
+		`  import "eth": sha256 changed from bb to cc`+"\n"+
+		`  import "eth": module "map_blocks" hash changed from 01 to 04`+"\n"+
+		`  import "eth": module "store_new" was added`+"\n"+
+		`  import "eth": module "store_old" was removed`+"\n"+
+		`  import "added" is not in the lock file`+"\n"+
+		`  import "removed" is in the lock file but not in the manifest`, err.Error())
+}
@@ -1,7 +1,6 @@
 package manifest
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"math"
@@ -11,7 +10,6 @@ import (
 	"strings"
 
 	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
-	"gopkg.in/yaml.v3"
 )
 
 const UNSET = math.MaxUint64
@@ -38,6 +36,7 @@ type Manifest struct {
 	Binaries    map[string]Binary `yaml:"binaries"`
 	Modules     []*Module         `yaml:"modules"`
 	Params      map[string]string `yaml:"params"`
+	Overrides   []*ModuleOverride `yaml:"overrides"`
 
 	BlockFilters map[string]string         `yaml:"blockFilters"`
 	Network      string                    `yaml:"network"`
@@ -93,9 +92,10 @@ type Module struct {
 	ValueType    string `yaml:"valueType"`
 	Binary       string `yaml:"binary"`
 
-	Inputs []*Input     `yaml:"inputs"`
-	Output StreamOutput `yaml:"output"`
-	Use    string       `yaml:"use"`
+	Inputs       []*Input      `yaml:"inputs"`
+	ParamsSchema *ParamsSchema `yaml:"paramsSchema"`
+	Output       StreamOutput  `yaml:"output"`
+	Use          string        `yaml:"use"`
 }
 
 type BlockFilter struct {
@@ -126,7 +126,7 @@ type StreamOutput struct {
 	Type string `yaml:"type"`
 }
 
-func decodeYamlManifestFromFile(yamlFilePath, workingDir string) (out *Manifest, err error) {
+func decodeYamlManifestFromFile(yamlFilePath, workingDir string, overlays ...string) (out *Manifest, err error) {
 	//if yamlFilePath is a relative path, make it absolute
 	if !filepath.IsAbs(yamlFilePath) {
 		yamlFilePath = filepath.Join(workingDir, yamlFilePath)
@@ -137,12 +137,16 @@ func decodeYamlManifestFromFile(yamlFilePath, workingDir string) (out *Manifest,
 		return nil, fmt.Errorf("reading substreams manifest %q: %w", yamlFilePath, err)
 	}
 
-	decoder := yaml.NewDecoder(bytes.NewReader(cnt))
-	decoder.KnownFields(true)
-	if err := decoder.Decode(&out); err != nil {
-		return nil, fmt.Errorf("decoding manifest content: %w", err)
+	_, overlayPaths, err := resolveOverlays(yamlFilePath, "", overlays)
+	if err != nil {
+		return nil, err
+	}
+	overlaysCnt, err := readOverlays(overlayPaths)
+	if err != nil {
+		return nil, err
 	}
-	return
+
+	return decodeManifest(cnt, overlaysCnt)
 }
 
 func (i *Input) IsMap() bool {
@@ -0,0 +1,182 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OverlayPath returns the path of overlay `name` of the manifest at `manifestPath`: `substreams.prod.yaml`
+// for overlay `prod` of `substreams.yaml`.
+func OverlayPath(manifestPath, name string) string {
+	ext := filepath.Ext(manifestPath)
+	return strings.TrimSuffix(manifestPath, ext) + "." + name + ext
+}
+
+// resolveOverlays returns the paths of the overlays to apply on the manifest at `manifestPath`: the overlay
+// of `network` if it exists, followed by the explicitly requested `overlays`, which must exist.
+func resolveOverlays(manifestPath, network string, overlays []string) ([]string, []string, error) {
+	var names, paths []string
+	if network != "" {
+		path := OverlayPath(manifestPath, network)
+		if _, err := os.Stat(path); err == nil {
+			names = append(names, network)
+			paths = append(paths, path)
+		}
+	}
+
+	for _, name := range overlays {
+		if name == "" || strings.ContainsAny(name, `/\`) {
+			return nil, nil, fmt.Errorf("invalid overlay name %q", name)
+		}
+		if slices.Contains(names, name) {
+			continue
+		}
+
+		path := OverlayPath(manifestPath, name)
+		if _, err := os.Stat(path); err != nil {
+			return nil, nil, fmt.Errorf("overlay %q: %w", name, err)
+		}
+		names = append(names, name)
+		paths = append(paths, path)
+	}
+
+	return names, paths, nil
+}
+
+// decodeManifest decodes the manifest content `cnt`, deep-merged with the content of each overlay in order.
+func decodeManifest(cnt []byte, overlays [][]byte) (*Manifest, error) {
+	if len(overlays) != 0 {
+		merged, err := mergeOverlays(cnt, overlays)
+		if err != nil {
+			return nil, err
+		}
+		cnt = merged
+	}
+
+	out := &Manifest{}
+	decoder := yaml.NewDecoder(bytes.NewReader(cnt))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(out); err != nil {
+		return nil, fmt.Errorf("decoding manifest content: %w", err)
+	}
+	return out, nil
+}
+
+func readOverlays(paths []string) ([][]byte, error) {
+	var out [][]byte
+	for _, path := range paths {
+		cnt, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading overlay %q: %w", path, err)
+		}
+		out = append(out, cnt)
+	}
+	return out, nil
+}
+
+func mergeOverlays(cnt []byte, overlays [][]byte) ([]byte, error) {
+	base := &yaml.Node{}
+	if err := yaml.Unmarshal(cnt, base); err != nil {
+		return nil, fmt.Errorf("decoding manifest content: %w", err)
+	}
+
+	for idx, overlayCnt := range overlays {
+		overlay := &yaml.Node{}
+		if err := yaml.Unmarshal(overlayCnt, overlay); err != nil {
+			return nil, fmt.Errorf("decoding overlay [%d]: %w", idx, err)
+		}
+		if len(overlay.Content) == 0 {
+			continue
+		}
+		if len(base.Content) == 0 {
+			base = overlay
+			continue
+		}
+		base.Content[0] = mergeYAMLNodes(base.Content[0], overlay.Content[0])
+	}
+
+	return yaml.Marshal(base)
+}
+
+// mergeYAMLNodes deep-merges `overlay` into `base`:
+//   - mappings are merged key by key, a `null` overlay value removes the key;
+//   - sequences of mappings all having a `name` key (like `modules`) are merged item by item by name,
+//     overlay items with a new name being appended;
+//   - anything else (scalars, other sequences, or nodes of different kinds) is replaced by the overlay.
+func mergeYAMLNodes(base, overlay *yaml.Node) *yaml.Node {
+	switch {
+	case base.Kind == yaml.MappingNode && overlay.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(overlay.Content); i += 2 {
+			key, value := overlay.Content[i], overlay.Content[i+1]
+			idx := mappingKeyIndex(base, key.Value)
+
+			switch {
+			case value.Tag == "!!null":
+				if idx != -1 {
+					base.Content = append(base.Content[:idx], base.Content[idx+2:]...)
+				}
+			case idx == -1:
+				base.Content = append(base.Content, key, value)
+			default:
+				base.Content[idx+1] = mergeYAMLNodes(base.Content[idx+1], value)
+			}
+		}
+		return base
+
+	case base.Kind == yaml.SequenceNode && overlay.Kind == yaml.SequenceNode && namedItems(base) && namedItems(overlay):
+		for _, item := range overlay.Content {
+			name := mappingValue(item, "name").Value
+			merged := false
+			for idx, baseItem := range base.Content {
+				if mappingValue(baseItem, "name").Value == name {
+					base.Content[idx] = mergeYAMLNodes(baseItem, item)
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				base.Content = append(base.Content, item)
+			}
+		}
+		return base
+	}
+
+	return overlay
+}
+
+func mappingKeyIndex(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if idx := mappingKeyIndex(node, key); idx != -1 {
+		return node.Content[idx+1]
+	}
+	return nil
+}
+
+func namedItems(seq *yaml.Node) bool {
+	if len(seq.Content) == 0 {
+		return false
+	}
+	for _, item := range seq.Content {
+		if item.Kind != yaml.MappingNode {
+			return false
+		}
+		if name := mappingValue(item, "name"); name == nil || name.Kind != yaml.ScalarNode {
+			return false
+		}
+	}
+	return true
+}
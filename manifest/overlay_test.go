@@ -0,0 +1,137 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestMergeOverlays(t *testing.T) {
+	base := `
+package:
+  name: test
+  version: v0.1.0
+imports:
+  eth: ./eth.spkg
+  sol: ./sol.spkg
+protobuf:
+  files: [a.proto, b.proto]
+modules:
+  - name: map_a
+    initialBlock: 10
+    inputs:
+      - source: sf.ethereum.type.v2.Block
+  - name: map_b
+    initialBlock: 20
+`
+	overlay := `
+package:
+  version: v0.1.0-prod
+imports:
+  eth: ./eth-prod.spkg
+  sol: null
+protobuf:
+  files: [c.proto]
+modules:
+  - name: map_b
+    initialBlock: 200
+  - name: map_c
+    initialBlock: 300
+`
+
+	merged, err := mergeOverlays([]byte(base), [][]byte{[]byte(overlay)})
+	require.NoError(t, err)
+
+	var out map[string]any
+	require.NoError(t, yaml.Unmarshal(merged, &out))
+	assert.Equal(t, map[string]any{
+		"package": map[string]any{"name": "test", "version": "v0.1.0-prod"},
+		"imports": map[string]any{"eth": "./eth-prod.spkg"},
+		"protobuf": map[string]any{
+			"files": []any{"c.proto"},
+		},
+		"modules": []any{
+			map[string]any{"name": "map_a", "initialBlock": 10, "inputs": []any{map[string]any{"source": "sf.ethereum.type.v2.Block"}}},
+			map[string]any{"name": "map_b", "initialBlock": 200},
+			map[string]any{"name": "map_c", "initialBlock": 300},
+		},
+	}, out)
+}
+
+func TestReader_Overlays(t *testing.T) {
+	dir := t.TempDir()
+
+	spkg1Content, err := os.ReadFile("testdata/spkg1/spkg1-v0.0.0.spkg")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "dep.spkg"), spkg1Content, 0644))
+
+	path := filepath.Join(dir, "substreams.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+specVersion: v0.1.0
+package:
+  name: test
+  version: v0.0.0
+
+network: mainnet
+
+binaries:
+  default:
+    type: native
+    native: mainnet_impl
+
+modules:
+  - name: map_transfers
+    kind: map
+    initialBlock: 100
+    inputs:
+      - source: sf.ethereum.type.v2.Block
+    output:
+      type: proto:eth.Transfers
+`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "substreams.sepolia.yaml"), []byte(`
+network: sepolia
+binaries:
+  default:
+    native: sepolia_impl
+modules:
+  - name: map_transfers
+    initialBlock: 5
+`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "substreams.deps.yaml"), []byte(`
+imports:
+  dep: ./dep.spkg
+`), 0644))
+
+	reader := newTestReader(t, path)
+	pkg, _, err := reader.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "mainnet_impl", string(pkg.Modules.Binaries[0].Content))
+	assert.Equal(t, uint64(100), pkg.Modules.Modules[0].InitialBlock)
+	assert.Equal(t, filepath.Join(dir, "substreams.lock"), reader.LockfilePath())
+
+	// The overlay of the network is picked automatically
+	reader = newTestReader(t, path, WithOverrideNetwork("sepolia"), WithOverlays("deps"))
+	pkg, _, err = reader.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "sepolia", pkg.Network)
+	assert.Equal(t, "sepolia_impl", string(pkg.Modules.Binaries[0].Content))
+	assert.Equal(t, uint64(5), pkg.Modules.Modules[0].InitialBlock)
+	assert.Len(t, pkg.PackageMeta, 2)
+	assert.Equal(t, filepath.Join(dir, "substreams.sepolia.deps.lock"), reader.LockfilePath())
+	assert.Len(t, reader.Lockfile().Imports, 1)
+
+	// Networks without overlay use the manifest as is, but explicit overlays must exist
+	_, _, err = newTestReader(t, path, WithOverrideNetwork("holesky")).Read()
+	require.NoError(t, err)
+
+	_, _, err = newTestReader(t, path, WithOverlays("prod")).Read()
+	assert.ErrorContains(t, err, `overlay "prod": stat `+filepath.Join(dir, "substreams.prod.yaml"))
+
+	manif, err := LoadManifestFile(path, dir, "sepolia")
+	require.NoError(t, err)
+	assert.Equal(t, "sepolia", manif.Network)
+}
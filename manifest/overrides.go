@@ -0,0 +1,211 @@
+package manifest
+
+import (
+	"fmt"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+// ModuleOverride patches a module imported from another package, see the `overrides` section of the manifest.
+type ModuleOverride struct {
+	Module       string       `yaml:"module"`
+	InitialBlock *uint64      `yaml:"initialBlock"`
+	Params       *string      `yaml:"params"`
+	BlockFilter  *BlockFilter `yaml:"blockFilter"`
+
+	// Inputs replaces the inputs of the module, one for one: each input must be of the same kind and
+	// carry the same type as the input it replaces.
+	Inputs []*Input `yaml:"inputs"`
+}
+
+func validateOverrides(manif *Manifest) error {
+	localModules := map[string]bool{}
+	for _, mod := range manif.Modules {
+		localModules[mod.Name] = true
+	}
+
+	seen := map[string]bool{}
+	for idx, override := range manif.Overrides {
+		if override.Module == "" {
+			return fmt.Errorf("override [%d]: missing 'module'", idx)
+		}
+		if localModules[override.Module] {
+			return fmt.Errorf("override %q: module is defined in this manifest, change it directly", override.Module)
+		}
+		if seen[override.Module] {
+			return fmt.Errorf("override %q: module overridden more than once", override.Module)
+		}
+		seen[override.Module] = true
+
+		if override.BlockFilter != nil && override.BlockFilter.Module == "" {
+			return fmt.Errorf("override %q: missing 'blockFilter.module'", override.Module)
+		}
+		for inputIdx, input := range override.Inputs {
+			if err := input.parse(); err != nil {
+				return fmt.Errorf("override %q: invalid input [%d]: %w", override.Module, inputIdx, err)
+			}
+		}
+	}
+	return nil
+}
+
+// applyOverrides patches the imported modules of `pkg` with the `overrides` of the manifest. It must
+// run once imports are merged, module names being prefixed by then.
+func applyOverrides(pkg *pbsubstreams.Package, manif *Manifest) error {
+	modules := make(map[string]*pbsubstreams.Module)
+	for _, mod := range pkg.Modules.Modules {
+		modules[mod.Name] = mod
+	}
+
+	// initial blocks replaced by the overrides, by module name
+	previousInitialBlocks := make(map[string]uint64)
+	for _, override := range manif.Overrides {
+		mod, found := modules[override.Module]
+		if !found {
+			return fmt.Errorf("override %q: module not found", override.Module)
+		}
+		_, isBlockIndex := mod.Kind.(*pbsubstreams.Module_KindBlockIndex_)
+
+		if override.InitialBlock != nil {
+			if isBlockIndex {
+				return fmt.Errorf("override %q: block index module cannot have initial block", override.Module)
+			}
+			previousInitialBlocks[mod.Name] = mod.InitialBlock
+			mod.InitialBlock = *override.InitialBlock
+		}
+
+		if override.BlockFilter != nil {
+			if isBlockIndex {
+				return fmt.Errorf("override %q: block index module cannot have block filter", override.Module)
+			}
+			mod.BlockFilter = &pbsubstreams.Module_BlockFilter{
+				Module: override.BlockFilter.Module,
+				Query:  override.BlockFilter.Query,
+			}
+		}
+
+		if override.Inputs != nil {
+			if err := overrideInputs(mod, override, modules); err != nil {
+				return fmt.Errorf("override %q: %w", override.Module, err)
+			}
+		}
+
+		if override.Params != nil {
+			if len(mod.Inputs) == 0 || mod.Inputs[0].GetParams() == nil {
+				return fmt.Errorf("override %q: module does not have 'params' as its first input type", override.Module)
+			}
+			mod.Inputs[0].GetParams().Value = *override.Params
+		}
+	}
+
+	for name, previous := range previousInitialBlocks {
+		resetInheritedInitialBlocks(pkg.Modules.Modules, name, previous, previousInitialBlocks)
+	}
+
+	return nil
+}
+
+// resetInheritedInitialBlocks unsets the initial block of the modules depending on module `changed` that
+// still start at `previous`, its initial block before the override, for it to be computed again. The
+// imported modules had their initial block computed when their package was built, those inheriting it
+// cannot be told apart from those setting the same value. Modules in `overridden` keep their own.
+func resetInheritedInitialBlocks(modules []*pbsubstreams.Module, changed string, previous uint64, overridden map[string]uint64) {
+	reset := map[string]bool{changed: true}
+	for updated := true; updated; {
+		updated = false
+		for _, mod := range modules {
+			if reset[mod.Name] || mod.InitialBlock != previous {
+				continue
+			}
+			if _, found := overridden[mod.Name]; found {
+				continue
+			}
+			for _, input := range mod.Inputs {
+				parent := input.GetMap().GetModuleName()
+				if parent == "" {
+					parent = input.GetStore().GetModuleName()
+				}
+				if reset[parent] {
+					mod.InitialBlock = UNSET
+					reset[mod.Name] = true
+					updated = true
+					break
+				}
+			}
+		}
+	}
+}
+
+func overrideInputs(mod *pbsubstreams.Module, override *ModuleOverride, modules map[string]*pbsubstreams.Module) error {
+	if len(override.Inputs) != len(mod.Inputs) {
+		return fmt.Errorf("expected %d inputs, got %d", len(mod.Inputs), len(override.Inputs))
+	}
+
+	replacement := &pbsubstreams.Module{}
+	if err := (&Module{Name: mod.Name, Inputs: override.Inputs}).setInputsToProto(replacement); err != nil {
+		return err
+	}
+
+	for idx, input := range replacement.Inputs {
+		previous := mod.Inputs[idx]
+
+		switch {
+		case input.GetParams() != nil:
+			if previous.GetParams() == nil {
+				return fmt.Errorf("input [%d]: cannot replace %q with params", idx, duplicateStringInput(previous))
+			}
+			// the current value is kept, use `params` to change it
+			input.GetParams().Value = previous.GetParams().Value
+
+		case input.GetSource() != nil:
+			if previous.GetSource().GetType() != input.GetSource().Type {
+				return fmt.Errorf("input [%d]: cannot replace %q with %q", idx, duplicateStringInput(previous), duplicateStringInput(input))
+			}
+
+		case input.GetMap() != nil:
+			if previous.GetMap() == nil {
+				return fmt.Errorf("input [%d]: cannot replace %q with %q", idx, duplicateStringInput(previous), duplicateStringInput(input))
+			}
+			if err := checkSameOutput(modules, previous.GetMap().ModuleName, input.GetMap().ModuleName); err != nil {
+				return fmt.Errorf("input [%d]: %w", idx, err)
+			}
+
+		case input.GetStore() != nil:
+			if previous.GetStore() == nil || previous.GetStore().Mode != input.GetStore().Mode {
+				return fmt.Errorf("input [%d]: cannot replace %q with %q", idx, duplicateStringInput(previous), duplicateStringInput(input))
+			}
+			if err := checkSameOutput(modules, previous.GetStore().ModuleName, input.GetStore().ModuleName); err != nil {
+				return fmt.Errorf("input [%d]: %w", idx, err)
+			}
+		}
+	}
+
+	mod.Inputs = replacement.Inputs
+	return nil
+}
+
+// checkSameOutput checks that module `replacement` produces the same kind of output as module `previous`.
+func checkSameOutput(modules map[string]*pbsubstreams.Module, previous, replacement string) error {
+	replacementMod, found := modules[replacement]
+	if !found {
+		return fmt.Errorf("module %q not found", replacement)
+	}
+	previousMod, found := modules[previous]
+	if !found {
+		return nil
+	}
+
+	switch previousKind := previousMod.Kind.(type) {
+	case *pbsubstreams.Module_KindMap_:
+		kind, ok := replacementMod.Kind.(*pbsubstreams.Module_KindMap_)
+		if !ok || kind.KindMap.OutputType != previousKind.KindMap.OutputType {
+			return fmt.Errorf("module %q does not output %q like %q", replacement, previousKind.KindMap.OutputType, previous)
+		}
+	case *pbsubstreams.Module_KindStore_:
+		kind, ok := replacementMod.Kind.(*pbsubstreams.Module_KindStore_)
+		if !ok || kind.KindStore.ValueType != previousKind.KindStore.ValueType || kind.KindStore.UpdatePolicy != previousKind.KindStore.UpdatePolicy {
+			return fmt.Errorf("module %q is not a %s store of %q like %q", replacement, previousKind.KindStore.UpdatePolicy, previousKind.KindStore.ValueType, previous)
+		}
+	}
+	return nil
+}
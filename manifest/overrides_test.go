@@ -0,0 +1,209 @@
+package manifest
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestReader_Overrides(t *testing.T) {
+	dir := t.TempDir()
+
+	mapInput := func(name string) *pbsubstreams.Module_Input {
+		return &pbsubstreams.Module_Input{Input: &pbsubstreams.Module_Input_Map_{Map: &pbsubstreams.Module_Input_Map{ModuleName: name}}}
+	}
+	sourceInput := &pbsubstreams.Module_Input{Input: &pbsubstreams.Module_Input_Source_{Source: &pbsubstreams.Module_Input_Source{Type: "sf.ethereum.type.v2.Block"}}}
+	mapKind := func(outputType string) *pbsubstreams.Module_KindMap_ {
+		return &pbsubstreams.Module_KindMap_{KindMap: &pbsubstreams.Module_KindMap{OutputType: outputType}}
+	}
+
+	cnt, err := proto.Marshal(&pbsubstreams.Package{
+		Version:     1,
+		PackageMeta: []*pbsubstreams.PackageMetadata{{Name: "eth", Version: "v0.1.0"}},
+		ModuleMeta:  []*pbsubstreams.ModuleMetadata{{}, {}, {}},
+		Modules: &pbsubstreams.Modules{
+			Binaries: []*pbsubstreams.Binary{{Type: "native", Content: []byte("eth")}},
+			Modules: []*pbsubstreams.Module{
+				{Name: "map_calls", Kind: mapKind("proto:eth.Calls"), Inputs: []*pbsubstreams.Module_Input{sourceInput}, InitialBlock: 100},
+				{Name: "index_calls", Kind: &pbsubstreams.Module_KindBlockIndex_{KindBlockIndex: &pbsubstreams.Module_KindBlockIndex{OutputType: "proto:sf.substreams.index.v1.Keys"}}, Inputs: []*pbsubstreams.Module_Input{mapInput("map_calls")}, InitialBlock: UNSET},
+				{Name: "map_transfers", Kind: mapKind("proto:eth.Transfers"), InitialBlock: 100, Inputs: []*pbsubstreams.Module_Input{
+					{Input: &pbsubstreams.Module_Input_Params_{Params: &pbsubstreams.Module_Input_Params{Value: "upstream"}}},
+					mapInput("map_calls"),
+				}},
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "eth.spkg"), cnt, 0644))
+
+	read := func(overrides string) (*pbsubstreams.Package, *ModuleGraph, error) {
+		t.Helper()
+		path := filepath.Join(dir, "substreams.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(`
+specVersion: v0.1.0
+package:
+  name: test
+  version: v0.0.0
+
+imports:
+  eth: ./eth.spkg
+
+binaries:
+  default:
+    type: native
+    native: test
+
+modules:
+  - name: map_filtered_calls
+    kind: map
+    inputs:
+      - source: sf.ethereum.type.v2.Block
+    output:
+      type: proto:eth.Calls
+  - name: map_filtered_traces
+    kind: map
+    inputs:
+      - source: sf.ethereum.type.v2.Block
+    output:
+      type: proto:eth.Traces
+  - name: map_transfer_counts
+    kind: map
+    inputs:
+      - map: eth:map_transfers
+    output:
+      type: proto:eth.Counts
+`+overrides), 0644))
+		return newTestReader(t, path, SkipLockVerificationReader()).Read()
+	}
+
+	findModule := func(pkg *pbsubstreams.Package, name string) *pbsubstreams.Module {
+		for _, mod := range pkg.Modules.Modules {
+			if mod.Name == name {
+				return mod
+			}
+		}
+		require.FailNow(t, "module not found", name)
+		return nil
+	}
+	hash := func(pkg *pbsubstreams.Package, graph *ModuleGraph, name string) string {
+		h, err := NewModuleHashes().HashModule(pkg.Modules, findModule(pkg, name), graph)
+		require.NoError(t, err)
+		return hex.EncodeToString(h)
+	}
+
+	basePkg, baseGraph, err := read("")
+	require.NoError(t, err)
+
+	pkg, graph, err := read(`
+overrides:
+  - module: eth:map_transfers
+    initialBlock: 200
+    params: "min=10"
+    blockFilter:
+      module: eth:index_calls
+      query: "to:0xdead"
+    inputs:
+      - params: string
+      - map: map_filtered_calls
+`)
+	require.NoError(t, err)
+
+	mod := findModule(pkg, "eth:map_transfers")
+	assert.Equal(t, uint64(200), mod.InitialBlock)
+	assert.Equal(t, "min=10", mod.Inputs[0].GetParams().Value)
+	assert.Equal(t, "map_filtered_calls", mod.Inputs[1].GetMap().ModuleName)
+	assert.Equal(t, &pbsubstreams.Module_BlockFilter{Module: "eth:index_calls", Query: "to:0xdead"}, mod.BlockFilter)
+
+	assert.Equal(t, hash(basePkg, baseGraph, "eth:map_calls"), hash(pkg, graph, "eth:map_calls"))
+	assert.NotEqual(t, hash(basePkg, baseGraph, "eth:map_transfers"), hash(pkg, graph, "eth:map_transfers"))
+
+	ancestors, err := graph.AncestorsOf("eth:map_transfers")
+	require.NoError(t, err)
+	var ancestorNames []string
+	for _, ancestor := range ancestors {
+		ancestorNames = append(ancestorNames, ancestor.Name)
+	}
+	assert.Equal(t, []string{"map_filtered_calls"}, ancestorNames)
+
+	// The block filter alone is part of the module hash
+	filteredPkg, filteredGraph, err := read(`
+overrides:
+  - module: eth:map_transfers
+    blockFilter:
+      module: eth:index_calls
+      query: "to:0xdead"
+`)
+	require.NoError(t, err)
+	assert.NotEqual(t, hash(basePkg, baseGraph, "eth:map_transfers"), hash(filteredPkg, filteredGraph, "eth:map_transfers"))
+
+	// The modules inheriting the initial block, imported or not, start with the overridden one
+	startPkg, _, err := read(`
+overrides:
+  - module: eth:map_calls
+    initialBlock: 150
+`)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(150), findModule(startPkg, "eth:map_calls").InitialBlock)
+	assert.Equal(t, uint64(150), findModule(startPkg, "eth:map_transfers").InitialBlock)
+	assert.Equal(t, uint64(150), findModule(startPkg, "map_transfer_counts").InitialBlock)
+	assert.Equal(t, uint64(100), findModule(basePkg, "map_transfer_counts").InitialBlock)
+
+	// unless they are overridden too
+	startPkg, _, err = read(`
+overrides:
+  - module: eth:map_transfers
+    initialBlock: 100
+  - module: eth:map_calls
+    initialBlock: 50
+`)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(50), findModule(startPkg, "eth:map_calls").InitialBlock)
+	assert.Equal(t, uint64(100), findModule(startPkg, "eth:map_transfers").InitialBlock)
+	assert.Equal(t, uint64(100), findModule(startPkg, "map_transfer_counts").InitialBlock)
+
+	tests := []struct {
+		name        string
+		overrides   string
+		expectedErr string
+	}{
+		{
+			name:        "unknown module",
+			overrides:   "overrides:\n  - module: eth:map_unknown\n    initialBlock: 1",
+			expectedErr: `override "eth:map_unknown": module not found`,
+		},
+		{
+			name:        "local module",
+			overrides:   "overrides:\n  - module: map_filtered_calls\n    initialBlock: 1",
+			expectedErr: `override "map_filtered_calls": module is defined in this manifest, change it directly`,
+		},
+		{
+			name:        "input with another type",
+			overrides:   "overrides:\n  - module: eth:map_transfers\n    inputs:\n      - params: string\n      - map: map_filtered_traces",
+			expectedErr: `override "eth:map_transfers": input [1]: module "map_filtered_traces" does not output "proto:eth.Calls" like "eth:map_calls"`,
+		},
+		{
+			name:        "inputs count",
+			overrides:   "overrides:\n  - module: eth:map_transfers\n    inputs:\n      - map: map_filtered_calls",
+			expectedErr: `override "eth:map_transfers": expected 2 inputs, got 1`,
+		},
+		{
+			name:        "params without params input",
+			overrides:   "overrides:\n  - module: eth:map_calls\n    params: foo",
+			expectedErr: `override "eth:map_calls": module does not have 'params' as its first input type`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := read(test.overrides)
+			assert.ErrorContains(t, err, test.expectedErr)
+		})
+	}
+}
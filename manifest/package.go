@@ -15,6 +15,10 @@ type manifestConverter struct {
 
 	sinkConfigDynamicMessage       *dynamic.Message
 	skipSourceCodeImportValidation bool
+	skipLockVerification           bool
+
+	lockedImports []*LockedImport
+	lockFilePath  string
 }
 
 func newManifestConverter(inputPath string, skipSourceCodeImportValidation bool) *manifestConverter {
@@ -59,6 +63,10 @@ func (r *manifestConverter) validateManifest(manif *Manifest) error {
 		return fmt.Errorf("invalid 'specVersion', must be v0.1.0")
 	}
 
+	if err := validateOverrides(manif); err != nil {
+		return err
+	}
+
 	// TODO: put some limits on the NUMBER of modules (max 50 ?)
 	// TODO: put a limit on the SIZE of the WASM payload (max 10MB per binary?)
 
@@ -121,6 +129,15 @@ func (r *manifestConverter) validateManifest(manif *Manifest) error {
 				return fmt.Errorf("module %q: invalid input [%d]: %w", s.Name, idx, err)
 			}
 		}
+
+		if s.ParamsSchema != nil {
+			if len(s.Inputs) == 0 || !s.Inputs[0].IsParams() {
+				return fmt.Errorf("module %q: 'paramsSchema' requires 'params' as the first input", s.Name)
+			}
+			if _, err := s.ParamsSchema.ToProto(); err != nil {
+				return fmt.Errorf("module %q: invalid 'paramsSchema': %w", s.Name, err)
+			}
+		}
 	}
 
 	return nil
@@ -240,9 +257,32 @@ func (r *manifestConverter) manifestToPkg(manif *Manifest) (*pbsubstreams.Packag
 		}
 	}
 
-	if err := loadImports(pkg, manif); err != nil {
+	var lock *Lockfile
+	if !r.skipLockVerification {
+		lockFilePath := r.lockFilePath
+		if lockFilePath == "" {
+			lockFilePath = filepath.Join(manif.Workdir, LockFileName)
+		}
+		if lock, err = ReadLockfile(lockFilePath); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	lockedImports, err := loadImports(pkg, manif, lock)
+	if err != nil {
 		return nil, nil, nil, fmt.Errorf("error loading imports: %w", err)
 	}
+	r.lockedImports = lockedImports
+
+	if err := applyOverrides(pkg, manif); err != nil {
+		return nil, nil, nil, fmt.Errorf("error applying overrides: %w", err)
+	}
+
+	if lock != nil {
+		if err := lock.Verify(lockedImports); err != nil {
+			return nil, nil, nil, err
+		}
+	}
 
 	if err := r.loadSinkConfig(pkg, manif); err != nil {
 		return nil, nil, nil, fmt.Errorf("error parsing sink configuration: %w", err)
@@ -344,7 +384,7 @@ func (r *manifestConverter) convertToPkg(m *Manifest) (pkg *pbsubstreams.Package
 		}
 
 		switch binaryDef.Type {
-		case "wasm/rust-v1":
+		case "wasm/rust-v1", "wasm/wasi":
 			// OPTIM(abourget): also check if it's not already in
 			// `Binaries`, by comparing its, length + hash or value.
 			codeIndex, found := moduleCodeIndexes[binaryDef.File]
@@ -362,6 +402,17 @@ func (r *manifestConverter) convertToPkg(m *Manifest) (pkg *pbsubstreams.Package
 				moduleCodeIndexes[binaryDef.File] = codeIndex
 			}
 			pbmod, err = mod.ToProtoWASM(uint32(codeIndex))
+		case "native":
+			if binaryDef.Native == "" {
+				return nil, fmt.Errorf("module %q: binary %q of type 'native' must define 'native'", mod.Name, binaryName)
+			}
+			codeIndex, found := moduleCodeIndexes["native:"+binaryDef.Native]
+			if !found {
+				pkg.Modules.Binaries = append(pkg.Modules.Binaries, &pbsubstreams.Binary{Type: binaryDef.Type, Content: []byte(binaryDef.Native)})
+				codeIndex = len(pkg.Modules.Binaries) - 1
+				moduleCodeIndexes["native:"+binaryDef.Native] = codeIndex
+			}
+			pbmod, err = mod.ToProtoWASM(uint32(codeIndex))
 		default:
 			return nil, fmt.Errorf("module %q: invalid code type %q", mod.Name, binaryDef.Type)
 		}
@@ -369,6 +420,15 @@ func (r *manifestConverter) convertToPkg(m *Manifest) (pkg *pbsubstreams.Package
 			return nil, err
 		}
 
+		if mod.ParamsSchema != nil {
+			if pbmeta.ParamsSchema, err = mod.ParamsSchema.ToProto(); err != nil {
+				return nil, fmt.Errorf("module %q: invalid 'paramsSchema': %w", mod.Name, err)
+			}
+			if len(pbmod.Inputs) != 0 && pbmod.Inputs[0].GetParams() != nil {
+				pbmod.Inputs[0].GetParams().Value = pbmeta.ParamsSchema.DefaultValue
+			}
+		}
+
 		pkg.ModuleMeta = append(pkg.ModuleMeta, pbmeta)
 		pkg.Modules.Modules = append(pkg.Modules.Modules, pbmod)
 	}
@@ -0,0 +1,169 @@
+package manifest
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+// CanonicalPackageBytes returns the bytes covered by a package signature: the deterministic
+// serialization of the package without its signatures.
+func CanonicalPackageBytes(pkg *pbsubstreams.Package) ([]byte, error) {
+	unsigned := proto.Clone(pkg).(*pbsubstreams.Package)
+	unsigned.Signatures = nil
+
+	cnt, err := proto.MarshalOptions{Deterministic: true}.Marshal(unsigned)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling package: %w", err)
+	}
+	return cnt, nil
+}
+
+// BinariesDigest returns the SHA-256 digest of the binaries of `modules`, in order, which is what
+// servers can verify from the modules of a request.
+func BinariesDigest(modules *pbsubstreams.Modules) []byte {
+	h := sha256.New()
+	lenBytes := make([]byte, 8)
+	for _, bin := range modules.GetBinaries() {
+		binary.LittleEndian.PutUint64(lenBytes, uint64(len(bin.Type)))
+		h.Write(lenBytes)
+		h.Write([]byte(bin.Type))
+		binary.LittleEndian.PutUint64(lenBytes, uint64(len(bin.Content)))
+		h.Write(lenBytes)
+		h.Write(bin.Content)
+	}
+	return h.Sum(nil)
+}
+
+// SignPackage attaches to `pkg` a signature by `key`, replacing any previous signature by the same key.
+func SignPackage(pkg *pbsubstreams.Package, key ed25519.PrivateKey) error {
+	cnt, err := CanonicalPackageBytes(pkg)
+	if err != nil {
+		return err
+	}
+
+	publicKey := key.Public().(ed25519.PublicKey)
+	signature := &pbsubstreams.PackageSignature{
+		PublicKey:         publicKey,
+		PackageSignature:  ed25519.Sign(key, cnt),
+		BinariesSignature: ed25519.Sign(key, BinariesDigest(pkg.Modules)),
+	}
+
+	signatures := []*pbsubstreams.PackageSignature{signature}
+	for _, existing := range pkg.Signatures {
+		if !bytes.Equal(existing.PublicKey, publicKey) {
+			signatures = append(signatures, existing)
+		}
+	}
+	pkg.Signatures = signatures
+	return nil
+}
+
+// VerifyPackageSignature ensures `pkg` carries a valid signature by one of the `trusted` keys.
+func VerifyPackageSignature(pkg *pbsubstreams.Package, trusted []ed25519.PublicKey) error {
+	if len(pkg.Signatures) == 0 {
+		return fmt.Errorf("package is not signed")
+	}
+
+	cnt, err := CanonicalPackageBytes(pkg)
+	if err != nil {
+		return err
+	}
+
+	return verifySignatures(pkg.Signatures, trusted, func(sig *pbsubstreams.PackageSignature) bool {
+		return ed25519.Verify(sig.PublicKey, cnt, sig.PackageSignature)
+	})
+}
+
+// VerifyBinariesSignature ensures one of `signatures`, sent alongside `modules` in a request, is a
+// valid signature of the modules' binaries by one of the `trusted` keys.
+func VerifyBinariesSignature(modules *pbsubstreams.Modules, signatures []*pbsubstreams.PackageSignature, trusted []ed25519.PublicKey) error {
+	if len(signatures) == 0 {
+		return fmt.Errorf("package is not signed")
+	}
+
+	digest := BinariesDigest(modules)
+	return verifySignatures(signatures, trusted, func(sig *pbsubstreams.PackageSignature) bool {
+		return ed25519.Verify(sig.PublicKey, digest, sig.BinariesSignature)
+	})
+}
+
+func verifySignatures(signatures []*pbsubstreams.PackageSignature, trusted []ed25519.PublicKey, verify func(sig *pbsubstreams.PackageSignature) bool) error {
+	var signers []string
+	for _, sig := range signatures {
+		if len(sig.PublicKey) != ed25519.PublicKeySize {
+			continue
+		}
+		signers = append(signers, hex.EncodeToString(sig.PublicKey))
+
+		if !isTrustedKey(sig.PublicKey, trusted) {
+			continue
+		}
+		if !verify(sig) {
+			return fmt.Errorf("invalid signature by trusted key %s, the package was modified after being signed", hex.EncodeToString(sig.PublicKey))
+		}
+		return nil
+	}
+
+	return fmt.Errorf("package is not signed by a trusted key (signed by: %s)", strings.Join(signers, ", "))
+}
+
+func isTrustedKey(key []byte, trusted []ed25519.PublicKey) bool {
+	for _, trustedKey := range trusted {
+		if bytes.Equal(key, trustedKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadSigningKey reads an ed25519 private key from `path`, either a PKCS #8 PEM file (as generated by
+// `openssl genpkey -algorithm ed25519`) or the hex-encoded 32 bytes seed.
+func ReadSigningKey(path string) (ed25519.PrivateKey, error) {
+	cnt, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading signing key: %w", err)
+	}
+
+	if block, _ := pem.Decode(cnt); block != nil {
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing signing key %q: %w", path, err)
+		}
+		edKey, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("signing key %q: expected an ed25519 key, got %T", path, key)
+		}
+		return edKey, nil
+	}
+
+	seed, err := hex.DecodeString(strings.TrimSpace(string(cnt)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("signing key %q: expected a PKCS #8 PEM file or a hex-encoded %d bytes seed", path, ed25519.SeedSize)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// ParsePublicKeys parses hex-encoded ed25519 public keys, as printed by `substreams pack --sign-key`.
+func ParsePublicKeys(in []string) ([]ed25519.PublicKey, error) {
+	out := make([]ed25519.PublicKey, 0, len(in))
+	for _, key := range in {
+		decoded, err := hex.DecodeString(strings.TrimSpace(key))
+		if err != nil || len(decoded) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid public key %q: expected %d hex-encoded bytes", key, ed25519.PublicKeySize)
+		}
+		out = append(out, decoded)
+	}
+	return out, nil
+}
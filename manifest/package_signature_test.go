@@ -0,0 +1,124 @@
+package manifest
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestSignPackage(t *testing.T) {
+	signerPub, signer, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	otherPub, other, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	newPkg := func() *pbsubstreams.Package {
+		return &pbsubstreams.Package{
+			Version: 1,
+			Modules: &pbsubstreams.Modules{
+				Binaries: []*pbsubstreams.Binary{{Type: "wasm/rust-v1", Content: []byte{0x00, 0x61, 0x73, 0x6d}}},
+			},
+			PackageMeta: []*pbsubstreams.PackageMetadata{{Name: "test", Version: "v0.1.0"}},
+		}
+	}
+
+	pkg := newPkg()
+	assert.EqualError(t, VerifyPackageSignature(pkg, []ed25519.PublicKey{signerPub}), "package is not signed")
+
+	require.NoError(t, SignPackage(pkg, signer))
+	require.NoError(t, SignPackage(pkg, other))
+	require.NoError(t, SignPackage(pkg, signer))
+	require.Len(t, pkg.Signatures, 2, "signing again with the same key replaces its signature")
+
+	// Survives serialization
+	cnt, err := proto.Marshal(pkg)
+	require.NoError(t, err)
+	pkg = &pbsubstreams.Package{}
+	require.NoError(t, proto.Unmarshal(cnt, pkg))
+
+	assert.NoError(t, VerifyPackageSignature(pkg, []ed25519.PublicKey{signerPub}))
+	assert.NoError(t, VerifyPackageSignature(pkg, []ed25519.PublicKey{otherPub}))
+	assert.NoError(t, VerifyBinariesSignature(pkg.Modules, pkg.Signatures, []ed25519.PublicKey{signerPub}))
+
+	untrusted, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	err = VerifyPackageSignature(pkg, []ed25519.PublicKey{untrusted})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "package is not signed by a trusted key (signed by: "+hex.EncodeToString(signerPub))
+
+	// Metadata changes only invalidate the package signature, binaries changes invalidate both
+	pkg.PackageMeta[0].Doc = "tampered"
+	assert.ErrorContains(t, VerifyPackageSignature(pkg, []ed25519.PublicKey{signerPub}), "the package was modified after being signed")
+	assert.NoError(t, VerifyBinariesSignature(pkg.Modules, pkg.Signatures, []ed25519.PublicKey{signerPub}))
+
+	pkg.Modules.Binaries[0].Content = append(pkg.Modules.Binaries[0].Content, 0x01)
+	assert.ErrorContains(t, VerifyBinariesSignature(pkg.Modules, pkg.Signatures, []ed25519.PublicKey{signerPub}), "the package was modified after being signed")
+	assert.EqualError(t, VerifyBinariesSignature(pkg.Modules, nil, []ed25519.PublicKey{signerPub}), "package is not signed")
+}
+
+func TestReader_TrustedSigners(t *testing.T) {
+	dir := t.TempDir()
+
+	signerPub, signer, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	pkg, _, err := newTestReader(t, "testdata/spkg1/spkg1-v0.0.0.spkg").Read()
+	require.NoError(t, err)
+
+	_, _, err = newTestReader(t, "testdata/spkg1/spkg1-v0.0.0.spkg", WithTrustedSigners([]ed25519.PublicKey{signerPub})).Read()
+	assert.EqualError(t, err, "verifying package signature: package is not signed")
+
+	require.NoError(t, SignPackage(pkg, signer))
+	cnt, err := proto.Marshal(pkg)
+	require.NoError(t, err)
+	signedPath := filepath.Join(dir, "signed.spkg")
+	require.NoError(t, os.WriteFile(signedPath, cnt, 0644))
+
+	_, _, err = newTestReader(t, signedPath, WithTrustedSigners([]ed25519.PublicKey{signerPub})).Read()
+	assert.NoError(t, err)
+}
+
+func TestReadSigningKey(t *testing.T) {
+	dir := t.TempDir()
+	_, key, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	pemPath := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(pemPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+
+	seedPath := filepath.Join(dir, "key.hex")
+	require.NoError(t, os.WriteFile(seedPath, []byte(hex.EncodeToString(key.Seed())+"\n"), 0600))
+
+	for _, path := range []string{pemPath, seedPath} {
+		read, err := ReadSigningKey(path)
+		require.NoError(t, err, path)
+		assert.True(t, key.Equal(read), path)
+	}
+
+	keys, err := ParsePublicKeys([]string{hex.EncodeToString(key.Public().(ed25519.PublicKey))})
+	require.NoError(t, err)
+	assert.True(t, key.Public().(ed25519.PublicKey).Equal(keys[0]))
+
+	_, err = ParsePublicKeys([]string{"abcd"})
+	assert.EqualError(t, err, `invalid public key "abcd": expected 32 hex-encoded bytes`)
+}
+
+func newTestReader(t *testing.T, input string, opts ...Option) *Reader {
+	t.Helper()
+
+	reader, err := NewReader(input, opts...)
+	require.NoError(t, err)
+	return reader
+}
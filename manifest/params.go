@@ -1,7 +1,11 @@
 package manifest
 
 import (
+	"encoding/json"
 	"fmt"
+	"math/big"
+	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/schollz/closestmatch"
@@ -46,3 +50,103 @@ func ApplyParams(params map[string]string, pkg *pbsubstreams.Package) error {
 	}
 	return nil
 }
+
+// ParamsSchema describes the value expected by the `params` input of a module.
+type ParamsSchema struct {
+	Type    string   `yaml:"type"`
+	Regex   string   `yaml:"regex"`
+	Enum    []string `yaml:"enum"`
+	Default *string  `yaml:"default"`
+	Doc     string   `yaml:"doc"`
+}
+
+var paramsSchemaTypes = map[string]pbsubstreams.ParamsSchema_Type{
+	"":        pbsubstreams.ParamsSchema_TYPE_STRING,
+	"string":  pbsubstreams.ParamsSchema_TYPE_STRING,
+	"int":     pbsubstreams.ParamsSchema_TYPE_INT,
+	"address": pbsubstreams.ParamsSchema_TYPE_ADDRESS,
+	"json":    pbsubstreams.ParamsSchema_TYPE_JSON,
+}
+
+func (s *ParamsSchema) ToProto() (*pbsubstreams.ParamsSchema, error) {
+	paramsType, found := paramsSchemaTypes[s.Type]
+	if !found {
+		return nil, fmt.Errorf("invalid type %q, must be one of: string, int, address, json", s.Type)
+	}
+	if s.Regex != "" {
+		if _, err := regexp.Compile(s.Regex); err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+	}
+
+	out := &pbsubstreams.ParamsSchema{
+		Type:       paramsType,
+		Regex:      s.Regex,
+		EnumValues: s.Enum,
+		Doc:        s.Doc,
+	}
+	if s.Default != nil {
+		if err := ValidateParamValue(out, *s.Default); err != nil {
+			return nil, fmt.Errorf("invalid default: %w", err)
+		}
+		out.DefaultValue = *s.Default
+	}
+	return out, nil
+}
+
+var addressRegexp = regexp.MustCompile(`^(0x)?[0-9a-fA-F]{1,64}$`)
+
+// ValidateParamValue checks `value` against `schema`. An empty value is considered unset and always valid.
+func ValidateParamValue(schema *pbsubstreams.ParamsSchema, value string) error {
+	if value == "" {
+		return nil
+	}
+
+	switch schema.Type {
+	case pbsubstreams.ParamsSchema_TYPE_INT:
+		if _, ok := new(big.Int).SetString(value, 10); !ok {
+			return fmt.Errorf("value %q is not a decimal integer", value)
+		}
+	case pbsubstreams.ParamsSchema_TYPE_ADDRESS:
+		if !addressRegexp.MatchString(value) {
+			return fmt.Errorf("value %q is not a hex-encoded address", value)
+		}
+	case pbsubstreams.ParamsSchema_TYPE_JSON:
+		if !json.Valid([]byte(value)) {
+			return fmt.Errorf("value %q is not valid JSON", value)
+		}
+	}
+
+	if schema.Regex != "" {
+		re, err := regexp.Compile("^(?:" + schema.Regex + ")$")
+		if err != nil {
+			return fmt.Errorf("invalid regex in params schema: %w", err)
+		}
+		if !re.MatchString(value) {
+			return fmt.Errorf("value %q does not match %q", value, schema.Regex)
+		}
+	}
+
+	if len(schema.EnumValues) != 0 && !slices.Contains(schema.EnumValues, value) {
+		return fmt.Errorf("value %q is not one of: %s", value, strings.Join(schema.EnumValues, ", "))
+	}
+
+	return nil
+}
+
+// ValidateParams checks the `params` value of every module of `pkg` declaring a params schema.
+func ValidateParams(pkg *pbsubstreams.Package) error {
+	for idx, mod := range pkg.Modules.Modules {
+		if idx >= len(pkg.ModuleMeta) {
+			break
+		}
+		schema := pkg.ModuleMeta[idx].GetParamsSchema()
+		if schema == nil || len(mod.Inputs) == 0 || mod.Inputs[0].GetParams() == nil {
+			continue
+		}
+		if err := ValidateParamValue(schema, mod.Inputs[0].GetParams().Value); err != nil {
+			return fmt.Errorf("param for module %q: %w", mod.Name, err)
+		}
+	}
+	return nil
+}
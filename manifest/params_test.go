@@ -0,0 +1,107 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestValidateParamValue(t *testing.T) {
+	tests := []struct {
+		name        string
+		schema      *pbsubstreams.ParamsSchema
+		value       string
+		expectedErr string
+	}{
+		{"empty value is unset", &pbsubstreams.ParamsSchema{Type: pbsubstreams.ParamsSchema_TYPE_INT}, "", ""},
+		{"int", &pbsubstreams.ParamsSchema{Type: pbsubstreams.ParamsSchema_TYPE_INT}, "115792089237316195423570985008687907853269984665640564039457584007913129639935", ""},
+		{"invalid int", &pbsubstreams.ParamsSchema{Type: pbsubstreams.ParamsSchema_TYPE_INT}, "12a", `value "12a" is not a decimal integer`},
+		{"address", &pbsubstreams.ParamsSchema{Type: pbsubstreams.ParamsSchema_TYPE_ADDRESS}, "0xdAC17F958D2ee523a2206206994597C13D831ec7", ""},
+		{"invalid address", &pbsubstreams.ParamsSchema{Type: pbsubstreams.ParamsSchema_TYPE_ADDRESS}, "0xzz", `value "0xzz" is not a hex-encoded address`},
+		{"json", &pbsubstreams.ParamsSchema{Type: pbsubstreams.ParamsSchema_TYPE_JSON}, `{"min": 10}`, ""},
+		{"invalid json", &pbsubstreams.ParamsSchema{Type: pbsubstreams.ParamsSchema_TYPE_JSON}, `{"min":`, `value "{\"min\":" is not valid JSON`},
+		{"regex matches the whole value", &pbsubstreams.ParamsSchema{Regex: "[a-z]+"}, "abc1", `value "abc1" does not match "[a-z]+"`},
+		{"enum", &pbsubstreams.ParamsSchema{EnumValues: []string{"mainnet", "sepolia"}}, "sepolia", ""},
+		{"not in enum", &pbsubstreams.ParamsSchema{EnumValues: []string{"mainnet", "sepolia"}}, "goerli", `value "goerli" is not one of: mainnet, sepolia`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateParamValue(test.schema, test.value)
+			if test.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.expectedErr)
+			}
+		})
+	}
+}
+
+func TestReader_ParamsSchema(t *testing.T) {
+	dir := t.TempDir()
+
+	write := func(schema string) string {
+		t.Helper()
+		path := filepath.Join(dir, "substreams.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(`
+specVersion: v0.1.0
+package:
+  name: test
+  version: v0.0.0
+
+binaries:
+  default:
+    type: native
+    native: test
+
+modules:
+  - name: map_transfers
+    kind: map
+    inputs:
+      - params: string
+      - source: sf.ethereum.type.v2.Block
+`+schema+`
+    output:
+      type: proto:eth.Transfers
+`), 0644))
+		return path
+	}
+
+	path := write(`
+    paramsSchema:
+      type: int
+      default: "1000"
+      doc: Minimum amount of the transfers`)
+
+	pkg, _, err := newTestReader(t, path).Read()
+	require.NoError(t, err)
+	assert.Equal(t, "1000", pkg.Modules.Modules[0].Inputs[0].GetParams().Value)
+	assert.Equal(t, &pbsubstreams.ParamsSchema{
+		Type:         pbsubstreams.ParamsSchema_TYPE_INT,
+		DefaultValue: "1000",
+		Doc:          "Minimum amount of the transfers",
+	}, pkg.ModuleMeta[0].ParamsSchema)
+
+	pkg, _, err = newTestReader(t, path, WithParams(map[string]string{"map_transfers": "10"})).Read()
+	require.NoError(t, err)
+	assert.Equal(t, "10", pkg.Modules.Modules[0].Inputs[0].GetParams().Value)
+
+	_, _, err = newTestReader(t, path, WithParams(map[string]string{"map_transfers": "ten"})).Read()
+	assert.EqualError(t, err, `param for module "map_transfers": value "ten" is not a decimal integer`)
+
+	_, _, err = newTestReader(t, write(`
+    paramsSchema:
+      type: int
+      default: "ten"`)).Read()
+	assert.ErrorContains(t, err, `module "map_transfers": invalid 'paramsSchema': invalid default: value "ten" is not a decimal integer`)
+
+	_, _, err = newTestReader(t, write(`
+    paramsSchema:
+      type: float`)).Read()
+	assert.ErrorContains(t, err, `module "map_transfers": invalid 'paramsSchema': invalid type "float", must be one of: string, int, address, json`)
+}
@@ -0,0 +1,255 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+var systemProtoFileNames = sync.OnceValue(func() map[string]bool {
+	out := map[string]bool{}
+	fds, err := readSystemProtobufs()
+	if err != nil {
+		panic(fmt.Errorf("reading system protobufs: %w", err))
+	}
+	for _, file := range fds.File {
+		out[file.GetName()] = true
+	}
+	return out
+})
+
+// mergeProtoFiles adds the proto files of `src` to `dest`, deduplicating identical files. When both
+// packages hold a different version of the same file, the one that is a compatible superset of the
+// other is kept, system files from `dest` always win, and anything else is a conflict. A message or
+// enum defined in two different files is a conflict too.
+func mergeProtoFiles(src, dest *pbsubstreams.Package) error {
+	filesIndex := map[string]int{}
+	typesOwner := map[string]string{}
+	for idx, file := range dest.ProtoFiles {
+		filesIndex[file.GetName()] = idx
+		for _, name := range protoFileTypeNames(file) {
+			typesOwner[name] = file.GetName()
+		}
+	}
+
+	for _, file := range src.ProtoFiles {
+		name := file.GetName()
+
+		if idx, found := filesIndex[name]; found {
+			existing := dest.ProtoFiles[idx]
+			switch {
+			case sameProtoFile(existing, file):
+				continue
+			case systemProtoFileNames()[name]:
+				zlog.Debug("keeping system version of proto file", zap.String("proto_file", name))
+				continue
+			}
+
+			existingCovers, reason := protoFileCovers(existing, file)
+			if existingCovers {
+				zlog.Debug("keeping existing superset of proto file", zap.String("proto_file", name))
+				continue
+			}
+			if covers, _ := protoFileCovers(file, existing); covers {
+				zlog.Debug("replacing proto file with imported superset", zap.String("proto_file", name))
+				dest.ProtoFiles[idx] = file
+				for _, typeName := range protoFileTypeNames(file) {
+					typesOwner[typeName] = name
+				}
+				continue
+			}
+			return fmt.Errorf("proto file %q conflicts with an already loaded version: %s", name, reason)
+		}
+
+		for _, typeName := range protoFileTypeNames(file) {
+			if owner, found := typesOwner[typeName]; found {
+				return fmt.Errorf("proto type %q is defined in both %q and %q", typeName, owner, name)
+			}
+			typesOwner[typeName] = name
+		}
+
+		filesIndex[name] = len(dest.ProtoFiles)
+		dest.ProtoFiles = append(dest.ProtoFiles, file)
+	}
+
+	return nil
+}
+
+func sameProtoFile(a, b *descriptorpb.FileDescriptorProto) bool {
+	a = proto.Clone(a).(*descriptorpb.FileDescriptorProto)
+	b = proto.Clone(b).(*descriptorpb.FileDescriptorProto)
+	a.SourceCodeInfo = nil
+	b.SourceCodeInfo = nil
+	return proto.Equal(a, b)
+}
+
+// protoFileTypeNames returns the fully-qualified names of the messages and enums of `file`, nested ones included.
+func protoFileTypeNames(file *descriptorpb.FileDescriptorProto) (out []string) {
+	prefix := file.GetPackage()
+
+	var walk func(prefix string, messages []*descriptorpb.DescriptorProto, enums []*descriptorpb.EnumDescriptorProto)
+	walk = func(prefix string, messages []*descriptorpb.DescriptorProto, enums []*descriptorpb.EnumDescriptorProto) {
+		for _, enum := range enums {
+			out = append(out, qualifiedProtoName(prefix, enum.GetName()))
+		}
+		for _, msg := range messages {
+			name := qualifiedProtoName(prefix, msg.GetName())
+			out = append(out, name)
+			walk(name, msg.NestedType, msg.EnumType)
+		}
+	}
+	walk(prefix, file.MessageType, file.EnumType)
+
+	return out
+}
+
+func qualifiedProtoName(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// protoFileCovers returns whether `a` is a compatible superset of `b`: same package, and every
+// message, field and enum value of `b` exists in `a` with the same number and type. Otherwise, the
+// reason is the first incompatibility found.
+func protoFileCovers(a, b *descriptorpb.FileDescriptorProto) (bool, string) {
+	if a.GetPackage() != b.GetPackage() {
+		return false, fmt.Sprintf("package %q differs from %q", b.GetPackage(), a.GetPackage())
+	}
+
+	messages := map[string]*descriptorpb.DescriptorProto{}
+	enums := map[string]*descriptorpb.EnumDescriptorProto{}
+	indexProtoTypes(a.GetPackage(), a.MessageType, a.EnumType, messages, enums)
+
+	otherMessages := map[string]*descriptorpb.DescriptorProto{}
+	otherEnums := map[string]*descriptorpb.EnumDescriptorProto{}
+	indexProtoTypes(b.GetPackage(), b.MessageType, b.EnumType, otherMessages, otherEnums)
+
+	for name, otherMsg := range otherMessages {
+		msg, found := messages[name]
+		if !found {
+			return false, fmt.Sprintf("message %q is missing", name)
+		}
+
+		fields := map[int32]*descriptorpb.FieldDescriptorProto{}
+		for _, field := range msg.Field {
+			fields[field.GetNumber()] = field
+		}
+		for _, otherField := range otherMsg.Field {
+			field, found := fields[otherField.GetNumber()]
+			if !found {
+				return false, fmt.Sprintf("field %s.%s (%d) is missing", name, otherField.GetName(), otherField.GetNumber())
+			}
+			if field.GetType() != otherField.GetType() || field.GetTypeName() != otherField.GetTypeName() || field.GetLabel() != otherField.GetLabel() {
+				return false, fmt.Sprintf("field %s.%s (%d) is %s, not %s", name, otherField.GetName(), otherField.GetNumber(), describeProtoField(otherField), describeProtoField(field))
+			}
+		}
+	}
+
+	for name, otherEnum := range otherEnums {
+		enum, found := enums[name]
+		if !found {
+			return false, fmt.Sprintf("enum %q is missing", name)
+		}
+
+		values := map[string]int32{}
+		for _, value := range enum.Value {
+			values[value.GetName()] = value.GetNumber()
+		}
+		for _, otherValue := range otherEnum.Value {
+			number, found := values[otherValue.GetName()]
+			if !found || number != otherValue.GetNumber() {
+				return false, fmt.Sprintf("enum value %s.%s (%d) is missing", name, otherValue.GetName(), otherValue.GetNumber())
+			}
+		}
+	}
+
+	return true, ""
+}
+
+func indexProtoTypes(prefix string, messages []*descriptorpb.DescriptorProto, enums []*descriptorpb.EnumDescriptorProto, messagesOut map[string]*descriptorpb.DescriptorProto, enumsOut map[string]*descriptorpb.EnumDescriptorProto) {
+	for _, enum := range enums {
+		enumsOut[qualifiedProtoName(prefix, enum.GetName())] = enum
+	}
+	for _, msg := range messages {
+		name := qualifiedProtoName(prefix, msg.GetName())
+		messagesOut[name] = msg
+		indexProtoTypes(name, msg.NestedType, msg.EnumType, messagesOut, enumsOut)
+	}
+}
+
+func describeProtoField(field *descriptorpb.FieldDescriptorProto) string {
+	out := strings.ToLower(strings.TrimPrefix(field.GetLabel().String(), "LABEL_")) + " "
+	if field.GetTypeName() != "" {
+		return out + strings.TrimPrefix(field.GetTypeName(), ".")
+	}
+	return out + strings.ToLower(strings.TrimPrefix(field.GetType().String(), "TYPE_"))
+}
+
+// PruneProtoFiles removes from `pkg` the proto files not needed to decode any module input or output,
+// store value or sink configuration: only files defining one of those types, and their dependencies,
+// are kept.
+func PruneProtoFiles(pkg *pbsubstreams.Package) {
+	roots := map[string]bool{}
+	for _, mod := range pkg.Modules.GetModules() {
+		switch kind := mod.Kind.(type) {
+		case *pbsubstreams.Module_KindMap_:
+			roots[strings.TrimPrefix(kind.KindMap.OutputType, "proto:")] = true
+		case *pbsubstreams.Module_KindStore_:
+			roots[strings.TrimPrefix(kind.KindStore.ValueType, "proto:")] = true
+		case *pbsubstreams.Module_KindBlockIndex_:
+			roots[strings.TrimPrefix(kind.KindBlockIndex.OutputType, "proto:")] = true
+		}
+		for _, input := range mod.Inputs {
+			if source := input.GetSource(); source != nil {
+				roots[source.Type] = true
+			}
+		}
+	}
+	if pkg.SinkConfig != nil {
+		typeURL := pkg.SinkConfig.TypeUrl
+		roots[typeURL[strings.LastIndex(typeURL, "/")+1:]] = true
+	}
+
+	files := map[string]*descriptorpb.FileDescriptorProto{}
+	keep := map[string]bool{}
+	var visit func(name string)
+	visit = func(name string) {
+		if keep[name] {
+			return
+		}
+		keep[name] = true
+		for _, dep := range files[name].GetDependency() {
+			visit(dep)
+		}
+	}
+
+	for _, file := range pkg.ProtoFiles {
+		files[file.GetName()] = file
+	}
+	for _, file := range pkg.ProtoFiles {
+		for _, typeName := range protoFileTypeNames(file) {
+			if roots[typeName] {
+				visit(file.GetName())
+				break
+			}
+		}
+	}
+
+	var kept []*descriptorpb.FileDescriptorProto
+	for _, file := range pkg.ProtoFiles {
+		if keep[file.GetName()] {
+			kept = append(kept, file)
+		} else {
+			zlog.Debug("pruning unused proto file", zap.String("proto_file", file.GetName()))
+		}
+	}
+	pkg.ProtoFiles = kept
+}
@@ -0,0 +1,121 @@
+package manifest
+
+import (
+	"testing"
+
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func parseProtoFiles(t *testing.T, sources map[string]string, names ...string) []*descriptorpb.FileDescriptorProto {
+	t.Helper()
+
+	parser := protoparse.Parser{Accessor: protoparse.FileContentsFromMap(sources)}
+	fds, err := parser.ParseFiles(names...)
+	require.NoError(t, err)
+
+	out := make([]*descriptorpb.FileDescriptorProto, len(fds))
+	for i, fd := range fds {
+		out[i] = fd.AsFileDescriptorProto()
+	}
+	return out
+}
+
+func protoFileNames(files []*descriptorpb.FileDescriptorProto) (out []string) {
+	for _, file := range files {
+		out = append(out, file.GetName())
+	}
+	return
+}
+
+func TestMergeProtoFiles(t *testing.T) {
+	v1 := `syntax = "proto3"; package token.v1; message Transfer { string from = 1; string to = 2; }`
+	v2 := `syntax = "proto3"; package token.v1; message Transfer { string from = 1; string to = 2; string amount = 3; } enum Kind { UNKNOWN = 0; }`
+	incompatible := `syntax = "proto3"; package token.v1; message Transfer { string from = 1; uint64 to = 2; }`
+	moved := `syntax = "proto3"; package token.v1; message Transfer { string from = 1; }`
+
+	parse := func(name, content string) *descriptorpb.FileDescriptorProto {
+		return parseProtoFiles(t, map[string]string{name: content}, name)[0]
+	}
+	pkgWith := func(files ...*descriptorpb.FileDescriptorProto) *pbsubstreams.Package {
+		return &pbsubstreams.Package{ProtoFiles: files}
+	}
+
+	t.Run("identical files are deduplicated", func(t *testing.T) {
+		dest := pkgWith(parse("token.proto", v1))
+		require.NoError(t, mergeProtoFiles(pkgWith(parse("token.proto", v1)), dest))
+		assert.Len(t, dest.ProtoFiles, 1)
+	})
+
+	t.Run("imported superset replaces existing file", func(t *testing.T) {
+		dest := pkgWith(parse("token.proto", v1))
+		require.NoError(t, mergeProtoFiles(pkgWith(parse("token.proto", v2)), dest))
+		require.Len(t, dest.ProtoFiles, 1)
+		assert.Len(t, dest.ProtoFiles[0].MessageType[0].Field, 3)
+	})
+
+	t.Run("existing superset is kept", func(t *testing.T) {
+		dest := pkgWith(parse("token.proto", v2))
+		require.NoError(t, mergeProtoFiles(pkgWith(parse("token.proto", v1)), dest))
+		require.Len(t, dest.ProtoFiles, 1)
+		assert.Len(t, dest.ProtoFiles[0].MessageType[0].Field, 3)
+	})
+
+	t.Run("incompatible versions of a file", func(t *testing.T) {
+		err := mergeProtoFiles(pkgWith(parse("token.proto", incompatible)), pkgWith(parse("token.proto", v1)))
+		assert.EqualError(t, err, `proto file "token.proto" conflicts with an already loaded version: field token.v1.Transfer.to (2) is optional uint64, not optional string`)
+	})
+
+	t.Run("same type in two files", func(t *testing.T) {
+		err := mergeProtoFiles(pkgWith(parse("other/token.proto", moved)), pkgWith(parse("token.proto", v1)))
+		assert.EqualError(t, err, `proto type "token.v1.Transfer" is defined in both "token.proto" and "other/token.proto"`)
+	})
+
+	t.Run("system files always keep the system version", func(t *testing.T) {
+		systemFiles, err := readSystemProtobufs()
+		require.NoError(t, err)
+
+		var clock *descriptorpb.FileDescriptorProto
+		for _, file := range systemFiles.File {
+			if file.GetName() == "sf/substreams/v1/clock.proto" {
+				clock = file
+			}
+		}
+		require.NotNil(t, clock)
+
+		older := proto.Clone(clock).(*descriptorpb.FileDescriptorProto)
+		older.MessageType[0].Field[0].Type = descriptorpb.FieldDescriptorProto_TYPE_BYTES.Enum()
+
+		dest := pkgWith(clock)
+		require.NoError(t, mergeProtoFiles(pkgWith(older), dest))
+		assert.Same(t, clock, dest.ProtoFiles[0])
+	})
+}
+
+func TestPruneProtoFiles(t *testing.T) {
+	files := parseProtoFiles(t, map[string]string{
+		"shared.proto": `syntax = "proto3"; package shared; message Amount { string value = 1; }`,
+		"output.proto": `syntax = "proto3"; package out; import "shared.proto"; message Transfers { repeated shared.Amount amounts = 1; }`,
+		"store.proto":  `syntax = "proto3"; package st; message Balance { string value = 1; }`,
+		"unused.proto": `syntax = "proto3"; package unused; message Internal { string value = 1; }`,
+	}, "output.proto", "store.proto", "unused.proto")
+	files = append([]*descriptorpb.FileDescriptorProto{parseProtoFiles(t, map[string]string{
+		"shared.proto": `syntax = "proto3"; package shared; message Amount { string value = 1; }`,
+	}, "shared.proto")[0]}, files...)
+
+	pkg := &pbsubstreams.Package{
+		ProtoFiles: files,
+		Modules: &pbsubstreams.Modules{Modules: []*pbsubstreams.Module{
+			{Name: "map_transfers", Kind: &pbsubstreams.Module_KindMap_{KindMap: &pbsubstreams.Module_KindMap{OutputType: "proto:out.Transfers"}}},
+			{Name: "store_balances", Kind: &pbsubstreams.Module_KindStore_{KindStore: &pbsubstreams.Module_KindStore{ValueType: "proto:st.Balance"}}},
+		}},
+	}
+
+	PruneProtoFiles(pkg)
+	assert.Equal(t, []string{"shared.proto", "output.proto", "store.proto"}, protoFileNames(pkg.ProtoFiles))
+}
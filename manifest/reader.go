@@ -3,6 +3,7 @@ package manifest
 import (
 	"bytes"
 	"context"
+	"crypto/ed25519"
 	"fmt"
 	"io"
 	"net/http"
@@ -16,15 +17,14 @@ import (
 	ipfs "github.com/ipfs/go-ipfs-api"
 	"github.com/jhump/protoreflect/dynamic"
 	"github.com/streamingfast/dstore"
+	"go.uber.org/zap"
 	"golang.org/x/mod/semver"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/jhump/protoreflect/desc"
 	"github.com/streamingfast/cli"
 	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
-	"go.uber.org/zap"
 	"gopkg.in/yaml.v2"
-	yaml3 "gopkg.in/yaml.v3"
 )
 
 var IPFSURL string
@@ -60,11 +60,20 @@ type Reader struct {
 
 	collectProtoDefinitionsFunc func(protoDefinitions []*desc.FileDescriptor)
 
+	// imports resolved while reading a local manifest
+	lockedImports []*LockedImport
+	// overlays merged into the local manifest, see `WithOverlays`
+	appliedOverlays []string
+
 	//options
 	skipSourceCodeImportValidation bool
 	skipModuleOutputTypeValidation bool
 	skipPackageValidation          bool
+	skipLockVerification           bool
+	trustedSigners                 []ed25519.PublicKey
+	pruneProtoFiles                bool
 	overrideNetwork                string
+	overlays                       []string
 	overrideOutputModule           string
 	params                         map[string]string
 }
@@ -110,6 +119,12 @@ func (r *Reader) Read() (*pbsubstreams.Package, *ModuleGraph, error) {
 		return nil, nil, err
 	}
 
+	if len(r.trustedSigners) != 0 {
+		if err := VerifyPackageSignature(pkg, r.trustedSigners); err != nil {
+			return nil, nil, fmt.Errorf("verifying package signature: %w", err)
+		}
+	}
+
 	if !r.skipPackageValidation {
 		if err := validatePackage(pkg, r.skipModuleOutputTypeValidation); err != nil {
 			return nil, nil, fmt.Errorf("package validation failed: %w", err)
@@ -120,6 +135,10 @@ func (r *Reader) Read() (*pbsubstreams.Package, *ModuleGraph, error) {
 		}
 	}
 
+	if r.pruneProtoFiles {
+		PruneProtoFiles(pkg)
+	}
+
 	graph, err := NewModuleGraph(pkg.Modules.Modules)
 	if err != nil {
 		return nil, nil, err
@@ -153,6 +172,10 @@ func (r *Reader) Read() (*pbsubstreams.Package, *ModuleGraph, error) {
 		}
 	}
 
+	if err := ValidateParams(pkg); err != nil {
+		return nil, nil, err
+	}
+
 	if err := computeInitialBlock(pkg.Modules.Modules, graph); err != nil {
 		return nil, nil, err
 	}
@@ -319,11 +342,27 @@ func (r *Reader) getPkg() (*pbsubstreams.Package, error) {
 	}
 
 	if strings.HasSuffix(r.currentInput, ".yaml") || strings.HasSuffix(r.currentInput, ".yml") {
-		manif := &Manifest{}
-		decoder := yaml3.NewDecoder(bytes.NewReader(r.currentData))
-		decoder.KnownFields(true)
+		var overlays [][]byte
+		if r.IsRemotePackage(r.currentInput) {
+			if len(r.overlays) != 0 {
+				return nil, fmt.Errorf("overlays are only supported for local manifests")
+			}
+		} else {
+			names, paths, err := resolveOverlays(r.currentInput, r.overrideNetwork, r.overlays)
+			if err != nil {
+				return nil, err
+			}
+			if len(names) != 0 {
+				zlog.Info("applying manifest overlays", zap.String("manifest", r.currentInput), zap.Strings("overlays", names))
+			}
+			if overlays, err = readOverlays(paths); err != nil {
+				return nil, err
+			}
+			r.appliedOverlays = names
+		}
 
-		if err := decoder.Decode(&manif); err != nil {
+		manif, err := decodeManifest(r.currentData, overlays)
+		if err != nil {
 			return nil, fmt.Errorf("unable to unmarshal manifest: %w", err)
 		}
 
@@ -446,10 +485,13 @@ func validatePackage(pkg *pbsubstreams.Package, skipModuleOutputTypeValidation b
 
 func (r *Reader) newPkgFromManifest(manif *Manifest) (*pbsubstreams.Package, error) {
 	converter := newManifestConverter(r.currentInput, r.skipSourceCodeImportValidation)
+	converter.skipLockVerification = r.skipLockVerification
+	converter.lockFilePath = r.LockfilePath()
 	pkg, descriptors, dynMessage, err := converter.Convert(manif)
 	if err != nil {
 		return nil, err
 	}
+	r.lockedImports = converter.lockedImports
 	r.sinkConfigDynamicMessage = dynMessage
 
 	if r.collectProtoDefinitionsFunc != nil {
@@ -478,6 +520,26 @@ func (r *Reader) resolvePkg() (*pbsubstreams.Package, error) {
 	return pkg, nil
 }
 
+// Lockfile returns the lock pinning what each `imports` entry resolved to, only available after
+// a local manifest has been read.
+func (r *Reader) Lockfile() *Lockfile {
+	if !r.IsLocalManifest() || r.pkg == nil {
+		return nil
+	}
+	return NewLockfile(r.lockedImports)
+}
+
+// LockfilePath returns the path of the lock file of a local manifest, next to it. Overlays can change
+// `imports`, so each combination of overlays has its own lock file: `substreams.prod.lock` when
+// overlay `prod` was applied.
+func (r *Reader) LockfilePath() string {
+	name := LockFileName
+	if len(r.appliedOverlays) != 0 {
+		name = strings.TrimSuffix(LockFileName, ".lock") + "." + strings.Join(r.appliedOverlays, ".") + ".lock"
+	}
+	return filepath.Join(filepath.Dir(r.currentInput), name)
+}
+
 // IsRemotePackage determines if reader's input to read the manifest is a remote file accessible over
 // HTTP/HTTPS, Google Cloud Storage, S3 or Azure Storage.
 func (r *Reader) IsRemotePackage(input string) bool {
@@ -642,8 +704,10 @@ func ValidateModules(mods *pbsubstreams.Modules) error {
 	return nil
 }
 
-func LoadManifestFile(inputPath, workingDir string) (*Manifest, error) {
-	m, err := decodeYamlManifestFromFile(inputPath, workingDir)
+// LoadManifestFile decodes the manifest at `inputPath`, deep-merged with the given overlays in order
+// (see `OverlayPath`).
+func LoadManifestFile(inputPath, workingDir string, overlays ...string) (*Manifest, error) {
+	m, err := decodeYamlManifestFromFile(inputPath, workingDir, overlays...)
 	if err != nil {
 		return nil, fmt.Errorf("decoding yaml: %w", err)
 	}
@@ -699,29 +763,69 @@ func LoadManifestFile(inputPath, workingDir string) (*Manifest, error) {
 }
 
 // loop through the Manifest, and get the `imports` statements,
-// pull the Package files from Disk, and merge them into this one
-func loadImports(pkg *pbsubstreams.Package, manif *Manifest) error {
+// pull the Package files from Disk, and merge them into this one.
+// It returns what each import resolved to, as recorded in the lock file.
+// Imports of the form `<package>@<version>` are resolved against the registry at `RegistryURL`,
+// preferring the version pinned in `lock` (which may be nil) while it matches.
+func loadImports(pkg *pbsubstreams.Package, manif *Manifest, lock *Lockfile) ([]*LockedImport, error) {
+	lockedByName := map[string]*LockedImport{}
+	if lock != nil {
+		for _, imp := range lock.Imports {
+			lockedByName[imp.Name] = imp
+		}
+	}
+
+	var locked []*LockedImport
 	for _, kv := range manif.Imports {
 		importName := kv[0]
-		importPath := manif.resolvePath(kv[1])
+
+		var importPath string
+		var registryEntry *RegistryEntry
+		if packageName, constraint, ok := parseRegistryImport(kv[1]); ok {
+			var err error
+			registryEntry, importPath, err = resolveRegistryImport(packageName, constraint, lockedByName[importName])
+			if err != nil {
+				return nil, fmt.Errorf("importing %q: %w", kv[1], err)
+			}
+		} else {
+			importPath = manif.resolvePath(kv[1])
+		}
 
 		subpkgReader, err := NewReader(importPath)
 		if err != nil {
-			return fmt.Errorf("importing %q: %w", importPath, err)
+			return nil, fmt.Errorf("importing %q: %w", importPath, err)
+		}
+
+		subpkg, subgraph, err := subpkgReader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("importing %q: %w", importPath, err)
 		}
 
-		subpkg, _, err := subpkgReader.Read()
+		lockedURL := importPath
+		if registryEntry == nil {
+			lockedURL = lockURL(importPath, manif.Workdir)
+		}
+		lockedImport, err := newLockedImport(importName, lockedURL, subpkgReader.currentData, subpkg, subgraph)
 		if err != nil {
-			return fmt.Errorf("importing %q: %w", importPath, err)
+			return nil, fmt.Errorf("importing %q: %w", importPath, err)
+		}
+		if registryEntry != nil {
+			if registryEntry.SHA256 != "" && registryEntry.SHA256 != lockedImport.SHA256 {
+				return nil, fmt.Errorf("importing %q: sha256 %s does not match %s from the registry index", importPath, lockedImport.SHA256, registryEntry.SHA256)
+			}
+			lockedImport.Version = registryEntry.Version
 		}
+		locked = append(locked, lockedImport)
 
 		prefixModules(subpkg.Modules.Modules, importName)
 		reindexAndMergePackage(subpkg, pkg)
-		mergeProtoFiles(subpkg, pkg)
+		if err := mergeProtoFiles(subpkg, pkg); err != nil {
+			return nil, fmt.Errorf("importing %q: %w", importPath, err)
+		}
 		mergeNetworks(subpkg, pkg, importName)
 	}
 
-	return nil
+	return locked, nil
 }
 
 var PNGHeader = []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}
@@ -796,31 +900,6 @@ func reindexAndMergePackage(src, dest *pbsubstreams.Package) {
 	dest.PackageMeta = append(dest.PackageMeta, src.PackageMeta...)
 }
 
-func mergeProtoFiles(src, dest *pbsubstreams.Package) {
-	seenFiles := map[string]bool{}
-
-	for _, file := range dest.ProtoFiles {
-		seenFiles[*file.Name] = true
-	}
-
-	for _, file := range src.ProtoFiles {
-		key := *file.Name
-		if seenFiles[key] {
-			zlog.Debug("skipping proto file already seen", zap.String("proto_file", *file.Name))
-			continue
-		}
-		seenFiles[key] = true
-		dest.ProtoFiles = append(dest.ProtoFiles, file)
-	}
-
-	// TODO: do DEDUPLICATION of those protofiles and/or of the messages therein,
-	// otherwise they can duplicate quickly.
-
-	// TODO: eventually, we want the last Message type to win, or perhaps we'd search in reverse order
-	// upon `print` or generation? The thing is we'll want tools like `protoc` and `buf` to use the most
-	// recent, but it'll simply go in list order..
-}
-
 var storeValidTypes = map[string]bool{
 	"bigint":     true,
 	"int64":      true,
@@ -1,6 +1,10 @@
 package manifest
 
-import "github.com/jhump/protoreflect/desc"
+import (
+	"crypto/ed25519"
+
+	"github.com/jhump/protoreflect/desc"
+)
 
 type Option func(r *Reader) *Reader
 
@@ -25,6 +29,32 @@ func SkipPackageValidationReader() Option {
 	}
 }
 
+// SkipLockVerificationReader does not fail when the manifest's `imports` drifted from its lock file,
+// used to refresh it.
+func SkipLockVerificationReader() Option {
+	return func(r *Reader) *Reader {
+		r.skipLockVerification = true
+		return r
+	}
+}
+
+// WithTrustedSigners fails reading a package not signed by one of `keys`, see `substreams pack --sign-key`.
+func WithTrustedSigners(keys []ed25519.PublicKey) Option {
+	return func(r *Reader) *Reader {
+		r.trustedSigners = keys
+		return r
+	}
+}
+
+// WithProtoFilesPruning removes from the package the proto files that are not needed to decode any
+// module input or output, store value or sink configuration.
+func WithProtoFilesPruning() Option {
+	return func(r *Reader) *Reader {
+		r.pruneProtoFiles = true
+		return r
+	}
+}
+
 func WithOverrideNetwork(network string) Option {
 	return func(r *Reader) *Reader {
 		r.overrideNetwork = network
@@ -52,3 +82,12 @@ func WithCollectProtoDefinitions(f func(protoDefinitions []*desc.FileDescriptor)
 		return r
 	}
 }
+
+// WithOverlays deep-merges the overlays `substreams.<name>.yaml` found next to a local manifest into it,
+// in order. The overlay of the network given to `WithOverrideNetwork` is applied first, if it exists.
+func WithOverlays(names ...string) Option {
+	return func(r *Reader) *Reader {
+		r.overlays = names
+		return r
+	}
+}
@@ -0,0 +1,345 @@
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/streamingfast/dstore"
+	"golang.org/x/mod/semver"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+// RegistryURL is the location of the package registry used to resolve `<package>@<version>` imports:
+// any dstore URL (including a local directory) or an HTTP(S) base URL, holding an `index.json` file.
+var RegistryURL string
+
+const RegistryIndexFileName = "index.json"
+
+// RegistryIndex lists the published versions of each package of a registry.
+type RegistryIndex struct {
+	Packages map[string][]*RegistryEntry `json:"packages"`
+}
+
+type RegistryEntry struct {
+	Version string `json:"version"`
+	// URL of the `.spkg`, either absolute or relative to the registry
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// registryImportRegexp matches imports like `ethereum-common@^0.3`, `ethereum-common@v0.3.1` or `ethereum-common@latest`
+var registryImportRegexp = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9_-]*)@((?:\^|~|>=)?v?\d+(?:\.\d+){0,2}(?:-[0-9A-Za-z.-]+)?|\*|latest)$`)
+
+func parseRegistryImport(in string) (name, constraint string, ok bool) {
+	matches := registryImportRegexp.FindStringSubmatch(in)
+	if matches == nil {
+		return "", "", false
+	}
+	return matches[1], matches[2], true
+}
+
+// registryPackageName normalizes package names the same way `.spkg` file names are, so that both
+// `ethereum_common` and `ethereum-common` refer to the same package.
+func registryPackageName(name string) string {
+	return strings.ReplaceAll(name, "_", "-")
+}
+
+// versionConstraint is the range of versions [min, max) accepted by an import, `max` being unbounded
+// when empty. Pre-release versions only match an exact constraint.
+type versionConstraint struct {
+	min   string
+	max   string
+	exact bool
+}
+
+func parseVersionConstraint(in string) (*versionConstraint, error) {
+	if in == "*" || in == "latest" {
+		return &versionConstraint{min: "v0.0.0"}, nil
+	}
+
+	var op string
+	for _, prefix := range []string{"^", "~", ">="} {
+		if strings.HasPrefix(in, prefix) {
+			op = prefix
+			in = strings.TrimPrefix(in, prefix)
+			break
+		}
+	}
+
+	version := "v" + strings.TrimPrefix(in, "v")
+	if !semver.IsValid(version) {
+		return nil, fmt.Errorf("invalid version %q", in)
+	}
+
+	core := strings.SplitN(strings.SplitN(strings.TrimPrefix(version, "v"), "-", 2)[0], ".", 3)
+	parts := make([]int, 3)
+	for i, part := range core {
+		parts[i], _ = strconv.Atoi(part)
+	}
+	out := &versionConstraint{min: semver.Canonical(version)}
+
+	upper := func(idx int) string {
+		bumped := make([]int, 3)
+		copy(bumped, parts[:idx])
+		bumped[idx] = parts[idx] + 1
+		return fmt.Sprintf("v%d.%d.%d", bumped[0], bumped[1], bumped[2])
+	}
+
+	switch op {
+	case ">=":
+	case "^":
+		// the left-most non-zero component is the breaking one
+		switch {
+		case parts[0] != 0 || len(core) == 1:
+			out.max = upper(0)
+		case parts[1] != 0 || len(core) == 2:
+			out.max = upper(1)
+		default:
+			out.max = upper(2)
+		}
+	case "~":
+		if len(core) == 1 {
+			out.max = upper(0)
+		} else {
+			out.max = upper(1)
+		}
+	default:
+		// a partial version is a wildcard over the missing components
+		switch len(core) {
+		case 1:
+			out.max = upper(0)
+		case 2:
+			out.max = upper(1)
+		default:
+			out.exact = true
+		}
+	}
+
+	return out, nil
+}
+
+func (c *versionConstraint) matches(version string) bool {
+	if !semver.IsValid(version) {
+		return false
+	}
+	if c.exact {
+		return semver.Compare(version, c.min) == 0
+	}
+	if semver.Prerelease(version) != "" {
+		return false
+	}
+	if semver.Compare(version, c.min) < 0 {
+		return false
+	}
+	return c.max == "" || semver.Compare(version, c.max) < 0
+}
+
+// ReadRegistryIndex reads the index of the registry at `registryURL`, returning an empty index if it
+// does not exist yet on a dstore registry.
+func ReadRegistryIndex(ctx context.Context, registryURL string) (*RegistryIndex, error) {
+	var cnt []byte
+	if isHTTPRegistry(registryURL) {
+		indexURL := strings.TrimSuffix(registryURL, "/") + "/" + RegistryIndexFileName
+		resp, err := httpClient.Get(indexURL)
+		if err != nil {
+			return nil, fmt.Errorf("fetching registry index %q: %w", indexURL, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetching registry index %q: unexpected status %s", indexURL, resp.Status)
+		}
+		if cnt, err = io.ReadAll(resp.Body); err != nil {
+			return nil, fmt.Errorf("reading registry index %q: %w", indexURL, err)
+		}
+	} else {
+		store, err := registryStore(registryURL)
+		if err != nil {
+			return nil, err
+		}
+
+		exists, err := store.FileExists(ctx, RegistryIndexFileName)
+		if err != nil {
+			return nil, fmt.Errorf("checking registry index: %w", err)
+		}
+		if !exists {
+			return &RegistryIndex{Packages: map[string][]*RegistryEntry{}}, nil
+		}
+
+		reader, err := store.OpenObject(ctx, RegistryIndexFileName)
+		if err != nil {
+			return nil, fmt.Errorf("opening registry index: %w", err)
+		}
+		defer reader.Close()
+		if cnt, err = io.ReadAll(reader); err != nil {
+			return nil, fmt.Errorf("reading registry index: %w", err)
+		}
+	}
+
+	index := &RegistryIndex{}
+	if err := json.Unmarshal(cnt, index); err != nil {
+		return nil, fmt.Errorf("decoding registry index: %w", err)
+	}
+	if index.Packages == nil {
+		index.Packages = map[string][]*RegistryEntry{}
+	}
+	return index, nil
+}
+
+// Resolve returns the highest version of package `name` matching `constraint`, or `preferred`
+// (typically the version recorded in the lock file) if it still matches.
+func (i *RegistryIndex) Resolve(name, constraint, preferred string) (*RegistryEntry, error) {
+	c, err := parseVersionConstraint(constraint)
+	if err != nil {
+		return nil, fmt.Errorf("package %q: %w", name, err)
+	}
+
+	entries, found := i.Packages[registryPackageName(name)]
+	if !found {
+		return nil, fmt.Errorf("package %q not found in registry", name)
+	}
+
+	var best *RegistryEntry
+	for _, entry := range entries {
+		if !c.matches(entry.Version) {
+			continue
+		}
+		if preferred != "" && entry.Version == preferred {
+			return entry, nil
+		}
+		if best == nil || semver.Compare(entry.Version, best.Version) > 0 {
+			best = entry
+		}
+	}
+
+	if best == nil {
+		var versions []string
+		for _, entry := range entries {
+			versions = append(versions, entry.Version)
+		}
+		return nil, fmt.Errorf("package %q: no version matches %q (available: %s)", name, constraint, strings.Join(versions, ", "))
+	}
+	return best, nil
+}
+
+// resolveRegistryImport resolves an import against the registry, returning where to fetch it from.
+func resolveRegistryImport(name, constraint string, locked *LockedImport) (*RegistryEntry, string, error) {
+	if RegistryURL == "" {
+		return nil, "", fmt.Errorf("no package registry configured to resolve %s@%s", name, constraint)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	index, err := ReadRegistryIndex(ctx, RegistryURL)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var preferred string
+	if locked != nil {
+		preferred = locked.Version
+	}
+
+	entry, err := index.Resolve(name, constraint, preferred)
+	if err != nil {
+		return nil, "", err
+	}
+	return entry, registryEntryURL(RegistryURL, entry.URL), nil
+}
+
+func registryEntryURL(registryURL, entryURL string) string {
+	if hasRemotePrefix(entryURL) || filepath.IsAbs(entryURL) {
+		return entryURL
+	}
+	if hasRemotePrefix(registryURL) {
+		return strings.TrimSuffix(registryURL, "/") + "/" + entryURL
+	}
+	return filepath.Join(strings.TrimPrefix(registryURL, "file://"), filepath.FromSlash(entryURL))
+}
+
+func isHTTPRegistry(registryURL string) bool {
+	return strings.HasPrefix(registryURL, "http://") || strings.HasPrefix(registryURL, "https://")
+}
+
+func registryStore(registryURL string) (dstore.Store, error) {
+	store, err := dstore.NewSimpleStore(strings.TrimSuffix(registryURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("registry store %q: %w", registryURL, err)
+	}
+	return store, nil
+}
+
+// PublishPackage uploads the `.spkg` content `cnt` of `pkg` to the registry at `registryURL` and adds
+// it to the registry index. Publishing an existing version fails unless `overwrite` is set.
+//
+// The index is read, updated and written back without locking: publishing to the same registry
+// concurrently is not supported.
+func PublishPackage(ctx context.Context, registryURL string, pkg *pbsubstreams.Package, cnt []byte, overwrite bool) (*RegistryEntry, error) {
+	if isHTTPRegistry(registryURL) {
+		return nil, fmt.Errorf("cannot publish to HTTP registry %q, publish to its underlying storage instead", registryURL)
+	}
+	if len(pkg.PackageMeta) == 0 {
+		return nil, fmt.Errorf("package has no metadata")
+	}
+
+	name := registryPackageName(pkg.PackageMeta[0].Name)
+	version := pkg.PackageMeta[0].Version
+	if !semver.IsValid(version) {
+		return nil, fmt.Errorf("package %q: version %q should match Semver", name, version)
+	}
+
+	index, err := ReadRegistryIndex(ctx, registryURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*RegistryEntry
+	for _, entry := range index.Packages[name] {
+		if entry.Version == version {
+			if !overwrite {
+				return nil, fmt.Errorf("package %q version %s already published", name, version)
+			}
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	store, err := registryStore(registryURL)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &RegistryEntry{
+		Version: version,
+		URL:     fmt.Sprintf("%s/%s-%s.spkg", name, name, version),
+		SHA256:  sha256Hex(cnt),
+	}
+	if err := store.WriteObject(ctx, entry.URL, bytes.NewReader(cnt)); err != nil {
+		return nil, fmt.Errorf("uploading package: %w", err)
+	}
+
+	entries = append(entries, entry)
+	sort.Slice(entries, func(i, j int) bool { return semver.Compare(entries[i].Version, entries[j].Version) < 0 })
+	index.Packages[name] = entries
+
+	indexCnt, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding registry index: %w", err)
+	}
+	if err := store.WriteObject(ctx, RegistryIndexFileName, bytes.NewReader(indexCnt)); err != nil {
+		return nil, fmt.Errorf("writing registry index: %w", err)
+	}
+
+	return entry, nil
+}
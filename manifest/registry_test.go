@@ -0,0 +1,140 @@
+package manifest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestVersionConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		matching   []string
+		other      []string
+	}{
+		{"^0.3", []string{"v0.3.0", "v0.3.9"}, []string{"v0.2.9", "v0.4.0", "v0.3.1-rc1"}},
+		{"^0.0.3", []string{"v0.0.3"}, []string{"v0.0.4", "v0.1.0"}},
+		{"^1.2", []string{"v1.2.0", "v1.9.0"}, []string{"v1.1.9", "v2.0.0"}},
+		{"~1.2.3", []string{"v1.2.3", "v1.2.9"}, []string{"v1.2.2", "v1.3.0"}},
+		{">=1.2.0", []string{"v1.2.0", "v5.0.0"}, []string{"v1.1.0"}},
+		{"1.2", []string{"v1.2.0", "v1.2.7"}, []string{"v1.3.0"}},
+		{"v1.2.3", []string{"v1.2.3"}, []string{"v1.2.4"}},
+		{"1.0.0-rc1", []string{"v1.0.0-rc1"}, []string{"v1.0.0"}},
+		{"latest", []string{"v0.0.1", "v9.0.0"}, []string{"v1.0.0-rc1"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.constraint, func(t *testing.T) {
+			c, err := parseVersionConstraint(test.constraint)
+			require.NoError(t, err)
+			for _, version := range test.matching {
+				assert.True(t, c.matches(version), version)
+			}
+			for _, version := range test.other {
+				assert.False(t, c.matches(version), version)
+			}
+		})
+	}
+}
+
+func TestParseRegistryImport(t *testing.T) {
+	name, constraint, ok := parseRegistryImport("ethereum-common@^0.3")
+	assert.True(t, ok)
+	assert.Equal(t, "ethereum-common", name)
+	assert.Equal(t, "^0.3", constraint)
+
+	for _, in := range []string{"./dep@1.spkg", "https://user@example.com/dep.spkg", "dep-v0.1.0.spkg", "dep@v1.yaml"} {
+		_, _, ok := parseRegistryImport(in)
+		assert.False(t, ok, in)
+	}
+}
+
+func TestReader_RegistryImports(t *testing.T) {
+	registryDir := t.TempDir()
+	projectDir := t.TempDir()
+	ctx := context.Background()
+
+	publish := func(version string) *RegistryEntry {
+		t.Helper()
+		cnt, err := proto.Marshal(&pbsubstreams.Package{
+			Version:     1,
+			Modules:     &pbsubstreams.Modules{},
+			PackageMeta: []*pbsubstreams.PackageMetadata{{Name: "spkg1", Version: version}},
+		})
+		require.NoError(t, err)
+
+		pkg := &pbsubstreams.Package{}
+		require.NoError(t, proto.Unmarshal(cnt, pkg))
+		entry, err := PublishPackage(ctx, registryDir, pkg, cnt, false)
+		require.NoError(t, err)
+		return entry
+	}
+
+	publish("v0.3.0")
+	publish("v0.3.2")
+	publish("v0.4.0")
+
+	_, err := PublishPackage(ctx, registryDir, &pbsubstreams.Package{PackageMeta: []*pbsubstreams.PackageMetadata{{Name: "spkg1", Version: "v0.3.2"}}}, nil, false)
+	assert.EqualError(t, err, `package "spkg1" version v0.3.2 already published`)
+
+	index, err := ReadRegistryIndex(ctx, registryDir)
+	require.NoError(t, err)
+	require.Len(t, index.Packages["spkg1"], 3)
+	assert.Equal(t, "spkg1/spkg1-v0.3.0.spkg", index.Packages["spkg1"][0].URL)
+
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "substreams.yaml"), []byte(`
+specVersion: v0.1.0
+package:
+  name: test
+  version: v0.0.0
+
+imports:
+  dep: spkg1@^0.3
+`), 0644))
+
+	defer func(previous string) { RegistryURL = previous }(RegistryURL)
+
+	read := func(opts ...Option) *Reader {
+		t.Helper()
+		reader, err := NewReader(filepath.Join(projectDir, "substreams.yaml"), opts...)
+		require.NoError(t, err)
+		pkg, _, err := reader.Read()
+		require.NoError(t, err)
+		require.Len(t, pkg.PackageMeta, 2)
+		return reader
+	}
+
+	RegistryURL = registryDir
+	lock := read().Lockfile()
+	require.Len(t, lock.Imports, 1)
+	assert.Equal(t, "v0.3.2", lock.Imports[0].Version)
+	assert.Equal(t, filepath.Join(registryDir, "spkg1", "spkg1-v0.3.2.spkg"), lock.Imports[0].URL)
+	require.NoError(t, lock.Write(filepath.Join(projectDir, LockFileName)))
+
+	// A newer compatible version is published, the locked one is kept until the lock is refreshed
+	publish("v0.3.5")
+	assert.Equal(t, "v0.3.2", read().Lockfile().Imports[0].Version)
+	assert.Equal(t, "v0.3.5", read(SkipLockVerificationReader()).Lockfile().Imports[0].Version)
+
+	// Same registry served over HTTP
+	server := httptest.NewServer(http.FileServer(http.Dir(registryDir)))
+	defer server.Close()
+
+	RegistryURL = server.URL
+	lock = read(SkipLockVerificationReader()).Lockfile()
+	assert.Equal(t, "v0.3.5", lock.Imports[0].Version)
+	assert.Equal(t, server.URL+"/spkg1/spkg1-v0.3.5.spkg", lock.Imports[0].URL)
+
+	RegistryURL = ""
+	_, _, err = newTestReader(t, filepath.Join(projectDir, "substreams.yaml")).Read()
+	assert.ErrorContains(t, err, "no package registry configured to resolve spkg1@^0.3")
+}
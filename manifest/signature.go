@@ -44,6 +44,23 @@ func (m *ModuleHashes) Iter(cb func(hash, name string) error) error {
 	return nil
 }
 
+// OutputModuleHash returns the hex hash of the sink module of the package.
+func OutputModuleHash(pkg *pbsubstreams.Package) (string, error) {
+	graph, err := NewModuleGraph(pkg.Modules.Modules)
+	if err != nil {
+		return "", fmt.Errorf("creating module graph: %w", err)
+	}
+	mod, err := graph.Module(pkg.SinkModule)
+	if err != nil {
+		return "", fmt.Errorf("cannot find module %s", pkg.SinkModule)
+	}
+	hash, err := NewModuleHashes().HashModule(pkg.Modules, mod, graph)
+	if err != nil {
+		return "", fmt.Errorf("hashing module: %w", err)
+	}
+	return hex.EncodeToString(hash), nil
+}
+
 func (m *ModuleHashes) HashModule(modules *pbsubstreams.Modules, module *pbsubstreams.Module, graph *ModuleGraph) (ModuleHash, error) {
 	m.mu.RLock()
 	if cachedHash := m.cache[module.Name]; cachedHash != nil {
@@ -66,6 +83,8 @@ func (m *ModuleHashes) HashModule(modules *pbsubstreams.Modules, module *pbsubst
 		buf.WriteString("map")
 	case *pbsubstreams.Module_KindStore_:
 		buf.WriteString("store")
+	case *pbsubstreams.Module_KindBlockIndex_:
+		buf.WriteString("block_index")
 	default:
 		return nil, fmt.Errorf("invalid module file %T", module.Kind)
 	}
@@ -99,6 +118,20 @@ func (m *ModuleHashes) HashModule(modules *pbsubstreams.Modules, module *pbsubst
 		buf.Write(sig)
 	}
 
+	// only hashed when set, so that the hashes of modules without block filter stay the same
+	if blockFilter := module.BlockFilter; blockFilter != nil {
+		buf.WriteString("block_filter")
+		buf.WriteString(blockFilter.Module)
+		buf.WriteString(blockFilter.Query)
+		if filterModule, err := graph.Module(blockFilter.Module); err == nil {
+			sig, err := m.HashModule(modules, filterModule, graph)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(sig)
+		}
+	}
+
 	buf.WriteString("entrypoint")
 	buf.WriteString(module.BinaryEntrypoint)
 
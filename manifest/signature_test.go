@@ -113,3 +113,19 @@ func Test_HashModule(t *testing.T) {
 		})
 	}
 }
+
+func TestOutputModuleHash(t *testing.T) {
+	reader, err := NewReader("testdata/with-params.yaml")
+	require.NoError(t, err)
+	pkg, _, err := reader.Read()
+	require.NoError(t, err)
+
+	pkg.SinkModule = "mod2"
+	hash, err := OutputModuleHash(pkg)
+	require.NoError(t, err)
+	assert.Equal(t, "6aca30692dfa835efe09fbf51b0a1735ea3b3155", hash)
+
+	pkg.SinkModule = "unknown"
+	_, err = OutputModuleHash(pkg)
+	assert.ErrorContains(t, err, "cannot find module unknown")
+}
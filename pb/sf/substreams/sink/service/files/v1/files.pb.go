@@ -0,0 +1,165 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.27.0
+// 	protoc        (unknown)
+// source: sf/substreams/sink/service/files/v1/files.proto
+
+package pbsinkfiles
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Service deploys a substreams-sink-files process writing the output of the sink module to files.
+type Service struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Encoder of the output, one of "lines" (the default, one line per element of the output),
+	// "parquet" or "proto:<field path>"
+	Encoder string `protobuf:"bytes,1,opt,name=encoder,proto3" json:"encoder,omitempty"`
+	// Number of blocks in each file, 10000 when unset
+	FileBlockCount uint64 `protobuf:"varint,2,opt,name=file_block_count,json=fileBlockCount,proto3" json:"file_block_count,omitempty"`
+}
+
+func (x *Service) Reset() {
+	*x = Service{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sf_substreams_sink_service_files_v1_files_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Service) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Service) ProtoMessage() {}
+
+func (x *Service) ProtoReflect() protoreflect.Message {
+	mi := &file_sf_substreams_sink_service_files_v1_files_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Service.ProtoReflect.Descriptor instead.
+func (*Service) Descriptor() ([]byte, []int) {
+	return file_sf_substreams_sink_service_files_v1_files_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Service) GetEncoder() string {
+	if x != nil {
+		return x.Encoder
+	}
+	return ""
+}
+
+func (x *Service) GetFileBlockCount() uint64 {
+	if x != nil {
+		return x.FileBlockCount
+	}
+	return 0
+}
+
+var File_sf_substreams_sink_service_files_v1_files_proto protoreflect.FileDescriptor
+
+var file_sf_substreams_sink_service_files_v1_files_proto_rawDesc = []byte{
+	0x0a, 0x2f, 0x73, 0x66, 0x2f, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2f,
+	0x73, 0x69, 0x6e, 0x6b, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2f, 0x66, 0x69, 0x6c,
+	0x65, 0x73, 0x2f, 0x76, 0x31, 0x2f, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x12, 0x23, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73,
+	0x2e, 0x73, 0x69, 0x6e, 0x6b, 0x2e, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x66, 0x69,
+	0x6c, 0x65, 0x73, 0x2e, 0x76, 0x31, 0x22, 0x4d, 0x0a, 0x07, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63,
+	0x65, 0x12, 0x18, 0x0a, 0x07, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x07, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x65, 0x72, 0x12, 0x28, 0x0a, 0x10, 0x66,
+	0x69, 0x6c, 0x65, 0x5f, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0e, 0x66, 0x69, 0x6c, 0x65, 0x42, 0x6c, 0x6f, 0x63, 0x6b,
+	0x43, 0x6f, 0x75, 0x6e, 0x74, 0x42, 0x58, 0x5a, 0x56, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e,
+	0x63, 0x6f, 0x6d, 0x2f, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x69, 0x6e, 0x67, 0x66, 0x61, 0x73,
+	0x74, 0x2f, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2f, 0x70, 0x62, 0x2f,
+	0x73, 0x66, 0x2f, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2f, 0x73, 0x69,
+	0x6e, 0x6b, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2f, 0x66, 0x69, 0x6c, 0x65, 0x73,
+	0x2f, 0x76, 0x31, 0x3b, 0x70, 0x62, 0x73, 0x69, 0x6e, 0x6b, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x62,
+	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_sf_substreams_sink_service_files_v1_files_proto_rawDescOnce sync.Once
+	file_sf_substreams_sink_service_files_v1_files_proto_rawDescData = file_sf_substreams_sink_service_files_v1_files_proto_rawDesc
+)
+
+func file_sf_substreams_sink_service_files_v1_files_proto_rawDescGZIP() []byte {
+	file_sf_substreams_sink_service_files_v1_files_proto_rawDescOnce.Do(func() {
+		file_sf_substreams_sink_service_files_v1_files_proto_rawDescData = protoimpl.X.CompressGZIP(file_sf_substreams_sink_service_files_v1_files_proto_rawDescData)
+	})
+	return file_sf_substreams_sink_service_files_v1_files_proto_rawDescData
+}
+
+var file_sf_substreams_sink_service_files_v1_files_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_sf_substreams_sink_service_files_v1_files_proto_goTypes = []interface{}{
+	(*Service)(nil), // 0: sf.substreams.sink.service.files.v1.Service
+}
+var file_sf_substreams_sink_service_files_v1_files_proto_depIdxs = []int32{
+	0, // [0:0] is the sub-list for method output_type
+	0, // [0:0] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_sf_substreams_sink_service_files_v1_files_proto_init() }
+func file_sf_substreams_sink_service_files_v1_files_proto_init() {
+	if File_sf_substreams_sink_service_files_v1_files_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_sf_substreams_sink_service_files_v1_files_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Service); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_sf_substreams_sink_service_files_v1_files_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_sf_substreams_sink_service_files_v1_files_proto_goTypes,
+		DependencyIndexes: file_sf_substreams_sink_service_files_v1_files_proto_depIdxs,
+		MessageInfos:      file_sf_substreams_sink_service_files_v1_files_proto_msgTypes,
+	}.Build()
+	File_sf_substreams_sink_service_files_v1_files_proto = out.File
+	file_sf_substreams_sink_service_files_v1_files_proto_rawDesc = nil
+	file_sf_substreams_sink_service_files_v1_files_proto_goTypes = nil
+	file_sf_substreams_sink_service_files_v1_files_proto_depIdxs = nil
+}
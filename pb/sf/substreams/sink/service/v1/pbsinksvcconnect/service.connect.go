@@ -49,19 +49,31 @@ const (
 	ProviderResumeProcedure = "/sf.substreams.sink.service.v1.Provider/Resume"
 	// ProviderRemoveProcedure is the fully-qualified name of the Provider's Remove RPC.
 	ProviderRemoveProcedure = "/sf.substreams.sink.service.v1.Provider/Remove"
+	// ProviderHistoryProcedure is the fully-qualified name of the Provider's History RPC.
+	ProviderHistoryProcedure = "/sf.substreams.sink.service.v1.Provider/History"
+	// ProviderRollbackProcedure is the fully-qualified name of the Provider's Rollback RPC.
+	ProviderRollbackProcedure = "/sf.substreams.sink.service.v1.Provider/Rollback"
+	// ProviderLogsProcedure is the fully-qualified name of the Provider's Logs RPC.
+	ProviderLogsProcedure = "/sf.substreams.sink.service.v1.Provider/Logs"
+	// ProviderWatchDeploymentsProcedure is the fully-qualified name of the Provider's WatchDeployments RPC.
+	ProviderWatchDeploymentsProcedure = "/sf.substreams.sink.service.v1.Provider/WatchDeployments"
 )
 
 // These variables are the protoreflect.Descriptor objects for the RPCs defined in this package.
 var (
-	providerServiceDescriptor      = v1.File_sf_substreams_sink_service_v1_service_proto.Services().ByName("Provider")
-	providerDeployMethodDescriptor = providerServiceDescriptor.Methods().ByName("Deploy")
-	providerUpdateMethodDescriptor = providerServiceDescriptor.Methods().ByName("Update")
-	providerInfoMethodDescriptor   = providerServiceDescriptor.Methods().ByName("Info")
-	providerListMethodDescriptor   = providerServiceDescriptor.Methods().ByName("List")
-	providerPauseMethodDescriptor  = providerServiceDescriptor.Methods().ByName("Pause")
-	providerStopMethodDescriptor   = providerServiceDescriptor.Methods().ByName("Stop")
-	providerResumeMethodDescriptor = providerServiceDescriptor.Methods().ByName("Resume")
-	providerRemoveMethodDescriptor = providerServiceDescriptor.Methods().ByName("Remove")
+	providerServiceDescriptor                = v1.File_sf_substreams_sink_service_v1_service_proto.Services().ByName("Provider")
+	providerDeployMethodDescriptor           = providerServiceDescriptor.Methods().ByName("Deploy")
+	providerUpdateMethodDescriptor           = providerServiceDescriptor.Methods().ByName("Update")
+	providerInfoMethodDescriptor             = providerServiceDescriptor.Methods().ByName("Info")
+	providerListMethodDescriptor             = providerServiceDescriptor.Methods().ByName("List")
+	providerPauseMethodDescriptor            = providerServiceDescriptor.Methods().ByName("Pause")
+	providerStopMethodDescriptor             = providerServiceDescriptor.Methods().ByName("Stop")
+	providerResumeMethodDescriptor           = providerServiceDescriptor.Methods().ByName("Resume")
+	providerRemoveMethodDescriptor           = providerServiceDescriptor.Methods().ByName("Remove")
+	providerHistoryMethodDescriptor          = providerServiceDescriptor.Methods().ByName("History")
+	providerRollbackMethodDescriptor         = providerServiceDescriptor.Methods().ByName("Rollback")
+	providerLogsMethodDescriptor             = providerServiceDescriptor.Methods().ByName("Logs")
+	providerWatchDeploymentsMethodDescriptor = providerServiceDescriptor.Methods().ByName("WatchDeployments")
 )
 
 // ProviderClient is a client for the sf.substreams.sink.service.v1.Provider service.
@@ -74,6 +86,10 @@ type ProviderClient interface {
 	Stop(context.Context, *connect.Request[v1.StopRequest]) (*connect.Response[v1.StopResponse], error)
 	Resume(context.Context, *connect.Request[v1.ResumeRequest]) (*connect.Response[v1.ResumeResponse], error)
 	Remove(context.Context, *connect.Request[v1.RemoveRequest]) (*connect.Response[v1.RemoveResponse], error)
+	History(context.Context, *connect.Request[v1.HistoryRequest]) (*connect.Response[v1.HistoryResponse], error)
+	Rollback(context.Context, *connect.Request[v1.RollbackRequest]) (*connect.Response[v1.RollbackResponse], error)
+	Logs(context.Context, *connect.Request[v1.LogsRequest]) (*connect.ServerStreamForClient[v1.LogsResponse], error)
+	WatchDeployments(context.Context, *connect.Request[v1.WatchDeploymentsRequest]) (*connect.ServerStreamForClient[v1.WatchDeploymentsResponse], error)
 }
 
 // NewProviderClient constructs a client for the sf.substreams.sink.service.v1.Provider service. By
@@ -134,19 +150,47 @@ func NewProviderClient(httpClient connect.HTTPClient, baseURL string, opts ...co
 			connect.WithSchema(providerRemoveMethodDescriptor),
 			connect.WithClientOptions(opts...),
 		),
+		history: connect.NewClient[v1.HistoryRequest, v1.HistoryResponse](
+			httpClient,
+			baseURL+ProviderHistoryProcedure,
+			connect.WithSchema(providerHistoryMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		rollback: connect.NewClient[v1.RollbackRequest, v1.RollbackResponse](
+			httpClient,
+			baseURL+ProviderRollbackProcedure,
+			connect.WithSchema(providerRollbackMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		logs: connect.NewClient[v1.LogsRequest, v1.LogsResponse](
+			httpClient,
+			baseURL+ProviderLogsProcedure,
+			connect.WithSchema(providerLogsMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		watchDeployments: connect.NewClient[v1.WatchDeploymentsRequest, v1.WatchDeploymentsResponse](
+			httpClient,
+			baseURL+ProviderWatchDeploymentsProcedure,
+			connect.WithSchema(providerWatchDeploymentsMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
 	}
 }
 
 // providerClient implements ProviderClient.
 type providerClient struct {
-	deploy *connect.Client[v1.DeployRequest, v1.DeployResponse]
-	update *connect.Client[v1.UpdateRequest, v1.UpdateResponse]
-	info   *connect.Client[v1.InfoRequest, v1.InfoResponse]
-	list   *connect.Client[v1.ListRequest, v1.ListResponse]
-	pause  *connect.Client[v1.PauseRequest, v1.PauseResponse]
-	stop   *connect.Client[v1.StopRequest, v1.StopResponse]
-	resume *connect.Client[v1.ResumeRequest, v1.ResumeResponse]
-	remove *connect.Client[v1.RemoveRequest, v1.RemoveResponse]
+	deploy           *connect.Client[v1.DeployRequest, v1.DeployResponse]
+	update           *connect.Client[v1.UpdateRequest, v1.UpdateResponse]
+	info             *connect.Client[v1.InfoRequest, v1.InfoResponse]
+	list             *connect.Client[v1.ListRequest, v1.ListResponse]
+	pause            *connect.Client[v1.PauseRequest, v1.PauseResponse]
+	stop             *connect.Client[v1.StopRequest, v1.StopResponse]
+	resume           *connect.Client[v1.ResumeRequest, v1.ResumeResponse]
+	remove           *connect.Client[v1.RemoveRequest, v1.RemoveResponse]
+	history          *connect.Client[v1.HistoryRequest, v1.HistoryResponse]
+	rollback         *connect.Client[v1.RollbackRequest, v1.RollbackResponse]
+	logs             *connect.Client[v1.LogsRequest, v1.LogsResponse]
+	watchDeployments *connect.Client[v1.WatchDeploymentsRequest, v1.WatchDeploymentsResponse]
 }
 
 // Deploy calls sf.substreams.sink.service.v1.Provider.Deploy.
@@ -189,6 +233,26 @@ func (c *providerClient) Remove(ctx context.Context, req *connect.Request[v1.Rem
 	return c.remove.CallUnary(ctx, req)
 }
 
+// History calls sf.substreams.sink.service.v1.Provider.History.
+func (c *providerClient) History(ctx context.Context, req *connect.Request[v1.HistoryRequest]) (*connect.Response[v1.HistoryResponse], error) {
+	return c.history.CallUnary(ctx, req)
+}
+
+// Rollback calls sf.substreams.sink.service.v1.Provider.Rollback.
+func (c *providerClient) Rollback(ctx context.Context, req *connect.Request[v1.RollbackRequest]) (*connect.Response[v1.RollbackResponse], error) {
+	return c.rollback.CallUnary(ctx, req)
+}
+
+// Logs calls sf.substreams.sink.service.v1.Provider.Logs.
+func (c *providerClient) Logs(ctx context.Context, req *connect.Request[v1.LogsRequest]) (*connect.ServerStreamForClient[v1.LogsResponse], error) {
+	return c.logs.CallServerStream(ctx, req)
+}
+
+// WatchDeployments calls sf.substreams.sink.service.v1.Provider.WatchDeployments.
+func (c *providerClient) WatchDeployments(ctx context.Context, req *connect.Request[v1.WatchDeploymentsRequest]) (*connect.ServerStreamForClient[v1.WatchDeploymentsResponse], error) {
+	return c.watchDeployments.CallServerStream(ctx, req)
+}
+
 // ProviderHandler is an implementation of the sf.substreams.sink.service.v1.Provider service.
 type ProviderHandler interface {
 	Deploy(context.Context, *connect.Request[v1.DeployRequest]) (*connect.Response[v1.DeployResponse], error)
@@ -199,6 +263,10 @@ type ProviderHandler interface {
 	Stop(context.Context, *connect.Request[v1.StopRequest]) (*connect.Response[v1.StopResponse], error)
 	Resume(context.Context, *connect.Request[v1.ResumeRequest]) (*connect.Response[v1.ResumeResponse], error)
 	Remove(context.Context, *connect.Request[v1.RemoveRequest]) (*connect.Response[v1.RemoveResponse], error)
+	History(context.Context, *connect.Request[v1.HistoryRequest]) (*connect.Response[v1.HistoryResponse], error)
+	Rollback(context.Context, *connect.Request[v1.RollbackRequest]) (*connect.Response[v1.RollbackResponse], error)
+	Logs(context.Context, *connect.Request[v1.LogsRequest], *connect.ServerStream[v1.LogsResponse]) error
+	WatchDeployments(context.Context, *connect.Request[v1.WatchDeploymentsRequest], *connect.ServerStream[v1.WatchDeploymentsResponse]) error
 }
 
 // NewProviderHandler builds an HTTP handler from the service implementation. It returns the path on
@@ -255,6 +323,30 @@ func NewProviderHandler(svc ProviderHandler, opts ...connect.HandlerOption) (str
 		connect.WithSchema(providerRemoveMethodDescriptor),
 		connect.WithHandlerOptions(opts...),
 	)
+	providerHistoryHandler := connect.NewUnaryHandler(
+		ProviderHistoryProcedure,
+		svc.History,
+		connect.WithSchema(providerHistoryMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	providerRollbackHandler := connect.NewUnaryHandler(
+		ProviderRollbackProcedure,
+		svc.Rollback,
+		connect.WithSchema(providerRollbackMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	providerLogsHandler := connect.NewServerStreamHandler(
+		ProviderLogsProcedure,
+		svc.Logs,
+		connect.WithSchema(providerLogsMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	providerWatchDeploymentsHandler := connect.NewServerStreamHandler(
+		ProviderWatchDeploymentsProcedure,
+		svc.WatchDeployments,
+		connect.WithSchema(providerWatchDeploymentsMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
 	return "/sf.substreams.sink.service.v1.Provider/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case ProviderDeployProcedure:
@@ -273,6 +365,14 @@ func NewProviderHandler(svc ProviderHandler, opts ...connect.HandlerOption) (str
 			providerResumeHandler.ServeHTTP(w, r)
 		case ProviderRemoveProcedure:
 			providerRemoveHandler.ServeHTTP(w, r)
+		case ProviderHistoryProcedure:
+			providerHistoryHandler.ServeHTTP(w, r)
+		case ProviderRollbackProcedure:
+			providerRollbackHandler.ServeHTTP(w, r)
+		case ProviderLogsProcedure:
+			providerLogsHandler.ServeHTTP(w, r)
+		case ProviderWatchDeploymentsProcedure:
+			providerWatchDeploymentsHandler.ServeHTTP(w, r)
 		default:
 			http.NotFound(w, r)
 		}
@@ -313,3 +413,19 @@ func (UnimplementedProviderHandler) Resume(context.Context, *connect.Request[v1.
 func (UnimplementedProviderHandler) Remove(context.Context, *connect.Request[v1.RemoveRequest]) (*connect.Response[v1.RemoveResponse], error) {
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("sf.substreams.sink.service.v1.Provider.Remove is not implemented"))
 }
+
+func (UnimplementedProviderHandler) History(context.Context, *connect.Request[v1.HistoryRequest]) (*connect.Response[v1.HistoryResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("sf.substreams.sink.service.v1.Provider.History is not implemented"))
+}
+
+func (UnimplementedProviderHandler) Rollback(context.Context, *connect.Request[v1.RollbackRequest]) (*connect.Response[v1.RollbackResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("sf.substreams.sink.service.v1.Provider.Rollback is not implemented"))
+}
+
+func (UnimplementedProviderHandler) Logs(context.Context, *connect.Request[v1.LogsRequest], *connect.ServerStream[v1.LogsResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("sf.substreams.sink.service.v1.Provider.Logs is not implemented"))
+}
+
+func (UnimplementedProviderHandler) WatchDeployments(context.Context, *connect.Request[v1.WatchDeploymentsRequest], *connect.ServerStream[v1.WatchDeploymentsResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("sf.substreams.sink.service.v1.Provider.WatchDeployments is not implemented"))
+}
@@ -30,6 +30,10 @@ type ProviderClient interface {
 	Stop(ctx context.Context, in *StopRequest, opts ...grpc.CallOption) (*StopResponse, error)
 	Resume(ctx context.Context, in *ResumeRequest, opts ...grpc.CallOption) (*ResumeResponse, error)
 	Remove(ctx context.Context, in *RemoveRequest, opts ...grpc.CallOption) (*RemoveResponse, error)
+	History(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*HistoryResponse, error)
+	Rollback(ctx context.Context, in *RollbackRequest, opts ...grpc.CallOption) (*RollbackResponse, error)
+	Logs(ctx context.Context, in *LogsRequest, opts ...grpc.CallOption) (Provider_LogsClient, error)
+	WatchDeployments(ctx context.Context, in *WatchDeploymentsRequest, opts ...grpc.CallOption) (Provider_WatchDeploymentsClient, error)
 }
 
 type providerClient struct {
@@ -112,6 +116,88 @@ func (c *providerClient) Remove(ctx context.Context, in *RemoveRequest, opts ...
 	return out, nil
 }
 
+func (c *providerClient) History(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*HistoryResponse, error) {
+	out := new(HistoryResponse)
+	err := c.cc.Invoke(ctx, "/sf.substreams.sink.service.v1.Provider/History", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *providerClient) Rollback(ctx context.Context, in *RollbackRequest, opts ...grpc.CallOption) (*RollbackResponse, error) {
+	out := new(RollbackResponse)
+	err := c.cc.Invoke(ctx, "/sf.substreams.sink.service.v1.Provider/Rollback", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *providerClient) Logs(ctx context.Context, in *LogsRequest, opts ...grpc.CallOption) (Provider_LogsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Provider_ServiceDesc.Streams[0], "/sf.substreams.sink.service.v1.Provider/Logs", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &providerLogsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Provider_LogsClient interface {
+	Recv() (*LogsResponse, error)
+	grpc.ClientStream
+}
+
+type providerLogsClient struct {
+	grpc.ClientStream
+}
+
+func (x *providerLogsClient) Recv() (*LogsResponse, error) {
+	m := new(LogsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *providerClient) WatchDeployments(ctx context.Context, in *WatchDeploymentsRequest, opts ...grpc.CallOption) (Provider_WatchDeploymentsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Provider_ServiceDesc.Streams[1], "/sf.substreams.sink.service.v1.Provider/WatchDeployments", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &providerWatchDeploymentsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Provider_WatchDeploymentsClient interface {
+	Recv() (*WatchDeploymentsResponse, error)
+	grpc.ClientStream
+}
+
+type providerWatchDeploymentsClient struct {
+	grpc.ClientStream
+}
+
+func (x *providerWatchDeploymentsClient) Recv() (*WatchDeploymentsResponse, error) {
+	m := new(WatchDeploymentsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // ProviderServer is the server API for Provider service.
 // All implementations should embed UnimplementedProviderServer
 // for forward compatibility
@@ -124,6 +210,10 @@ type ProviderServer interface {
 	Stop(context.Context, *StopRequest) (*StopResponse, error)
 	Resume(context.Context, *ResumeRequest) (*ResumeResponse, error)
 	Remove(context.Context, *RemoveRequest) (*RemoveResponse, error)
+	History(context.Context, *HistoryRequest) (*HistoryResponse, error)
+	Rollback(context.Context, *RollbackRequest) (*RollbackResponse, error)
+	Logs(*LogsRequest, Provider_LogsServer) error
+	WatchDeployments(*WatchDeploymentsRequest, Provider_WatchDeploymentsServer) error
 }
 
 // UnimplementedProviderServer should be embedded to have forward compatible implementations.
@@ -154,6 +244,18 @@ func (UnimplementedProviderServer) Resume(context.Context, *ResumeRequest) (*Res
 func (UnimplementedProviderServer) Remove(context.Context, *RemoveRequest) (*RemoveResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Remove not implemented")
 }
+func (UnimplementedProviderServer) History(context.Context, *HistoryRequest) (*HistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method History not implemented")
+}
+func (UnimplementedProviderServer) Rollback(context.Context, *RollbackRequest) (*RollbackResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Rollback not implemented")
+}
+func (UnimplementedProviderServer) Logs(*LogsRequest, Provider_LogsServer) error {
+	return status.Errorf(codes.Unimplemented, "method Logs not implemented")
+}
+func (UnimplementedProviderServer) WatchDeployments(*WatchDeploymentsRequest, Provider_WatchDeploymentsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchDeployments not implemented")
+}
 
 // UnsafeProviderServer may be embedded to opt out of forward compatibility for this service.
 // Use of this interface is not recommended, as added methods to ProviderServer will
@@ -310,6 +412,84 @@ func _Provider_Remove_Handler(srv interface{}, ctx context.Context, dec func(int
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Provider_History_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProviderServer).History(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sf.substreams.sink.service.v1.Provider/History",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProviderServer).History(ctx, req.(*HistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provider_Rollback_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RollbackRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProviderServer).Rollback(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sf.substreams.sink.service.v1.Provider/Rollback",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProviderServer).Rollback(ctx, req.(*RollbackRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provider_Logs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(LogsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ProviderServer).Logs(m, &providerLogsServer{stream})
+}
+
+type Provider_LogsServer interface {
+	Send(*LogsResponse) error
+	grpc.ServerStream
+}
+
+type providerLogsServer struct {
+	grpc.ServerStream
+}
+
+func (x *providerLogsServer) Send(m *LogsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Provider_WatchDeployments_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchDeploymentsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ProviderServer).WatchDeployments(m, &providerWatchDeploymentsServer{stream})
+}
+
+type Provider_WatchDeploymentsServer interface {
+	Send(*WatchDeploymentsResponse) error
+	grpc.ServerStream
+}
+
+type providerWatchDeploymentsServer struct {
+	grpc.ServerStream
+}
+
+func (x *providerWatchDeploymentsServer) Send(m *WatchDeploymentsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // Provider_ServiceDesc is the grpc.ServiceDesc for Provider service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -349,7 +529,26 @@ var Provider_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Remove",
 			Handler:    _Provider_Remove_Handler,
 		},
+		{
+			MethodName: "History",
+			Handler:    _Provider_History_Handler,
+		},
+		{
+			MethodName: "Rollback",
+			Handler:    _Provider_Rollback_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Logs",
+			Handler:       _Provider_Logs_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchDeployments",
+			Handler:       _Provider_WatchDeployments_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "sf/substreams/sink/service/v1/service.proto",
 }
@@ -22,6 +22,60 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type ParamsSchema_Type int32
+
+const (
+	ParamsSchema_TYPE_STRING ParamsSchema_Type = 0
+	// Decimal integer, of any size
+	ParamsSchema_TYPE_INT ParamsSchema_Type = 1
+	// Hex-encoded address, with or without `0x` prefix, of at most 32 bytes
+	ParamsSchema_TYPE_ADDRESS ParamsSchema_Type = 2
+	ParamsSchema_TYPE_JSON    ParamsSchema_Type = 3
+)
+
+// Enum value maps for ParamsSchema_Type.
+var (
+	ParamsSchema_Type_name = map[int32]string{
+		0: "TYPE_STRING",
+		1: "TYPE_INT",
+		2: "TYPE_ADDRESS",
+		3: "TYPE_JSON",
+	}
+	ParamsSchema_Type_value = map[string]int32{
+		"TYPE_STRING":  0,
+		"TYPE_INT":     1,
+		"TYPE_ADDRESS": 2,
+		"TYPE_JSON":    3,
+	}
+)
+
+func (x ParamsSchema_Type) Enum() *ParamsSchema_Type {
+	p := new(ParamsSchema_Type)
+	*p = x
+	return p
+}
+
+func (x ParamsSchema_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ParamsSchema_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_sf_substreams_v1_package_proto_enumTypes[0].Descriptor()
+}
+
+func (ParamsSchema_Type) Type() protoreflect.EnumType {
+	return &file_sf_substreams_v1_package_proto_enumTypes[0]
+}
+
+func (x ParamsSchema_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ParamsSchema_Type.Descriptor instead.
+func (ParamsSchema_Type) EnumDescriptor() ([]byte, []int) {
+	return file_sf_substreams_v1_package_proto_rawDescGZIP(), []int{5, 0}
+}
+
 type Package struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -42,6 +96,8 @@ type Package struct {
 	Image        []byte                    `protobuf:"bytes,12,opt,name=image,proto3" json:"image,omitempty"`
 	Networks     map[string]*NetworkParams `protobuf:"bytes,13,rep,name=networks,proto3" json:"networks,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
 	BlockFilters map[string]string         `protobuf:"bytes,14,rep,name=block_filters,json=blockFilters,proto3" json:"block_filters,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// Signatures attached with `substreams pack --sign-key`, they are not part of the signed bytes.
+	Signatures []*PackageSignature `protobuf:"bytes,15,rep,name=signatures,proto3" json:"signatures,omitempty"`
 }
 
 func (x *Package) Reset() {
@@ -153,6 +209,81 @@ func (x *Package) GetBlockFilters() map[string]string {
 	return nil
 }
 
+func (x *Package) GetSignatures() []*PackageSignature {
+	if x != nil {
+		return x.Signatures
+	}
+	return nil
+}
+
+// PackageSignature is an ed25519 signature of a package by the holder of `public_key`.
+type PackageSignature struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Raw 32 bytes ed25519 public key of the signer
+	PublicKey []byte `protobuf:"bytes,1,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	// Signature over the canonical package bytes: the deterministic serialization of the package
+	// without its `signatures`.
+	PackageSignature []byte `protobuf:"bytes,2,opt,name=package_signature,json=packageSignature,proto3" json:"package_signature,omitempty"`
+	// Signature over the digest of the package's binaries, verifiable from the modules sent in a request.
+	BinariesSignature []byte `protobuf:"bytes,3,opt,name=binaries_signature,json=binariesSignature,proto3" json:"binaries_signature,omitempty"`
+}
+
+func (x *PackageSignature) Reset() {
+	*x = PackageSignature{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sf_substreams_v1_package_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PackageSignature) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PackageSignature) ProtoMessage() {}
+
+func (x *PackageSignature) ProtoReflect() protoreflect.Message {
+	mi := &file_sf_substreams_v1_package_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PackageSignature.ProtoReflect.Descriptor instead.
+func (*PackageSignature) Descriptor() ([]byte, []int) {
+	return file_sf_substreams_v1_package_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PackageSignature) GetPublicKey() []byte {
+	if x != nil {
+		return x.PublicKey
+	}
+	return nil
+}
+
+func (x *PackageSignature) GetPackageSignature() []byte {
+	if x != nil {
+		return x.PackageSignature
+	}
+	return nil
+}
+
+func (x *PackageSignature) GetBinariesSignature() []byte {
+	if x != nil {
+		return x.BinariesSignature
+	}
+	return nil
+}
+
 type NetworkParams struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -165,7 +296,7 @@ type NetworkParams struct {
 func (x *NetworkParams) Reset() {
 	*x = NetworkParams{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_sf_substreams_v1_package_proto_msgTypes[1]
+		mi := &file_sf_substreams_v1_package_proto_msgTypes[2]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -178,7 +309,7 @@ func (x *NetworkParams) String() string {
 func (*NetworkParams) ProtoMessage() {}
 
 func (x *NetworkParams) ProtoReflect() protoreflect.Message {
-	mi := &file_sf_substreams_v1_package_proto_msgTypes[1]
+	mi := &file_sf_substreams_v1_package_proto_msgTypes[2]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -191,7 +322,7 @@ func (x *NetworkParams) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use NetworkParams.ProtoReflect.Descriptor instead.
 func (*NetworkParams) Descriptor() ([]byte, []int) {
-	return file_sf_substreams_v1_package_proto_rawDescGZIP(), []int{1}
+	return file_sf_substreams_v1_package_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *NetworkParams) GetInitialBlocks() map[string]uint64 {
@@ -222,7 +353,7 @@ type PackageMetadata struct {
 func (x *PackageMetadata) Reset() {
 	*x = PackageMetadata{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_sf_substreams_v1_package_proto_msgTypes[2]
+		mi := &file_sf_substreams_v1_package_proto_msgTypes[3]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -235,7 +366,7 @@ func (x *PackageMetadata) String() string {
 func (*PackageMetadata) ProtoMessage() {}
 
 func (x *PackageMetadata) ProtoReflect() protoreflect.Message {
-	mi := &file_sf_substreams_v1_package_proto_msgTypes[2]
+	mi := &file_sf_substreams_v1_package_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -248,7 +379,7 @@ func (x *PackageMetadata) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PackageMetadata.ProtoReflect.Descriptor instead.
 func (*PackageMetadata) Descriptor() ([]byte, []int) {
-	return file_sf_substreams_v1_package_proto_rawDescGZIP(), []int{2}
+	return file_sf_substreams_v1_package_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *PackageMetadata) GetVersion() string {
@@ -287,12 +418,14 @@ type ModuleMetadata struct {
 	// Corresponds to the index in `Package.metadata.package_meta`
 	PackageIndex uint64 `protobuf:"varint,1,opt,name=package_index,json=packageIndex,proto3" json:"package_index,omitempty"`
 	Doc          string `protobuf:"bytes,2,opt,name=doc,proto3" json:"doc,omitempty"`
+	// Schema of the module's `params` input value, validated when params are applied.
+	ParamsSchema *ParamsSchema `protobuf:"bytes,3,opt,name=params_schema,json=paramsSchema,proto3" json:"params_schema,omitempty"`
 }
 
 func (x *ModuleMetadata) Reset() {
 	*x = ModuleMetadata{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_sf_substreams_v1_package_proto_msgTypes[3]
+		mi := &file_sf_substreams_v1_package_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -305,7 +438,7 @@ func (x *ModuleMetadata) String() string {
 func (*ModuleMetadata) ProtoMessage() {}
 
 func (x *ModuleMetadata) ProtoReflect() protoreflect.Message {
-	mi := &file_sf_substreams_v1_package_proto_msgTypes[3]
+	mi := &file_sf_substreams_v1_package_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -318,7 +451,7 @@ func (x *ModuleMetadata) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ModuleMetadata.ProtoReflect.Descriptor instead.
 func (*ModuleMetadata) Descriptor() ([]byte, []int) {
-	return file_sf_substreams_v1_package_proto_rawDescGZIP(), []int{3}
+	return file_sf_substreams_v1_package_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *ModuleMetadata) GetPackageIndex() uint64 {
@@ -335,6 +468,94 @@ func (x *ModuleMetadata) GetDoc() string {
 	return ""
 }
 
+func (x *ModuleMetadata) GetParamsSchema() *ParamsSchema {
+	if x != nil {
+		return x.ParamsSchema
+	}
+	return nil
+}
+
+type ParamsSchema struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Type ParamsSchema_Type `protobuf:"varint,1,opt,name=type,proto3,enum=sf.substreams.v1.ParamsSchema_Type" json:"type,omitempty"`
+	// Regular expression the whole value must match
+	Regex string `protobuf:"bytes,2,opt,name=regex,proto3" json:"regex,omitempty"`
+	// When not empty, the value must be one of them
+	EnumValues   []string `protobuf:"bytes,3,rep,name=enum_values,json=enumValues,proto3" json:"enum_values,omitempty"`
+	DefaultValue string   `protobuf:"bytes,4,opt,name=default_value,json=defaultValue,proto3" json:"default_value,omitempty"`
+	Doc          string   `protobuf:"bytes,5,opt,name=doc,proto3" json:"doc,omitempty"`
+}
+
+func (x *ParamsSchema) Reset() {
+	*x = ParamsSchema{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sf_substreams_v1_package_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ParamsSchema) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ParamsSchema) ProtoMessage() {}
+
+func (x *ParamsSchema) ProtoReflect() protoreflect.Message {
+	mi := &file_sf_substreams_v1_package_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ParamsSchema.ProtoReflect.Descriptor instead.
+func (*ParamsSchema) Descriptor() ([]byte, []int) {
+	return file_sf_substreams_v1_package_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ParamsSchema) GetType() ParamsSchema_Type {
+	if x != nil {
+		return x.Type
+	}
+	return ParamsSchema_TYPE_STRING
+}
+
+func (x *ParamsSchema) GetRegex() string {
+	if x != nil {
+		return x.Regex
+	}
+	return ""
+}
+
+func (x *ParamsSchema) GetEnumValues() []string {
+	if x != nil {
+		return x.EnumValues
+	}
+	return nil
+}
+
+func (x *ParamsSchema) GetDefaultValue() string {
+	if x != nil {
+		return x.DefaultValue
+	}
+	return ""
+}
+
+func (x *ParamsSchema) GetDoc() string {
+	if x != nil {
+		return x.Doc
+	}
+	return ""
+}
+
 var File_sf_substreams_v1_package_proto protoreflect.FileDescriptor
 
 var file_sf_substreams_v1_package_proto_rawDesc = []byte{
@@ -347,7 +568,7 @@ var file_sf_substreams_v1_package_proto_rawDesc = []byte{
 	0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x61, 0x6e, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a,
 	0x1e, 0x73, 0x66, 0x2f, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2f, 0x76,
 	0x31, 0x2f, 0x6d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22,
-	0xec, 0x05, 0x0a, 0x07, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x12, 0x45, 0x0a, 0x0b, 0x70,
+	0xb0, 0x06, 0x0a, 0x07, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x12, 0x45, 0x0a, 0x0b, 0x70,
 	0x72, 0x6f, 0x74, 0x6f, 0x5f, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b,
 	0x32, 0x24, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62,
 	0x75, 0x66, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x44, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x6f,
@@ -383,52 +604,86 @@ var file_sf_substreams_v1_package_proto_rawDesc = []byte{
 	0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x76,
 	0x31, 0x2e, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x46,
 	0x69, 0x6c, 0x74, 0x65, 0x72, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0c, 0x62, 0x6c, 0x6f,
-	0x63, 0x6b, 0x46, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x73, 0x1a, 0x5c, 0x0a, 0x0d, 0x4e, 0x65, 0x74,
-	0x77, 0x6f, 0x72, 0x6b, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65,
-	0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x35, 0x0a, 0x05,
-	0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1f, 0x2e, 0x73, 0x66,
-	0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x4e,
-	0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x52, 0x05, 0x76, 0x61,
-	0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x3f, 0x0a, 0x11, 0x42, 0x6c, 0x6f, 0x63, 0x6b,
-	0x46, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03,
-	0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14,
-	0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76,
-	0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x4a, 0x04, 0x08, 0x02, 0x10, 0x05, 0x22, 0xab,
-	0x02, 0x0a, 0x0d, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73,
-	0x12, 0x58, 0x0a, 0x0d, 0x69, 0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x42, 0x6c, 0x6f, 0x63, 0x6b,
-	0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x32, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62,
-	0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x4e, 0x65, 0x74, 0x77, 0x6f,
-	0x72, 0x6b, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x2e, 0x49, 0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c,
-	0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0d, 0x69, 0x6e, 0x69,
-	0x74, 0x69, 0x61, 0x6c, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x12, 0x43, 0x0a, 0x06, 0x70, 0x61,
-	0x72, 0x61, 0x6d, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x2b, 0x2e, 0x73, 0x66, 0x2e,
-	0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x4e, 0x65,
-	0x74, 0x77, 0x6f, 0x72, 0x6b, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x2e, 0x50, 0x61, 0x72, 0x61,
-	0x6d, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x06, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x1a,
-	0x40, 0x0a, 0x12, 0x49, 0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x73,
-	0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01,
-	0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
-	0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38,
-	0x01, 0x1a, 0x39, 0x0a, 0x0b, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79,
+	0x63, 0x6b, 0x46, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x73, 0x12, 0x42, 0x0a, 0x0a, 0x73, 0x69, 0x67,
+	0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x73, 0x18, 0x0f, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x22, 0x2e,
+	0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x76, 0x31,
+	0x2e, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x53, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72,
+	0x65, 0x52, 0x0a, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x73, 0x1a, 0x5c, 0x0a,
+	0x0d, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10,
+	0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79,
+	0x12, 0x35, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x1f, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e,
+	0x76, 0x31, 0x2e, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73,
+	0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x3f, 0x0a, 0x11, 0x42,
+	0x6c, 0x6f, 0x63, 0x6b, 0x46, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79,
 	0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b,
 	0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x63, 0x0a, 0x0f,
-	0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x12,
-	0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x10, 0x0a, 0x03, 0x75, 0x72, 0x6c,
-	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x12, 0x12, 0x0a, 0x04, 0x6e,
-	0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12,
-	0x10, 0x0a, 0x03, 0x64, 0x6f, 0x63, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x64, 0x6f,
-	0x63, 0x22, 0x47, 0x0a, 0x0e, 0x4d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x4d, 0x65, 0x74, 0x61, 0x64,
-	0x61, 0x74, 0x61, 0x12, 0x23, 0x0a, 0x0d, 0x70, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x5f, 0x69,
-	0x6e, 0x64, 0x65, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0c, 0x70, 0x61, 0x63, 0x6b,
-	0x61, 0x67, 0x65, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x10, 0x0a, 0x03, 0x64, 0x6f, 0x63, 0x18,
-	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x64, 0x6f, 0x63, 0x42, 0x46, 0x5a, 0x44, 0x67, 0x69,
-	0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x69,
-	0x6e, 0x67, 0x66, 0x61, 0x73, 0x74, 0x2f, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d,
-	0x73, 0x2f, 0x70, 0x62, 0x2f, 0x73, 0x66, 0x2f, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61,
-	0x6d, 0x73, 0x2f, 0x76, 0x31, 0x3b, 0x70, 0x62, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61,
-	0x6d, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x4a, 0x04, 0x08, 0x02,
+	0x10, 0x05, 0x22, 0x8d, 0x01, 0x0a, 0x10, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x53, 0x69,
+	0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x70, 0x75, 0x62, 0x6c, 0x69,
+	0x63, 0x5f, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x70, 0x75, 0x62,
+	0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x12, 0x2b, 0x0a, 0x11, 0x70, 0x61, 0x63, 0x6b, 0x61, 0x67,
+	0x65, 0x5f, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0c, 0x52, 0x10, 0x70, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x53, 0x69, 0x67, 0x6e, 0x61, 0x74,
+	0x75, 0x72, 0x65, 0x12, 0x2d, 0x0a, 0x12, 0x62, 0x69, 0x6e, 0x61, 0x72, 0x69, 0x65, 0x73, 0x5f,
+	0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x11, 0x62, 0x69, 0x6e, 0x61, 0x72, 0x69, 0x65, 0x73, 0x53, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75,
+	0x72, 0x65, 0x22, 0xab, 0x02, 0x0a, 0x0d, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x50, 0x61,
+	0x72, 0x61, 0x6d, 0x73, 0x12, 0x58, 0x0a, 0x0d, 0x69, 0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x42,
+	0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x32, 0x2e, 0x73, 0x66,
+	0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x4e,
+	0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x2e, 0x49, 0x6e, 0x69,
+	0x74, 0x69, 0x61, 0x6c, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52,
+	0x0d, 0x69, 0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x12, 0x43,
+	0x0a, 0x06, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x2b,
+	0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x76,
+	0x31, 0x2e, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x2e,
+	0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x06, 0x70, 0x61, 0x72,
+	0x61, 0x6d, 0x73, 0x1a, 0x40, 0x0a, 0x12, 0x49, 0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x42, 0x6c,
+	0x6f, 0x63, 0x6b, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x39, 0x0a, 0x0b, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x45,
+	0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01,
+	0x22, 0x63, 0x0a, 0x0f, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x4d, 0x65, 0x74, 0x61, 0x64,
+	0x61, 0x74, 0x61, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x10, 0x0a,
+	0x03, 0x75, 0x72, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x12,
+	0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e,
+	0x61, 0x6d, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x64, 0x6f, 0x63, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x03, 0x64, 0x6f, 0x63, 0x22, 0x8c, 0x01, 0x0a, 0x0e, 0x4d, 0x6f, 0x64, 0x75, 0x6c, 0x65,
+	0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x12, 0x23, 0x0a, 0x0d, 0x70, 0x61, 0x63, 0x6b,
+	0x61, 0x67, 0x65, 0x5f, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52,
+	0x0c, 0x70, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x10, 0x0a,
+	0x03, 0x64, 0x6f, 0x63, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x64, 0x6f, 0x63, 0x12,
+	0x43, 0x0a, 0x0d, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x5f, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73,
+	0x53, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x52, 0x0c, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x53, 0x63,
+	0x68, 0x65, 0x6d, 0x61, 0x22, 0xfd, 0x01, 0x0a, 0x0c, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x53,
+	0x63, 0x68, 0x65, 0x6d, 0x61, 0x12, 0x37, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0e, 0x32, 0x23, 0x2e, 0x73, 0x66, 0x2e, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65,
+	0x61, 0x6d, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x53, 0x63, 0x68,
+	0x65, 0x6d, 0x61, 0x2e, 0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x14,
+	0x0a, 0x05, 0x72, 0x65, 0x67, 0x65, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x72,
+	0x65, 0x67, 0x65, 0x78, 0x12, 0x1f, 0x0a, 0x0b, 0x65, 0x6e, 0x75, 0x6d, 0x5f, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0a, 0x65, 0x6e, 0x75, 0x6d, 0x56,
+	0x61, 0x6c, 0x75, 0x65, 0x73, 0x12, 0x23, 0x0a, 0x0d, 0x64, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74,
+	0x5f, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x64, 0x65,
+	0x66, 0x61, 0x75, 0x6c, 0x74, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x64, 0x6f,
+	0x63, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x64, 0x6f, 0x63, 0x22, 0x46, 0x0a, 0x04,
+	0x54, 0x79, 0x70, 0x65, 0x12, 0x0f, 0x0a, 0x0b, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x53, 0x54, 0x52,
+	0x49, 0x4e, 0x47, 0x10, 0x00, 0x12, 0x0c, 0x0a, 0x08, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x49, 0x4e,
+	0x54, 0x10, 0x01, 0x12, 0x10, 0x0a, 0x0c, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x41, 0x44, 0x44, 0x52,
+	0x45, 0x53, 0x53, 0x10, 0x02, 0x12, 0x0d, 0x0a, 0x09, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x4a, 0x53,
+	0x4f, 0x4e, 0x10, 0x03, 0x42, 0x46, 0x5a, 0x44, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63,
+	0x6f, 0x6d, 0x2f, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x69, 0x6e, 0x67, 0x66, 0x61, 0x73, 0x74,
+	0x2f, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2f, 0x70, 0x62, 0x2f, 0x73,
+	0x66, 0x2f, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x2f, 0x76, 0x31, 0x3b,
+	0x70, 0x62, 0x73, 0x75, 0x62, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x73, 0x62, 0x06, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -443,36 +698,43 @@ func file_sf_substreams_v1_package_proto_rawDescGZIP() []byte {
 	return file_sf_substreams_v1_package_proto_rawDescData
 }
 
-var file_sf_substreams_v1_package_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_sf_substreams_v1_package_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_sf_substreams_v1_package_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
 var file_sf_substreams_v1_package_proto_goTypes = []interface{}{
-	(*Package)(nil),                          // 0: sf.substreams.v1.Package
-	(*NetworkParams)(nil),                    // 1: sf.substreams.v1.NetworkParams
-	(*PackageMetadata)(nil),                  // 2: sf.substreams.v1.PackageMetadata
-	(*ModuleMetadata)(nil),                   // 3: sf.substreams.v1.ModuleMetadata
-	nil,                                      // 4: sf.substreams.v1.Package.NetworksEntry
-	nil,                                      // 5: sf.substreams.v1.Package.BlockFiltersEntry
-	nil,                                      // 6: sf.substreams.v1.NetworkParams.InitialBlocksEntry
-	nil,                                      // 7: sf.substreams.v1.NetworkParams.ParamsEntry
-	(*descriptorpb.FileDescriptorProto)(nil), // 8: google.protobuf.FileDescriptorProto
-	(*Modules)(nil),                          // 9: sf.substreams.v1.Modules
-	(*anypb.Any)(nil),                        // 10: google.protobuf.Any
+	(ParamsSchema_Type)(0),                   // 0: sf.substreams.v1.ParamsSchema.Type
+	(*Package)(nil),                          // 1: sf.substreams.v1.Package
+	(*PackageSignature)(nil),                 // 2: sf.substreams.v1.PackageSignature
+	(*NetworkParams)(nil),                    // 3: sf.substreams.v1.NetworkParams
+	(*PackageMetadata)(nil),                  // 4: sf.substreams.v1.PackageMetadata
+	(*ModuleMetadata)(nil),                   // 5: sf.substreams.v1.ModuleMetadata
+	(*ParamsSchema)(nil),                     // 6: sf.substreams.v1.ParamsSchema
+	nil,                                      // 7: sf.substreams.v1.Package.NetworksEntry
+	nil,                                      // 8: sf.substreams.v1.Package.BlockFiltersEntry
+	nil,                                      // 9: sf.substreams.v1.NetworkParams.InitialBlocksEntry
+	nil,                                      // 10: sf.substreams.v1.NetworkParams.ParamsEntry
+	(*descriptorpb.FileDescriptorProto)(nil), // 11: google.protobuf.FileDescriptorProto
+	(*Modules)(nil),                          // 12: sf.substreams.v1.Modules
+	(*anypb.Any)(nil),                        // 13: google.protobuf.Any
 }
 var file_sf_substreams_v1_package_proto_depIdxs = []int32{
-	8,  // 0: sf.substreams.v1.Package.proto_files:type_name -> google.protobuf.FileDescriptorProto
-	9,  // 1: sf.substreams.v1.Package.modules:type_name -> sf.substreams.v1.Modules
-	3,  // 2: sf.substreams.v1.Package.module_meta:type_name -> sf.substreams.v1.ModuleMetadata
-	2,  // 3: sf.substreams.v1.Package.package_meta:type_name -> sf.substreams.v1.PackageMetadata
-	10, // 4: sf.substreams.v1.Package.sink_config:type_name -> google.protobuf.Any
-	4,  // 5: sf.substreams.v1.Package.networks:type_name -> sf.substreams.v1.Package.NetworksEntry
-	5,  // 6: sf.substreams.v1.Package.block_filters:type_name -> sf.substreams.v1.Package.BlockFiltersEntry
-	6,  // 7: sf.substreams.v1.NetworkParams.initialBlocks:type_name -> sf.substreams.v1.NetworkParams.InitialBlocksEntry
-	7,  // 8: sf.substreams.v1.NetworkParams.params:type_name -> sf.substreams.v1.NetworkParams.ParamsEntry
-	1,  // 9: sf.substreams.v1.Package.NetworksEntry.value:type_name -> sf.substreams.v1.NetworkParams
-	10, // [10:10] is the sub-list for method output_type
-	10, // [10:10] is the sub-list for method input_type
-	10, // [10:10] is the sub-list for extension type_name
-	10, // [10:10] is the sub-list for extension extendee
-	0,  // [0:10] is the sub-list for field type_name
+	11, // 0: sf.substreams.v1.Package.proto_files:type_name -> google.protobuf.FileDescriptorProto
+	12, // 1: sf.substreams.v1.Package.modules:type_name -> sf.substreams.v1.Modules
+	5,  // 2: sf.substreams.v1.Package.module_meta:type_name -> sf.substreams.v1.ModuleMetadata
+	4,  // 3: sf.substreams.v1.Package.package_meta:type_name -> sf.substreams.v1.PackageMetadata
+	13, // 4: sf.substreams.v1.Package.sink_config:type_name -> google.protobuf.Any
+	7,  // 5: sf.substreams.v1.Package.networks:type_name -> sf.substreams.v1.Package.NetworksEntry
+	8,  // 6: sf.substreams.v1.Package.block_filters:type_name -> sf.substreams.v1.Package.BlockFiltersEntry
+	2,  // 7: sf.substreams.v1.Package.signatures:type_name -> sf.substreams.v1.PackageSignature
+	9,  // 8: sf.substreams.v1.NetworkParams.initialBlocks:type_name -> sf.substreams.v1.NetworkParams.InitialBlocksEntry
+	10, // 9: sf.substreams.v1.NetworkParams.params:type_name -> sf.substreams.v1.NetworkParams.ParamsEntry
+	6,  // 10: sf.substreams.v1.ModuleMetadata.params_schema:type_name -> sf.substreams.v1.ParamsSchema
+	0,  // 11: sf.substreams.v1.ParamsSchema.type:type_name -> sf.substreams.v1.ParamsSchema.Type
+	3,  // 12: sf.substreams.v1.Package.NetworksEntry.value:type_name -> sf.substreams.v1.NetworkParams
+	13, // [13:13] is the sub-list for method output_type
+	13, // [13:13] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
 }
 
 func init() { file_sf_substreams_v1_package_proto_init() }
@@ -495,7 +757,7 @@ func file_sf_substreams_v1_package_proto_init() {
 			}
 		}
 		file_sf_substreams_v1_package_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*NetworkParams); i {
+			switch v := v.(*PackageSignature); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -507,7 +769,7 @@ func file_sf_substreams_v1_package_proto_init() {
 			}
 		}
 		file_sf_substreams_v1_package_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*PackageMetadata); i {
+			switch v := v.(*NetworkParams); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -519,6 +781,18 @@ func file_sf_substreams_v1_package_proto_init() {
 			}
 		}
 		file_sf_substreams_v1_package_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PackageMetadata); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sf_substreams_v1_package_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*ModuleMetadata); i {
 			case 0:
 				return &v.state
@@ -530,19 +804,32 @@ func file_sf_substreams_v1_package_proto_init() {
 				return nil
 			}
 		}
+		file_sf_substreams_v1_package_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ParamsSchema); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_sf_substreams_v1_package_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   8,
+			NumEnums:      1,
+			NumMessages:   10,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
 		GoTypes:           file_sf_substreams_v1_package_proto_goTypes,
 		DependencyIndexes: file_sf_substreams_v1_package_proto_depIdxs,
+		EnumInfos:         file_sf_substreams_v1_package_proto_enumTypes,
 		MessageInfos:      file_sf_substreams_v1_package_proto_msgTypes,
 	}.Build()
 	File_sf_substreams_v1_package_proto = out.File
@@ -81,8 +81,17 @@ func (e *BaseExecutor) wasmCall(outputGetter execout.ExecutionOutputGetter) (cal
 		stats := reqctx.ReqStats(e.ctx)
 		//t0 := time.Now()
 		call = wasm.NewCall(clock, e.moduleName, e.entrypoint, stats, e.wasmArguments)
+		traceRecorder := wasm.TraceRecorderFromContext(e.ctx)
+		if traceRecorder != nil {
+			call.StartTrace(e.wasmArguments)
+		}
 		inst, err = e.wasmModule.ExecuteNewCall(e.ctx, call, e.cachedInstance, e.wasmArguments)
 		//Timer += time.Since(t0)
+		if traceRecorder != nil {
+			if recordErr := traceRecorder.Record(call.FinishTrace(err)); recordErr != nil {
+				return nil, fmt.Errorf("block %d: module %q: recording execution trace: %w", clock.Number, e.moduleName, recordErr)
+			}
+		}
 		if panicErr := call.Err(); panicErr != nil {
 			errExecutor := &ErrorExecutor{
 				message:    panicErr.Error(),
@@ -2,11 +2,14 @@ package outputmodules
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/streamingfast/substreams/manifest"
 	pbssinternal "github.com/streamingfast/substreams/pb/sf/substreams/intern/v2"
 	pbsubstreamsrpc "github.com/streamingfast/substreams/pb/sf/substreams/rpc/v2"
 	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"github.com/streamingfast/substreams/wasm"
 )
 
 // Deprecated: use ValidateTier1Request
@@ -99,9 +102,10 @@ func validateModuleGraph(mods []*pbsubstreams.Module, outputModule string, block
 }
 
 func validateBinaryTypes(bins []*pbsubstreams.Binary) error {
+	supported := wasm.SupportedBinaryTypes()
 	for _, binary := range bins {
-		if binary.Type != "wasm/rust-v1" {
-			return fmt.Errorf(`unsupported binary type: %q, please use "wasm/rust-v1"`, binary.Type)
+		if !slices.Contains(supported, binary.Type) {
+			return fmt.Errorf(`unsupported binary type: %q, please use one of %q`, binary.Type, strings.Join(supported, ", "))
 		}
 	}
 	return nil
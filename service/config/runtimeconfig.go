@@ -21,6 +21,7 @@ type RuntimeConfig struct {
 	WorkerFactory   work.WorkerFactory
 
 	ModuleExecutionTracing bool
+	ExecutionTraceDir      string // if not empty, every module execution of every request is recorded there, see `substreams tools replay-trace`
 	MaxConcurrentRequests  int64
 }
 
@@ -1,6 +1,7 @@
 package service
 
 import (
+	_ "github.com/streamingfast/substreams/wasm/native"
 	//_ "github.com/streamingfast/substreams/wasm/wasmtime"
 	_ "github.com/streamingfast/substreams/wasm/wazero"
 )
@@ -1,6 +1,8 @@
 package service
 
 import (
+	"crypto/ed25519"
+
 	"github.com/streamingfast/substreams/wasm"
 )
 
@@ -30,6 +32,32 @@ func WithModuleExecutionTracing() Option {
 	}
 }
 
+// WithExecutionTraceDir records the inputs, state calls, extension calls and output of every
+// module execution to a JSONL file per request in `dir`. This is heavy and meant for debugging.
+func WithExecutionTraceDir(dir string) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.runtimeConfig.ExecutionTraceDir = dir
+		case *Tier2Service:
+			s.runtimeConfig.ExecutionTraceDir = dir
+		}
+	}
+}
+
+// WithTrustedPackageSigners restricts tier1 to requests whose modules' binaries are signed by one
+// of `keys`, see `substreams pack --sign-key`.
+func WithTrustedPackageSigners(keys []ed25519.PublicKey) Option {
+	return func(a anyTierService) {
+		switch s := a.(type) {
+		case *Tier1Service:
+			s.trustedPackageSigners = keys
+		case *Tier2Service:
+			// not used, tier2 only receives requests from tier1
+		}
+	}
+}
+
 func WithWASMExtensioner(ext wasm.WASMExtensioner) Option {
 	return func(a anyTierService) {
 		switch s := a.(type) {
@@ -64,3 +64,11 @@ func (s *Tier2Service) TestProcessRange(ctx context.Context, request *pbssintern
 
 	return s.processRange(ctx, request, respFunc)
 }
+
+func (s *Tier1Service) TestRuntimeConfig() config.RuntimeConfig {
+	return s.runtimeConfig
+}
+
+func (s *Tier2Service) TestRuntimeConfig() config.RuntimeConfig {
+	return s.runtimeConfig
+}
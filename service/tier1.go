@@ -3,6 +3,7 @@ package service
 import (
 	"bytes"
 	"context"
+	"crypto/ed25519"
 	"errors"
 	"fmt"
 	"io"
@@ -29,6 +30,7 @@ import (
 	"connectrpc.com/connect"
 	"github.com/streamingfast/substreams"
 	"github.com/streamingfast/substreams/client"
+	"github.com/streamingfast/substreams/manifest"
 	"github.com/streamingfast/substreams/metrics"
 	"github.com/streamingfast/substreams/orchestrator/plan"
 	"github.com/streamingfast/substreams/orchestrator/work"
@@ -75,6 +77,8 @@ type Tier1Service struct {
 	tier2RequestParameters reqctx.Tier2RequestParameters
 
 	pipelineOptions []pipeline.Option
+
+	trustedPackageSigners []ed25519.PublicKey
 }
 
 func getBlockTypeFromStreamFactory(sf *StreamFactory) (string, error) {
@@ -229,6 +233,12 @@ func (s *Tier1Service) Blocks(
 		return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("missing modules in request"))
 	}
 
+	if len(s.trustedPackageSigners) != 0 {
+		if err := manifest.VerifyBinariesSignature(request.Modules, request.PackageSignatures, s.trustedPackageSigners); err != nil {
+			return connect.NewError(connect.CodePermissionDenied, fmt.Errorf("verifying package signature: %w", err))
+		}
+	}
+
 	if err := outputmodules.ValidateTier1Request(request, s.blockType); err != nil {
 		return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("validate request: %w", err))
 	}
@@ -410,6 +420,14 @@ func (s *Tier1Service) blocks(ctx context.Context, request *pbsubstreamsrpc.Requ
 	if s.runtimeConfig.ModuleExecutionTracing {
 		ctx = reqctx.WithModuleExecutionTracing(ctx)
 	}
+	if s.runtimeConfig.ExecutionTraceDir != "" {
+		recorder, err := newExecutionTraceRecorder(s.runtimeConfig.ExecutionTraceDir, requestDetails)
+		if err != nil {
+			return fmt.Errorf("setting up execution trace recorder: %w", err)
+		}
+		defer recorder.Close()
+		ctx = wasm.WithTraceRecorder(ctx, recorder)
+	}
 
 	if err := s.writePackage(ctx, request, outputGraph); err != nil {
 		logger.Warn("cannot write package", zap.Error(err))
@@ -257,6 +257,14 @@ func (s *Tier2Service) processRange(ctx context.Context, request *pbssinternal.P
 	if s.runtimeConfig.ModuleExecutionTracing {
 		ctx = reqctx.WithModuleExecutionTracing(ctx)
 	}
+	if s.runtimeConfig.ExecutionTraceDir != "" {
+		recorder, err := newExecutionTraceRecorder(s.runtimeConfig.ExecutionTraceDir, requestDetails)
+		if err != nil {
+			return fmt.Errorf("setting up execution trace recorder: %w", err)
+		}
+		defer recorder.Close()
+		ctx = wasm.WithTraceRecorder(ctx, recorder)
+	}
 
 	requestDetails.CacheTag = s.runtimeConfig.DefaultCacheTag
 	if auth := dauth.FromContext(ctx); auth != nil {
@@ -1,10 +1,16 @@
 package service
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"sort"
+	"time"
 
 	"github.com/streamingfast/bstream"
 	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"github.com/streamingfast/substreams/reqctx"
+	"github.com/streamingfast/substreams/wasm"
 )
 
 func sortClocksDistributor(clockDistributor map[uint64]*pbsubstreams.Clock) (sortedClockDistributor []*pbsubstreams.Clock) {
@@ -25,3 +31,19 @@ func irreversibleCursorFromClock(clock *pbsubstreams.Clock) *bstream.Cursor {
 		HeadBlock: bstream.NewBlockRef(clock.Id, clock.Number),
 	}
 }
+
+// newExecutionTraceRecorder creates the file receiving the execution traces of a single request,
+// named after the tier, output module and block range so it can be found back easily.
+func newExecutionTraceRecorder(dir string, details *reqctx.RequestDetails) (*wasm.FileTraceRecorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating directory %q: %w", dir, err)
+	}
+
+	tier := "tier1"
+	if details.IsTier2Request {
+		tier = "tier2"
+	}
+	filename := fmt.Sprintf("%s-%s-%d-%d-%d.jsonl", tier, details.OutputModule, details.ResolvedStartBlockNum, details.StopBlockNum, time.Now().UnixNano())
+
+	return wasm.NewFileTraceRecorder(filepath.Join(dir, filename))
+}
@@ -3,6 +3,8 @@ package context
 import (
 	"context"
 	"net/http"
+
+	"github.com/streamingfast/substreams/sink-server/resources"
 )
 
 type sinkContextKey int
@@ -48,3 +50,17 @@ func GetHeader(ctx context.Context) http.Header {
 	}
 	return nil
 }
+
+const sizeKey = sinkContextKey(4)
+
+func SetSize(ctx context.Context, size *resources.Size) context.Context {
+	return context.WithValue(ctx, sizeKey, size)
+}
+
+// GetSize returns the resource limits of the deployment, nil when it is not limited.
+func GetSize(ctx context.Context) *resources.Size {
+	if size, ok := ctx.Value(sizeKey).(*resources.Size); ok {
+		return size
+	}
+	return nil
+}
@@ -0,0 +1,473 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/cli/cli/compose/types"
+	pbsql "github.com/streamingfast/substreams-sink-sql/pb/sf/substreams/sink/sql/v1"
+	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	sinkcontext "github.com/streamingfast/substreams/sink-server/context"
+	"github.com/streamingfast/substreams/sink-server/progress"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+// A blue/green update runs the new package in the 'next' folder of the deployment, as a second sink writing
+// to the 'next' schema of the same postgres database. The frontends keep reading the 'public' schema until
+// the new sink has caught up with the chain head: the schemas are then renamed in a single transaction, the
+// replaced one being kept as 'previous' along with its package and sink state, in the 'previous' folder, to
+// allow a rollback.
+const (
+	nextFolder     = "next"
+	previousFolder = "previous"
+	nextSchema     = "next"
+	previousSchema = "previous"
+
+	// nextSinkMetricsPort publishes the metrics of the sink of a pending update
+	nextSinkMetricsPort = uint32(9103) // TODO: assign dynamically
+)
+
+var (
+	// blueGreenCheckInterval is how often the progress of a pending update is checked
+	blueGreenCheckInterval = 15 * time.Second
+	// blueGreenMaxLag is how close to the chain head the sink of a pending update must be to be switched over
+	blueGreenMaxLag = time.Minute
+)
+
+type pendingUpdate struct {
+	PackageInfo *pbsinksvc.PackageInfo
+	StartedAt   time.Time
+}
+
+func nextSinkServiceName(deploymentID string) string {
+	return deploymentID + "-sink-next"
+}
+
+// UpdateBlueGreen deploys `pkg` next to the current package of the deployment, the frontends being switched
+// over to it once its sink has caught up with the chain head.
+func (e *DockerEngine) UpdateBlueGreen(ctx context.Context, deploymentID string, pkg *pbsubstreams.Package, zlog *zap.Logger) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	info, err := e.readDeploymentInfo(deploymentID)
+	if err != nil {
+		return err
+	}
+	if info.Next != nil {
+		return fmt.Errorf("deployment %q already has a pending update, roll it back before deploying another one", deploymentID)
+	}
+	if _, found := info.ServiceInfo[postgresServiceName(deploymentID)]; !found {
+		return fmt.Errorf("blue/green updates of sinks without a postgres database: %w", errors.ErrUnsupported)
+	}
+
+	sinkConfig, err := postgresSinkConfig(pkg)
+	if err != nil {
+		return err
+	}
+	packageInfo, err := newPackageInfo(pkg)
+	if err != nil {
+		return err
+	}
+
+	if err := e.psql(deploymentID, fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE; CREATE SCHEMA %s;", nextSchema, nextSchema)); err != nil {
+		return fmt.Errorf("creating schema %q: %w", nextSchema, err)
+	}
+
+	folder := filepath.Join(deploymentID, nextFolder)
+	if err := os.RemoveAll(filepath.Join(e.dir, folder)); err != nil {
+		return fmt.Errorf("cleaning up folder %q: %w", folder, err)
+	}
+	sink, err := e.newSQLSink(sqlSinkSlot{
+		name:        nextSinkServiceName(deploymentID),
+		folder:      folder,
+		dbHost:      postgresServiceName(deploymentID),
+		schema:      nextSchema,
+		metricsPort: nextSinkMetricsPort,
+	}, pkg, sinkConfig)
+	if err != nil {
+		return fmt.Errorf("creating sink of the update: %w", err)
+	}
+	if size := sinkcontext.GetSize(ctx); size != nil {
+		setLimits(&sink, size.Sink)
+	}
+
+	manifest, err := nextManifest(deploymentID, sink)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(e.dir, folder, "docker-compose.yaml"), manifest, 0644); err != nil {
+		return fmt.Errorf("writing docker-compose file: %w", err)
+	}
+	if out, err := e.composeNext(deploymentID, "up", "-d", "--wait"); err != nil {
+		return fmt.Errorf("starting sink of the update: %q, %w", out, err)
+	}
+
+	info.Next = &pendingUpdate{PackageInfo: packageInfo, StartedAt: time.Now()}
+	if err := e.saveDeploymentInfo(deploymentID, info); err != nil {
+		return err
+	}
+
+	e.watchPendingUpdate(ctx, deploymentID, zlog)
+	return nil
+}
+
+// nextManifest is the docker compose file of the sink of a pending update, joining the network of the
+// deployment to reach its database.
+func nextManifest(deploymentID string, sink types.ServiceConfig) ([]byte, error) {
+	config := types.Config{
+		Version:  "3",
+		Services: []types.ServiceConfig{sink},
+		Networks: map[string]types.NetworkConfig{
+			"default": {
+				Name:     deploymentID + "_default",
+				External: types.External{External: true},
+			},
+		},
+		Secrets: secretsConfig([]types.ServiceConfig{sink}, "../secrets"),
+		// the project would be named after its folder otherwise, the same for every deployment
+		Extras: map[string]interface{}{"name": deploymentID + "-" + nextFolder},
+	}
+	return yaml.Marshal(config)
+}
+
+func postgresSinkConfig(pkg *pbsubstreams.Package) (*pbsql.Service, error) {
+	sinkConfig := &pbsql.Service{}
+	if pkg.SinkConfig == nil || pkg.SinkConfig.TypeUrl != string(sinkConfig.ProtoReflect().Descriptor().FullName()) {
+		return nil, fmt.Errorf("blue/green updates of sinks other than 'sf.substreams.sink.sql.v1.Service': %w", errors.ErrUnsupported)
+	}
+	if err := pkg.SinkConfig.UnmarshalTo(sinkConfig); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal sinkconfig: %w", err)
+	}
+	if sinkConfig.Engine != pbsql.Service_postgres {
+		return nil, fmt.Errorf("blue/green updates of %s sinks: %w", sinkConfig.Engine, errors.ErrUnsupported)
+	}
+	return sinkConfig, nil
+}
+
+// watchPendingUpdate switches the deployment over to its pending update once it has caught up, unless it is
+// already being watched. It stops when the update is switched over or cancelled, or when the engine is shut down.
+func (e *DockerEngine) watchPendingUpdate(ctx context.Context, deploymentID string, zlog *zap.Logger) {
+	e.watchMutex.Lock()
+	defer e.watchMutex.Unlock()
+	if e.watching == nil {
+		e.watching = make(map[string]bool)
+	}
+	if e.watching[deploymentID] {
+		return
+	}
+	e.watching[deploymentID] = true
+
+	// the request context is only kept for its values, the production mode and parameters of the deployment
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer func() {
+			e.watchMutex.Lock()
+			delete(e.watching, deploymentID)
+			e.watchMutex.Unlock()
+		}()
+
+		ticker := time.NewTicker(blueGreenCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-e.shutdown:
+				return
+			case <-ticker.C:
+			}
+
+			done, err := e.checkPendingUpdate(ctx, deploymentID, zlog)
+			if err != nil {
+				zlog.Warn("cannot check pending update", zap.String("deployment_id", deploymentID), zap.Error(err))
+			}
+			if done {
+				return
+			}
+		}
+	}()
+}
+
+func (e *DockerEngine) checkPendingUpdate(ctx context.Context, deploymentID string, zlog *zap.Logger) (done bool, err error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	info, err := e.readDeploymentInfo(deploymentID)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return true, nil
+		}
+		return false, err
+	}
+	if info.Next == nil {
+		return true, nil
+	}
+
+	if !caughtUp(e.progress.Progress(ctx, nextSinkServiceName(deploymentID), e.nextProgressSource(deploymentID, info), zlog)) {
+		return false, nil
+	}
+
+	zlog.Info("pending update caught up, switching over", zap.String("deployment_id", deploymentID), zap.String("package", info.Next.PackageInfo.Name+"-"+info.Next.PackageInfo.Version))
+	if err := e.promote(ctx, deploymentID, info, zlog); err != nil {
+		return false, fmt.Errorf("switching over to the pending update: %w", err)
+	}
+	return true, nil
+}
+
+// caughtUp tells if the sink is close enough to the chain head to serve the frontends.
+func caughtUp(p *pbsinksvc.SinkProgress) bool {
+	return p != nil && p.HeadLagSeconds > 0 && p.HeadLagSeconds < blueGreenMaxLag.Seconds()
+}
+
+// promote switches the frontends over to the pending update, the current package becoming the previous one.
+// The sinks are stopped during the switch, the frontends keep serving the current data until the schemas are
+// renamed.
+func (e *DockerEngine) promote(ctx context.Context, deploymentID string, info *deploymentInfo, zlog *zap.Logger) error {
+	pkg, err := readPackage(filepath.Join(e.dir, deploymentID, nextFolder, "config", "sink", "substreams.spkg"))
+	if err != nil {
+		return err
+	}
+
+	if out, err := e.compose(deploymentID, "stop", sinkServiceName(deploymentID)); err != nil {
+		return fmt.Errorf("stopping sink: %q, %w", out, err)
+	}
+	if out, err := e.composeNext(deploymentID, "down"); err != nil {
+		return fmt.Errorf("stopping sink of the update: %q, %w", out, err)
+	}
+
+	err = e.psql(deploymentID, fmt.Sprintf("BEGIN; DROP SCHEMA IF EXISTS %s CASCADE; ALTER SCHEMA public RENAME TO %s; ALTER SCHEMA %s RENAME TO public; COMMIT;", previousSchema, previousSchema, nextSchema))
+	if err != nil {
+		// nothing changed, the current sink is restarted and the switch will be tried again
+		if out, startErr := e.compose(deploymentID, "start", sinkServiceName(deploymentID)); startErr != nil {
+			zlog.Warn("cannot restart sink", zap.String("deployment_id", deploymentID), zap.String("output", out), zap.Error(startErr))
+		}
+		if out, startErr := e.composeNext(deploymentID, "up", "-d"); startErr != nil {
+			zlog.Warn("cannot restart sink of the update", zap.String("deployment_id", deploymentID), zap.String("output", out), zap.Error(startErr))
+		}
+		return fmt.Errorf("renaming schemas: %w", err)
+	}
+
+	// the state of the sinks (its setup marker) follows their schema
+	deploymentDir := filepath.Join(e.dir, deploymentID)
+	previousDir := filepath.Join(deploymentDir, previousFolder)
+	if err := os.RemoveAll(previousDir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(previousDir, "data"), 0755); err != nil {
+		return err
+	}
+	moves := [][2]string{
+		{filepath.Join(deploymentDir, "config", "sink", "substreams.spkg"), filepath.Join(previousDir, "substreams.spkg")},
+		{filepath.Join(deploymentDir, "data", "sink"), filepath.Join(previousDir, "data", "sink")},
+		{filepath.Join(deploymentDir, nextFolder, "data", "sink"), filepath.Join(deploymentDir, "data", "sink")},
+	}
+	for _, move := range moves {
+		if err := movePath(move[0], move[1]); err != nil {
+			return fmt.Errorf("moving sink state: %w", err)
+		}
+	}
+	if err := os.RemoveAll(filepath.Join(deploymentDir, nextFolder)); err != nil {
+		return err
+	}
+
+	previous := info.PackageInfo
+	if err := e.redeploy(ctx, deploymentID, pkg, zlog); err != nil {
+		return err
+	}
+	e.progress.Forget(deploymentID)
+	e.progress.Forget(nextSinkServiceName(deploymentID))
+	return e.setPreviousPackage(deploymentID, previous)
+}
+
+// Rollback cancels the pending update of the deployment if there is one, otherwise it switches the frontends
+// back to the package replaced by the last blue/green update.
+func (e *DockerEngine) Rollback(ctx context.Context, deploymentID string, zlog *zap.Logger) (*pbsinksvc.RollbackResponse, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	info, err := e.readDeploymentInfo(deploymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Next != nil {
+		if out, err := e.composeNext(deploymentID, "down"); err != nil {
+			return nil, fmt.Errorf("stopping sink of the update: %q, %w", out, err)
+		}
+		if err := e.psql(deploymentID, fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE;", nextSchema)); err != nil {
+			return nil, fmt.Errorf("dropping schema %q: %w", nextSchema, err)
+		}
+		if err := os.RemoveAll(filepath.Join(e.dir, deploymentID, nextFolder)); err != nil {
+			return nil, err
+		}
+		e.progress.Forget(nextSinkServiceName(deploymentID))
+
+		info.Next = nil
+		if err := e.saveDeploymentInfo(deploymentID, info); err != nil {
+			return nil, err
+		}
+		return &pbsinksvc.RollbackResponse{PackageInfo: info.PackageInfo, CancelledPendingUpdate: true}, nil
+	}
+
+	if info.Previous == nil {
+		return nil, fmt.Errorf("deployment %q has no previous package to roll back to", deploymentID)
+	}
+
+	deploymentDir := filepath.Join(e.dir, deploymentID)
+	previousDir := filepath.Join(deploymentDir, previousFolder)
+	pkg, err := readPackage(filepath.Join(previousDir, "substreams.spkg"))
+	if err != nil {
+		return nil, err
+	}
+
+	if out, err := e.compose(deploymentID, "stop", sinkServiceName(deploymentID)); err != nil {
+		return nil, fmt.Errorf("stopping sink: %q, %w", out, err)
+	}
+	err = e.psql(deploymentID, fmt.Sprintf("BEGIN; ALTER SCHEMA public RENAME TO rollback; ALTER SCHEMA %s RENAME TO public; ALTER SCHEMA rollback RENAME TO %s; COMMIT;", previousSchema, previousSchema))
+	if err != nil {
+		if out, startErr := e.compose(deploymentID, "start", sinkServiceName(deploymentID)); startErr != nil {
+			zlog.Warn("cannot restart sink", zap.String("deployment_id", deploymentID), zap.String("output", out), zap.Error(startErr))
+		}
+		return nil, fmt.Errorf("renaming schemas: %w", err)
+	}
+
+	// the current package and sink state become the previous ones, so that the rollback can be undone
+	swaps := [][2]string{
+		{filepath.Join(deploymentDir, "config", "sink", "substreams.spkg"), filepath.Join(previousDir, "substreams.spkg")},
+		{filepath.Join(deploymentDir, "data", "sink"), filepath.Join(previousDir, "data", "sink")},
+	}
+	for _, swap := range swaps {
+		if err := swapPaths(swap[0], swap[1]); err != nil {
+			return nil, fmt.Errorf("swapping sink state: %w", err)
+		}
+	}
+
+	current := info.PackageInfo
+	if err := e.redeploy(ctx, deploymentID, pkg, zlog); err != nil {
+		return nil, err
+	}
+	e.progress.Forget(deploymentID)
+	if err := e.setPreviousPackage(deploymentID, current); err != nil {
+		return nil, err
+	}
+	return &pbsinksvc.RollbackResponse{PackageInfo: info.Previous}, nil
+}
+
+// redeploy applies the manifest of `pkg` on the deployment, starting its stopped sink. The frontends are left
+// untouched.
+func (e *DockerEngine) redeploy(ctx context.Context, deploymentID string, pkg *pbsubstreams.Package, zlog *zap.Logger) error {
+	manifest, usedPorts, serviceInfo, runMeFirst, err := e.createManifest(ctx, deploymentID, pkg, zlog)
+	if err != nil {
+		return fmt.Errorf("creating manifest from package: %w", err)
+	}
+	if err := e.writeDeploymentInfo(deploymentID, usedPorts, runMeFirst, serviceInfo, pkg); err != nil {
+		return fmt.Errorf("cannot write Service Info: %w", err)
+	}
+	output, err := e.applyManifest(deploymentID, manifest, runMeFirst, false)
+	if err != nil {
+		return fmt.Errorf("applying manifest: %w\noutput: %s", err, output)
+	}
+	return nil
+}
+
+func (e *DockerEngine) setPreviousPackage(deploymentID string, previous *pbsinksvc.PackageInfo) error {
+	info, err := e.readDeploymentInfo(deploymentID)
+	if err != nil {
+		return err
+	}
+	info.Previous = previous
+	return e.saveDeploymentInfo(deploymentID, info)
+}
+
+// nextProgressSource reads the progress of the sink of the pending update from the 'next' schema and its metrics.
+func (e *DockerEngine) nextProgressSource(deploymentID string, info *deploymentInfo) *progress.Source {
+	var cursorDSN string
+	if creds, err := e.secrets.Get(deploymentID); err == nil {
+		cursorDSN = fmt.Sprintf("postgres://%s:%s@localhost:5432/substreams?sslmode=disable&search_path=%s", dbUser, creds[dbPasswordSecret], nextSchema)
+	}
+
+	var outputModuleHash string
+	if info.Next != nil && info.Next.PackageInfo != nil {
+		outputModuleHash = info.Next.PackageInfo.OutputModuleHash
+	}
+
+	return &progress.Source{
+		CursorDSN:        cursorDSN,
+		OutputModuleHash: outputModuleHash,
+		MetricsURL:       fmt.Sprintf("http://localhost:%d/metrics", nextSinkMetricsPort),
+		Logs: func(ctx context.Context) ([]string, error) {
+			cmd := exec.CommandContext(ctx, "docker", "compose", "logs", nextSinkServiceName(deploymentID), "--no-log-prefix", "--tail", "200")
+			cmd.Dir = filepath.Join(e.dir, deploymentID, nextFolder)
+			out, err := cmd.Output()
+			if err != nil {
+				return nil, err
+			}
+			return strings.Split(string(out), "\n"), nil
+		},
+	}
+}
+
+// psql runs `query` on the postgres database of the deployment. The query is given on stdin so that the
+// secrets it may contain are not in the arguments of the process.
+func (e *DockerEngine) psql(deploymentID string, query string) error {
+	cmd := exec.Command("docker", "compose", "exec", "-T", postgresServiceName(deploymentID), "psql", "-U", dbUser, "-d", "substreams", "-v", "ON_ERROR_STOP=1")
+	cmd.Dir = filepath.Join(e.dir, deploymentID)
+	cmd.Stdin = strings.NewReader(query)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (e *DockerEngine) compose(deploymentID string, args ...string) (string, error) {
+	cmd := exec.Command("docker", append([]string{"compose"}, args...)...)
+	cmd.Dir = filepath.Join(e.dir, deploymentID)
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// composeNext runs the docker compose command on the pending update of the deployment, if any.
+func (e *DockerEngine) composeNext(deploymentID string, args ...string) (string, error) {
+	if _, err := os.Stat(filepath.Join(e.dir, deploymentID, nextFolder, "docker-compose.yaml")); err != nil {
+		return "", nil
+	}
+	return e.compose(filepath.Join(deploymentID, nextFolder), args...)
+}
+
+func readPackage(path string) (*pbsubstreams.Package, error) {
+	cnt, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading package: %w", err)
+	}
+	pkg := &pbsubstreams.Package{}
+	if err := proto.Unmarshal(cnt, pkg); err != nil {
+		return nil, fmt.Errorf("unmarshalling package %q: %w", path, err)
+	}
+	return pkg, nil
+}
+
+// movePath renames `from` to `to`, the state of a sink that never started being absent.
+func movePath(from, to string) error {
+	if err := os.Rename(from, to); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func swapPaths(a, b string) error {
+	tmp := a + ".swap"
+	if err := movePath(a, tmp); err != nil {
+		return err
+	}
+	if err := movePath(b, a); err != nil {
+		return err
+	}
+	return movePath(tmp, b)
+}
@@ -0,0 +1,78 @@
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	pbsql "github.com/streamingfast/substreams-sink-sql/pb/sf/substreams/sink/sql/v1"
+	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestNextManifest(t *testing.T) {
+	e := &DockerEngine{dir: t.TempDir(), token: "secret-token"}
+	pkg := testPackage(t, &pbsql.Service{Engine: pbsql.Service_postgres})
+
+	sinkConfig, err := postgresSinkConfig(pkg)
+	require.NoError(t, err)
+
+	sink, err := e.newSQLSink(sqlSinkSlot{
+		name:        nextSinkServiceName("dep"),
+		folder:      filepath.Join("dep", nextFolder),
+		dbHost:      postgresServiceName("dep"),
+		schema:      nextSchema,
+		metricsPort: nextSinkMetricsPort,
+	}, pkg, sinkConfig)
+	require.NoError(t, err)
+
+	content, err := nextManifest("dep", sink)
+	require.NoError(t, err)
+
+	config := &struct {
+		Name     string `yaml:"name"`
+		Services map[string]struct {
+			Links []string `yaml:"links"`
+			Ports []struct {
+				Published uint32 `yaml:"published"`
+			} `yaml:"ports"`
+		} `yaml:"services"`
+		Networks map[string]struct {
+			Name     string `yaml:"name"`
+			External bool   `yaml:"external"`
+		} `yaml:"networks"`
+		Secrets map[string]struct {
+			File string `yaml:"file"`
+		} `yaml:"secrets"`
+	}{}
+	require.NoError(t, yaml.Unmarshal(content, config))
+
+	assert.Equal(t, "dep-next", config.Name)
+	require.Contains(t, config.Services, "dep-sink-next")
+	assert.Empty(t, config.Services["dep-sink-next"].Links)
+	assert.Equal(t, nextSinkMetricsPort, config.Services["dep-sink-next"].Ports[0].Published)
+	assert.Equal(t, "dep_default", config.Networks["default"].Name)
+	assert.True(t, config.Networks["default"].External)
+	assert.Equal(t, "../secrets/"+dbPasswordSecret, config.Secrets[dbPasswordSecret].File)
+
+	script, err := os.ReadFile(filepath.Join(e.dir, "dep", nextFolder, "config", "sink", "start.sh"))
+	require.NoError(t, err)
+	assert.Contains(t, string(script), "@dep-postgres:5432/substreams?sslmode=disable&schema=next")
+}
+
+func TestPostgresSinkConfig(t *testing.T) {
+	_, err := postgresSinkConfig(testPackage(t, &pbsql.Service{Engine: pbsql.Service_clickhouse}))
+	assert.ErrorContains(t, err, "blue/green updates of clickhouse sinks")
+
+	_, err = postgresSinkConfig(testPackage(t, nil))
+	assert.Error(t, err)
+}
+
+func TestCaughtUp(t *testing.T) {
+	assert.False(t, caughtUp(nil))
+	assert.False(t, caughtUp(&pbsinksvc.SinkProgress{LastProcessedBlock: 100}), "unknown lag")
+	assert.False(t, caughtUp(&pbsinksvc.SinkProgress{HeadLagSeconds: 3600}))
+	assert.True(t, caughtUp(&pbsinksvc.SinkProgress{HeadLagSeconds: 12}))
+}
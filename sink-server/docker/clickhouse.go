@@ -11,7 +11,7 @@ import (
 )
 
 func (e *DockerEngine) newClickhouse(deploymentID string, pkg *pbsubstreams.Package) (types.ServiceConfig, string, error) {
-	name := fmt.Sprintf("%s-clickhouse", deploymentID)
+	name := clickhouseServiceName(deploymentID)
 
 	dataFolder := filepath.Join(e.dir, deploymentID, "data", "clickhouse")
 	if err := os.MkdirAll(dataFolder, 0755); err != nil {
@@ -38,8 +38,7 @@ func (e *DockerEngine) newClickhouse(deploymentID string, pkg *pbsubstreams.Pack
 		},
 
 		Environment: map[string]*string{
-			"CLICKHOUSE_USER":                      deref("dev-node"),
-			"CLICKHOUSE_PASSWORD":                  deref("insecure-change-me-in-prod"),
+			"CLICKHOUSE_USER":                      deref(dbUser),
 			"CLICKHOUSE_DB":                        deref("substreams"),
 			"CLICKHOUSE_DEFAULT_ACCESS_MANAGEMENT": deref("1"),
 			//"POSTGRES_INITDB_ARGS":      deref("-E UTF8 --locale=C"),
@@ -60,14 +59,23 @@ func (e *DockerEngine) newClickhouse(deploymentID string, pkg *pbsubstreams.Pack
 		},
 	}
 
-	motd := fmt.Sprintf("Clickhouse service %q available at DSN: 'clickhouse://%s:%s@localhost:%d/%s', connect to CLI using 'docker exec -ti %s clickhouse client'",
+	// the entrypoint of the image creates the user from its password
+	withSecretsEnv(&conf, []string{dbPasswordSecret}, map[string]string{
+		"CLICKHOUSE_PASSWORD": secretRef(dbPasswordSecret),
+	}, "/entrypoint.sh")
+
+	motd := fmt.Sprintf("Clickhouse service %q available at DSN: 'clickhouse://%s:<password>@localhost:%d/%s', the password is in %q, connect to CLI using 'docker exec -ti %s clickhouse client'",
 		name,
 		*conf.Environment["CLICKHOUSE_USER"],
-		*conf.Environment["CLICKHOUSE_PASSWORD"],
 		clickhousePort,
 		*conf.Environment["CLICKHOUSE_DB"],
+		filepath.Join(e.dir, deploymentID, "secrets", dbPasswordSecret),
 		name,
 	)
 
 	return conf, motd, nil
 }
+
+func clickhouseServiceName(deploymentID string) string {
+	return deploymentID + "-clickhouse"
+}
@@ -37,8 +37,13 @@ func (e *DockerEngine) newDBT(deploymentID string, serviceName string, config *p
 		return conf, "", fmt.Errorf("unable to extract profile name from dbt_project.yml: %w", err)
 	}
 
+	creds, err := e.credentials(deploymentID)
+	if err != nil {
+		return conf, "", err
+	}
+
 	//create dbt_project.yml file
-	dbtProfileYaml, err := createDbtProfileYml(dbtProfileName, engine)
+	dbtProfileYaml, err := createDbtProfileYml(dbtProfileName, engine, creds[dbPasswordSecret])
 	if err != nil {
 		return conf, "", fmt.Errorf("unable to create dbt_project.yml file: %w", err)
 	}
@@ -67,7 +72,7 @@ func (e *DockerEngine) newDBT(deploymentID string, serviceName string, config *p
 		return conf, "", fmt.Errorf("creating folder %q: %w", scriptFolder, err)
 	}
 
-	err = os.WriteFile(filepath.Join(e.dir, deploymentID, "data", "profile", "profiles.yml"), dbtProfileYaml, 0644)
+	err = os.WriteFile(filepath.Join(e.dir, deploymentID, "data", "profile", "profiles.yml"), dbtProfileYaml, 0600)
 	if err != nil {
 		return conf, "", fmt.Errorf("writing profiles.yml file: %w", err)
 	}
@@ -228,7 +233,7 @@ func extractProfileName(dbtProjectYaml []byte) (string, error) {
 	return "", fmt.Errorf("unable to extract profile name from dbt_project.yml")
 }
 
-func createDbtProfileYml(profileName string, engine string) ([]byte, error) {
+func createDbtProfileYml(profileName string, engine string, password string) ([]byte, error) {
 	port := 5432
 	host := "postgres"
 	switch engine {
@@ -245,12 +250,12 @@ func createDbtProfileYml(profileName string, engine string) ([]byte, error) {
       threads: 1
       host: %s
       port: %d
-      user: dev-node
-      password: insecure-change-me-in-prod
+      user: %s
+      password: %s
       dbname: substreams
       schema: public
   target: dev
-`, profileName, engine, host, port)
+`, profileName, engine, host, port, dbUser, password)
 
 	return []byte(data), nil
 }
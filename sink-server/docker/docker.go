@@ -3,7 +3,6 @@ package docker
 import (
 	"bytes"
 	"context"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,19 +11,20 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	pbsql "github.com/streamingfast/substreams-sink-sql/pb/sf/substreams/sink/sql/v1"
 	"github.com/streamingfast/substreams/manifest"
 	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
 	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
 	sinkcontext "github.com/streamingfast/substreams/sink-server/context"
+	"github.com/streamingfast/substreams/sink-server/progress"
+	"github.com/streamingfast/substreams/sink-server/secrets"
 
 	types "github.com/docker/cli/cli/compose/types"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"gopkg.in/yaml.v3"
 )
 
@@ -33,13 +33,25 @@ type DockerEngine struct {
 	dir      string
 	endpoint string
 	token    string
+	progress *progress.Tracker
+	secrets  *secrets.Store
+
+	// deployments whose pending blue/green update is watched, the watchers stop when shutdown is closed
+	watchMutex sync.Mutex
+	watching   map[string]bool
+	shutdown   chan struct{}
 }
 
-func NewEngine(dir string, sf_token string, endpoint string) (*DockerEngine, error) {
+// NewEngine returns an engine keeping its deployments in `dir`. Their generated credentials are encrypted
+// with `secretsKey` (see secrets.LoadKey), or with a key generated in the data folder when nil.
+func NewEngine(dir string, sf_token string, endpoint string, secretsKey []byte) (*DockerEngine, error) {
 	out := &DockerEngine{
 		dir:      dir,
 		token:    sf_token,
 		endpoint: endpoint,
+		progress: progress.NewTracker(),
+		watching: make(map[string]bool),
+		shutdown: make(chan struct{}),
 	}
 	if err := out.CheckVersion(); err != nil {
 		return nil, err
@@ -47,38 +59,26 @@ func NewEngine(dir string, sf_token string, endpoint string) (*DockerEngine, err
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, err
 	}
+	store, err := secrets.Open(filepath.Join(dir, secretsFolder), secretsKey)
+	if err != nil {
+		return nil, err
+	}
+	out.secrets = store
 	return out, nil
 }
 
+// secretsFolder holds the encrypted secrets of the deployments, in the data folder without being one of them
+const secretsFolder = ".secrets"
+
 type deploymentInfo struct {
 	PackageInfo *pbsinksvc.PackageInfo
 	ServiceInfo map[string]string
 	UsedPorts   []uint32
 	RunMeFirst  []string
-}
 
-func getModuleHash(mod string, pkg *pbsubstreams.Package) (hash string, err error) {
-	graph, err := manifest.NewModuleGraph(pkg.Modules.Modules)
-	if err != nil {
-		return "", fmt.Errorf("creating module graph: %w", err)
-	}
-
-	hashes := manifest.NewModuleHashes()
-
-	for _, module := range pkg.Modules.Modules {
-		if module.Name != mod {
-			continue
-		}
-		h, err := hashes.HashModule(pkg.Modules, module, graph)
-		if err != nil {
-			return "", fmt.Errorf("hashing module: %w", err)
-		}
-		hash = hex.EncodeToString(h)
-	}
-	if hash == "" {
-		return hash, fmt.Errorf("cannot find module %s", mod)
-	}
-	return
+	// Next is the package of the pending blue/green update, Previous the one it replaced, for rollbacks
+	Next     *pendingUpdate         `json:",omitempty"`
+	Previous *pbsinksvc.PackageInfo `json:",omitempty"`
 }
 
 func (e *DockerEngine) CheckVersion() error {
@@ -98,9 +98,23 @@ func (e *DockerEngine) CheckVersion() error {
 	return fmt.Errorf("Cannot determine docker compose version %q. Upgrade your Docker engine here: https://docs.docker.com/engine/install/", ver)
 }
 
-func (e *DockerEngine) writeDeploymentInfo(deploymentID string, usedPorts []uint32, runMeFirst []string, svcInfo map[string]string, pkg *pbsubstreams.Package) error {
+func newPackageInfo(pkg *pbsubstreams.Package) (*pbsinksvc.PackageInfo, error) {
 	pkgMeta := pkg.PackageMeta[0]
-	hash, err := getModuleHash(pkg.SinkModule, pkg)
+	hash, err := manifest.OutputModuleHash(pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pbsinksvc.PackageInfo{
+		Name:             pkgMeta.Name,
+		Version:          pkgMeta.Version,
+		OutputModuleName: pkg.SinkModule,
+		OutputModuleHash: hash,
+	}, nil
+}
+
+func (e *DockerEngine) writeDeploymentInfo(deploymentID string, usedPorts []uint32, runMeFirst []string, svcInfo map[string]string, pkg *pbsubstreams.Package) error {
+	packageInfo, err := newPackageInfo(pkg)
 	if err != nil {
 		return err
 	}
@@ -108,16 +122,14 @@ func (e *DockerEngine) writeDeploymentInfo(deploymentID string, usedPorts []uint
 	depInfo := &deploymentInfo{
 		ServiceInfo: svcInfo,
 		UsedPorts:   usedPorts,
-		PackageInfo: &pbsinksvc.PackageInfo{
-			Name:             pkgMeta.Name,
-			Version:          pkgMeta.Version,
-			OutputModuleName: pkg.SinkModule,
-			OutputModuleHash: hash,
-		},
-		RunMeFirst: runMeFirst,
+		PackageInfo: packageInfo,
+		RunMeFirst:  runMeFirst,
 	}
+	return e.saveDeploymentInfo(deploymentID, depInfo)
+}
 
-	json, err := json.Marshal(depInfo)
+func (e *DockerEngine) saveDeploymentInfo(deploymentID string, info *deploymentInfo) error {
+	json, err := json.Marshal(info)
 	if err != nil {
 		return err
 	}
@@ -144,7 +156,7 @@ func (e *DockerEngine) Create(ctx context.Context, deploymentID string, pkg *pbs
 		return nil, fmt.Errorf("this substreams-sink engine only supports a single active deployment. Stop any active sink before launching another one or use `sink-update`")
 	}
 
-	manifest, usedPorts, serviceInfo, runMeFirst, err := e.createManifest(ctx, deploymentID, e.token, pkg)
+	manifest, usedPorts, serviceInfo, runMeFirst, err := e.createManifest(ctx, deploymentID, pkg, zlog)
 	if err != nil {
 		return nil, fmt.Errorf("creating manifest from package: %w", err)
 	}
@@ -166,6 +178,10 @@ func (e *DockerEngine) Update(ctx context.Context, deploymentID string, pkg *pbs
 	e.mutex.Lock()
 	defer e.mutex.Unlock()
 
+	if info, err := e.readDeploymentInfo(deploymentID); err == nil && info.Next != nil {
+		return fmt.Errorf("deployment %q has a pending update, roll it back before updating it again", deploymentID)
+	}
+
 	if reset {
 		if _, err := e.Stop(ctx, deploymentID, zlog); err != nil {
 			return err
@@ -174,12 +190,19 @@ func (e *DockerEngine) Update(ctx context.Context, deploymentID string, pkg *pbs
 		if err := os.RemoveAll(filepath.Join(e.dir, deploymentID)); err != nil {
 			return fmt.Errorf("cannot cleanup the deployment folder: %w", err)
 		}
+		e.progress.Forget(deploymentID)
 		if err := os.MkdirAll(filepath.Join(e.dir, deploymentID), 0755); err != nil {
 			return fmt.Errorf("cannot re-create the deployment folder: %w", err)
 		}
+		// the database is created again from the new credentials
+		if _, err := e.generateCredentials(deploymentID); err != nil {
+			return fmt.Errorf("rotating credentials: %w", err)
+		}
+	} else if err := e.rotateCredentials(deploymentID, zlog); err != nil {
+		return fmt.Errorf("rotating credentials: %w", err)
 	}
 
-	manifest, usedPorts, serviceInfo, runMeFirst, err := e.createManifest(ctx, deploymentID, e.token, pkg)
+	manifest, usedPorts, serviceInfo, runMeFirst, err := e.createManifest(ctx, deploymentID, pkg, zlog)
 	if err != nil {
 		return fmt.Errorf("creating manifest from package: %w", err)
 	}
@@ -196,6 +219,8 @@ func (e *DockerEngine) Update(ctx context.Context, deploymentID string, pkg *pbs
 	return nil
 }
 
+// otherDeploymentIsActive tells if another deployment may hold the host ports, which are the same for all
+// the deployments of this engine.
 func (e *DockerEngine) otherDeploymentIsActive(ctx context.Context, deploymentID string, zlog *zap.Logger) bool {
 	if deps, _ := e.list(ctx, zlog); deps != nil {
 		for _, dep := range deps {
@@ -300,63 +325,71 @@ func (e *DockerEngine) Info(ctx context.Context, deploymentID string, zlog *zap.
 	}
 
 	var sinkProgress *pbsinksvc.SinkProgress
-	blk := getProgressBlock(sinkServiceName(deploymentID), filepath.Join(e.dir, deploymentID), zlog)
-	if blk != 0 {
-		sinkProgress = &pbsinksvc.SinkProgress{
-			LastProcessedBlock: blk,
+	if status != pbsinksvc.DeploymentStatus_STOPPED {
+		sinkProgress = e.progress.Progress(ctx, deploymentID, e.progressSource(deploymentID, info, zlog), zlog)
+	}
+
+	var pending *pbsinksvc.PendingUpdate
+	if info.Next != nil {
+		pending = &pbsinksvc.PendingUpdate{
+			PackageInfo: info.Next.PackageInfo,
+			StartedAt:   timestamppb.New(info.Next.StartedAt),
+		}
+		if status != pbsinksvc.DeploymentStatus_STOPPED {
+			pending.Progress = e.progress.Progress(ctx, nextSinkServiceName(deploymentID), e.nextProgressSource(deploymentID, info), zlog)
+			// the watcher does not survive a restart of the server
+			e.watchPendingUpdate(ctx, deploymentID, zlog)
 		}
 	}
 
 	return &pbsinksvc.InfoResponse{
-		Status:      status,
-		Services:    info.ServiceInfo,
-		Reason:      reason,
-		PackageInfo: info.PackageInfo,
-		Progress:    sinkProgress,
-		Motd:        `Running your deployment inside local docker containers`,
+		Status:              status,
+		Services:            info.ServiceInfo,
+		Reason:              reason,
+		PackageInfo:         info.PackageInfo,
+		Progress:            sinkProgress,
+		Motd:                `Running your deployment inside local docker containers`,
+		PendingUpdate:       pending,
+		PreviousPackageInfo: info.Previous,
 	}, nil
 }
 
-func getProgressBlock(serviceName, dir string, zlog *zap.Logger) uint64 {
-	cmd := exec.Command("docker", "compose", "logs", serviceName, "--no-log-prefix")
-	cmd.Dir = dir
-	out, err := cmd.Output()
-	if err != nil {
-		zlog.Debug("cannot get progress block", zap.Error(err))
-		return 0
-	}
-
-	lines := strings.Split(string(out), "\n")
-	if len(lines) == 0 {
-		zlog.Debug("got no output lines from sink for progress")
-		return 0
-	}
-
-	for i := len(lines) - 1; i >= 0; i-- {
-		if strings.Contains(lines[i], "postgres sink stats") { // postgres sink can be ahead of substreams sink, so we use the former
-			stats := &StreamStats{}
-			if err := json.Unmarshal([]byte(lines[i]), stats); err == nil {
-				parts := strings.Split(stats.LastBlock, " ")
-				if len(parts) == 2 {
-					blk := strings.TrimPrefix(parts[0], "#")
-					blknum, err := strconv.ParseUint(blk, 10, 64)
-					if err == nil {
-						return blknum
-					} else {
-						zlog.Debug("cannot parse blocknum in stream stats", zap.Error(err), zap.String("blk", blk))
-					}
-				}
-			} else {
-				zlog.Info("cannot unmarshal sink stream stats", zap.Error(err))
-			}
+// progressSource reads the progress of the sink from the database and the metrics published on the host.
+func (e *DockerEngine) progressSource(deploymentID string, info *deploymentInfo, zlog *zap.Logger) *progress.Source {
+	// both databases are published on the same host port, through the HTTP interface for clickhouse,
+	// the sinks without a database only report their metrics
+	var cursorDSN string
+	_, isPostgres := info.ServiceInfo[postgresServiceName(deploymentID)]
+	_, isClickhouse := info.ServiceInfo[clickhouseServiceName(deploymentID)]
+	if isPostgres || isClickhouse {
+		if creds, err := e.secrets.Get(deploymentID); err != nil {
+			zlog.Info("cannot read credentials of deployment, progress is read from its metrics only", zap.String("deployment_id", deploymentID), zap.Error(err))
+		} else if isPostgres {
+			cursorDSN = fmt.Sprintf("postgres://%s:%s@localhost:5432/substreams?sslmode=disable", dbUser, creds[dbPasswordSecret])
+		} else {
+			cursorDSN = fmt.Sprintf("http://%s:%s@localhost:5432/substreams", dbUser, creds[dbPasswordSecret])
 		}
 	}
-	return 0
 
-}
+	var outputModuleHash string
+	if info.PackageInfo != nil {
+		outputModuleHash = info.PackageInfo.OutputModuleHash
+	}
 
-type StreamStats struct {
-	LastBlock string `json:"last_block"`
+	return &progress.Source{
+		CursorDSN:        cursorDSN,
+		OutputModuleHash: outputModuleHash,
+		MetricsURL:       fmt.Sprintf("http://localhost:%d/metrics", sinkMetricsPort),
+		Logs: func(ctx context.Context) ([]string, error) {
+			cmd := exec.CommandContext(ctx, "docker", "compose", "logs", sinkServiceName(deploymentID), "--no-log-prefix", "--tail", "200")
+			cmd.Dir = filepath.Join(e.dir, deploymentID)
+			out, err := cmd.Output()
+			if err != nil {
+				return nil, err
+			}
+			return strings.Split(string(out), "\n"), nil
+		},
+	}
 }
 
 type dockerComposePSOutput struct {
@@ -379,6 +412,9 @@ func (e *DockerEngine) list(ctx context.Context, zlog *zap.Logger) (out []*pbsin
 
 	for _, f := range files {
 		id := f.Name()
+		if !f.IsDir() || strings.HasPrefix(id, ".") { // hidden folders hold the secrets and the deployment registry
+			continue
+		}
 		info, err := e.Info(ctx, id, zlog)
 		if err != nil {
 			zlog.Warn("cannot get info for deployment", zap.String("id", id))
@@ -389,6 +425,7 @@ func (e *DockerEngine) list(ctx context.Context, zlog *zap.Logger) (out []*pbsin
 			Status:      info.Status,
 			Reason:      info.Reason,
 			PackageInfo: info.PackageInfo,
+			Progress:    info.Progress,
 		})
 	}
 	return out, nil
@@ -421,6 +458,9 @@ func (e *DockerEngine) Resume(ctx context.Context, deploymentID string, _ pbsink
 	if err != nil {
 		return "", fmt.Errorf("resuming docker compose: %q, %w", out, err)
 	}
+	if nextOut, err := e.composeNext(deploymentID, "up", "-d", "--wait"); err != nil {
+		return "", fmt.Errorf("resuming sink of the pending update: %q, %w", nextOut, err)
+	}
 	return string(out), nil
 }
 
@@ -431,10 +471,18 @@ func (e *DockerEngine) Pause(ctx context.Context, deploymentID string, zlog *zap
 	if err != nil {
 		return "", fmt.Errorf("pausing docker compose: %q, %w", out, err)
 	}
+	if nextOut, err := e.composeNext(deploymentID, "stop"); err != nil {
+		return "", fmt.Errorf("pausing sink of the pending update: %q, %w", nextOut, err)
+	}
 	return string(out), nil
 }
 
 func (e *DockerEngine) Stop(ctx context.Context, deploymentID string, zlog *zap.Logger) (string, error) {
+	// the sink of the pending update uses the network of the deployment, it goes down first
+	if out, err := e.composeNext(deploymentID, "down"); err != nil {
+		return "", fmt.Errorf("stopping sink of the pending update: %q, %w", out, err)
+	}
+
 	cmd := exec.Command("docker", "compose", "down")
 	cmd.Dir = filepath.Join(e.dir, deploymentID)
 	out, err := cmd.CombinedOutput()
@@ -445,17 +493,28 @@ func (e *DockerEngine) Stop(ctx context.Context, deploymentID string, zlog *zap.
 }
 
 func (e *DockerEngine) Remove(ctx context.Context, deploymentID string, zlog *zap.Logger) (string, error) {
+	if out, err := e.composeNext(deploymentID, "down"); err != nil {
+		return "", fmt.Errorf("stopping sink of the pending update: %q, %w", out, err)
+	}
+
 	cmd := exec.Command("docker", "compose", "down")
 	cmd.Dir = filepath.Join(e.dir, deploymentID)
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		return "", fmt.Errorf("pausing docker compose: %q, %w", out, err)
 	}
+	e.progress.Forget(deploymentID)
+	e.progress.Forget(nextSinkServiceName(deploymentID))
+	if err := e.secrets.Delete(deploymentID); err != nil {
+		return string(out), err
+	}
 	err = os.RemoveAll(filepath.Join(e.dir, deploymentID))
 	return string(out), err
 }
 
-func (e *DockerEngine) applyManifest(deployment string, man []byte, runMeFirst []string, restartSink bool) (string, error) {
+// applyManifest writes the manifest and starts its services. With `recreate`, the running containers are
+// re-created so that they restart with the new package and credentials.
+func (e *DockerEngine) applyManifest(deployment string, man []byte, runMeFirst []string, recreate bool) (string, error) {
 	w, err := os.Create(filepath.Join(e.dir, deployment, "docker-compose.yaml"))
 	if err != nil {
 		return "", fmt.Errorf("creating docker-compose file: %w", err)
@@ -464,9 +523,14 @@ func (e *DockerEngine) applyManifest(deployment string, man []byte, runMeFirst [
 	if _, err := io.Copy(w, r); err != nil {
 		return "", fmt.Errorf("writing docker-compose file: %w", err)
 	}
+	upArgs := []string{"compose", "up", "-d", "--wait"}
+	if recreate {
+		upArgs = append(upArgs, "--force-recreate")
+	}
+
 	// these services need to be healthy first
 	if runMeFirst != nil {
-		args := append([]string{"compose", "up", "-d", "--wait"}, runMeFirst...)
+		args := append(upArgs, runMeFirst...)
 		cmd := exec.Command("docker", args...)
 		cmd.Dir = filepath.Join(e.dir, deployment)
 		out, err := cmd.CombinedOutput()
@@ -475,21 +539,9 @@ func (e *DockerEngine) applyManifest(deployment string, man []byte, runMeFirst [
 		}
 	}
 
-	cmd := exec.Command("docker", "compose", "up", "-d", "--wait")
+	cmd := exec.Command("docker", upArgs...)
 	cmd.Dir = filepath.Join(e.dir, deployment)
 	out, err := cmd.CombinedOutput()
-	if err != nil {
-		return string(out), err
-	}
-
-	if restartSink {
-		cmd := exec.Command("docker", "compose", "restart", sinkServiceName(deployment))
-		cmd.Dir = filepath.Join(e.dir, deployment)
-		out2, err2 := cmd.CombinedOutput()
-		out = append(out, out2...)
-		err = err2
-	}
-
 	return string(out), err
 }
 
@@ -502,6 +554,8 @@ func toDuration(in time.Duration) *types.Duration {
 }
 
 func (e *DockerEngine) Shutdown(ctx context.Context, _ error, zlog *zap.Logger) error {
+	close(e.shutdown)
+
 	e.mutex.Lock()
 	defer e.mutex.Unlock()
 
@@ -522,107 +576,26 @@ func (e *DockerEngine) Shutdown(ctx context.Context, _ error, zlog *zap.Logger)
 	return err
 }
 
-func (e *DockerEngine) createManifest(ctx context.Context, deploymentID string, token string, pkg *pbsubstreams.Package) (content []byte, usedPorts []uint32, servicesDesc map[string]string, runMeFirst []string, err error) {
-	if pkg.SinkConfig.TypeUrl != "sf.substreams.sink.sql.v1.Service" {
-		return nil, nil, nil, nil, fmt.Errorf("invalid sinkconfig type: %q. Only sf.substreams.sink.sql.v1.Service is supported for now.", pkg.SinkConfig.TypeUrl)
-	}
-	sinkConfig := &pbsql.Service{}
-	if err := pkg.SinkConfig.UnmarshalTo(sinkConfig); err != nil {
-		return nil, nil, nil, nil, fmt.Errorf("cannot unmarshal sinkconfig: %w", err)
-	}
-
-	servicesDesc = make(map[string]string)
-	var services []types.ServiceConfig
-
-	var dbServiceName string
-	var isPostgres, isClickhouse bool
-
-	switch sinkConfig.Engine {
-	case pbsql.Service_clickhouse:
-		db, dbMotd, err := e.newClickhouse(deploymentID, pkg)
-		if err != nil {
-			return nil, nil, nil, nil, fmt.Errorf("creating clickhouse deployment: %w", err)
-		}
-		dbServiceName = db.Name
-		servicesDesc[db.Name] = dbMotd
-		runMeFirst = append(runMeFirst, db.Name)
-		services = append(services, db)
-		isClickhouse = true
-
-	case pbsql.Service_postgres, pbsql.Service_unset:
-		pg, pgMotd, err := e.newPostgres(deploymentID, pkg)
-		if err != nil {
-			return nil, nil, nil, nil, fmt.Errorf("creating postgres deployment: %w", err)
-		}
-		dbServiceName = pg.Name
-		servicesDesc[pg.Name] = pgMotd
-		runMeFirst = append(runMeFirst, pg.Name)
-		services = append(services, pg)
-		isPostgres = true
-	}
-
-	sink, sinkMotd, err := e.newSink(deploymentID, dbServiceName, pkg, sinkConfig)
+// createManifest builds the docker compose file of the deployment with the Provisioner registered for the
+// type of its sink config.
+func (e *DockerEngine) createManifest(ctx context.Context, deploymentID string, pkg *pbsubstreams.Package, zlog *zap.Logger) (content []byte, usedPorts []uint32, servicesDesc map[string]string, runMeFirst []string, err error) {
+	provisioner, err := getProvisioner(pkg)
 	if err != nil {
-		return nil, nil, nil, nil, fmt.Errorf("creating postgres deployment: %w", err)
-	}
-	servicesDesc[sink.Name] = sinkMotd
-	services = append(services, sink)
-
-	isProduction := sinkcontext.GetProductionMode(ctx)
-	env := sinkcontext.GetParameterMap(ctx)
-
-	if isTruthy(env["SF_PGWEB"]) || !isProduction {
-		pgweb, motd := e.newPGWeb(deploymentID, dbServiceName)
-		servicesDesc[pgweb.Name] = motd
-		services = append(services, pgweb)
-	}
-
-	if sinkConfig.PostgraphileFrontend != nil && sinkConfig.PostgraphileFrontend.Enabled {
-		postgraphile, motd := e.newPostgraphile(deploymentID, dbServiceName, isProduction)
-		servicesDesc[postgraphile.Name] = motd
-		services = append(services, postgraphile)
+		return nil, nil, nil, nil, err
 	}
 
-	//todo: handle development mode for DBT stuff
-	var engine string
-	if isPostgres {
-		engine = "postgres"
-	} else if isClickhouse {
-		engine = "clickhouse"
+	if err := e.writeSecretFiles(deploymentID); err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("writing secrets: %w", err)
 	}
-	if sinkConfig.DbtConfig != nil && sinkConfig.DbtConfig.Enabled {
-		if engine == "" {
-			return nil, nil, nil, nil, fmt.Errorf("cannot create dbt deployment: no valid engine specified")
-		}
-
-		dbt, motd, err := e.newDBT(deploymentID, dbServiceName, sinkConfig.DbtConfig, engine, isProduction)
-		if err != nil {
-			return nil, nil, nil, nil, fmt.Errorf("creating dbt deployment: %w", err)
-		}
 
-		if dbt != nil {
-			servicesDesc[dbt.Name] = motd
-			if dbt != nil {
-				services = append(services, *dbt)
-			}
-		} else {
-			servicesDesc["dbt"] = motd
-		}
-	}
-
-	if sinkConfig.RestFrontend != nil && sinkConfig.RestFrontend.Enabled {
-		rest, motd := e.newRestFrontend(deploymentID, dbServiceName)
-		servicesDesc[rest.Name] = motd
-		services = append(services, rest)
+	provisioned, err := provisioner.Provision(ctx, e, deploymentID, pkg, zlog)
+	if err != nil {
+		return nil, nil, nil, nil, err
 	}
 
-	if !isProduction { //dev only for now
-		sinkinfo, motd := e.newSinkInfo(deploymentID, dbServiceName, engine)
-		servicesDesc[sinkinfo.Name] = motd
-		services = append(services, sinkinfo)
-	}
+	limitServices(deploymentID, provisioned.Services, sinkcontext.GetSize(ctx))
 
-	for _, svc := range services {
+	for _, svc := range provisioned.Services {
 		for _, port := range svc.Ports {
 			usedPorts = append(usedPorts, port.Published)
 		}
@@ -630,8 +603,9 @@ func (e *DockerEngine) createManifest(ctx context.Context, deploymentID string,
 
 	config := types.Config{
 		Version:  "3",
-		Services: services,
+		Services: provisioned.Services,
+		Secrets:  secretsConfig(provisioned.Services, "./secrets"),
 	}
 	content, err = yaml.Marshal(config)
-	return
+	return content, usedPorts, provisioned.ServicesDesc, provisioned.RunMeFirst, err
 }
@@ -0,0 +1,79 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/cli/cli/compose/types"
+	"github.com/streamingfast/substreams/manifest"
+	pbsinkfiles "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/files/v1"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"go.uber.org/zap"
+)
+
+const defaultFileBlockCount = 10000
+
+// provisionFiles runs substreams-sink-files, writing the output of the sink module to files in the
+// 'data/files' folder of the deployment.
+func provisionFiles(ctx context.Context, e *DockerEngine, deploymentID string, pkg *pbsubstreams.Package, zlog *zap.Logger) (*Provisioned, error) {
+	sinkConfig := &pbsinkfiles.Service{}
+	if err := pkg.SinkConfig.UnmarshalTo(sinkConfig); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal sinkconfig: %w", err)
+	}
+
+	encoder := sinkConfig.Encoder
+	switch {
+	case encoder == "":
+		encoder = "lines"
+	case encoder == "lines", encoder == "parquet", strings.HasPrefix(encoder, "proto:"):
+	default:
+		return nil, fmt.Errorf("invalid encoder %q: must be one of 'lines', 'parquet' or 'proto:<field path>'", encoder)
+	}
+
+	fileBlockCount := sinkConfig.FileBlockCount
+	if fileBlockCount == 0 {
+		fileBlockCount = defaultFileBlockCount
+	}
+
+	endpoint, err := manifest.ExtractNetworkEndpoint(pkg.Network, e.endpoint, zlog)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := e.writeSinkConfig(deploymentID, pkg); err != nil {
+		return nil, err
+	}
+
+	outputFolder := filepath.Join(e.dir, deploymentID, "data", "files")
+	if err := os.MkdirAll(outputFolder, 0755); err != nil {
+		return nil, fmt.Errorf("creating folder %q: %w", outputFolder, err)
+	}
+
+	sink := e.newSinkService(deploymentID, "ghcr.io/streamingfast/substreams-sink-files:latest",
+		[]string{"/app/substreams-sink-files"},
+		[]string{
+			"run",
+			endpoint,
+			"/opt/subservices/config/substreams.spkg",
+			pkg.SinkModule,
+			"/opt/subservices/output",
+			"--encoder=" + encoder,
+			fmt.Sprintf("--file-block-count=%d", fileBlockCount),
+			"--file-working-dir=/opt/subservices/data/working",
+			"--state-store=/opt/subservices/data/state.yaml",
+			fmt.Sprintf("--metrics-listen-addr=0.0.0.0:%d", sinkMetricsPort),
+		},
+	)
+	sink.Volumes = append(sink.Volumes, types.ServiceVolumeConfig{
+		Type:   "bind",
+		Source: "./data/files",
+		Target: "/opt/subservices/output",
+	})
+
+	out := &Provisioned{ServicesDesc: make(map[string]string)}
+	out.add(sink, fmt.Sprintf("Files sink service writing %q files of %d blocks to %q (metrics at http://localhost:%d/metrics). Use 'substreams alpha service logs %s --service sink' to see the logs.", encoder, fileBlockCount, outputFolder, sinkMetricsPort, deploymentID))
+	return out, nil
+}
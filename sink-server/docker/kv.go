@@ -0,0 +1,61 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/docker/cli/cli/compose/types"
+	"github.com/streamingfast/substreams/manifest"
+	pbsinkkv "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/kv/v1"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"go.uber.org/zap"
+)
+
+// kvSinkTypes are the `sink_config` types deployed with substreams-sink-kv: the one of this repo and the
+// one declared by the packages written for substreams-sink-kv. Both are empty messages.
+var kvSinkTypes = []string{
+	string((&pbsinkkv.GenericService{}).ProtoReflect().Descriptor().FullName()),
+	"sf.substreams.sink.kv.v1.GenericService",
+}
+
+// kvPort is the host port of the key-value API, 8000 being the default port of `substreams alpha service serve`.
+const kvPort = uint32(8181) // TODO: assign dynamically
+
+// provisionKV runs substreams-sink-kv, writing the `KVOperations` of the sink module to a badger store
+// and serving them on its generic API.
+func provisionKV(ctx context.Context, e *DockerEngine, deploymentID string, pkg *pbsubstreams.Package, zlog *zap.Logger) (*Provisioned, error) {
+	if !slices.Contains(kvSinkTypes, pkg.SinkConfig.TypeUrl) {
+		return nil, fmt.Errorf("invalid sinkconfig type %q for substreams-sink-kv", pkg.SinkConfig.TypeUrl)
+	}
+
+	endpoint, err := manifest.ExtractNetworkEndpoint(pkg.Network, e.endpoint, zlog)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := e.writeSinkConfig(deploymentID, pkg); err != nil {
+		return nil, err
+	}
+
+	sink := e.newSinkService(deploymentID, "ghcr.io/streamingfast/substreams-sink-kv:latest",
+		[]string{"/app/substreams-sink-kv"},
+		[]string{
+			"inject",
+			endpoint,
+			"badger3:///opt/subservices/data/kv.db",
+			"/opt/subservices/config/substreams.spkg",
+			pkg.SinkModule,
+			"--server-listen-addr=0.0.0.0:8000",
+			fmt.Sprintf("--metrics-listen-addr=0.0.0.0:%d", sinkMetricsPort),
+		},
+	)
+	sink.Ports = append(sink.Ports, types.ServicePortConfig{
+		Published: kvPort,
+		Target:    8000,
+	})
+
+	out := &Provisioned{ServicesDesc: make(map[string]string)}
+	out.add(sink, fmt.Sprintf("Key-value sink service available at localhost:%d, query it with 'grpcurl -plaintext -d '{\"key\":\"...\"}' localhost:%d sf.substreams.sink.kv.v1.Kv/Get' (metrics at http://localhost:%d/metrics). Use 'substreams alpha service logs %s --service sink' to see the logs.", kvPort, kvPort, sinkMetricsPort, deploymentID))
+	return out, nil
+}
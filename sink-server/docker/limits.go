@@ -0,0 +1,38 @@
+package docker
+
+import (
+	"strconv"
+
+	"github.com/docker/cli/cli/compose/types"
+	"github.com/streamingfast/substreams/sink-server/resources"
+)
+
+// limitServices sets the limits of the size of the deployment on its services, depending on their role. The
+// services are not limited without a size.
+func limitServices(deploymentID string, services []types.ServiceConfig, size *resources.Size) {
+	if size == nil {
+		return
+	}
+	for i := range services {
+		limits := size.Other
+		switch services[i].Name {
+		case sinkServiceName(deploymentID):
+			limits = size.Sink
+		case postgresServiceName(deploymentID), clickhouseServiceName(deploymentID):
+			limits = size.Database
+		}
+		setLimits(&services[i], limits)
+	}
+}
+
+func setLimits(conf *types.ServiceConfig, limits resources.Limits) {
+	if limits.IsZero() {
+		return
+	}
+	conf.Deploy.Resources.Limits = &types.ResourceLimit{
+		MemoryBytes: types.UnitBytes(limits.MemoryBytes),
+	}
+	if limits.CPUs != 0 {
+		conf.Deploy.Resources.Limits.NanoCPUs = strconv.FormatFloat(limits.CPUs, 'f', -1, 64)
+	}
+}
@@ -0,0 +1,50 @@
+package docker
+
+import (
+	"context"
+	"testing"
+
+	pbsql "github.com/streamingfast/substreams-sink-sql/pb/sf/substreams/sink/sql/v1"
+	sinkcontext "github.com/streamingfast/substreams/sink-server/context"
+	"github.com/streamingfast/substreams/sink-server/resources"
+	"github.com/streamingfast/substreams/sink-server/secrets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+func TestCreateManifest_Size(t *testing.T) {
+	store, err := secrets.Open(t.TempDir(), nil)
+	require.NoError(t, err)
+	e := &DockerEngine{dir: t.TempDir(), secrets: store}
+
+	type limits struct {
+		CPUs   string `yaml:"cpus"`
+		Memory string `yaml:"memory"`
+	}
+	config := &struct {
+		Services map[string]struct {
+			Deploy struct {
+				Resources struct {
+					Limits *limits `yaml:"limits"`
+				} `yaml:"resources"`
+			} `yaml:"deploy"`
+		} `yaml:"services"`
+	}{}
+
+	ctx := sinkcontext.SetSize(context.Background(), resources.DefaultSizes["small"])
+	content, _, _, _, err := e.createManifest(ctx, "dep", testPackage(t, &pbsql.Service{Engine: pbsql.Service_postgres}), zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, yaml.Unmarshal(content, config))
+
+	assert.Equal(t, &limits{CPUs: "1", Memory: "1073741824"}, config.Services["dep-postgres"].Deploy.Resources.Limits)
+	assert.Equal(t, &limits{CPUs: "1", Memory: "536870912"}, config.Services["dep-sink"].Deploy.Resources.Limits)
+	assert.Equal(t, &limits{CPUs: "0.5", Memory: "268435456"}, config.Services["dep-pgweb"].Deploy.Resources.Limits)
+
+	// not limited without a size
+	content, _, _, _, err = e.createManifest(context.Background(), "dep", testPackage(t, &pbsql.Service{Engine: pbsql.Service_postgres}), zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, yaml.Unmarshal(content, config))
+	assert.Nil(t, config.Services["dep-sink"].Deploy.Resources.Limits)
+}
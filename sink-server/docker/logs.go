@@ -0,0 +1,81 @@
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Logs streams the output of `docker compose logs` on the deployment.
+func (e *DockerEngine) Logs(ctx context.Context, deploymentID string, service string, follow bool, since time.Time, send func(*pbsinksvc.LogsResponse) error, zlog *zap.Logger) error {
+	args := []string{"compose", "logs", "--no-color", "--timestamps"}
+	if follow {
+		args = append(args, "--follow")
+	}
+	if !since.IsZero() {
+		args = append(args, "--since", since.UTC().Format(time.RFC3339))
+	}
+	if service != "" {
+		args = append(args, service)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Dir = filepath.Join(e.dir, deploymentID)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("running `docker compose logs`: %w", err)
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if err := send(parseComposeLogLine(scanner.Text())); err != nil {
+			cancel()
+			cmd.Wait()
+			return err
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("running `docker compose logs`: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return scanner.Err()
+}
+
+// parseComposeLogLine splits a line of `docker compose logs --timestamps`, formatted as
+// '<container> | <timestamp> <line>', the containers being named after their service.
+func parseComposeLogLine(in string) *pbsinksvc.LogsResponse {
+	out := &pbsinksvc.LogsResponse{Line: in}
+	prefix, rest, found := strings.Cut(in, " | ")
+	if !found {
+		return out
+	}
+	out.Service = strings.TrimSpace(prefix)
+	out.Line = rest
+
+	ts, line, _ := strings.Cut(rest, " ")
+	if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+		out.Timestamp = timestamppb.New(t)
+		out.Line = line
+	}
+	return out
+}
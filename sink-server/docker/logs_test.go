@@ -0,0 +1,56 @@
+package docker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseComposeLogLine(t *testing.T) {
+	tests := []struct {
+		name        string
+		in          string
+		wantService string
+		wantTime    time.Time
+		wantLine    string
+	}{
+		{
+			name:        "timestamped",
+			in:          "abcd1234-sink      | 2024-03-01T10:00:00.123456789Z {\"level\":\"info\",\"msg\":\"starting\"}",
+			wantService: "abcd1234-sink",
+			wantTime:    time.Date(2024, 3, 1, 10, 0, 0, 123456789, time.UTC),
+			wantLine:    "{\"level\":\"info\",\"msg\":\"starting\"}",
+		},
+		{
+			name:        "empty line",
+			in:          "abcd1234-postgres  | 2024-03-01T10:00:00Z",
+			wantService: "abcd1234-postgres",
+			wantTime:    time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name:        "no timestamp",
+			in:          "abcd1234-sink  | Error: connection refused",
+			wantService: "abcd1234-sink",
+			wantLine:    "Error: connection refused",
+		},
+		{
+			name:     "no prefix",
+			in:       "no such service: foo",
+			wantLine: "no such service: foo",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := parseComposeLogLine(test.in)
+			assert.Equal(t, test.wantService, out.Service)
+			assert.Equal(t, test.wantLine, out.Line)
+			if test.wantTime.IsZero() {
+				assert.Nil(t, out.Timestamp)
+			} else {
+				assert.Equal(t, test.wantTime, out.Timestamp.AsTime())
+			}
+		})
+	}
+}
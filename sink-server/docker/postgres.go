@@ -11,7 +11,7 @@ import (
 )
 
 func (e *DockerEngine) newPostgres(deploymentID string, pkg *pbsubstreams.Package) (types.ServiceConfig, string, error) {
-	name := fmt.Sprintf("%s-postgres", deploymentID)
+	name := postgresServiceName(deploymentID)
 	localPort := uint32(5432) // TODO: assign dynamically
 
 	dataFolder := filepath.Join(e.dir, deploymentID, "data", "postgres")
@@ -35,12 +35,15 @@ func (e *DockerEngine) newPostgres(deploymentID string, pkg *pbsubstreams.Packag
 			"-cshared_preload_libraries=pg_stat_statements",
 		},
 		Environment: map[string]*string{
-			"POSTGRES_USER":             deref("dev-node"),
-			"POSTGRES_PASSWORD":         deref("insecure-change-me-in-prod"),
+			"POSTGRES_USER":             deref(dbUser),
+			"POSTGRES_PASSWORD_FILE":    deref("/run/secrets/" + dbPasswordSecret),
 			"POSTGRES_DB":               deref("substreams"),
 			"POSTGRES_INITDB_ARGS":      deref("-E UTF8 --locale=C"),
 			"POSTGRES_HOST_AUTH_METHOD": deref("md5"),
 		},
+		Secrets: []types.ServiceSecretConfig{
+			{Source: dbPasswordSecret},
+		},
 		Volumes: []types.ServiceVolumeConfig{
 			{
 				Type:   "bind",
@@ -49,20 +52,24 @@ func (e *DockerEngine) newPostgres(deploymentID string, pkg *pbsubstreams.Packag
 			},
 		},
 		HealthCheck: &types.HealthCheckConfig{
-			Test:     []string{"CMD", "pg_isready", "-U", "dev-node"},
+			Test:     []string{"CMD", "pg_isready", "-U", dbUser},
 			Interval: toDuration(time.Second * 5),
 			Timeout:  toDuration(time.Second * 4),
 			Retries:  deref(uint64(10)),
 		},
 	}
 
-	motd := fmt.Sprintf("PostgreSQL service %q available at DSN: 'postgres://%s:%s@localhost:%d/%s?sslmode=disable'",
+	motd := fmt.Sprintf("PostgreSQL service %q available at DSN: 'postgres://%s:<password>@localhost:%d/%s?sslmode=disable', the password is in %q",
 		name,
 		*conf.Environment["POSTGRES_USER"],
-		*conf.Environment["POSTGRES_PASSWORD"],
 		localPort,
 		*conf.Environment["POSTGRES_DB"],
+		filepath.Join(e.dir, deploymentID, "secrets", dbPasswordSecret),
 	)
 
 	return conf, motd, nil
 }
+
+func postgresServiceName(deploymentID string) string {
+	return deploymentID + "-postgres"
+}
@@ -0,0 +1,138 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/docker/cli/cli/compose/types"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// Provisioned holds the docker compose services created by a Provisioner for a deployment.
+type Provisioned struct {
+	Services []types.ServiceConfig
+	// ServicesDesc is the message shown to the user for each service, keyed by service name
+	ServicesDesc map[string]string
+	// RunMeFirst are the services that must be healthy before the others are started
+	RunMeFirst []string
+}
+
+func (p *Provisioned) add(svc types.ServiceConfig, motd string) {
+	p.Services = append(p.Services, svc)
+	p.ServicesDesc[svc.Name] = motd
+}
+
+// A Provisioner creates the services running the sink of a given `sink_config` type. The service running
+// the sink itself must be named after `sinkServiceName(deploymentID)`, so that it can be paused.
+type Provisioner interface {
+	Provision(ctx context.Context, e *DockerEngine, deploymentID string, pkg *pbsubstreams.Package, zlog *zap.Logger) (*Provisioned, error)
+}
+
+type ProvisionerFunc func(ctx context.Context, e *DockerEngine, deploymentID string, pkg *pbsubstreams.Package, zlog *zap.Logger) (*Provisioned, error)
+
+func (f ProvisionerFunc) Provision(ctx context.Context, e *DockerEngine, deploymentID string, pkg *pbsubstreams.Package, zlog *zap.Logger) (*Provisioned, error) {
+	return f(ctx, e, deploymentID, pkg, zlog)
+}
+
+var provisioners = map[string]Provisioner{}
+
+// RegisterProvisioner sets the Provisioner used for packages whose `sink_config` is of type `typeURL`
+// (ex: 'sf.substreams.sink.sql.v1.Service'), replacing any previous one.
+func RegisterProvisioner(typeURL string, provisioner Provisioner) {
+	provisioners[typeURL] = provisioner
+}
+
+// SupportedSinkTypes returns the `sink_config` types that can be deployed, sorted.
+func SupportedSinkTypes() []string {
+	out := make([]string, 0, len(provisioners))
+	for typeURL := range provisioners {
+		out = append(out, typeURL)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func getProvisioner(pkg *pbsubstreams.Package) (Provisioner, error) {
+	if pkg.SinkConfig == nil {
+		return nil, fmt.Errorf("package has no sink config, supported types are: %s", strings.Join(SupportedSinkTypes(), ", "))
+	}
+	provisioner, found := provisioners[pkg.SinkConfig.TypeUrl]
+	if !found {
+		return nil, fmt.Errorf("invalid sinkconfig type: %q, supported types are: %s", pkg.SinkConfig.TypeUrl, strings.Join(SupportedSinkTypes(), ", "))
+	}
+	return provisioner, nil
+}
+
+func init() {
+	RegisterProvisioner("sf.substreams.sink.sql.v1.Service", ProvisionerFunc(provisionSQL))
+	RegisterProvisioner("sf.substreams.sink.service.files.v1.Service", ProvisionerFunc(provisionFiles))
+	for _, typeURL := range kvSinkTypes {
+		RegisterProvisioner(typeURL, ProvisionerFunc(provisionKV))
+	}
+}
+
+// writeSinkConfig creates the config and data folders of the sink, and writes the package in its config
+// folder as 'substreams.spkg'.
+func (e *DockerEngine) writeSinkConfig(deploymentID string, pkg *pbsubstreams.Package) (configFolder string, err error) {
+	configFolder = filepath.Join(e.dir, deploymentID, "config", "sink")
+	if err := os.MkdirAll(configFolder, 0755); err != nil {
+		return "", fmt.Errorf("creating folder %q: %w", configFolder, err)
+	}
+
+	dataFolder := filepath.Join(e.dir, deploymentID, "data", "sink")
+	if err := os.MkdirAll(dataFolder, 0755); err != nil {
+		return "", fmt.Errorf("creating folder %q: %w", dataFolder, err)
+	}
+
+	pkgContent, err := proto.Marshal(pkg)
+	if err != nil {
+		return "", fmt.Errorf("marshalling package: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(configFolder, "substreams.spkg"), pkgContent, 0644); err != nil {
+		return "", fmt.Errorf("writing file: %w", err)
+	}
+	return configFolder, nil
+}
+
+// newSinkService returns the service running `image` as the sink of the deployment, with the config and
+// data folders of the sink mounted under '/opt/subservices' and its metrics published. The API token is
+// read from its secret when the sink starts.
+func (e *DockerEngine) newSinkService(deploymentID string, image string, entrypoint []string, command []string) types.ServiceConfig {
+	name := sinkServiceName(deploymentID)
+	conf := types.ServiceConfig{
+		Name:          name,
+		ContainerName: name,
+		Image:         image,
+		Restart:       "on-failure",
+		Command:       command,
+		Volumes: []types.ServiceVolumeConfig{
+			{
+				Type:   "bind",
+				Source: "./data/sink",
+				Target: "/opt/subservices/data",
+			},
+			{
+				Type:   "bind",
+				Source: "./config/sink",
+				Target: "/opt/subservices/config",
+			},
+		},
+		Ports: []types.ServicePortConfig{
+			{
+				Published: sinkMetricsPort,
+				Target:    sinkMetricsPort,
+			},
+		},
+	}
+	withSecretsEnv(&conf, []string{apiTokenSecret}, map[string]string{
+		"SUBSTREAMS_API_TOKEN": secretRef(apiTokenSecret),
+	}, entrypoint...)
+	return conf
+}
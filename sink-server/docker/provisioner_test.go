@@ -0,0 +1,174 @@
+package docker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pbsql "github.com/streamingfast/substreams-sink-sql/pb/sf/substreams/sink/sql/v1"
+	pbsinkfiles "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/files/v1"
+	pbsinkkv "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/kv/v1"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"github.com/streamingfast/substreams/sink-server/secrets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"gopkg.in/yaml.v3"
+)
+
+func testPackage(t *testing.T, sinkConfig proto.Message) *pbsubstreams.Package {
+	t.Helper()
+
+	pkg := &pbsubstreams.Package{
+		Network:    "mainnet",
+		SinkModule: "map_output",
+	}
+	if sinkConfig != nil {
+		cfg, err := anypb.New(sinkConfig)
+		require.NoError(t, err)
+		// the manifest reader sets the type url without its prefix
+		cfg.TypeUrl = string(sinkConfig.ProtoReflect().Descriptor().FullName())
+		pkg.SinkConfig = cfg
+	}
+	return pkg
+}
+
+func TestCreateManifest(t *testing.T) {
+	tests := []struct {
+		name             string
+		sinkConfig       proto.Message
+		sinkTypeURL      string // overrides the type of `sinkConfig`
+		expectedServices []string
+		expectedCommand  []string
+		expectedPorts    []uint32
+		expectedError    string
+	}{
+		{
+			name:             "sql",
+			sinkConfig:       &pbsql.Service{Engine: pbsql.Service_postgres},
+			expectedServices: []string{"dep-postgres", "dep-sink", "dep-pgweb", "dep-sinkinfo"},
+			expectedPorts:    []uint32{5432, sinkMetricsPort, 8081, 8282},
+		},
+		{
+			name:             "sql clickhouse with rest frontend",
+			sinkConfig:       &pbsql.Service{Engine: pbsql.Service_clickhouse, RestFrontend: &pbsql.RESTFrontend{Enabled: true}},
+			expectedServices: []string{"dep-clickhouse", "dep-sink", "dep-pgweb", "dep-rest", "dep-sinkinfo"},
+			expectedPorts:    []uint32{5432, 9000, sinkMetricsPort, 8081, 3000, 8282},
+		},
+		{
+			name:             "files",
+			sinkConfig:       &pbsinkfiles.Service{Encoder: "proto:.items[]", FileBlockCount: 1000},
+			expectedServices: []string{"dep-sink"},
+			expectedCommand: []string{
+				"run", "mainnet.example.com:443", "/opt/subservices/config/substreams.spkg", "map_output", "/opt/subservices/output",
+				"--encoder=proto:.items[]", "--file-block-count=1000", "--file-working-dir=/opt/subservices/data/working",
+				"--state-store=/opt/subservices/data/state.yaml", "--metrics-listen-addr=0.0.0.0:9102",
+			},
+			expectedPorts: []uint32{sinkMetricsPort},
+		},
+		{
+			name:             "files defaults",
+			sinkConfig:       &pbsinkfiles.Service{},
+			expectedServices: []string{"dep-sink"},
+			expectedCommand: []string{
+				"run", "mainnet.example.com:443", "/opt/subservices/config/substreams.spkg", "map_output", "/opt/subservices/output",
+				"--encoder=lines", "--file-block-count=10000", "--file-working-dir=/opt/subservices/data/working",
+				"--state-store=/opt/subservices/data/state.yaml", "--metrics-listen-addr=0.0.0.0:9102",
+			},
+			expectedPorts: []uint32{sinkMetricsPort},
+		},
+		{
+			name:          "files invalid encoder",
+			sinkConfig:    &pbsinkfiles.Service{Encoder: "csv"},
+			expectedError: `invalid encoder "csv"`,
+		},
+		{
+			name:             "kv",
+			sinkConfig:       &pbsinkkv.GenericService{},
+			expectedServices: []string{"dep-sink"},
+			expectedCommand: []string{
+				"inject", "mainnet.example.com:443", "badger3:///opt/subservices/data/kv.db", "/opt/subservices/config/substreams.spkg", "map_output",
+				"--server-listen-addr=0.0.0.0:8000", "--metrics-listen-addr=0.0.0.0:9102",
+			},
+			expectedPorts: []uint32{sinkMetricsPort, 8181},
+		},
+		{
+			name:             "kv upstream type",
+			sinkConfig:       &pbsinkkv.GenericService{},
+			sinkTypeURL:      "sf.substreams.sink.kv.v1.GenericService",
+			expectedServices: []string{"dep-sink"},
+			expectedCommand: []string{
+				"inject", "mainnet.example.com:443", "badger3:///opt/subservices/data/kv.db", "/opt/subservices/config/substreams.spkg", "map_output",
+				"--server-listen-addr=0.0.0.0:8000", "--metrics-listen-addr=0.0.0.0:9102",
+			},
+			expectedPorts: []uint32{sinkMetricsPort, 8181},
+		},
+		{
+			name:          "unsupported",
+			sinkConfig:    &pbsubstreams.Clock{},
+			expectedError: `invalid sinkconfig type: "sf.substreams.v1.Clock", supported types are: sf.substreams.sink.kv.v1.GenericService, sf.substreams.sink.service.files.v1.Service, sf.substreams.sink.service.kv.v1.GenericService, sf.substreams.sink.sql.v1.Service`,
+		},
+		{
+			name:          "no sink config",
+			expectedError: "package has no sink config",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store, err := secrets.Open(t.TempDir(), nil)
+			require.NoError(t, err)
+			e := &DockerEngine{dir: t.TempDir(), endpoint: "mainnet.example.com:443", token: "secret-token", secrets: store}
+
+			pkg := testPackage(t, test.sinkConfig)
+			if test.sinkTypeURL != "" {
+				pkg.SinkConfig.TypeUrl = test.sinkTypeURL
+			}
+
+			content, usedPorts, servicesDesc, _, err := e.createManifest(context.Background(), "dep", pkg, zap.NewNop())
+			if test.expectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.ElementsMatch(t, test.expectedPorts, usedPorts)
+
+			creds, err := store.Get("dep")
+			require.NoError(t, err)
+			assert.NotContains(t, string(content), creds[dbPasswordSecret])
+			assert.NotContains(t, string(content), "secret-token")
+			for _, desc := range servicesDesc {
+				assert.NotContains(t, desc, creds[dbPasswordSecret])
+			}
+			token, err := os.ReadFile(filepath.Join(e.dir, "dep", "secrets", apiTokenSecret))
+			require.NoError(t, err)
+			assert.Equal(t, "secret-token", string(token))
+
+			config := &struct {
+				Services map[string]struct {
+					Command []string `yaml:"command"`
+					EnvFile []string `yaml:"env_file"`
+				} `yaml:"services"`
+			}{}
+			require.NoError(t, yaml.Unmarshal(content, config))
+
+			var names []string
+			for name, svc := range config.Services {
+				names = append(names, name)
+				assert.Contains(t, servicesDesc, name)
+				assert.Empty(t, svc.EnvFile, "secrets are mounted as files in %s", name)
+				if name == "dep-sink" && test.expectedCommand != nil {
+					assert.Equal(t, test.expectedCommand, svc.Command)
+				}
+			}
+			assert.ElementsMatch(t, test.expectedServices, names)
+
+			_, err = os.Stat(filepath.Join(e.dir, "dep", "config", "sink", "substreams.spkg"))
+			assert.NoError(t, err)
+		})
+	}
+}
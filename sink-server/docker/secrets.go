@@ -0,0 +1,143 @@
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/docker/cli/cli/compose/types"
+	"github.com/streamingfast/substreams/sink-server/secrets"
+	"go.uber.org/zap"
+)
+
+const (
+	// dbUser is the user of the database of the deployments, its password is generated for each deployment
+	dbUser = "dev-node"
+
+	dbPasswordSecret = "db_password"
+	apiTokenSecret   = "substreams_api_token"
+)
+
+// credentials returns the secrets of the deployment, generating them the first time.
+func (e *DockerEngine) credentials(deploymentID string) (map[string]string, error) {
+	creds, err := e.secrets.Get(deploymentID)
+	if err == nil {
+		return creds, nil
+	}
+	if !errors.Is(err, secrets.ErrNotFound) {
+		return nil, err
+	}
+	return e.generateCredentials(deploymentID)
+}
+
+// generateCredentials replaces the secrets of the deployment with new ones.
+func (e *DockerEngine) generateCredentials(deploymentID string) (map[string]string, error) {
+	password, err := secrets.Generate()
+	if err != nil {
+		return nil, err
+	}
+	creds := map[string]string{dbPasswordSecret: password}
+	if err := e.secrets.Put(deploymentID, creds); err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
+
+// rotateCredentials replaces the secrets of a deployment being updated without reset, its services are then
+// re-created with the new ones. The clickhouse user is created again from its password when the service starts,
+// but the password of a postgres database is kept in its data: it is changed in the running database first, and
+// the credentials are left unchanged if it cannot be.
+func (e *DockerEngine) rotateCredentials(deploymentID string, zlog *zap.Logger) error {
+	info, err := e.readDeploymentInfo(deploymentID)
+	if err != nil {
+		return err
+	}
+	if _, found := info.ServiceInfo[postgresServiceName(deploymentID)]; !found {
+		_, err := e.generateCredentials(deploymentID)
+		return err
+	}
+
+	password, err := secrets.Generate()
+	if err != nil {
+		return err
+	}
+
+	if err := e.psql(deploymentID, fmt.Sprintf(`ALTER USER "%s" WITH PASSWORD '%s';`, dbUser, password)); err != nil {
+		zlog.Warn("cannot change the database password, keeping the current credentials", zap.String("deployment_id", deploymentID), zap.Error(err))
+		return nil
+	}
+
+	return e.secrets.Put(deploymentID, map[string]string{dbPasswordSecret: password})
+}
+
+// writeSecretFiles writes the secrets of the deployment and the API token in its 'secrets' folder, from
+// where they are mounted in the services under '/run/secrets'.
+func (e *DockerEngine) writeSecretFiles(deploymentID string) error {
+	creds, err := e.credentials(deploymentID)
+	if err != nil {
+		return err
+	}
+
+	folder := filepath.Join(e.dir, deploymentID, "secrets")
+	if err := os.MkdirAll(folder, 0700); err != nil {
+		return fmt.Errorf("creating folder %q: %w", folder, err)
+	}
+
+	files := map[string]string{apiTokenSecret: e.token}
+	for name, value := range creds {
+		files[name] = value
+	}
+	for name, value := range files {
+		// readable by the user of the container, the folder itself is private
+		if err := os.WriteFile(filepath.Join(folder, name), []byte(value), 0644); err != nil {
+			return fmt.Errorf("writing secret %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// secretsConfig declares the secrets used by the services of the manifest, read from `folder`.
+func secretsConfig(services []types.ServiceConfig, folder string) map[string]types.SecretConfig {
+	out := make(map[string]types.SecretConfig)
+	for _, svc := range services {
+		for _, secret := range svc.Secrets {
+			out[secret.Source] = types.SecretConfig{File: folder + "/" + secret.Source}
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// secretRef is the shell expansion of the content of a secret mounted in a service.
+func secretRef(name string) string {
+	return fmt.Sprintf("$(cat /run/secrets/%s)", name)
+}
+
+// withSecretsEnv mounts the secrets in the service, and runs its `entrypoint` through a shell exporting
+// each variable of `env`, whose values can use `secretRef`. The secrets never appear in the manifest
+// nor in the configuration of the container. With no `entrypoint`, the service command is run instead.
+func withSecretsEnv(conf *types.ServiceConfig, secretNames []string, env map[string]string, entrypoint ...string) {
+	for _, name := range secretNames {
+		conf.Secrets = append(conf.Secrets, types.ServiceSecretConfig{Source: name})
+	}
+
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var script strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&script, "export %s=\"%s\" && ", k, env[k])
+	}
+	script.WriteString(`exec "$0" "$@"`)
+
+	// compose interpolates the variables of the manifest, the shell must see them unchanged
+	conf.Entrypoint = append([]string{"/bin/sh", "-c", strings.ReplaceAll(script.String(), "$", "$$")}, entrypoint...)
+}
@@ -22,11 +22,15 @@ func (e *DockerEngine) newSinkInfo(deploymentID string, dbService string, dbType
 			},
 		},
 		Command: []string{
-			fmt.Sprintf("%s://dev-node:insecure-change-me-in-prod@postgres:5432/substreams?sslmode=disable", dbType),
+			fmt.Sprintf("%s://%s@postgres:5432/substreams?sslmode=disable", dbType, dbUser),
 		},
 		Links:     []string{dbService + ":" + dbType},
 		DependsOn: []string{dbService},
 	}
+	// the DSN has no password, it is read from the environment by the database driver
+	withSecretsEnv(&conf, []string{dbPasswordSecret}, map[string]string{
+		"PGPASSWORD": secretRef(dbPasswordSecret),
+	}, "/app/sqlsinkinfo")
 
 	motd = fmt.Sprintf("Sink info service %q available at URL: 'http://localhost:%d/sinkinfo'",
 		name,
@@ -34,5 +38,4 @@ func (e *DockerEngine) newSinkInfo(deploymentID string, dbService string, dbType
 	)
 
 	return conf, motd
-
 }
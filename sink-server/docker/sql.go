@@ -0,0 +1,101 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	pbsql "github.com/streamingfast/substreams-sink-sql/pb/sf/substreams/sink/sql/v1"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	sinkcontext "github.com/streamingfast/substreams/sink-server/context"
+	"go.uber.org/zap"
+)
+
+// provisionSQL runs substreams-sink-sql against a postgres or clickhouse database, with its optional frontends.
+func provisionSQL(ctx context.Context, e *DockerEngine, deploymentID string, pkg *pbsubstreams.Package, _ *zap.Logger) (*Provisioned, error) {
+	sinkConfig := &pbsql.Service{}
+	if err := pkg.SinkConfig.UnmarshalTo(sinkConfig); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal sinkconfig: %w", err)
+	}
+
+	out := &Provisioned{ServicesDesc: make(map[string]string)}
+
+	var dbServiceName string
+	var isPostgres, isClickhouse bool
+
+	switch sinkConfig.Engine {
+	case pbsql.Service_clickhouse:
+		db, dbMotd, err := e.newClickhouse(deploymentID, pkg)
+		if err != nil {
+			return nil, fmt.Errorf("creating clickhouse deployment: %w", err)
+		}
+		dbServiceName = db.Name
+		out.add(db, dbMotd)
+		out.RunMeFirst = append(out.RunMeFirst, db.Name)
+		isClickhouse = true
+
+	case pbsql.Service_postgres, pbsql.Service_unset:
+		pg, pgMotd, err := e.newPostgres(deploymentID, pkg)
+		if err != nil {
+			return nil, fmt.Errorf("creating postgres deployment: %w", err)
+		}
+		dbServiceName = pg.Name
+		out.add(pg, pgMotd)
+		out.RunMeFirst = append(out.RunMeFirst, pg.Name)
+		isPostgres = true
+	}
+
+	sink, sinkMotd, err := e.newSink(deploymentID, dbServiceName, pkg, sinkConfig)
+	if err != nil {
+		return nil, fmt.Errorf("creating postgres deployment: %w", err)
+	}
+	out.add(sink, sinkMotd)
+
+	isProduction := sinkcontext.GetProductionMode(ctx)
+	env := sinkcontext.GetParameterMap(ctx)
+
+	if isTruthy(env["SF_PGWEB"]) || !isProduction {
+		pgweb, motd := e.newPGWeb(deploymentID, dbServiceName)
+		out.add(pgweb, motd)
+	}
+
+	if sinkConfig.PostgraphileFrontend != nil && sinkConfig.PostgraphileFrontend.Enabled {
+		postgraphile, motd := e.newPostgraphile(deploymentID, dbServiceName, isProduction)
+		out.add(postgraphile, motd)
+	}
+
+	//todo: handle development mode for DBT stuff
+	var engine string
+	if isPostgres {
+		engine = "postgres"
+	} else if isClickhouse {
+		engine = "clickhouse"
+	}
+	if sinkConfig.DbtConfig != nil && sinkConfig.DbtConfig.Enabled {
+		if engine == "" {
+			return nil, fmt.Errorf("cannot create dbt deployment: no valid engine specified")
+		}
+
+		dbt, motd, err := e.newDBT(deploymentID, dbServiceName, sinkConfig.DbtConfig, engine, isProduction)
+		if err != nil {
+			return nil, fmt.Errorf("creating dbt deployment: %w", err)
+		}
+
+		if dbt != nil {
+			out.add(*dbt, motd)
+		} else {
+			out.ServicesDesc["dbt"] = motd
+		}
+	}
+
+	if sinkConfig.RestFrontend != nil && sinkConfig.RestFrontend.Enabled {
+		rest, motd := e.newRestFrontend(deploymentID, dbServiceName)
+		out.add(rest, motd)
+	}
+
+	if !isProduction { //dev only for now
+		sinkinfo, motd := e.newSinkInfo(deploymentID, dbServiceName, engine)
+		out.add(sinkinfo, motd)
+	}
+
+	return out, nil
+}
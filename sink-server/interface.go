@@ -2,10 +2,13 @@ package server
 
 import (
 	"context"
+	"time"
 
 	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
 	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
 	docker "github.com/streamingfast/substreams/sink-server/docker"
+	"github.com/streamingfast/substreams/sink-server/kubernetes"
+	"github.com/streamingfast/substreams/sink-server/process"
 
 	"go.uber.org/zap"
 )
@@ -13,6 +16,11 @@ import (
 type Engine interface {
 	Create(ctx context.Context, deploymentID string, pkg *pbsubstreams.Package, zlog *zap.Logger) (*pbsinksvc.InfoResponse, error)
 	Update(ctx context.Context, deploymentID string, pkg *pbsubstreams.Package, reset bool, zlog *zap.Logger) error
+	// UpdateBlueGreen deploys the package next to the current one, switching over to it once it has caught up.
+	// Engines that cannot do so return an error wrapping errors.ErrUnsupported.
+	UpdateBlueGreen(ctx context.Context, deploymentID string, pkg *pbsubstreams.Package, zlog *zap.Logger) error
+	// Rollback cancels the pending blue/green update, or switches back to the package replaced by the last one.
+	Rollback(ctx context.Context, deploymentID string, zlog *zap.Logger) (*pbsinksvc.RollbackResponse, error)
 
 	Resume(ctx context.Context, deploymentID string, currentState pbsinksvc.DeploymentStatus, zlog *zap.Logger) (string, error)
 	Pause(ctx context.Context, deploymentID string, zlog *zap.Logger) (string, error)
@@ -22,8 +30,14 @@ type Engine interface {
 
 	Info(ctx context.Context, deploymentID string, zlog *zap.Logger) (*pbsinksvc.InfoResponse, error)
 	List(ctx context.Context, zlog *zap.Logger) ([]*pbsinksvc.DeploymentWithStatus, error)
+	// Logs sends the lines logged by `service`, or by all the services of the deployment when empty, skipping
+	// those logged before `since` when it is set. With `follow`, it keeps sending the new lines until the
+	// context is done. `send` may be called concurrently.
+	Logs(ctx context.Context, deploymentID string, service string, follow bool, since time.Time, send func(*pbsinksvc.LogsResponse) error, zlog *zap.Logger) error
 
 	Shutdown(ctx context.Context, err error, zlog *zap.Logger) error
 }
 
 var _ Engine = &docker.DockerEngine{}
+var _ Engine = &process.ProcessEngine{}
+var _ Engine = &kubernetes.KubernetesEngine{}
@@ -0,0 +1,317 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func (e *KubernetesEngine) getSink(ctx context.Context, deploymentID string) (*appsv1.Deployment, error) {
+	sink, err := e.client.AppsV1().Deployments(e.config.Namespace).Get(ctx, sinkName(deploymentID), metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("deployment %q not found", deploymentID)
+		}
+		return nil, fmt.Errorf("getting sink deployment: %w", err)
+	}
+	return sink, nil
+}
+
+func (e *KubernetesEngine) applySecret(ctx context.Context, obj *corev1.Secret) error {
+	client := e.client.CoreV1().Secrets(e.config.Namespace)
+	existing, err := client.Get(ctx, obj.Name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		_, err = client.Create(ctx, obj, metav1.CreateOptions{})
+	case err == nil:
+		obj.ResourceVersion = existing.ResourceVersion
+		_, err = client.Update(ctx, obj, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("applying secret %q: %w", obj.Name, err)
+	}
+	return nil
+}
+
+func (e *KubernetesEngine) applyService(ctx context.Context, obj *corev1.Service) error {
+	client := e.client.CoreV1().Services(e.config.Namespace)
+	existing, err := client.Get(ctx, obj.Name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		_, err = client.Create(ctx, obj, metav1.CreateOptions{})
+	case err == nil:
+		obj.ResourceVersion = existing.ResourceVersion
+		// allocated by the cluster, immutable
+		obj.Spec.ClusterIP = existing.Spec.ClusterIP
+		obj.Spec.ClusterIPs = existing.Spec.ClusterIPs
+		_, err = client.Update(ctx, obj, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("applying service %q: %w", obj.Name, err)
+	}
+	return nil
+}
+
+func (e *KubernetesEngine) applyStatefulSet(ctx context.Context, obj *appsv1.StatefulSet) error {
+	client := e.client.AppsV1().StatefulSets(e.config.Namespace)
+	existing, err := client.Get(ctx, obj.Name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		_, err = client.Create(ctx, obj, metav1.CreateOptions{})
+	case err == nil:
+		obj.ResourceVersion = existing.ResourceVersion
+		// the volume claims of a StatefulSet cannot be updated
+		obj.Spec.VolumeClaimTemplates = existing.Spec.VolumeClaimTemplates
+		// keep a stopped deployment as is
+		obj.Spec.Replicas = existing.Spec.Replicas
+		_, err = client.Update(ctx, obj, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("applying statefulset %q: %w", obj.Name, err)
+	}
+	return nil
+}
+
+func (e *KubernetesEngine) applyDeployment(ctx context.Context, obj *appsv1.Deployment) error {
+	client := e.client.AppsV1().Deployments(e.config.Namespace)
+	existing, err := client.Get(ctx, obj.Name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		_, err = client.Create(ctx, obj, metav1.CreateOptions{})
+	case err == nil:
+		obj.ResourceVersion = existing.ResourceVersion
+		// keep a paused or stopped deployment as is
+		obj.Spec.Replicas = existing.Spec.Replicas
+		_, err = client.Update(ctx, obj, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("applying deployment %q: %w", obj.Name, err)
+	}
+	return nil
+}
+
+// deleteStaleFrontends deletes the frontends of the deployment that are not part of `frontends` anymore.
+func (e *KubernetesEngine) deleteStaleFrontends(ctx context.Context, deploymentID string, frontends []*frontend) error {
+	deployments, err := e.client.AppsV1().Deployments(e.config.Namespace).List(ctx, metav1.ListOptions{LabelSelector: deploymentSelector(deploymentID)})
+	if err != nil {
+		return fmt.Errorf("listing deployments: %w", err)
+	}
+
+	for _, deployment := range deployments.Items {
+		component := deployment.Labels[labelComponent]
+		if component == componentSink || slices.ContainsFunc(frontends, func(fe *frontend) bool { return fe.name == deployment.Name }) {
+			continue
+		}
+		if err := e.client.AppsV1().Deployments(e.config.Namespace).Delete(ctx, deployment.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("deleting deployment %q: %w", deployment.Name, err)
+		}
+		if err := e.client.CoreV1().Services(e.config.Namespace).Delete(ctx, deployment.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("deleting service %q: %w", deployment.Name, err)
+		}
+	}
+	return nil
+}
+
+var (
+	// deleteTimeout is how long waitDeleted waits for the garbage collector to remove the objects
+	deleteTimeout      = 5 * time.Minute
+	deletePollInterval = 1 * time.Second
+)
+
+// deletedObject is an object whose deletion was requested, `get` reading it until it is gone.
+type deletedObject struct {
+	kind string
+	name string
+	get  func(ctx context.Context) error
+}
+
+// deleteObjects deletes the workloads and the database volumes of the deployment, and its services and
+// secrets too when `all` is set. The objects are deleted in the foreground: they are still returned by the
+// API server until their dependents are gone, see waitDeleted.
+func (e *KubernetesEngine) deleteObjects(ctx context.Context, deploymentID string, all bool) (deleted []deletedObject, err error) {
+	ns := e.config.Namespace
+	listOptions := metav1.ListOptions{LabelSelector: deploymentSelector(deploymentID)}
+	deleteOptions := metav1.DeleteOptions{PropagationPolicy: ptr(metav1.DeletePropagationForeground)}
+	ignoreNotFound := func(err error) error {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	deployments, err := e.client.AppsV1().Deployments(ns).List(ctx, listOptions)
+	if err != nil {
+		return nil, fmt.Errorf("listing deployments: %w", err)
+	}
+	for _, obj := range deployments.Items {
+		if err := ignoreNotFound(e.client.AppsV1().Deployments(ns).Delete(ctx, obj.Name, deleteOptions)); err != nil {
+			return nil, fmt.Errorf("deleting deployment %q: %w", obj.Name, err)
+		}
+		name := obj.Name
+		deleted = append(deleted, deletedObject{kind: "deployment", name: name, get: func(ctx context.Context) error {
+			_, err := e.client.AppsV1().Deployments(ns).Get(ctx, name, metav1.GetOptions{})
+			return err
+		}})
+	}
+
+	statefulSets, err := e.client.AppsV1().StatefulSets(ns).List(ctx, listOptions)
+	if err != nil {
+		return nil, fmt.Errorf("listing statefulsets: %w", err)
+	}
+	for _, obj := range statefulSets.Items {
+		if err := ignoreNotFound(e.client.AppsV1().StatefulSets(ns).Delete(ctx, obj.Name, deleteOptions)); err != nil {
+			return nil, fmt.Errorf("deleting statefulset %q: %w", obj.Name, err)
+		}
+		name := obj.Name
+		deleted = append(deleted, deletedObject{kind: "statefulset", name: name, get: func(ctx context.Context) error {
+			_, err := e.client.AppsV1().StatefulSets(ns).Get(ctx, name, metav1.GetOptions{})
+			return err
+		}})
+	}
+
+	// the volume claims of a StatefulSet are not deleted with it
+	claims, err := e.client.CoreV1().PersistentVolumeClaims(ns).List(ctx, listOptions)
+	if err != nil {
+		return nil, fmt.Errorf("listing persistent volume claims: %w", err)
+	}
+	for _, obj := range claims.Items {
+		if err := ignoreNotFound(e.client.CoreV1().PersistentVolumeClaims(ns).Delete(ctx, obj.Name, deleteOptions)); err != nil {
+			return nil, fmt.Errorf("deleting persistent volume claim %q: %w", obj.Name, err)
+		}
+		name := obj.Name
+		deleted = append(deleted, deletedObject{kind: "persistent volume claim", name: name, get: func(ctx context.Context) error {
+			_, err := e.client.CoreV1().PersistentVolumeClaims(ns).Get(ctx, name, metav1.GetOptions{})
+			return err
+		}})
+	}
+
+	if !all {
+		return deleted, nil
+	}
+
+	services, err := e.client.CoreV1().Services(ns).List(ctx, listOptions)
+	if err != nil {
+		return nil, fmt.Errorf("listing services: %w", err)
+	}
+	for _, obj := range services.Items {
+		if err := ignoreNotFound(e.client.CoreV1().Services(ns).Delete(ctx, obj.Name, deleteOptions)); err != nil {
+			return nil, fmt.Errorf("deleting service %q: %w", obj.Name, err)
+		}
+		name := obj.Name
+		deleted = append(deleted, deletedObject{kind: "service", name: name, get: func(ctx context.Context) error {
+			_, err := e.client.CoreV1().Services(ns).Get(ctx, name, metav1.GetOptions{})
+			return err
+		}})
+	}
+
+	secrets, err := e.client.CoreV1().Secrets(ns).List(ctx, listOptions)
+	if err != nil {
+		return nil, fmt.Errorf("listing secrets: %w", err)
+	}
+	for _, obj := range secrets.Items {
+		if err := ignoreNotFound(e.client.CoreV1().Secrets(ns).Delete(ctx, obj.Name, deleteOptions)); err != nil {
+			return nil, fmt.Errorf("deleting secret %q: %w", obj.Name, err)
+		}
+		name := obj.Name
+		deleted = append(deleted, deletedObject{kind: "secret", name: name, get: func(ctx context.Context) error {
+			_, err := e.client.CoreV1().Secrets(ns).Get(ctx, name, metav1.GetOptions{})
+			return err
+		}})
+	}
+	return deleted, nil
+}
+
+// waitDeleted waits until each object is not found anymore, so that objects with the same names can be
+// created again instead of updating the ones being garbage collected.
+func (e *KubernetesEngine) waitDeleted(ctx context.Context, objects []deletedObject) error {
+	ctx, cancel := context.WithTimeout(ctx, deleteTimeout)
+	defer cancel()
+
+	for _, obj := range objects {
+		for {
+			err := obj.get(ctx)
+			if apierrors.IsNotFound(err) {
+				break
+			}
+			if err != nil && ctx.Err() == nil {
+				return fmt.Errorf("getting %s %q: %w", obj.kind, obj.name, err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("%s %q still being deleted after %s: %w", obj.kind, obj.name, deleteTimeout, ctx.Err())
+			case <-time.After(deletePollInterval):
+			}
+		}
+	}
+	return nil
+}
+
+// scale sets the replicas of all the workloads of the deployment, its database being scaled first when
+// `databaseFirst` is set.
+func (e *KubernetesEngine) scale(ctx context.Context, deploymentID string, replicas int32, databaseFirst bool) error {
+	if _, err := e.getSink(ctx, deploymentID); err != nil {
+		return err
+	}
+	listOptions := metav1.ListOptions{LabelSelector: deploymentSelector(deploymentID)}
+
+	statefulSets, err := e.client.AppsV1().StatefulSets(e.config.Namespace).List(ctx, listOptions)
+	if err != nil {
+		return fmt.Errorf("listing statefulsets: %w", err)
+	}
+	scaleDatabase := func() error {
+		for i := range statefulSets.Items {
+			sts := &statefulSets.Items[i]
+			sts.Spec.Replicas = ptr(replicas)
+			if _, err := e.client.AppsV1().StatefulSets(e.config.Namespace).Update(ctx, sts, metav1.UpdateOptions{}); err != nil {
+				return fmt.Errorf("scaling statefulset %q: %w", sts.Name, err)
+			}
+		}
+		return nil
+	}
+
+	if databaseFirst {
+		if err := scaleDatabase(); err != nil {
+			return err
+		}
+	}
+
+	deployments, err := e.client.AppsV1().Deployments(e.config.Namespace).List(ctx, listOptions)
+	if err != nil {
+		return fmt.Errorf("listing deployments: %w", err)
+	}
+	for i := range deployments.Items {
+		if err := e.scaleDeployment(ctx, &deployments.Items[i], replicas); err != nil {
+			return err
+		}
+	}
+
+	if !databaseFirst {
+		return scaleDatabase()
+	}
+	return nil
+}
+
+func (e *KubernetesEngine) scaleDeployment(ctx context.Context, deployment *appsv1.Deployment, replicas int32) error {
+	deployment.Spec.Replicas = ptr(replicas)
+	if _, err := e.client.AppsV1().Deployments(e.config.Namespace).Update(ctx, deployment, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("scaling deployment %q: %w", deployment.Name, err)
+	}
+	return nil
+}
+
+func isTruthy(str string) bool {
+	switch strings.ToLower(strings.TrimSpace(str)) {
+	case "true", "1", "yes", "on", "y", "enabled":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,435 @@
+package kubernetes
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	pbsql "github.com/streamingfast/substreams-sink-sql/pb/sf/substreams/sink/sql/v1"
+	"github.com/streamingfast/substreams/manifest"
+	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	sinkcontext "github.com/streamingfast/substreams/sink-server/context"
+	"github.com/streamingfast/substreams/sink-server/progress"
+	"github.com/streamingfast/substreams/sink-server/resources"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	labelManagedBy    = "app.kubernetes.io/managed-by"
+	labelComponent    = "app.kubernetes.io/component"
+	labelDeploymentID = "substreams.streamingfast.io/deployment-id"
+
+	annotationPackageInfo = "substreams.streamingfast.io/package-info"
+	annotationServices    = "substreams.streamingfast.io/services"
+	annotationPackageHash = "substreams.streamingfast.io/package-hash"
+
+	managedBy = "substreams-sink-server"
+
+	componentSink     = "sink"
+	componentDatabase = "database"
+)
+
+type Config struct {
+	Namespace string
+	// StorageSize is the size of the volume of the database of each deployment, 10Gi by default
+	StorageSize string
+}
+
+// KubernetesEngine runs each deployment as Kubernetes objects in a namespace: a StatefulSet and a Service
+// for the database, a Deployment for the sink, and a Deployment and a Service for each frontend. Pausing a
+// deployment scales its sink to 0, stopping it scales everything to 0, keeping the database volume.
+type KubernetesEngine struct {
+	mutex    sync.Mutex
+	client   clientset.Interface
+	config   Config
+	endpoint string
+	token    string
+	progress *progress.Tracker
+}
+
+// NewEngine connects to the cluster with the kubeconfig file at `configPath`, or with the in-cluster
+// configuration when empty.
+func NewEngine(configPath string, config Config, sfToken string, endpoint string) (*KubernetesEngine, error) {
+	var restConfig *rest.Config
+	var err error
+	if configPath != "" {
+		restConfig, err = clientcmd.BuildConfigFromFlags("", configPath)
+	} else {
+		restConfig, err = rest.InClusterConfig()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading kubernetes config: %w", err)
+	}
+
+	client, err := clientset.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("creating kubernetes client: %w", err)
+	}
+	return NewEngineFromClient(client, config, sfToken, endpoint), nil
+}
+
+func NewEngineFromClient(client clientset.Interface, config Config, sfToken string, endpoint string) *KubernetesEngine {
+	if config.StorageSize == "" {
+		config.StorageSize = "10Gi"
+	}
+	return &KubernetesEngine{
+		client:   client,
+		config:   config,
+		token:    sfToken,
+		endpoint: endpoint,
+		progress: progress.NewTracker(),
+	}
+}
+
+func deploymentLabels(deploymentID, component string) map[string]string {
+	return map[string]string{
+		labelManagedBy:    managedBy,
+		labelDeploymentID: deploymentID,
+		labelComponent:    component,
+	}
+}
+
+func deploymentSelector(deploymentID string) string {
+	return labels.SelectorFromSet(map[string]string{labelManagedBy: managedBy, labelDeploymentID: deploymentID}).String()
+}
+
+func (e *KubernetesEngine) objectMeta(deploymentID, name, component string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      name,
+		Namespace: e.config.Namespace,
+		Labels:    deploymentLabels(deploymentID, component),
+	}
+}
+
+func (e *KubernetesEngine) Create(ctx context.Context, deploymentID string, pkg *pbsubstreams.Package, zlog *zap.Logger) (*pbsinksvc.InfoResponse, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if _, err := e.client.AppsV1().Deployments(e.config.Namespace).Get(ctx, sinkName(deploymentID), metav1.GetOptions{}); err == nil {
+		return nil, fmt.Errorf("deployment %q already exists", deploymentID)
+	}
+
+	if err := e.apply(ctx, deploymentID, pkg); err != nil {
+		return nil, fmt.Errorf("applying kubernetes objects: %w", err)
+	}
+	return e.info(ctx, deploymentID, zlog)
+}
+
+func (e *KubernetesEngine) Update(ctx context.Context, deploymentID string, pkg *pbsubstreams.Package, reset bool, zlog *zap.Logger) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if _, err := e.getSink(ctx, deploymentID); err != nil {
+		return err
+	}
+
+	if reset {
+		// the package must fit in its secret before the previous objects are deleted
+		if _, _, err := e.packageSecret(deploymentID, pkg); err != nil {
+			return err
+		}
+		e.progress.Forget(deploymentID)
+		// the database and its volume are re-created from scratch
+		deleted, err := e.deleteObjects(ctx, deploymentID, false)
+		if err != nil {
+			return fmt.Errorf("resetting deployment: %w", err)
+		}
+		if err := e.waitDeleted(ctx, deleted); err != nil {
+			return fmt.Errorf("resetting deployment: %w", err)
+		}
+	}
+
+	if err := e.apply(ctx, deploymentID, pkg); err != nil {
+		return fmt.Errorf("applying kubernetes objects: %w", err)
+	}
+	return nil
+}
+
+func (e *KubernetesEngine) UpdateBlueGreen(ctx context.Context, deploymentID string, pkg *pbsubstreams.Package, zlog *zap.Logger) error {
+	return fmt.Errorf("blue/green updates by the kubernetes engine: %w", errors.ErrUnsupported)
+}
+
+func (e *KubernetesEngine) Rollback(ctx context.Context, deploymentID string, zlog *zap.Logger) (*pbsinksvc.RollbackResponse, error) {
+	return nil, fmt.Errorf("rollbacks by the kubernetes engine: %w", errors.ErrUnsupported)
+}
+
+// apply creates or updates all the objects of the deployment for `pkg`.
+func (e *KubernetesEngine) apply(ctx context.Context, deploymentID string, pkg *pbsubstreams.Package) error {
+	if pkg.SinkConfig.GetTypeUrl() != "sf.substreams.sink.sql.v1.Service" {
+		return fmt.Errorf("invalid sinkconfig type: %q. Only sf.substreams.sink.sql.v1.Service is supported for now.", pkg.SinkConfig.GetTypeUrl())
+	}
+	sinkConfig := &pbsql.Service{}
+	if err := pkg.SinkConfig.UnmarshalTo(sinkConfig); err != nil {
+		return fmt.Errorf("cannot unmarshal sinkconfig: %w", err)
+	}
+
+	// checked before any object is applied, a package over the size of a secret cannot be deployed
+	packageSecret, packageHash, err := e.packageSecret(deploymentID, pkg)
+	if err != nil {
+		return err
+	}
+
+	password, err := e.databasePassword(ctx, deploymentID)
+	if err != nil {
+		return err
+	}
+
+	db, err := newDatabase(deploymentID, e.config.Namespace, sinkConfig.Engine, password)
+	if err != nil {
+		return err
+	}
+	services := map[string]string{db.name: db.motd}
+
+	if err := e.applySecret(ctx, e.secretsObject(deploymentID, password, db)); err != nil {
+		return err
+	}
+	size := sinkcontext.GetSize(ctx)
+	statefulSet := e.databaseStatefulSet(deploymentID, db)
+	limitContainers(&statefulSet.Spec.Template.Spec, size, func(s *resources.Size) resources.Limits { return s.Database })
+	if err := e.applyStatefulSet(ctx, statefulSet); err != nil {
+		return err
+	}
+	if err := e.applyService(ctx, e.databaseService(deploymentID, db)); err != nil {
+		return err
+	}
+
+	var frontends []*frontend
+	isProduction := sinkcontext.GetProductionMode(ctx)
+	if db.engine == "postgres" && (isTruthy(sinkcontext.GetParameterMap(ctx)["SF_PGWEB"]) || !isProduction) {
+		frontends = append(frontends, newPGWeb(deploymentID, db))
+	}
+	if sinkConfig.PostgraphileFrontend.GetEnabled() {
+		if db.engine != "postgres" {
+			return fmt.Errorf("postgraphile not supported on %s", db.engine)
+		}
+		frontends = append(frontends, newPostgraphile(deploymentID, db, isProduction))
+	}
+	if sinkConfig.RestFrontend.GetEnabled() {
+		frontends = append(frontends, newRestFrontend(deploymentID, db))
+	}
+	if sinkConfig.DbtConfig.GetEnabled() {
+		services["dbt"] = "dbt is not supported by the kubernetes engine yet, it was not deployed"
+	}
+
+	for _, fe := range frontends {
+		frontendDeployment := e.frontendDeployment(deploymentID, fe)
+		limitContainers(&frontendDeployment.Spec.Template.Spec, size, func(s *resources.Size) resources.Limits { return s.Other })
+		if err := e.applyDeployment(ctx, frontendDeployment); err != nil {
+			return err
+		}
+		if err := e.applyService(ctx, e.frontendService(deploymentID, fe)); err != nil {
+			return err
+		}
+		services[fe.name] = fe.motd
+	}
+	if err := e.deleteStaleFrontends(ctx, deploymentID, frontends); err != nil {
+		return err
+	}
+
+	if err := e.applySecret(ctx, packageSecret); err != nil {
+		return err
+	}
+
+	hash, err := manifest.OutputModuleHash(pkg)
+	if err != nil {
+		return err
+	}
+	packageInfo, err := json.Marshal(&pbsinksvc.PackageInfo{
+		Name:             pkg.PackageMeta[0].Name,
+		Version:          pkg.PackageMeta[0].Version,
+		OutputModuleName: pkg.SinkModule,
+		OutputModuleHash: hash,
+	})
+	if err != nil {
+		return err
+	}
+
+	services[sinkName(deploymentID)] = fmt.Sprintf("Sink service (no exposed port). Use 'substreams alpha service logs %s --service sink' to see the logs.", deploymentID)
+	servicesJSON, err := json.Marshal(services)
+	if err != nil {
+		return err
+	}
+
+	sink := e.sinkDeployment(deploymentID, db, pkg.SinkModule, sinkConfig, packageHash)
+	limitContainers(&sink.Spec.Template.Spec, size, func(s *resources.Size) resources.Limits { return s.Sink })
+	sink.Annotations = map[string]string{
+		annotationPackageInfo: string(packageInfo),
+		annotationServices:    string(servicesJSON),
+	}
+	return e.applyDeployment(ctx, sink)
+}
+
+// databasePassword returns the database password of the deployment, generated on its creation.
+func (e *KubernetesEngine) databasePassword(ctx context.Context, deploymentID string) (string, error) {
+	existing, err := e.client.CoreV1().Secrets(e.config.Namespace).Get(ctx, secretsName(deploymentID), metav1.GetOptions{})
+	if err == nil {
+		if password := existing.Data["DB_PASSWORD"]; len(password) != 0 {
+			return string(password), nil
+		}
+	} else if !apierrors.IsNotFound(err) {
+		return "", fmt.Errorf("getting secret %q: %w", secretsName(deploymentID), err)
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generating database password: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func (e *KubernetesEngine) Resume(ctx context.Context, deploymentID string, _ pbsinksvc.DeploymentStatus, zlog *zap.Logger) (string, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if err := e.scale(ctx, deploymentID, 1, true); err != nil {
+		return "", fmt.Errorf("scaling up: %w", err)
+	}
+	return "scaled up", nil
+}
+
+func (e *KubernetesEngine) Pause(ctx context.Context, deploymentID string, zlog *zap.Logger) (string, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	// only the sink is scaled down, keeping the database and the frontends up
+	sink, err := e.getSink(ctx, deploymentID)
+	if err != nil {
+		return "", err
+	}
+	if err := e.scaleDeployment(ctx, sink, 0); err != nil {
+		return "", fmt.Errorf("scaling down sink: %w", err)
+	}
+	return "sink scaled down", nil
+}
+
+func (e *KubernetesEngine) Stop(ctx context.Context, deploymentID string, zlog *zap.Logger) (string, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if err := e.scale(ctx, deploymentID, 0, false); err != nil {
+		return "", fmt.Errorf("scaling down: %w", err)
+	}
+	return "scaled down", nil
+}
+
+func (e *KubernetesEngine) Remove(ctx context.Context, deploymentID string, zlog *zap.Logger) (string, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if _, err := e.getSink(ctx, deploymentID); err != nil {
+		return "", err
+	}
+	if _, err := e.deleteObjects(ctx, deploymentID, true); err != nil {
+		return "", fmt.Errorf("deleting kubernetes objects: %w", err)
+	}
+	e.progress.Forget(deploymentID)
+	return "removed", nil
+}
+
+func (e *KubernetesEngine) Info(ctx context.Context, deploymentID string, zlog *zap.Logger) (*pbsinksvc.InfoResponse, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.info(ctx, deploymentID, zlog)
+}
+
+func (e *KubernetesEngine) info(ctx context.Context, deploymentID string, zlog *zap.Logger) (*pbsinksvc.InfoResponse, error) {
+	sink, err := e.getSink(ctx, deploymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	packageInfo := &pbsinksvc.PackageInfo{}
+	if err := json.Unmarshal([]byte(sink.Annotations[annotationPackageInfo]), packageInfo); err != nil {
+		return nil, fmt.Errorf("decoding package info annotation: %w", err)
+	}
+	services := map[string]string{}
+	if err := json.Unmarshal([]byte(sink.Annotations[annotationServices]), &services); err != nil {
+		return nil, fmt.Errorf("decoding services annotation: %w", err)
+	}
+
+	status, reason, err := e.status(ctx, deploymentID, sink)
+	if err != nil {
+		return nil, err
+	}
+
+	var sinkProgress *pbsinksvc.SinkProgress
+	if status != pbsinksvc.DeploymentStatus_STOPPED && status != pbsinksvc.DeploymentStatus_STOPPING {
+		sinkProgress = e.progress.Progress(ctx, deploymentID, e.progressSource(ctx, deploymentID, packageInfo), zlog)
+	}
+
+	return &pbsinksvc.InfoResponse{
+		Status:      status,
+		Services:    services,
+		Reason:      reason,
+		PackageInfo: packageInfo,
+		Progress:    sinkProgress,
+		Motd:        fmt.Sprintf("Running your deployment in kubernetes namespace %q", e.config.Namespace),
+	}, nil
+}
+
+func (e *KubernetesEngine) List(ctx context.Context, zlog *zap.Logger) (out []*pbsinksvc.DeploymentWithStatus, err error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	selector := labels.SelectorFromSet(map[string]string{labelManagedBy: managedBy, labelComponent: componentSink}).String()
+	sinks, err := e.client.AppsV1().Deployments(e.config.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("listing sink deployments: %w", err)
+	}
+
+	for _, sink := range sinks.Items {
+		id := sink.Labels[labelDeploymentID]
+		info, err := e.info(ctx, id, zlog)
+		if err != nil {
+			zlog.Warn("cannot get info for deployment", zap.String("id", id), zap.Error(err))
+			continue
+		}
+		out = append(out, &pbsinksvc.DeploymentWithStatus{
+			Id:          id,
+			Status:      info.Status,
+			Reason:      info.Reason,
+			PackageInfo: info.PackageInfo,
+			Progress:    info.Progress,
+		})
+	}
+	return out, nil
+}
+
+// Shutdown leaves the deployments running in the cluster.
+func (e *KubernetesEngine) Shutdown(ctx context.Context, _ error, zlog *zap.Logger) error {
+	zlog.Info("kubernetes engine shutting down, deployments keep running in the cluster", zap.String("namespace", e.config.Namespace))
+	return nil
+}
+
+func secretsName(deploymentID string) string {
+	return deploymentID + "-secrets"
+}
+
+func packageSecretName(deploymentID string) string {
+	return deploymentID + "-package"
+}
+
+func (e *KubernetesEngine) secretsObject(deploymentID, password string, db *database) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: e.objectMeta(deploymentID, secretsName(deploymentID), componentSink),
+		Type:       corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			"SUBSTREAMS_API_TOKEN": []byte(e.token),
+			"DB_PASSWORD":          []byte(password),
+			"DSN":                  []byte(db.dsn),
+			"CURSOR_DSN":           []byte(db.cursorDSN),
+		},
+	}
+}
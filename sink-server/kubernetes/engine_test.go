@@ -0,0 +1,353 @@
+package kubernetes
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	pbsql "github.com/streamingfast/substreams-sink-sql/pb/sf/substreams/sink/sql/v1"
+	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	sinkcontext "github.com/streamingfast/substreams/sink-server/context"
+	"github.com/streamingfast/substreams/sink-server/resources"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/anypb"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const testNamespace = "sinks"
+
+func testPackage(t *testing.T, service *pbsql.Service) *pbsubstreams.Package {
+	t.Helper()
+
+	sinkConfig, err := anypb.New(service)
+	require.NoError(t, err)
+	// as set by the manifest reader
+	sinkConfig.TypeUrl = "sf.substreams.sink.sql.v1.Service"
+
+	return &pbsubstreams.Package{
+		PackageMeta: []*pbsubstreams.PackageMetadata{{Name: "test", Version: "v0.1.0"}},
+		Modules: &pbsubstreams.Modules{
+			Binaries: []*pbsubstreams.Binary{{Type: "wasm/rust-v1", Content: []byte{0x00}}},
+			Modules: []*pbsubstreams.Module{{
+				Name:             "db_out",
+				Kind:             &pbsubstreams.Module_KindMap_{KindMap: &pbsubstreams.Module_KindMap{OutputType: "proto:sf.substreams.sink.database.v1.DatabaseChanges"}},
+				BinaryEntrypoint: "db_out",
+				Inputs:           []*pbsubstreams.Module_Input{{Input: &pbsubstreams.Module_Input_Source_{Source: &pbsubstreams.Module_Input_Source{Type: "sf.ethereum.type.v2.Block"}}}},
+			}},
+		},
+		SinkModule: "db_out",
+		SinkConfig: sinkConfig,
+	}
+}
+
+func newTestEngine() (*KubernetesEngine, *fake.Clientset) {
+	client := fake.NewSimpleClientset()
+	return NewEngineFromClient(client, Config{Namespace: testNamespace}, "token", "mainnet.eth.streamingfast.io:443"), client
+}
+
+// markReady simulates the cluster bringing the workloads of the deployment to their wanted replicas.
+func markReady(t *testing.T, client *fake.Clientset, deploymentID string) {
+	t.Helper()
+	ctx := context.Background()
+	listOptions := metav1.ListOptions{LabelSelector: deploymentSelector(deploymentID)}
+
+	deployments, err := client.AppsV1().Deployments(testNamespace).List(ctx, listOptions)
+	require.NoError(t, err)
+	for _, d := range deployments.Items {
+		d.Status.Replicas = replicas(d.Spec.Replicas)
+		d.Status.ReadyReplicas = replicas(d.Spec.Replicas)
+		_, err := client.AppsV1().Deployments(testNamespace).UpdateStatus(ctx, &d, metav1.UpdateOptions{})
+		require.NoError(t, err)
+	}
+
+	statefulSets, err := client.AppsV1().StatefulSets(testNamespace).List(ctx, listOptions)
+	require.NoError(t, err)
+	for _, sts := range statefulSets.Items {
+		sts.Status.Replicas = replicas(sts.Spec.Replicas)
+		sts.Status.ReadyReplicas = replicas(sts.Spec.Replicas)
+		_, err := client.AppsV1().StatefulSets(testNamespace).UpdateStatus(ctx, &sts, metav1.UpdateOptions{})
+		require.NoError(t, err)
+	}
+}
+
+func assertStatus(t *testing.T, engine *KubernetesEngine, deploymentID string, expected pbsinksvc.DeploymentStatus) {
+	t.Helper()
+	info, err := engine.Info(context.Background(), deploymentID, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, expected, info.Status, info.Reason)
+}
+
+func TestKubernetesEngine_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	zlog := zap.NewNop()
+	engine, client := newTestEngine()
+
+	info, err := engine.Create(ctx, "abc", testPackage(t, &pbsql.Service{
+		Engine:               pbsql.Service_postgres,
+		PostgraphileFrontend: &pbsql.PostgraphileFrontend{Enabled: true},
+	}), zlog)
+	require.NoError(t, err)
+	assert.Equal(t, pbsinksvc.DeploymentStatus_STARTING, info.Status)
+	assert.Equal(t, "test", info.PackageInfo.Name)
+	assert.Equal(t, "db_out", info.PackageInfo.OutputModuleName)
+	assert.NotEmpty(t, info.PackageInfo.OutputModuleHash)
+	assert.Contains(t, info.Services, "abc-postgres")
+	assert.Contains(t, info.Services, "abc-postgraphile")
+	assert.Contains(t, info.Services, "abc-pgweb")
+
+	_, err = engine.Create(ctx, "abc", testPackage(t, &pbsql.Service{}), zlog)
+	require.Error(t, err)
+
+	sts, err := client.AppsV1().StatefulSets(testNamespace).Get(ctx, "abc-postgres", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "10Gi", sts.Spec.VolumeClaimTemplates[0].Spec.Resources.Requests.Storage().String())
+
+	secret, err := client.CoreV1().Secrets(testNamespace).Get(ctx, "abc-secrets", metav1.GetOptions{})
+	require.NoError(t, err)
+	password := secret.Data["DB_PASSWORD"]
+	assert.NotEmpty(t, password)
+
+	markReady(t, client, "abc")
+	assertStatus(t, engine, "abc", pbsinksvc.DeploymentStatus_RUNNING)
+
+	_, err = engine.Pause(ctx, "abc", zlog)
+	require.NoError(t, err)
+	assertStatus(t, engine, "abc", pbsinksvc.DeploymentStatus_PAUSING)
+	markReady(t, client, "abc")
+	assertStatus(t, engine, "abc", pbsinksvc.DeploymentStatus_PAUSED)
+
+	_, err = engine.Resume(ctx, "abc", pbsinksvc.DeploymentStatus_PAUSED, zlog)
+	require.NoError(t, err)
+	assertStatus(t, engine, "abc", pbsinksvc.DeploymentStatus_STARTING)
+	markReady(t, client, "abc")
+	assertStatus(t, engine, "abc", pbsinksvc.DeploymentStatus_RUNNING)
+
+	_, err = engine.Stop(ctx, "abc", zlog)
+	require.NoError(t, err)
+	assertStatus(t, engine, "abc", pbsinksvc.DeploymentStatus_STOPPING)
+	markReady(t, client, "abc")
+	assertStatus(t, engine, "abc", pbsinksvc.DeploymentStatus_STOPPED)
+
+	// update keeps the deployment stopped, the database password and drops the disabled frontend
+	err = engine.Update(ctx, "abc", testPackage(t, &pbsql.Service{Engine: pbsql.Service_postgres}), false, zlog)
+	require.NoError(t, err)
+	assertStatus(t, engine, "abc", pbsinksvc.DeploymentStatus_STOPPED)
+	secret, err = client.CoreV1().Secrets(testNamespace).Get(ctx, "abc-secrets", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, password, secret.Data["DB_PASSWORD"])
+	_, err = client.AppsV1().Deployments(testNamespace).Get(ctx, "abc-postgraphile", metav1.GetOptions{})
+	assert.Error(t, err)
+	_, err = client.CoreV1().Services(testNamespace).Get(ctx, "abc-postgraphile", metav1.GetOptions{})
+	assert.Error(t, err)
+
+	list, err := engine.List(ctx, zlog)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "abc", list[0].Id)
+
+	_, err = engine.Remove(ctx, "abc", zlog)
+	require.NoError(t, err)
+	_, err = engine.Info(ctx, "abc", zlog)
+	require.Error(t, err)
+
+	for _, check := range []func() (int, error){
+		func() (int, error) {
+			l, err := client.AppsV1().Deployments(testNamespace).List(ctx, metav1.ListOptions{})
+			return len(l.Items), err
+		},
+		func() (int, error) {
+			l, err := client.AppsV1().StatefulSets(testNamespace).List(ctx, metav1.ListOptions{})
+			return len(l.Items), err
+		},
+		func() (int, error) {
+			l, err := client.CoreV1().Services(testNamespace).List(ctx, metav1.ListOptions{})
+			return len(l.Items), err
+		},
+		func() (int, error) {
+			l, err := client.CoreV1().Secrets(testNamespace).List(ctx, metav1.ListOptions{})
+			return len(l.Items), err
+		},
+	} {
+		count, err := check()
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	}
+}
+
+func TestKubernetesEngine_UpdateReset(t *testing.T) {
+	ctx := context.Background()
+	zlog := zap.NewNop()
+	engine, client := newTestEngine()
+
+	_, err := engine.Create(ctx, "abc", testPackage(t, &pbsql.Service{}), zlog)
+	require.NoError(t, err)
+
+	// created by the statefulset controller in a real cluster
+	_, err = client.CoreV1().PersistentVolumeClaims(testNamespace).Create(ctx, &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data-abc-postgres-0", Namespace: testNamespace, Labels: deploymentLabels("abc", componentDatabase)},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	err = engine.Update(ctx, "abc", testPackage(t, &pbsql.Service{}), true, zlog)
+	require.NoError(t, err)
+
+	claims, err := client.CoreV1().PersistentVolumeClaims(testNamespace).List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, claims.Items)
+
+	_, err = client.AppsV1().StatefulSets(testNamespace).Get(ctx, "abc-postgres", metav1.GetOptions{})
+	require.NoError(t, err)
+	_, err = engine.Info(ctx, "abc", zlog)
+	require.NoError(t, err)
+
+	err = engine.Update(ctx, "unknown", testPackage(t, &pbsql.Service{}), true, zlog)
+	require.Error(t, err)
+}
+
+func TestKubernetesEngine_UpdateResetDelayedDeletion(t *testing.T) {
+	deletePollInterval = time.Millisecond
+	ctx := context.Background()
+	zlog := zap.NewNop()
+	engine, client := newTestEngine()
+
+	_, err := engine.Create(ctx, "abc", testPackage(t, &pbsql.Service{}), zlog)
+	require.NoError(t, err)
+
+	// the objects are removed by the garbage collector after their dependents, not by the delete request
+	var deletions sync.WaitGroup
+	client.PrependReactor("delete", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		del := action.(k8stesting.DeleteAction)
+		deletions.Add(1)
+		go func() {
+			defer deletions.Done()
+			time.Sleep(20 * time.Millisecond)
+			assert.NoError(t, client.Tracker().Delete(del.GetResource(), del.GetNamespace(), del.GetName()))
+		}()
+		return true, nil, nil
+	})
+
+	err = engine.Update(ctx, "abc", testPackage(t, &pbsql.Service{}), true, zlog)
+	require.NoError(t, err)
+	deletions.Wait()
+
+	// re-created once the previous ones were gone
+	_, err = client.AppsV1().StatefulSets(testNamespace).Get(ctx, "abc-postgres", metav1.GetOptions{})
+	require.NoError(t, err)
+	_, err = client.AppsV1().Deployments(testNamespace).Get(ctx, sinkName("abc"), metav1.GetOptions{})
+	require.NoError(t, err)
+}
+
+func TestKubernetesEngine_PackageTooLarge(t *testing.T) {
+	ctx := context.Background()
+	engine, client := newTestEngine()
+
+	pkg := testPackage(t, &pbsql.Service{})
+	pkg.Modules.Binaries[0].Content = make([]byte, maxPackageSize)
+	_, err := engine.Create(ctx, "abc", pkg, zap.NewNop())
+	assert.ErrorContains(t, err, "over the 1048576 bytes a kubernetes secret can hold")
+
+	secrets, err := client.CoreV1().Secrets(testNamespace).List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, secrets.Items)
+}
+
+func TestKubernetesEngine_Failing(t *testing.T) {
+	ctx := context.Background()
+	zlog := zap.NewNop()
+	engine, client := newTestEngine()
+
+	_, err := engine.Create(ctx, "abc", testPackage(t, &pbsql.Service{}), zlog)
+	require.NoError(t, err)
+	markReady(t, client, "abc")
+
+	_, err = client.CoreV1().Pods(testNamespace).Create(ctx, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "abc-sink-1234", Namespace: testNamespace, Labels: deploymentLabels("abc", componentSink)},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+			Name:                 "sink",
+			State:                corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+			LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1}},
+		}}},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	info, err := engine.Info(ctx, "abc", zlog)
+	require.NoError(t, err)
+	assert.Equal(t, pbsinksvc.DeploymentStatus_FAILING, info.Status)
+	assert.Contains(t, info.Reason, "CrashLoopBackOff")
+}
+
+func TestKubernetesEngine_Size(t *testing.T) {
+	ctx := sinkcontext.SetSize(context.Background(), resources.DefaultSizes["small"])
+	engine, client := newTestEngine()
+
+	_, err := engine.Create(ctx, "abc", testPackage(t, &pbsql.Service{}), zap.NewNop())
+	require.NoError(t, err)
+
+	sts, err := client.AppsV1().StatefulSets(testNamespace).Get(ctx, "abc-postgres", metav1.GetOptions{})
+	require.NoError(t, err)
+	limits := sts.Spec.Template.Spec.Containers[0].Resources.Limits
+	assert.Equal(t, "1", limits.Cpu().String())
+	assert.Equal(t, "1Gi", limits.Memory().String())
+
+	sink, err := client.AppsV1().Deployments(testNamespace).Get(ctx, "abc-sink", metav1.GetOptions{})
+	require.NoError(t, err)
+	limits = sink.Spec.Template.Spec.Containers[0].Resources.Limits
+	assert.Equal(t, "1", limits.Cpu().String())
+	assert.Equal(t, "512Mi", limits.Memory().String())
+
+	pgweb, err := client.AppsV1().Deployments(testNamespace).Get(ctx, "abc-pgweb", metav1.GetOptions{})
+	require.NoError(t, err)
+	limits = pgweb.Spec.Template.Spec.Containers[0].Resources.Limits
+	assert.Equal(t, "500m", limits.Cpu().String())
+	assert.Equal(t, "256Mi", limits.Memory().String())
+}
+
+func TestKubernetesEngine_Logs(t *testing.T) {
+	ctx := context.Background()
+	engine, client := newTestEngine()
+
+	_, err := engine.Create(ctx, "abc", testPackage(t, &pbsql.Service{Engine: pbsql.Service_postgres}), zap.NewNop())
+	require.NoError(t, err)
+
+	for name, container := range map[string]string{"abc-sink-1234-xyz": "sink", "abc-postgres-0": "postgres", "abc-pgweb-5678-xyz": "pgweb"} {
+		_, err := client.CoreV1().Pods(testNamespace).Create(ctx, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, Labels: deploymentLabels("abc", componentSink)},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: container}}},
+		}, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+
+	var mutex sync.Mutex
+	logs := func(service string) (out []*pbsinksvc.LogsResponse) {
+		err := engine.Logs(ctx, "abc", service, false, time.Time{}, func(resp *pbsinksvc.LogsResponse) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			out = append(out, resp)
+			return nil
+		}, zap.NewNop())
+		require.NoError(t, err)
+		return out
+	}
+
+	// the fake client answers "fake logs" for every container
+	sink := logs("abc-sink")
+	require.Len(t, sink, 1)
+	assert.Equal(t, "abc-sink", sink[0].Service)
+	assert.Equal(t, "fake logs", sink[0].Line)
+
+	services := map[string]bool{}
+	for _, resp := range logs("") {
+		services[resp.Service] = true
+	}
+	assert.Equal(t, map[string]bool{"abc-sink": true, "abc-postgres": true, "abc-pgweb": true}, services)
+
+	assert.Error(t, engine.Logs(ctx, "unknown", "", false, time.Time{}, func(*pbsinksvc.LogsResponse) error { return nil }, zap.NewNop()))
+}
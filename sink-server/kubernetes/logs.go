@@ -0,0 +1,114 @@
+package kubernetes
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type logSource struct {
+	service   string
+	pod       string
+	container string
+}
+
+// Logs streams the logs of the containers of the pods of the deployment, concurrently when following them.
+// The containers that cannot be read, like those waiting to start, are skipped.
+func (e *KubernetesEngine) Logs(ctx context.Context, deploymentID string, service string, follow bool, since time.Time, send func(*pbsinksvc.LogsResponse) error, zlog *zap.Logger) error {
+	sink, err := e.getSink(ctx, deploymentID)
+	if err != nil {
+		return err
+	}
+	services := map[string]string{}
+	if err := json.Unmarshal([]byte(sink.Annotations[annotationServices]), &services); err != nil {
+		return fmt.Errorf("decoding services annotation: %w", err)
+	}
+
+	pods, err := e.client.CoreV1().Pods(e.config.Namespace).List(ctx, metav1.ListOptions{LabelSelector: deploymentSelector(deploymentID)})
+	if err != nil {
+		return fmt.Errorf("listing pods: %w", err)
+	}
+	var sources []logSource
+	for _, pod := range pods.Items {
+		name := podService(pod.Name, services)
+		if name == "" || (service != "" && name != service) {
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			sources = append(sources, logSource{service: name, pod: pod.Name, container: container.Name})
+		}
+	}
+
+	opts := corev1.PodLogOptions{Follow: follow, Timestamps: true}
+	if !since.IsZero() {
+		opts.SinceTime = &metav1.Time{Time: since}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, len(sources))
+	for _, source := range sources {
+		go func(source logSource) {
+			err := e.containerLogs(ctx, source, opts, send, zlog)
+			if err != nil {
+				cancel()
+			}
+			errs <- err
+		}(source)
+	}
+
+	var out error
+	for range sources {
+		if err := <-errs; err != nil && out == nil {
+			out = err
+		}
+	}
+	return out
+}
+
+func (e *KubernetesEngine) containerLogs(ctx context.Context, source logSource, opts corev1.PodLogOptions, send func(*pbsinksvc.LogsResponse) error, zlog *zap.Logger) error {
+	opts.Container = source.container
+	stream, err := e.client.CoreV1().Pods(e.config.Namespace).GetLogs(source.pod, &opts).Stream(ctx)
+	if err != nil {
+		zlog.Info("cannot read container logs, skipping it", zap.String("pod", source.pod), zap.String("container", source.container), zap.Error(err))
+		return nil
+	}
+	defer stream.Close()
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		out := &pbsinksvc.LogsResponse{Service: source.service, Line: scanner.Text()}
+		ts, line, _ := strings.Cut(out.Line, " ")
+		if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+			out.Timestamp = timestamppb.New(t)
+			out.Line = line
+		}
+		if err := send(out); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("reading logs of pod %q: %w", source.pod, err)
+	}
+	return nil
+}
+
+// podService returns the service whose workload created the pod, its name being the prefix of the pod name.
+func podService(podName string, services map[string]string) (out string) {
+	for name := range services {
+		if strings.HasPrefix(podName, name+"-") && len(name) > len(out) {
+			out = name
+		}
+	}
+	return out
+}
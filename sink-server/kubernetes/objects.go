@@ -0,0 +1,351 @@
+package kubernetes
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	pbsql "github.com/streamingfast/substreams-sink-sql/pb/sf/substreams/sink/sql/v1"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"github.com/streamingfast/substreams/sink-server/resources"
+	"google.golang.org/protobuf/proto"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	sinkImage         = "ghcr.io/streamingfast/substreams-sink-sql:v4.0.1"
+	postgresImage     = "postgres:14"
+	clickhouseImage   = "clickhouse/clickhouse-server:23.9-alpine"
+	pgwebImage        = "sosedoff/pgweb:0.11.12"
+	postgraphileImage = "graphile/postgraphile:4"
+	restImage         = "docker.io/dfuse/sql-wrapper:latest"
+
+	databaseUser = "dev-node"
+	databaseName = "substreams"
+
+	// sinkMetricsPort serves the Prometheus metrics of the sink, used to report its progress
+	sinkMetricsPort = 9102
+
+	// maxPackageSize is the size of the data of a secret, mounted in the sink to deliver the package
+	maxPackageSize = 1024 * 1024
+)
+
+type database struct {
+	engine string // postgres or clickhouse
+	name   string
+	image  string
+	ports  []corev1.ContainerPort
+	env    []corev1.EnvVar
+	mount  string
+	probe  []string
+	dsn    string
+	// cursorDSN reaches the database from anywhere in the cluster, to read the progress of the sink
+	cursorDSN string
+	motd      string
+}
+
+func newDatabase(deploymentID, namespace string, engine pbsql.Service_Engine, password string) (*database, error) {
+	switch engine {
+	case pbsql.Service_postgres, pbsql.Service_unset:
+		name := deploymentID + "-postgres"
+		return &database{
+			engine: "postgres",
+			name:   name,
+			image:  postgresImage,
+			ports:  []corev1.ContainerPort{{Name: "postgres", ContainerPort: 5432}},
+			env: []corev1.EnvVar{
+				{Name: "POSTGRES_USER", Value: databaseUser},
+				{Name: "POSTGRES_PASSWORD", ValueFrom: secretKeyRef(deploymentID, "DB_PASSWORD")},
+				{Name: "POSTGRES_DB", Value: databaseName},
+				{Name: "POSTGRES_INITDB_ARGS", Value: "-E UTF8 --locale=C"},
+				{Name: "POSTGRES_HOST_AUTH_METHOD", Value: "md5"},
+				{Name: "PGDATA", Value: "/var/lib/postgresql/data/pgdata"},
+			},
+			mount:     "/var/lib/postgresql/data",
+			probe:     []string{"pg_isready", "-U", databaseUser},
+			dsn:       fmt.Sprintf("postgres://%s:%s@%s:5432/%s?sslmode=disable", databaseUser, password, name, databaseName),
+			cursorDSN: fmt.Sprintf("postgres://%s:%s@%s.%s.svc:5432/%s?sslmode=disable", databaseUser, password, name, namespace, databaseName),
+			motd:      fmt.Sprintf("PostgreSQL service %q available in the cluster at DSN: 'postgres://%s:<password>@%s:5432/%s?sslmode=disable', the password being the 'DB_PASSWORD' key of secret %q", name, databaseUser, name, databaseName, secretsName(deploymentID)),
+		}, nil
+
+	case pbsql.Service_clickhouse:
+		name := deploymentID + "-clickhouse"
+		return &database{
+			engine: "clickhouse",
+			name:   name,
+			image:  clickhouseImage,
+			ports:  []corev1.ContainerPort{{Name: "native", ContainerPort: 9000}, {Name: "http", ContainerPort: 8123}},
+			env: []corev1.EnvVar{
+				{Name: "CLICKHOUSE_USER", Value: databaseUser},
+				{Name: "CLICKHOUSE_PASSWORD", ValueFrom: secretKeyRef(deploymentID, "DB_PASSWORD")},
+				{Name: "CLICKHOUSE_DB", Value: databaseName},
+				{Name: "CLICKHOUSE_DEFAULT_ACCESS_MANAGEMENT", Value: "1"},
+			},
+			mount:     "/var/lib/clickhouse",
+			probe:     []string{"clickhouse", "status"},
+			dsn:       fmt.Sprintf("clickhouse://%s:%s@%s:9000/%s", databaseUser, password, name, databaseName),
+			cursorDSN: fmt.Sprintf("http://%s:%s@%s.%s.svc:8123/%s", databaseUser, password, name, namespace, databaseName),
+			motd:      fmt.Sprintf("Clickhouse service %q available in the cluster at DSN: 'clickhouse://%s:<password>@%s:9000/%s', the password being the 'DB_PASSWORD' key of secret %q", name, databaseUser, name, databaseName, secretsName(deploymentID)),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown service %q", engine)
+}
+
+func secretKeyRef(deploymentID, key string) *corev1.EnvVarSource {
+	return &corev1.EnvVarSource{
+		SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: secretsName(deploymentID)},
+			Key:                  key,
+		},
+	}
+}
+
+func (e *KubernetesEngine) databaseStatefulSet(deploymentID string, db *database) *appsv1.StatefulSet {
+	podLabels := deploymentLabels(deploymentID, componentDatabase)
+	return &appsv1.StatefulSet{
+		ObjectMeta: e.objectMeta(deploymentID, db.name, componentDatabase),
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:    ptr(int32(1)),
+			ServiceName: db.name,
+			Selector:    &metav1.LabelSelector{MatchLabels: podLabels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: podLabels},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:         db.engine,
+						Image:        db.image,
+						Ports:        db.ports,
+						Env:          db.env,
+						VolumeMounts: []corev1.VolumeMount{{Name: "data", MountPath: db.mount}},
+						ReadinessProbe: &corev1.Probe{
+							ProbeHandler:     corev1.ProbeHandler{Exec: &corev1.ExecAction{Command: db.probe}},
+							PeriodSeconds:    5,
+							TimeoutSeconds:   4,
+							FailureThreshold: 10,
+						},
+					}},
+				},
+			},
+			VolumeClaimTemplates: []corev1.PersistentVolumeClaim{{
+				ObjectMeta: metav1.ObjectMeta{Name: "data", Labels: deploymentLabels(deploymentID, componentDatabase)},
+				Spec: corev1.PersistentVolumeClaimSpec{
+					AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+					Resources: corev1.VolumeResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(e.config.StorageSize)},
+					},
+				},
+			}},
+		},
+	}
+}
+
+func (e *KubernetesEngine) databaseService(deploymentID string, db *database) *corev1.Service {
+	var ports []corev1.ServicePort
+	for _, port := range db.ports {
+		ports = append(ports, corev1.ServicePort{Name: port.Name, Port: port.ContainerPort, TargetPort: intstr.FromInt32(port.ContainerPort)})
+	}
+	return &corev1.Service{
+		ObjectMeta: e.objectMeta(deploymentID, db.name, componentDatabase),
+		Spec: corev1.ServiceSpec{
+			Selector: deploymentLabels(deploymentID, componentDatabase),
+			Ports:    ports,
+		},
+	}
+}
+
+func sinkName(deploymentID string) string {
+	return deploymentID + "-sink"
+}
+
+// packageSecret returns the secret holding the package of the deployment, and the hash of its content.
+func (e *KubernetesEngine) packageSecret(deploymentID string, pkg *pbsubstreams.Package) (*corev1.Secret, string, error) {
+	content, err := proto.Marshal(pkg)
+	if err != nil {
+		return nil, "", fmt.Errorf("marshalling package: %w", err)
+	}
+	if len(content) > maxPackageSize {
+		return nil, "", fmt.Errorf("package is %d bytes, over the %d bytes a kubernetes secret can hold: remove the modules, binaries or proto files the sink does not need, or deploy it with another engine", len(content), maxPackageSize)
+	}
+	hash := sha256.Sum256(content)
+
+	return &corev1.Secret{
+		ObjectMeta: e.objectMeta(deploymentID, packageSecretName(deploymentID), componentSink),
+		Type:       corev1.SecretTypeOpaque,
+		Data:       map[string][]byte{"substreams.spkg": content},
+	}, hex.EncodeToString(hash[:]), nil
+}
+
+func (e *KubernetesEngine) sinkDeployment(deploymentID string, db *database, outputModule string, sinkConfig *pbsql.Service, packageHash string) *appsv1.Deployment {
+	var setupFlags, runFlags []string
+	if sinkConfig.PostgraphileFrontend.GetEnabled() {
+		setupFlags = append(setupFlags, "--postgraphile")
+	}
+	if db.engine == "clickhouse" {
+		runFlags = append(runFlags, "--undo-buffer-size=12")
+	}
+	if e.endpoint != "" {
+		runFlags = append(runFlags, "-e", e.endpoint)
+	}
+
+	script := fmt.Sprintf(`set -xeu
+/app/substreams-sink-sql setup "$DSN" /opt/subservices/config/substreams.spkg --ignore-duplicate-table-errors %s
+exec /app/substreams-sink-sql run "$DSN" /opt/subservices/config/substreams.spkg --on-module-hash-mistmatch=warn --metrics-listen-addr=0.0.0.0:%d %s
+`, strings.Join(setupFlags, " "), sinkMetricsPort, strings.Join(runFlags, " "))
+
+	podLabels := deploymentLabels(deploymentID, componentSink)
+	return &appsv1.Deployment{
+		ObjectMeta: e.objectMeta(deploymentID, sinkName(deploymentID), componentSink),
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr(int32(1)),
+			Selector: &metav1.LabelSelector{MatchLabels: podLabels},
+			// never run two sinks writing to the same database
+			Strategy: appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: podLabels,
+					// a new package rolls the sink out
+					Annotations: map[string]string{annotationPackageHash: packageHash},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:    componentSink,
+						Image:   sinkImage,
+						Command: []string{"/bin/bash", "-c", script},
+						Ports:   []corev1.ContainerPort{{Name: "metrics", ContainerPort: sinkMetricsPort}},
+						Env: []corev1.EnvVar{
+							{Name: "DSN", ValueFrom: secretKeyRef(deploymentID, "DSN")},
+							{Name: "OUTPUT_MODULE", Value: outputModule},
+							{Name: "SUBSTREAMS_API_TOKEN", ValueFrom: secretKeyRef(deploymentID, "SUBSTREAMS_API_TOKEN")},
+						},
+						VolumeMounts: []corev1.VolumeMount{{Name: "package", MountPath: "/opt/subservices/config", ReadOnly: true}},
+					}},
+					Volumes: []corev1.Volume{{
+						Name:         "package",
+						VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: packageSecretName(deploymentID)}},
+					}},
+				},
+			},
+		},
+	}
+}
+
+type frontend struct {
+	component string
+	name      string
+	image     string
+	args      []string
+	env       []corev1.EnvVar
+	port      int32
+	motd      string
+}
+
+func newPGWeb(deploymentID string, db *database) *frontend {
+	name := deploymentID + "-pgweb"
+	return &frontend{
+		component: "pgweb",
+		name:      name,
+		image:     pgwebImage,
+		args:      []string{"pgweb", "--bind=0.0.0.0", "--listen=8081", "--binary-codec=hex"},
+		env:       []corev1.EnvVar{{Name: "DATABASE_URL", ValueFrom: secretKeyRef(deploymentID, "DSN")}},
+		port:      8081,
+		motd:      fmt.Sprintf("PGWeb service %q available in the cluster at URL: 'http://%s:8081'", name, name),
+	}
+}
+
+func newPostgraphile(deploymentID string, db *database, isProduction bool) *frontend {
+	name := deploymentID + "-postgraphile"
+	fe := &frontend{
+		component: "postgraphile",
+		name:      name,
+		image:     postgraphileImage,
+		args:      []string{"--connection", "$(DSN)", "--watch"},
+		env:       []corev1.EnvVar{{Name: "DSN", ValueFrom: secretKeyRef(deploymentID, "DSN")}},
+		port:      5000,
+		motd:      fmt.Sprintf("Postgraphile service %q available in the cluster at URL: 'http://%s:5000/graphiql' (API at 'http://%s:5000/graphql')", name, name, name),
+	}
+	if !isProduction {
+		fe.args = append(fe.args, "--cors")
+	}
+	return fe
+}
+
+func newRestFrontend(deploymentID string, db *database) *frontend {
+	name := deploymentID + "-rest"
+	return &frontend{
+		component: "rest",
+		name:      name,
+		image:     restImage,
+		env: []corev1.EnvVar{
+			{Name: "DB_PASSWORD", ValueFrom: secretKeyRef(deploymentID, "DB_PASSWORD")},
+			{Name: "CLICKHOUSE_URL", Value: fmt.Sprintf("tcp://%s:$(DB_PASSWORD)@%s:9000/%s?secure=false&skip_verify=true&connection_timeout=20s", databaseUser, db.name, databaseName)},
+		},
+		port: 3000,
+		motd: fmt.Sprintf("REST frontend service %q available in the cluster at URL: 'http://%s:3000'", name, name),
+	}
+}
+
+func (e *KubernetesEngine) frontendDeployment(deploymentID string, fe *frontend) *appsv1.Deployment {
+	podLabels := deploymentLabels(deploymentID, fe.component)
+	return &appsv1.Deployment{
+		ObjectMeta: e.objectMeta(deploymentID, fe.name, fe.component),
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr(int32(1)),
+			Selector: &metav1.LabelSelector{MatchLabels: podLabels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: podLabels},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:  fe.component,
+						Image: fe.image,
+						Args:  fe.args,
+						Env:   fe.env,
+						Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: fe.port}},
+					}},
+				},
+			},
+		},
+	}
+}
+
+func (e *KubernetesEngine) frontendService(deploymentID string, fe *frontend) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: e.objectMeta(deploymentID, fe.name, fe.component),
+		Spec: corev1.ServiceSpec{
+			Selector: deploymentLabels(deploymentID, fe.component),
+			Ports:    []corev1.ServicePort{{Name: "http", Port: fe.port, TargetPort: intstr.FromInt32(fe.port)}},
+		},
+	}
+}
+
+// limitContainers sets the limits of the role of the pod, as given by `limits`, on all its containers. The
+// pod is not limited without a size.
+func limitContainers(pod *corev1.PodSpec, size *resources.Size, limits func(*resources.Size) resources.Limits) {
+	if size == nil {
+		return
+	}
+	l := limits(size)
+	if l.IsZero() {
+		return
+	}
+
+	list := corev1.ResourceList{}
+	if l.CPUs != 0 {
+		list[corev1.ResourceCPU] = *resource.NewMilliQuantity(int64(l.CPUs*1000), resource.DecimalSI)
+	}
+	if l.MemoryBytes != 0 {
+		list[corev1.ResourceMemory] = *resource.NewQuantity(l.MemoryBytes, resource.BinarySI)
+	}
+	for i := range pod.Containers {
+		pod.Containers[i].Resources.Limits = list
+	}
+}
+
+func ptr[T any](in T) *T {
+	return &in
+}
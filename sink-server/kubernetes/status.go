@@ -0,0 +1,141 @@
+package kubernetes
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
+	"github.com/streamingfast/substreams/sink-server/progress"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// waitingFailures are the reasons of a waiting container that will not get better by themselves.
+var waitingFailures = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+}
+
+// status maps the replicas of the sink and the database, and the state of their pods, to a deployment status.
+func (e *KubernetesEngine) status(ctx context.Context, deploymentID string, sink *appsv1.Deployment) (pbsinksvc.DeploymentStatus, string, error) {
+	listOptions := metav1.ListOptions{LabelSelector: deploymentSelector(deploymentID)}
+	statefulSets, err := e.client.AppsV1().StatefulSets(e.config.Namespace).List(ctx, listOptions)
+	if err != nil {
+		return pbsinksvc.DeploymentStatus_UNKNOWN, "", fmt.Errorf("listing statefulsets: %w", err)
+	}
+
+	var dbWanted, dbReady int32
+	for _, sts := range statefulSets.Items {
+		dbWanted += replicas(sts.Spec.Replicas)
+		dbReady += sts.Status.ReadyReplicas
+	}
+	sinkWanted := replicas(sink.Spec.Replicas)
+	sinkRunning := sink.Status.Replicas
+
+	switch {
+	case sinkWanted == 0 && dbWanted == 0:
+		if sinkRunning != 0 || dbReady != 0 {
+			return pbsinksvc.DeploymentStatus_STOPPING, "", nil
+		}
+		return pbsinksvc.DeploymentStatus_STOPPED, "", nil
+	case sinkWanted == 0:
+		if sinkRunning != 0 {
+			return pbsinksvc.DeploymentStatus_PAUSING, "", nil
+		}
+		return pbsinksvc.DeploymentStatus_PAUSED, "", nil
+	}
+
+	if reason, err := e.failingReason(ctx, deploymentID); err != nil {
+		return pbsinksvc.DeploymentStatus_UNKNOWN, "", err
+	} else if reason != "" {
+		return pbsinksvc.DeploymentStatus_FAILING, reason, nil
+	}
+
+	for _, cond := range sink.Status.Conditions {
+		if cond.Type == appsv1.DeploymentReplicaFailure && cond.Status == corev1.ConditionTrue {
+			return pbsinksvc.DeploymentStatus_FAILING, cond.Message, nil
+		}
+		if cond.Type == appsv1.DeploymentProgressing && cond.Status == corev1.ConditionFalse {
+			return pbsinksvc.DeploymentStatus_FAILING, cond.Message, nil
+		}
+	}
+
+	if dbReady < dbWanted || sink.Status.ReadyReplicas < sinkWanted {
+		return pbsinksvc.DeploymentStatus_STARTING, "", nil
+	}
+	return pbsinksvc.DeploymentStatus_RUNNING, "", nil
+}
+
+// failingReason returns why a container of the deployment cannot run, if any.
+func (e *KubernetesEngine) failingReason(ctx context.Context, deploymentID string) (string, error) {
+	pods, err := e.client.CoreV1().Pods(e.config.Namespace).List(ctx, metav1.ListOptions{LabelSelector: deploymentSelector(deploymentID)})
+	if err != nil {
+		return "", fmt.Errorf("listing pods: %w", err)
+	}
+
+	for _, pod := range pods.Items {
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.State.Waiting == nil || !waitingFailures[cs.State.Waiting.Reason] {
+				continue
+			}
+			reason := fmt.Sprintf("%s: container %q is in %s", pod.Name, cs.Name, cs.State.Waiting.Reason)
+			if cs.LastTerminationState.Terminated != nil {
+				reason += fmt.Sprintf(" (last exit code %d)", cs.LastTerminationState.Terminated.ExitCode)
+			}
+			return reason, nil
+		}
+	}
+	return "", nil
+}
+
+// progressSource reads the progress of the sink from its database and from the metrics served by its pod,
+// both being only reachable when the server runs in the cluster.
+func (e *KubernetesEngine) progressSource(ctx context.Context, deploymentID string, packageInfo *pbsinksvc.PackageInfo) *progress.Source {
+	out := &progress.Source{OutputModuleHash: packageInfo.GetOutputModuleHash()}
+
+	if secret, err := e.client.CoreV1().Secrets(e.config.Namespace).Get(ctx, secretsName(deploymentID), metav1.GetOptions{}); err == nil {
+		out.CursorDSN = string(secret.Data["CURSOR_DSN"])
+	}
+
+	selector := labels.SelectorFromSet(deploymentLabels(deploymentID, componentSink)).String()
+	pods, err := e.client.CoreV1().Pods(e.config.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil || len(pods.Items) == 0 {
+		return out
+	}
+	pod := pods.Items[0]
+
+	if pod.Status.PodIP != "" {
+		out.MetricsURL = fmt.Sprintf("http://%s/metrics", net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(sinkMetricsPort)))
+	}
+	out.Logs = func(ctx context.Context) ([]string, error) {
+		stream, err := e.client.CoreV1().Pods(e.config.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{TailLines: ptr(int64(200))}).Stream(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer stream.Close()
+
+		var lines []string
+		scanner := bufio.NewScanner(stream)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		return lines, scanner.Err()
+	}
+	return out
+}
+
+func replicas(r *int32) int32 {
+	if r == nil {
+		return 1
+	}
+	return *r
+}
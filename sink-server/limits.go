@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	connect_go "connectrpc.com/connect"
+	"github.com/streamingfast/dauth"
+	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
+	sinkcontext "github.com/streamingfast/substreams/sink-server/context"
+	"github.com/streamingfast/substreams/sink-server/registry"
+	"github.com/streamingfast/substreams/sink-server/resources"
+)
+
+// Limits bound what the deployments of the server can use.
+type Limits struct {
+	// MaxDeploymentsPerOwner is the number of deployments an owner can have starting, running, paused or
+	// failing at the same time, 0 for no limit. The owner is the authenticated user of the request, all the deployments
+	// have the same owner when the server does not authenticate its requests.
+	MaxDeploymentsPerOwner int
+
+	// Sizes are the tiers the deployments can request with the 'SF_SIZE' parameter, DefaultSize being used
+	// when they do not. The services of the deployments are not limited without a size.
+	Sizes       resources.Sizes
+	DefaultSize string
+}
+
+func (l Limits) validate() error {
+	if l.DefaultSize == "" {
+		return nil
+	}
+	if _, err := l.Sizes.Get(l.DefaultSize); err != nil {
+		return fmt.Errorf("invalid default size: %w", err)
+	}
+	return nil
+}
+
+// withSize sets the size of the deployment from its parameters in the context.
+func (s *server) withSize(ctx context.Context) (context.Context, error) {
+	name := sinkcontext.GetParameterMap(ctx)[resources.SizeParameter]
+	if name == "" {
+		name = s.limits.DefaultSize
+	}
+	if name == "" {
+		return ctx, nil
+	}
+
+	size, err := s.limits.Sizes.Get(name)
+	if err != nil {
+		return nil, connect_go.NewError(connect_go.CodeInvalidArgument, err)
+	}
+	return sinkcontext.SetSize(ctx, size), nil
+}
+
+// withDeploymentParameters restores the mode, parameters and size given when the deployment was created,
+// for the requests re-creating its services.
+func (s *server) withDeploymentParameters(ctx context.Context, deploymentID string) (context.Context, error) {
+	record, err := s.registry.Record(deploymentID)
+	switch {
+	case errors.Is(err, registry.ErrNotFound):
+		// the deployment predates the registry
+	case err != nil:
+		return nil, fmt.Errorf("reading deployment %q: %w", deploymentID, err)
+	default:
+		ctx = sinkcontext.SetProductionMode(ctx, !record.DevelopmentMode)
+		ctx = sinkcontext.SetParameterMap(ctx, parameterMap(record.Parameters))
+	}
+	return s.withSize(ctx)
+}
+
+func parameterMap(params []*pbsinksvc.Parameter) map[string]string {
+	out := make(map[string]string, len(params))
+	for _, param := range params {
+		out[param.Key] = param.Value
+	}
+	return out
+}
+
+// checkQuota refuses to start the deployment when its owner already has the maximum number of active
+// deployments, not counting this one. The callers hold quotaLock until the deployment is started.
+func (s *server) checkQuota(ctx context.Context, deploymentID string) error {
+	max := s.limits.MaxDeploymentsPerOwner
+	if max <= 0 {
+		return nil
+	}
+	owner := dauth.FromContext(ctx).UserID()
+
+	deployments, err := s.engine.List(ctx, s.logger)
+	if err != nil {
+		return fmt.Errorf("listing deployments: %w", err)
+	}
+
+	active := 0
+	for _, dep := range deployments {
+		if dep.Id == deploymentID || !isActive(dep.Status) {
+			continue
+		}
+		// deployments predating the registry have no known owner
+		var depOwner string
+		if record, err := s.registry.Record(dep.Id); err == nil {
+			depOwner = record.Owner
+		} else if !errors.Is(err, registry.ErrNotFound) {
+			return fmt.Errorf("reading deployment %q: %w", dep.Id, err)
+		}
+		if depOwner == owner {
+			active++
+		}
+	}
+
+	if active >= max {
+		return connect_go.NewError(connect_go.CodeResourceExhausted, fmt.Errorf("%d active deployments out of %d allowed: stop or remove one before starting another one", active, max))
+	}
+	return nil
+}
+
+func isActive(status pbsinksvc.DeploymentStatus) bool {
+	switch status {
+	case pbsinksvc.DeploymentStatus_RUNNING, pbsinksvc.DeploymentStatus_FAILING, pbsinksvc.DeploymentStatus_PAUSED,
+		pbsinksvc.DeploymentStatus_STARTING, pbsinksvc.DeploymentStatus_RESUMING:
+		return true
+	}
+	return false
+}
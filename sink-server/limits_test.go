@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	connect_go "connectrpc.com/connect"
+	"github.com/streamingfast/dauth"
+	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
+	sinkcontext "github.com/streamingfast/substreams/sink-server/context"
+	"github.com/streamingfast/substreams/sink-server/notify"
+	"github.com/streamingfast/substreams/sink-server/registry"
+	"github.com/streamingfast/substreams/sink-server/resources"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type listEngine struct {
+	Engine
+	deployments []*pbsinksvc.DeploymentWithStatus
+}
+
+func (e *listEngine) List(context.Context, *zap.Logger) ([]*pbsinksvc.DeploymentWithStatus, error) {
+	return e.deployments, nil
+}
+
+func newTestServer(t *testing.T, engine Engine, limits Limits) *server {
+	t.Helper()
+	reg, err := registry.Open(filepath.Join(t.TempDir(), "registry.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { reg.Close() })
+	return &server{engine: engine, registry: reg, limits: limits, logger: zap.NewNop(), monitor: notify.NewMonitor(0), broker: notify.NewBroker()}
+}
+
+func withUser(userID string) context.Context {
+	return dauth.WithTrustedHeaders(context.Background(), dauth.TrustedHeaders{dauth.SFHeaderUserID: userID})
+}
+
+func TestCheckQuota(t *testing.T) {
+	engine := &listEngine{deployments: []*pbsinksvc.DeploymentWithStatus{
+		{Id: "alice1", Status: pbsinksvc.DeploymentStatus_RUNNING},
+		{Id: "alice2", Status: pbsinksvc.DeploymentStatus_STOPPED},
+		{Id: "bob1", Status: pbsinksvc.DeploymentStatus_PAUSED},
+		{Id: "dave1", Status: pbsinksvc.DeploymentStatus_STARTING},
+		{Id: "erin1", Status: pbsinksvc.DeploymentStatus_RESUMING},
+	}}
+	s := newTestServer(t, engine, Limits{MaxDeploymentsPerOwner: 1})
+	for id, owner := range map[string]string{"alice1": "alice", "alice2": "alice", "bob1": "bob", "dave1": "dave", "erin1": "erin"} {
+		require.NoError(t, s.registry.RecordDeploy(&pbsinksvc.DeploymentRecord{Id: id, Owner: owner}, ""))
+	}
+
+	err := s.checkQuota(withUser("alice"), "new")
+	assert.Equal(t, connect_go.CodeResourceExhausted, connect_go.CodeOf(err))
+	// the stopped deployment can be resumed once the active one is not counted
+	assert.NoError(t, s.checkQuota(withUser("alice"), "alice1"))
+	assert.NoError(t, s.checkQuota(withUser("carol"), "new"))
+	// deployments not yet running are counted
+	assert.Equal(t, connect_go.CodeResourceExhausted, connect_go.CodeOf(s.checkQuota(withUser("dave"), "new")))
+	assert.Equal(t, connect_go.CodeResourceExhausted, connect_go.CodeOf(s.checkQuota(withUser("erin"), "new")))
+
+	s.limits.MaxDeploymentsPerOwner = 2
+	assert.NoError(t, s.checkQuota(withUser("alice"), "new"))
+
+	s.limits.MaxDeploymentsPerOwner = 0
+	assert.NoError(t, s.checkQuota(withUser("bob"), "new"))
+}
+
+func TestWithSize(t *testing.T) {
+	s := newTestServer(t, nil, Limits{Sizes: resources.DefaultSizes, DefaultSize: "medium"})
+
+	ctx, err := s.withSize(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "medium", sinkcontext.GetSize(ctx).Name)
+
+	ctx, err = s.withSize(sinkcontext.SetParameterMap(context.Background(), map[string]string{resources.SizeParameter: "large"}))
+	require.NoError(t, err)
+	assert.Equal(t, "large", sinkcontext.GetSize(ctx).Name)
+
+	_, err = s.withSize(sinkcontext.SetParameterMap(context.Background(), map[string]string{resources.SizeParameter: "huge"}))
+	assert.Equal(t, connect_go.CodeInvalidArgument, connect_go.CodeOf(err))
+
+	// restored from the registry on update
+	require.NoError(t, s.registry.RecordDeploy(&pbsinksvc.DeploymentRecord{Id: "dep", Parameters: []*pbsinksvc.Parameter{{Key: resources.SizeParameter, Value: "small"}}}, ""))
+	ctx, err = s.withDeploymentParameters(context.Background(), "dep")
+	require.NoError(t, err)
+	assert.Equal(t, "small", sinkcontext.GetSize(ctx).Name)
+	assert.True(t, sinkcontext.GetProductionMode(ctx))
+
+	s.limits = Limits{}
+	ctx, err = s.withSize(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, sinkcontext.GetSize(ctx))
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
+	"github.com/streamingfast/substreams/sink-server/notify"
+	"go.uber.org/zap"
+)
+
+// Notifications configure the events emitted when the deployments change status, or when their sink
+// stalls, to the WatchDeployments subscribers and to webhooks.
+type Notifications struct {
+	// CheckInterval is how often the deployments are listed to detect the events, 30 seconds when zero
+	CheckInterval time.Duration
+	// StallTimeout is the period without any processed block after which a running sink is reported as
+	// stalled, 0 to disable it
+	StallTimeout time.Duration
+
+	// Webhooks are the URLs receiving the events of all the deployments as JSON POST requests, signed with
+	// WebhookSecret when it is set
+	Webhooks      []string
+	WebhookSecret string
+}
+
+func (n Notifications) validate() error {
+	for _, webhook := range n.Webhooks {
+		u, err := url.Parse(webhook)
+		if err != nil {
+			return fmt.Errorf("invalid webhook URL %q: %w", webhook, err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("invalid webhook URL %q: expecting an http or https URL", webhook)
+		}
+	}
+	return nil
+}
+
+// watchDeployments lists the deployments every CheckInterval until the context is done, recording their
+// status and publishing their events.
+func (s *server) watchDeployments(ctx context.Context) {
+	interval := s.notifications.CheckInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	for _, webhook := range s.webhooks {
+		go webhook.Run(ctx)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.checkDeployments(ctx, time.Now())
+		}
+	}
+}
+
+func (s *server) checkDeployments(ctx context.Context, now time.Time) {
+	deployments, err := s.engine.List(ctx, s.logger)
+	if err != nil {
+		s.logger.Warn("cannot list deployments to check their status", zap.Error(err))
+		return
+	}
+
+	for _, dep := range deployments {
+		s.observeStatus(dep.Id, dep.Status, dep.Reason)
+	}
+	for _, event := range s.monitor.Observe(deployments, now) {
+		s.logger.Info("deployment event", zap.String("deployment_id", event.DeploymentId), zap.String("kind", event.Kind), zap.Stringer("previous_status", event.PreviousStatus), zap.Stringer("new_status", event.NewStatus), zap.String("reason", event.Reason))
+		if dropped := s.broker.Publish(event); dropped != 0 {
+			s.logger.Warn("event dropped for slow watchers", zap.String("deployment_id", event.DeploymentId), zap.Int("watchers", dropped))
+		}
+		for _, webhook := range s.webhooks {
+			webhook.Notify(event)
+		}
+	}
+}
+
+// watches tells if the event is about one of the watched deployments, or about a deployment readable by
+// the user when none were given.
+func (s *server) watches(ctx context.Context, deploymentIDs []string, event *pbsinksvc.DeploymentEvent) bool {
+	if len(deploymentIDs) != 0 {
+		return slices.Contains(deploymentIDs, event.DeploymentId)
+	}
+	return s.checkOwner(ctx, event.DeploymentId) == nil
+}
+
+func newWebhooks(n Notifications, logger *zap.Logger) []*notify.Webhook {
+	out := make([]*notify.Webhook, 0, len(n.Webhooks))
+	for _, webhook := range n.Webhooks {
+		out = append(out, notify.NewWebhook(webhook, n.WebhookSecret, logger))
+	}
+	return out
+}
@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"sync"
+
+	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
+)
+
+// subscriberBuffer is the number of events kept for a subscriber that is not reading them.
+const subscriberBuffer = 64
+
+// Broker fans the events out to its subscribers, dropping them for the subscribers too slow to keep up.
+type Broker struct {
+	mutex       sync.Mutex
+	subscribers map[chan *pbsinksvc.DeploymentEvent]bool
+}
+
+func NewBroker() *Broker {
+	return &Broker{subscribers: make(map[chan *pbsinksvc.DeploymentEvent]bool)}
+}
+
+// Subscribe returns the channel receiving the events published from now on, until `unsubscribe` is called.
+func (b *Broker) Subscribe() (events <-chan *pbsinksvc.DeploymentEvent, unsubscribe func()) {
+	ch := make(chan *pbsinksvc.DeploymentEvent, subscriberBuffer)
+	b.mutex.Lock()
+	b.subscribers[ch] = true
+	b.mutex.Unlock()
+
+	return ch, func() {
+		b.mutex.Lock()
+		delete(b.subscribers, ch)
+		b.mutex.Unlock()
+	}
+}
+
+// Publish sends the event to the subscribers, it returns the number of subscribers that missed it.
+func (b *Broker) Publish(event *pbsinksvc.DeploymentEvent) (dropped int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			dropped++
+		}
+	}
+	return dropped
+}
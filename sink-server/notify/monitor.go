@@ -0,0 +1,95 @@
+package notify
+
+import (
+	"fmt"
+	"time"
+
+	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	KindStatus  = "status"
+	KindStalled = "stalled"
+)
+
+// Monitor turns the successive observations of the deployments into events: a change of status, or a sink
+// running without processing any block for the stall timeout.
+type Monitor struct {
+	stallTimeout time.Duration
+	deployments  map[string]*observed
+}
+
+type observed struct {
+	status    pbsinksvc.DeploymentStatus
+	lastBlock uint64
+	// progressAt is when the sink last processed a block, or when the status of the deployment last changed
+	progressAt time.Time
+	stalled    bool
+}
+
+// NewMonitor returns a monitor reporting the sinks that did not process any block for `stallTimeout`,
+// 0 disabling it.
+func NewMonitor(stallTimeout time.Duration) *Monitor {
+	return &Monitor{
+		stallTimeout: stallTimeout,
+		deployments:  make(map[string]*observed),
+	}
+}
+
+// Observe returns the events between the previous observation of the deployments and this one. The
+// deployments seen for the first time do not produce any event, those that are gone are forgotten. A
+// stalled sink is only reported once, until it processes a block again.
+func (m *Monitor) Observe(deployments []*pbsinksvc.DeploymentWithStatus, now time.Time) (out []*pbsinksvc.DeploymentEvent) {
+	seen := make(map[string]bool, len(deployments))
+	for _, dep := range deployments {
+		seen[dep.Id] = true
+		block := dep.Progress.GetLastProcessedBlock()
+
+		prev, found := m.deployments[dep.Id]
+		if !found {
+			m.deployments[dep.Id] = &observed{status: dep.Status, lastBlock: block, progressAt: now}
+			continue
+		}
+
+		if dep.Status != prev.status {
+			out = append(out, newEvent(dep, KindStatus, prev.status, dep.Reason, now))
+			prev.status = dep.Status
+			prev.progressAt = now
+			prev.stalled = false
+		}
+		if block != prev.lastBlock {
+			prev.lastBlock = block
+			prev.progressAt = now
+			prev.stalled = false
+		}
+
+		if m.stallTimeout <= 0 || prev.stalled || dep.Status != pbsinksvc.DeploymentStatus_RUNNING || dep.Progress == nil {
+			continue
+		}
+		if stalledFor := now.Sub(prev.progressAt); stalledFor >= m.stallTimeout {
+			prev.stalled = true
+			out = append(out, newEvent(dep, KindStalled, dep.Status, fmt.Sprintf("no block processed for %s", stalledFor.Round(time.Second)), now))
+		}
+	}
+
+	for id := range m.deployments {
+		if !seen[id] {
+			delete(m.deployments, id)
+		}
+	}
+	return out
+}
+
+func newEvent(dep *pbsinksvc.DeploymentWithStatus, kind string, previous pbsinksvc.DeploymentStatus, reason string, now time.Time) *pbsinksvc.DeploymentEvent {
+	return &pbsinksvc.DeploymentEvent{
+		DeploymentId:   dep.Id,
+		Timestamp:      timestamppb.New(now),
+		Kind:           kind,
+		PreviousStatus: previous,
+		NewStatus:      dep.Status,
+		Reason:         reason,
+		Progress:       dep.Progress,
+		PackageInfo:    dep.PackageInfo,
+	}
+}
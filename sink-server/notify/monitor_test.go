@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"testing"
+	"time"
+
+	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func deployment(id string, status pbsinksvc.DeploymentStatus, block uint64) *pbsinksvc.DeploymentWithStatus {
+	return &pbsinksvc.DeploymentWithStatus{Id: id, Status: status, Progress: &pbsinksvc.SinkProgress{LastProcessedBlock: block}}
+}
+
+func TestMonitor_Status(t *testing.T) {
+	m := NewMonitor(0)
+	now := time.Now()
+
+	assert.Empty(t, m.Observe([]*pbsinksvc.DeploymentWithStatus{deployment("a", pbsinksvc.DeploymentStatus_RUNNING, 10)}, now))
+	assert.Empty(t, m.Observe([]*pbsinksvc.DeploymentWithStatus{deployment("a", pbsinksvc.DeploymentStatus_RUNNING, 10)}, now.Add(time.Hour)))
+
+	failing := deployment("a", pbsinksvc.DeploymentStatus_FAILING, 10)
+	failing.Reason = "sink: exited"
+	events := m.Observe([]*pbsinksvc.DeploymentWithStatus{failing}, now.Add(2*time.Hour))
+	require.Len(t, events, 1)
+	assert.Equal(t, "a", events[0].DeploymentId)
+	assert.Equal(t, KindStatus, events[0].Kind)
+	assert.Equal(t, pbsinksvc.DeploymentStatus_RUNNING, events[0].PreviousStatus)
+	assert.Equal(t, pbsinksvc.DeploymentStatus_FAILING, events[0].NewStatus)
+	assert.Equal(t, "sink: exited", events[0].Reason)
+
+	// gone, then seen again as new
+	assert.Empty(t, m.Observe(nil, now.Add(3*time.Hour)))
+	assert.Empty(t, m.Observe([]*pbsinksvc.DeploymentWithStatus{deployment("a", pbsinksvc.DeploymentStatus_RUNNING, 10)}, now.Add(4*time.Hour)))
+}
+
+func TestMonitor_Stalled(t *testing.T) {
+	m := NewMonitor(10 * time.Minute)
+	now := time.Now()
+	observe := func(elapsed time.Duration, status pbsinksvc.DeploymentStatus, block uint64) []*pbsinksvc.DeploymentEvent {
+		return m.Observe([]*pbsinksvc.DeploymentWithStatus{deployment("a", status, block)}, now.Add(elapsed))
+	}
+
+	assert.Empty(t, observe(0, pbsinksvc.DeploymentStatus_RUNNING, 10))
+	assert.Empty(t, observe(5*time.Minute, pbsinksvc.DeploymentStatus_RUNNING, 20))
+	assert.Empty(t, observe(14*time.Minute, pbsinksvc.DeploymentStatus_RUNNING, 20))
+
+	events := observe(15*time.Minute, pbsinksvc.DeploymentStatus_RUNNING, 20)
+	require.Len(t, events, 1)
+	assert.Equal(t, KindStalled, events[0].Kind)
+	assert.Equal(t, "no block processed for 10m0s", events[0].Reason)
+	assert.Equal(t, uint64(20), events[0].Progress.LastProcessedBlock)
+
+	// reported once, until it progresses again
+	assert.Empty(t, observe(30*time.Minute, pbsinksvc.DeploymentStatus_RUNNING, 20))
+	assert.Empty(t, observe(31*time.Minute, pbsinksvc.DeploymentStatus_RUNNING, 21))
+	assert.Len(t, observe(41*time.Minute, pbsinksvc.DeploymentStatus_RUNNING, 21), 1)
+
+	// paused sinks do not stall
+	assert.Len(t, observe(42*time.Minute, pbsinksvc.DeploymentStatus_PAUSED, 21), 1)
+	assert.Empty(t, observe(60*time.Minute, pbsinksvc.DeploymentStatus_PAUSED, 21))
+}
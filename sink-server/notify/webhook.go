@@ -0,0 +1,141 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	// SignatureHeader holds 'sha256=' followed by the hex HMAC-SHA256 of '<timestamp>.<body>' with the secret
+	// of the webhook, TimestampHeader holding the unix time at which the request was signed.
+	SignatureHeader = "X-Substreams-Signature"
+	TimestampHeader = "X-Substreams-Timestamp"
+	EventHeader     = "X-Substreams-Event"
+
+	webhookQueueSize = 256
+)
+
+var (
+	webhookTimeout     = 10 * time.Second
+	webhookMaxAttempts = 5
+	// webhookRetryDelay is doubled after each failed attempt
+	webhookRetryDelay = 1 * time.Second
+)
+
+// Webhook posts the events as JSON to an URL, in the order they were notified. Failed deliveries are
+// retried on network errors and on 5xx, 408 and 429 responses, the events being dropped after the last
+// attempt or when the queue is full.
+type Webhook struct {
+	url    string
+	secret []byte
+	client *http.Client
+	queue  chan *pbsinksvc.DeploymentEvent
+	logger *zap.Logger
+}
+
+func NewWebhook(url string, secret string, logger *zap.Logger) *Webhook {
+	return &Webhook{
+		url:    url,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: webhookTimeout},
+		queue:  make(chan *pbsinksvc.DeploymentEvent, webhookQueueSize),
+		logger: logger.With(zap.String("webhook", url)),
+	}
+}
+
+// Notify queues the event for delivery by Run, without blocking.
+func (w *Webhook) Notify(event *pbsinksvc.DeploymentEvent) {
+	select {
+	case w.queue <- event:
+	default:
+		w.logger.Warn("webhook queue is full, dropping event", zap.String("deployment_id", event.DeploymentId), zap.String("kind", event.Kind))
+	}
+}
+
+// Run delivers the queued events until the context is done.
+func (w *Webhook) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-w.queue:
+			if err := w.deliver(ctx, event); err != nil {
+				w.logger.Warn("cannot deliver event to webhook", zap.String("deployment_id", event.DeploymentId), zap.String("kind", event.Kind), zap.Error(err))
+			}
+		}
+	}
+}
+
+func (w *Webhook) deliver(ctx context.Context, event *pbsinksvc.DeploymentEvent) error {
+	body, err := protojson.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshalling event: %w", err)
+	}
+
+	delay := webhookRetryDelay
+	for attempt := 1; ; attempt++ {
+		retry, err := w.post(ctx, event.Kind, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= webhookMaxAttempts {
+			return fmt.Errorf("attempt %d: %w", attempt, err)
+		}
+		w.logger.Debug("webhook delivery failed, retrying", zap.Int("attempt", attempt), zap.Duration("delay", delay), zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func (w *Webhook) post(ctx context.Context, kind string, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, kind)
+	req.Header.Set(TimestampHeader, timestamp)
+	if len(w.secret) != 0 {
+		req.Header.Set(SignatureHeader, Signature(w.secret, timestamp, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("unexpected response status %q", resp.Status)
+}
+
+// Signature returns the value of the SignatureHeader of a request with the given TimestampHeader and body.
+func Signature(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
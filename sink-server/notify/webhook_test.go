@@ -0,0 +1,92 @@
+package notify
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestWebhook(t *testing.T) {
+	webhookRetryDelay = time.Millisecond
+
+	var mutex sync.Mutex
+	var attempts int
+	var received []*pbsinksvc.DeploymentEvent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, Signature([]byte("secret"), r.Header.Get(TimestampHeader), body), r.Header.Get(SignatureHeader))
+		assert.Equal(t, KindStatus, r.Header.Get(EventHeader))
+
+		event := &pbsinksvc.DeploymentEvent{}
+		assert.NoError(t, protojson.Unmarshal(body, event))
+		received = append(received, event)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	webhook := NewWebhook(srv.URL, "secret", zap.NewNop())
+	go webhook.Run(ctx)
+
+	webhook.Notify(&pbsinksvc.DeploymentEvent{DeploymentId: "a", Kind: KindStatus, NewStatus: pbsinksvc.DeploymentStatus_FAILING})
+	require.Eventually(t, func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(received) == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, "a", received[0].DeploymentId)
+	assert.Equal(t, pbsinksvc.DeploymentStatus_FAILING, received[0].NewStatus)
+}
+
+func TestWebhook_NoRetry(t *testing.T) {
+	webhookRetryDelay = time.Millisecond
+
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		assert.Empty(t, r.Header.Get(SignatureHeader))
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	webhook := NewWebhook(srv.URL, "", zap.NewNop())
+	err := webhook.deliver(context.Background(), &pbsinksvc.DeploymentEvent{DeploymentId: "a"})
+	assert.ErrorContains(t, err, "400 Bad Request")
+	assert.Equal(t, 1, attempts)
+}
+
+func TestBroker(t *testing.T) {
+	broker := NewBroker()
+	events, unsubscribe := broker.Subscribe()
+
+	assert.Equal(t, 0, broker.Publish(&pbsinksvc.DeploymentEvent{DeploymentId: "a"}))
+	assert.Equal(t, "a", (<-events).DeploymentId)
+
+	for i := 0; i < subscriberBuffer; i++ {
+		broker.Publish(&pbsinksvc.DeploymentEvent{})
+	}
+	assert.Equal(t, 1, broker.Publish(&pbsinksvc.DeploymentEvent{}))
+
+	unsubscribe()
+	assert.Equal(t, 0, broker.Publish(&pbsinksvc.DeploymentEvent{}))
+}
@@ -0,0 +1,498 @@
+package process
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	pbsql "github.com/streamingfast/substreams-sink-sql/pb/sf/substreams/sink/sql/v1"
+	"github.com/streamingfast/substreams/manifest"
+	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"github.com/streamingfast/substreams/sink-server/progress"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+type Config struct {
+	// SinkBinary is the path of the substreams-sink-sql binary, looked up in the PATH if it has no directory
+	SinkBinary string
+	// DSN is the database used by the sinks, when empty an embedded PostgreSQL is started for each deployment
+	DSN string
+	// PostgresBinDir is the directory of the `initdb` and `postgres` binaries of the embedded PostgreSQL,
+	// looked up in the PATH when empty
+	PostgresBinDir string
+}
+
+// ProcessEngine runs the sinks of the deployments, and their embedded database, as supervised child
+// processes: failed processes are restarted, pausing a deployment suspends its sink process and the
+// output of the processes is written to log files under the deployment folder.
+type ProcessEngine struct {
+	mutex    sync.Mutex
+	dir      string
+	endpoint string
+	token    string
+	config   Config
+
+	sinkBinary     string
+	initdbBinary   string
+	postgresBinary string
+
+	deployments map[string]*deployment
+	progress    *progress.Tracker
+}
+
+type deployment struct {
+	services []*supervisor // in start order, the sink being the last one
+	sink     *supervisor
+}
+
+func NewEngine(dir string, sfToken string, endpoint string, config Config) (*ProcessEngine, error) {
+	out := &ProcessEngine{
+		dir:         dir,
+		token:       sfToken,
+		endpoint:    endpoint,
+		config:      config,
+		deployments: map[string]*deployment{},
+		progress:    progress.NewTracker(),
+	}
+
+	sinkBinary := config.SinkBinary
+	if sinkBinary == "" {
+		sinkBinary = "substreams-sink-sql"
+	}
+	var err error
+	if out.sinkBinary, err = exec.LookPath(sinkBinary); err != nil {
+		return nil, fmt.Errorf("cannot find sink binary: %w", err)
+	}
+
+	if config.DSN == "" {
+		if out.initdbBinary, err = lookPostgresBinary(config.PostgresBinDir, "initdb"); err != nil {
+			return nil, fmt.Errorf("cannot find embedded postgres binaries, set a DSN or install PostgreSQL: %w", err)
+		}
+		if out.postgresBinary, err = lookPostgresBinary(config.PostgresBinDir, "postgres"); err != nil {
+			return nil, fmt.Errorf("cannot find embedded postgres binaries, set a DSN or install PostgreSQL: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func lookPostgresBinary(binDir, name string) (string, error) {
+	if binDir != "" {
+		name = filepath.Join(binDir, name)
+	}
+	return exec.LookPath(name)
+}
+
+type deploymentInfo struct {
+	PackageInfo  *pbsinksvc.PackageInfo
+	ServiceInfo  map[string]string
+	DSN          string
+	SinkEngine   string
+	PostgresPort uint32 `json:",omitempty"` // set when using the embedded postgres
+	MetricsPort  uint32 `json:",omitempty"`
+	Unsupported  []string
+}
+
+func (e *ProcessEngine) deploymentDir(deploymentID string) string {
+	return filepath.Join(e.dir, deploymentID)
+}
+
+func (e *ProcessEngine) writeDeploymentInfo(deploymentID string, info *deploymentInfo) error {
+	content, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(e.deploymentDir(deploymentID), "info.json"), content, 0644)
+}
+
+func (e *ProcessEngine) readDeploymentInfo(deploymentID string) (info *deploymentInfo, err error) {
+	content, err := os.ReadFile(filepath.Join(e.deploymentDir(deploymentID), "info.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, &info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (e *ProcessEngine) Create(ctx context.Context, deploymentID string, pkg *pbsubstreams.Package, zlog *zap.Logger) (*pbsinksvc.InfoResponse, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if err := e.prepare(deploymentID, pkg, nil); err != nil {
+		return nil, err
+	}
+	if err := e.start(deploymentID, zlog); err != nil {
+		return nil, err
+	}
+
+	return e.info(ctx, deploymentID, zlog)
+}
+
+func (e *ProcessEngine) Update(ctx context.Context, deploymentID string, pkg *pbsubstreams.Package, reset bool, zlog *zap.Logger) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	previous, err := e.readDeploymentInfo(deploymentID)
+	if err != nil {
+		return fmt.Errorf("cannot read deployment info: %w", err)
+	}
+	if reset && previous.PostgresPort == 0 {
+		return fmt.Errorf("cannot reset deployment %q: it uses an external database, drop its tables then update without reset", deploymentID)
+	}
+
+	e.stop(deploymentID)
+
+	if reset {
+		if err := os.RemoveAll(filepath.Join(e.deploymentDir(deploymentID), "data")); err != nil {
+			return fmt.Errorf("cannot cleanup the deployment data folder: %w", err)
+		}
+		e.progress.Forget(deploymentID)
+	}
+
+	if err := e.prepare(deploymentID, pkg, previous); err != nil {
+		return err
+	}
+	return e.start(deploymentID, zlog)
+}
+
+func (e *ProcessEngine) UpdateBlueGreen(ctx context.Context, deploymentID string, pkg *pbsubstreams.Package, zlog *zap.Logger) error {
+	return fmt.Errorf("blue/green updates by the process engine: %w", errors.ErrUnsupported)
+}
+
+func (e *ProcessEngine) Rollback(ctx context.Context, deploymentID string, zlog *zap.Logger) (*pbsinksvc.RollbackResponse, error) {
+	return nil, fmt.Errorf("rollbacks by the process engine: %w", errors.ErrUnsupported)
+}
+
+// prepare writes the package and the info of the deployment in its folder, keeping the ports of the
+// `previous` info if any.
+func (e *ProcessEngine) prepare(deploymentID string, pkg *pbsubstreams.Package, previous *deploymentInfo) error {
+	if pkg.SinkConfig.GetTypeUrl() != "sf.substreams.sink.sql.v1.Service" {
+		return fmt.Errorf("invalid sinkconfig type: %q. Only sf.substreams.sink.sql.v1.Service is supported for now.", pkg.SinkConfig.GetTypeUrl())
+	}
+	sinkConfig := &pbsql.Service{}
+	if err := pkg.SinkConfig.UnmarshalTo(sinkConfig); err != nil {
+		return fmt.Errorf("cannot unmarshal sinkconfig: %w", err)
+	}
+
+	dir := e.deploymentDir(deploymentID)
+	for _, sub := range []string{"config", "logs", filepath.Join("data", "sink")} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return fmt.Errorf("creating folder %q: %w", sub, err)
+		}
+	}
+
+	pkgContent, err := proto.Marshal(pkg)
+	if err != nil {
+		return fmt.Errorf("marshalling package: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config", "substreams.spkg"), pkgContent, 0644); err != nil {
+		return fmt.Errorf("writing file: %w", err)
+	}
+
+	hash, err := manifest.OutputModuleHash(pkg)
+	if err != nil {
+		return err
+	}
+	info := &deploymentInfo{
+		PackageInfo: &pbsinksvc.PackageInfo{
+			Name:             pkg.PackageMeta[0].Name,
+			Version:          pkg.PackageMeta[0].Version,
+			OutputModuleName: pkg.SinkModule,
+			OutputModuleHash: hash,
+		},
+		ServiceInfo: map[string]string{},
+		DSN:         e.config.DSN,
+		SinkEngine:  "postgres",
+	}
+	if sinkConfig.Engine == pbsql.Service_clickhouse {
+		info.SinkEngine = "clickhouse"
+	}
+
+	if info.DSN == "" {
+		if info.SinkEngine != "postgres" {
+			return fmt.Errorf("the embedded database only supports postgres sinks, set a DSN to use %s", info.SinkEngine)
+		}
+		if previous != nil && previous.PostgresPort != 0 {
+			info.PostgresPort = previous.PostgresPort
+		} else if info.PostgresPort, err = freePort(); err != nil {
+			return fmt.Errorf("cannot allocate a port for postgres: %w", err)
+		}
+		info.DSN = embeddedPostgresDSN(info.PostgresPort)
+		info.ServiceInfo[postgresServiceName(deploymentID)] = fmt.Sprintf("PostgreSQL process available at DSN: '%s', logs in %q", info.DSN, e.logPath(deploymentID, postgresServiceName(deploymentID)))
+	}
+	if previous != nil && previous.MetricsPort != 0 {
+		info.MetricsPort = previous.MetricsPort
+	} else if info.MetricsPort, err = freePort(); err != nil {
+		return fmt.Errorf("cannot allocate a port for the sink metrics: %w", err)
+	}
+	info.ServiceInfo[sinkServiceName(deploymentID)] = fmt.Sprintf("Sink process (metrics at http://localhost:%d/metrics), logs in %q", info.MetricsPort, e.logPath(deploymentID, sinkServiceName(deploymentID)))
+
+	if sinkConfig.PostgraphileFrontend.GetEnabled() {
+		info.Unsupported = append(info.Unsupported, "postgraphile")
+	}
+	if sinkConfig.RestFrontend.GetEnabled() {
+		info.Unsupported = append(info.Unsupported, "rest")
+	}
+	if sinkConfig.DbtConfig.GetEnabled() {
+		info.Unsupported = append(info.Unsupported, "dbt")
+	}
+
+	return e.writeDeploymentInfo(deploymentID, info)
+}
+
+func (e *ProcessEngine) logPath(deploymentID, serviceName string) string {
+	return filepath.Join(e.deploymentDir(deploymentID), "logs", serviceName+".log")
+}
+
+// start launches the processes of the deployment, which must not be running.
+func (e *ProcessEngine) start(deploymentID string, zlog *zap.Logger) error {
+	info, err := e.readDeploymentInfo(deploymentID)
+	if err != nil {
+		return fmt.Errorf("cannot read deployment info: %w", err)
+	}
+
+	dep := &deployment{}
+	if info.PostgresPort != 0 {
+		dep.services = append(dep.services, e.newPostgres(deploymentID, info.PostgresPort, zlog))
+	}
+	dep.sink = e.newSink(deploymentID, info, zlog)
+	dep.services = append(dep.services, dep.sink)
+
+	for _, svc := range dep.services {
+		svc.Start()
+	}
+	e.deployments[deploymentID] = dep
+	return nil
+}
+
+func (e *ProcessEngine) stop(deploymentID string) {
+	dep, found := e.deployments[deploymentID]
+	if !found {
+		return
+	}
+	// the sink first, then the database
+	for i := len(dep.services) - 1; i >= 0; i-- {
+		dep.services[i].Stop()
+	}
+	delete(e.deployments, deploymentID)
+}
+
+func (e *ProcessEngine) Info(ctx context.Context, deploymentID string, zlog *zap.Logger) (*pbsinksvc.InfoResponse, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.info(ctx, deploymentID, zlog)
+}
+
+func (e *ProcessEngine) info(ctx context.Context, deploymentID string, zlog *zap.Logger) (*pbsinksvc.InfoResponse, error) {
+	info, err := e.readDeploymentInfo(deploymentID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read Service Info: %w", err)
+	}
+
+	status := pbsinksvc.DeploymentStatus_STOPPED
+	var reasons []string
+	if dep, found := e.deployments[deploymentID]; found {
+		status = pbsinksvc.DeploymentStatus_RUNNING
+		for _, svc := range dep.services {
+			state, lastErr, restarts := svc.State()
+			switch state {
+			case processFailing:
+				status = pbsinksvc.DeploymentStatus_FAILING
+				reasons = append(reasons, fmt.Sprintf("%s: %s (restarted %d times)", strings.TrimPrefix(svc.name, deploymentID+"-"), lastErr, restarts))
+			case processPaused, processExited:
+				if svc == dep.sink && status == pbsinksvc.DeploymentStatus_RUNNING {
+					status = pbsinksvc.DeploymentStatus_PAUSED
+					continue
+				}
+				if state == processExited {
+					status = pbsinksvc.DeploymentStatus_FAILING
+					reasons = append(reasons, fmt.Sprintf("%s: exited", strings.TrimPrefix(svc.name, deploymentID+"-")))
+				}
+			}
+		}
+	}
+
+	var sinkProgress *pbsinksvc.SinkProgress
+	if status != pbsinksvc.DeploymentStatus_STOPPED {
+		sinkProgress = e.progress.Progress(ctx, deploymentID, e.progressSource(deploymentID, info), zlog)
+	}
+
+	motd := fmt.Sprintf("Running your deployment as local processes, logs in %q", filepath.Join(e.deploymentDir(deploymentID), "logs"))
+	if len(info.Unsupported) != 0 {
+		motd += fmt.Sprintf(". Not supported by this engine and ignored: %s", strings.Join(info.Unsupported, ", "))
+	}
+
+	return &pbsinksvc.InfoResponse{
+		Status:      status,
+		Services:    info.ServiceInfo,
+		Reason:      strings.Join(reasons, ", "),
+		PackageInfo: info.PackageInfo,
+		Progress:    sinkProgress,
+		Motd:        motd,
+	}, nil
+}
+
+// progressSource reads the progress of the sink from its database and from the metrics it serves on its
+// own port. The cursors table of an external clickhouse is not read, its HTTP interface being unknown.
+func (e *ProcessEngine) progressSource(deploymentID string, info *deploymentInfo) *progress.Source {
+	out := &progress.Source{
+		OutputModuleHash: info.PackageInfo.GetOutputModuleHash(),
+		Logs: func(ctx context.Context) ([]string, error) {
+			return tailLines(e.logPath(deploymentID, sinkServiceName(deploymentID)), 64*1024), nil
+		},
+	}
+	if info.SinkEngine == "postgres" {
+		out.CursorDSN = info.DSN
+	}
+	if info.MetricsPort != 0 {
+		out.MetricsURL = fmt.Sprintf("http://localhost:%d/metrics", info.MetricsPort)
+	}
+	return out
+}
+
+// tailLines returns the lines of the last `size` bytes of the file at `path`.
+func tailLines(path string, size int64) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	if stat, err := f.Stat(); err == nil && stat.Size() > size {
+		if _, err := f.Seek(-size, io.SeekEnd); err != nil {
+			return nil
+		}
+	}
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return nil
+	}
+	return strings.Split(string(content), "\n")
+}
+
+func (e *ProcessEngine) List(ctx context.Context, zlog *zap.Logger) (out []*pbsinksvc.DeploymentWithStatus, err error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	files, err := os.ReadDir(e.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range files {
+		id := f.Name()
+		if !f.IsDir() || strings.HasPrefix(id, ".") { // hidden folders hold the deployment registry
+			continue
+		}
+		info, err := e.info(ctx, id, zlog)
+		if err != nil {
+			zlog.Warn("cannot get info for deployment", zap.String("id", id))
+			continue
+		}
+		out = append(out, &pbsinksvc.DeploymentWithStatus{
+			Id:          id,
+			Status:      info.Status,
+			Reason:      info.Reason,
+			PackageInfo: info.PackageInfo,
+			Progress:    info.Progress,
+		})
+	}
+	return out, nil
+}
+
+func (e *ProcessEngine) Resume(ctx context.Context, deploymentID string, _ pbsinksvc.DeploymentStatus, zlog *zap.Logger) (string, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	dep, found := e.deployments[deploymentID]
+	if !found {
+		if err := e.start(deploymentID, zlog); err != nil {
+			return "", fmt.Errorf("starting processes: %w", err)
+		}
+		return "started", nil
+	}
+
+	if state, _, _ := dep.sink.State(); state == processExited {
+		dep.sink.Start()
+		return "sink restarted", nil
+	}
+	if err := dep.sink.Resume(); err != nil {
+		return "", err
+	}
+	return "sink resumed", nil
+}
+
+func (e *ProcessEngine) Pause(ctx context.Context, deploymentID string, zlog *zap.Logger) (string, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	dep, found := e.deployments[deploymentID]
+	if !found {
+		return "", fmt.Errorf("deployment %q is not running", deploymentID)
+	}
+	// only suspend the sink process, keeping the database up
+	if err := dep.sink.Pause(); err != nil {
+		return "", err
+	}
+	return "sink paused", nil
+}
+
+func (e *ProcessEngine) Stop(ctx context.Context, deploymentID string, zlog *zap.Logger) (string, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if _, err := e.readDeploymentInfo(deploymentID); err != nil {
+		return "", fmt.Errorf("cannot read deployment info: %w", err)
+	}
+	e.stop(deploymentID)
+	return "stopped", nil
+}
+
+func (e *ProcessEngine) Remove(ctx context.Context, deploymentID string, zlog *zap.Logger) (string, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.stop(deploymentID)
+	e.progress.Forget(deploymentID)
+	if err := os.RemoveAll(e.deploymentDir(deploymentID)); err != nil {
+		return "", err
+	}
+	return "removed", nil
+}
+
+func (e *ProcessEngine) Shutdown(ctx context.Context, _ error, zlog *zap.Logger) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	var wg sync.WaitGroup
+	for id := range e.deployments {
+		zlog.Info("shutting down deployment", zap.String("deploymentID", id))
+		dep := e.deployments[id]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := len(dep.services) - 1; i >= 0; i-- {
+				dep.services[i].Stop()
+			}
+		}()
+	}
+	wg.Wait()
+	e.deployments = map[string]*deployment{}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package process
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
+	"github.com/streamingfast/substreams/sink-server/progress"
+)
+
+func TestProcessEngine_List(t *testing.T) {
+	dir := t.TempDir()
+	e := &ProcessEngine{
+		dir:         dir,
+		deployments: map[string]*deployment{},
+		progress:    progress.NewTracker(),
+	}
+
+	require.NoError(t, os.MkdirAll(e.deploymentDir("a1b2c3"), 0755))
+	require.NoError(t, e.writeDeploymentInfo("a1b2c3", &deploymentInfo{PackageInfo: &pbsinksvc.PackageInfo{Name: "uniswap"}}))
+
+	// the files and hidden folders of the data dir, like the deployment registry, are not deployments
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".registry"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".registry", "registry.db"), nil, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0644))
+
+	core, logs := observer.New(zap.WarnLevel)
+	out, err := e.List(context.Background(), zap.New(core))
+	require.NoError(t, err)
+	require.Len(t, out, 1)
+	assert.Equal(t, "a1b2c3", out[0].Id)
+	assert.Equal(t, pbsinksvc.DeploymentStatus_STOPPED, out[0].Status)
+	assert.Equal(t, "uniswap", out[0].PackageInfo.Name)
+	assert.Zero(t, logs.Len())
+}
@@ -0,0 +1,119 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
+	"go.uber.org/zap"
+)
+
+// followInterval is how often the log files are checked for new lines when following them.
+var followInterval = 500 * time.Millisecond
+
+// Logs reads the log files of the services of the deployment. Their lines are not timestamped: `since`
+// only skips the content of the files that were not written to after it.
+func (e *ProcessEngine) Logs(ctx context.Context, deploymentID string, service string, follow bool, since time.Time, send func(*pbsinksvc.LogsResponse) error, zlog *zap.Logger) error {
+	e.mutex.Lock()
+	info, err := e.readDeploymentInfo(deploymentID)
+	e.mutex.Unlock()
+	if err != nil {
+		return fmt.Errorf("cannot read Service Info: %w", err)
+	}
+
+	services := []string{service}
+	if service == "" {
+		services = services[:0]
+		for name := range info.ServiceInfo {
+			services = append(services, name)
+		}
+		sort.Strings(services)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, len(services))
+	for _, name := range services {
+		go func(name string) {
+			err := tailLog(ctx, e.logPath(deploymentID, name), follow, since, func(line string) error {
+				return send(&pbsinksvc.LogsResponse{Service: name, Line: line})
+			})
+			if err != nil {
+				cancel()
+			}
+			errs <- err
+		}(name)
+	}
+
+	var out error
+	for range services {
+		if err := <-errs; err != nil && out == nil {
+			out = err
+		}
+	}
+	return out
+}
+
+// tailLog calls `send` with each line of the file at `path` and, when `follow` is set, with the lines
+// appended to it until the context is done. A missing file is read as empty.
+func tailLog(ctx context.Context, path string, follow bool, since time.Time, send func(line string) error) error {
+	var offset int64
+	var partial string
+	first := true
+	for {
+		if stat, err := os.Stat(path); err == nil {
+			if first && !since.IsZero() && stat.ModTime().Before(since) {
+				offset = stat.Size()
+			}
+			if stat.Size() < offset { // truncated or re-created
+				offset = 0
+				partial = ""
+			}
+			first = false
+
+			content, err := readFrom(path, offset)
+			if err != nil {
+				return err
+			}
+			offset += int64(len(content))
+
+			lines := strings.Split(partial+string(content), "\n")
+			partial = lines[len(lines)-1]
+			for _, line := range lines[:len(lines)-1] {
+				if err := send(line); err != nil {
+					return err
+				}
+			}
+		}
+
+		if !follow {
+			if partial != "" {
+				return send(partial)
+			}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(followInterval):
+		}
+	}
+}
+
+func readFrom(path string, offset int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(f)
+}
@@ -0,0 +1,68 @@
+package process
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTailLog(t *testing.T) {
+	followInterval = 10 * time.Millisecond
+	path := filepath.Join(t.TempDir(), "test.log")
+	require.NoError(t, os.WriteFile(path, []byte("one\ntwo\nthr"), 0644))
+
+	var lines []string
+	collect := func(line string) error {
+		lines = append(lines, line)
+		return nil
+	}
+	require.NoError(t, tailLog(context.Background(), path, false, time.Time{}, collect))
+	assert.Equal(t, []string{"one", "two", "thr"}, lines)
+
+	lines = nil
+	require.NoError(t, tailLog(context.Background(), path, false, time.Now().Add(time.Hour), collect))
+	assert.Empty(t, lines)
+
+	lines = nil
+	require.NoError(t, tailLog(context.Background(), filepath.Join(t.TempDir(), "missing.log"), false, time.Time{}, collect))
+	assert.Empty(t, lines)
+
+	// following, the partial line is only sent once complete
+	var mutex sync.Mutex
+	var followed []string
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- tailLog(ctx, path, true, time.Time{}, func(line string) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			followed = append(followed, line)
+			return nil
+		})
+	}()
+
+	received := func(expected ...string) func() bool {
+		return func() bool {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return assert.ObjectsAreEqual(expected, followed)
+		}
+	}
+	require.Eventually(t, received("one", "two"), time.Second, 5*time.Millisecond)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString("ee\nfour\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	require.Eventually(t, received("one", "two", "three", "four"), time.Second, 5*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+}
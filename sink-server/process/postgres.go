@@ -0,0 +1,88 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const embeddedPostgresUser = "dev-node"
+
+func postgresServiceName(deploymentID string) string {
+	return deploymentID + "-postgres"
+}
+
+func embeddedPostgresDSN(port uint32) string {
+	return fmt.Sprintf("postgres://%s@localhost:%d/postgres?sslmode=disable", embeddedPostgresUser, port)
+}
+
+// newPostgres returns the supervisor of the embedded PostgreSQL of the deployment, listening on localhost
+// only, its data folder being initialized on first start.
+func (e *ProcessEngine) newPostgres(deploymentID string, port uint32, zlog *zap.Logger) *supervisor {
+	dataDir := filepath.Join(e.deploymentDir(deploymentID), "data", "postgres")
+	socketDir := filepath.Join(e.deploymentDir(deploymentID), "data", "postgres-socket")
+
+	name := postgresServiceName(deploymentID)
+	svc := newSupervisor(name, e.logPath(deploymentID, name), func() *exec.Cmd {
+		return exec.Command(e.postgresBinary,
+			"-D", dataDir,
+			"-p", strconv.FormatUint(uint64(port), 10),
+			"-k", socketDir,
+			"-c", "listen_addresses=localhost",
+		)
+	}, zlog)
+
+	svc.prestart = func(ctx context.Context, logs io.Writer) error {
+		if err := os.MkdirAll(socketDir, 0755); err != nil {
+			return err
+		}
+		if _, err := os.Stat(filepath.Join(dataDir, "PG_VERSION")); err == nil {
+			return nil
+		}
+		cmd := exec.CommandContext(ctx, e.initdbBinary, "-D", dataDir, "-U", embeddedPostgresUser, "--auth=trust", "-E", "UTF8", "--locale=C")
+		cmd.Stdout = logs
+		cmd.Stderr = logs
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("initdb: %w", err)
+		}
+		return nil
+	}
+	return svc
+}
+
+// waitForPort waits until something accepts TCP connections on `port` of localhost.
+func waitForPort(ctx context.Context, port uint32, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	addr := net.JoinHostPort("localhost", strconv.FormatUint(uint64(port), 10))
+	for {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for %s: %w", addr, err)
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
+
+func freePort() (uint32, error) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return uint32(listener.Addr().(*net.TCPAddr).Port), nil
+}
@@ -0,0 +1,37 @@
+//go:build !windows
+
+package process
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup starts `cmd` in its own process group, so signals reach the children it spawns too.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func signalGroup(cmd *exec.Cmd, sig syscall.Signal) error {
+	return syscall.Kill(-cmd.Process.Pid, sig)
+}
+
+func terminateProcess(cmd *exec.Cmd) error {
+	if err := signalGroup(cmd, syscall.SIGTERM); err != nil {
+		return err
+	}
+	// a paused process needs to be continued to handle the termination
+	return signalGroup(cmd, syscall.SIGCONT)
+}
+
+func killProcess(cmd *exec.Cmd) error {
+	return signalGroup(cmd, syscall.SIGKILL)
+}
+
+func pauseProcess(cmd *exec.Cmd) error {
+	return signalGroup(cmd, syscall.SIGSTOP)
+}
+
+func resumeProcess(cmd *exec.Cmd) error {
+	return signalGroup(cmd, syscall.SIGCONT)
+}
@@ -0,0 +1,28 @@
+//go:build windows
+
+package process
+
+import (
+	"errors"
+	"os/exec"
+)
+
+var errPauseUnsupported = errors.New("pausing processes is not supported on windows")
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func terminateProcess(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+func killProcess(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+func pauseProcess(cmd *exec.Cmd) error {
+	return errPauseUnsupported
+}
+
+func resumeProcess(cmd *exec.Cmd) error {
+	return errPauseUnsupported
+}
@@ -0,0 +1,66 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func sinkServiceName(deploymentID string) string {
+	return deploymentID + "-sink"
+}
+
+// newSink returns the supervisor of the substreams-sink-sql process of the deployment. The database is
+// set up before the first run, after waiting for the embedded PostgreSQL if any.
+func (e *ProcessEngine) newSink(deploymentID string, info *deploymentInfo, zlog *zap.Logger) *supervisor {
+	spkgPath := filepath.Join(e.deploymentDir(deploymentID), "config", "substreams.spkg")
+	setupMarker := filepath.Join(e.deploymentDir(deploymentID), "data", "sink", "setup-complete")
+	env := append(os.Environ(), "SUBSTREAMS_API_TOKEN="+e.token)
+
+	name := sinkServiceName(deploymentID)
+	svc := newSupervisor(name, e.logPath(deploymentID, name), func() *exec.Cmd {
+		// the default metrics and pprof addresses would be shared by the sinks of all the deployments
+		args := []string{"run", info.DSN, spkgPath, "--on-module-hash-mistmatch=warn", "--pprof-listen-addr="}
+		if info.MetricsPort != 0 {
+			args = append(args, fmt.Sprintf("--metrics-listen-addr=localhost:%d", info.MetricsPort))
+		} else {
+			args = append(args, "--metrics-listen-addr=")
+		}
+		if info.SinkEngine == "clickhouse" {
+			args = append(args, "--undo-buffer-size=12")
+		}
+		if e.endpoint != "" {
+			args = append(args, "-e", e.endpoint)
+		}
+		cmd := exec.Command(e.sinkBinary, args...)
+		cmd.Env = env
+		return cmd
+	}, zlog)
+
+	svc.prestart = func(ctx context.Context, logs io.Writer) error {
+		if info.PostgresPort != 0 {
+			if err := waitForPort(ctx, info.PostgresPort, 30*time.Second); err != nil {
+				return fmt.Errorf("embedded postgres: %w", err)
+			}
+		}
+		if _, err := os.Stat(setupMarker); err == nil {
+			return nil
+		}
+
+		cmd := exec.CommandContext(ctx, e.sinkBinary, "setup", info.DSN, spkgPath)
+		cmd.Env = env
+		cmd.Stdout = logs
+		cmd.Stderr = logs
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("sink setup: %w", err)
+		}
+		return os.WriteFile(setupMarker, nil, 0644)
+	}
+	return svc
+}
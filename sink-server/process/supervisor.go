@@ -0,0 +1,232 @@
+package process
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type processState int
+
+const (
+	processStopped processState = iota
+	processStarting
+	processRunning
+	processPaused
+	processFailing // exited with an error, waiting to be restarted
+	processExited  // exited successfully, not restarted
+)
+
+var (
+	minRestartDelay = 1 * time.Second
+	maxRestartDelay = 1 * time.Minute
+	stopTimeout     = 10 * time.Second
+)
+
+// supervisor runs a child process, restarting it with an exponential backoff when it fails, and
+// appending its output to a log file.
+type supervisor struct {
+	name    string
+	logPath string
+	logger  *zap.Logger
+
+	// command returns the command to run, a new one on each (re)start
+	command func() *exec.Cmd
+	// prestart, if set, runs before each (re)start, its failure counts as a failure of the process
+	prestart func(ctx context.Context, logs io.Writer) error
+
+	mutex    sync.Mutex
+	state    processState
+	cmd      *exec.Cmd
+	lastErr  error
+	restarts int
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+func newSupervisor(name, logPath string, command func() *exec.Cmd, logger *zap.Logger) *supervisor {
+	return &supervisor{
+		name:    name,
+		logPath: logPath,
+		command: command,
+		logger:  logger.With(zap.String("process", name)),
+	}
+}
+
+// Start launches the process in the background, it is restarted until Stop is called.
+func (s *supervisor) Start() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	s.state = processStarting
+	s.lastErr = nil
+	s.restarts = 0
+	go s.run(ctx, s.done)
+}
+
+func (s *supervisor) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	delay := minRestartDelay
+	for {
+		started := time.Now()
+		err := s.runOnce(ctx)
+		if ctx.Err() != nil {
+			s.setState(processStopped, nil)
+			return
+		}
+		if err == nil {
+			s.logger.Info("process exited")
+			s.setState(processExited, nil)
+			return
+		}
+
+		if time.Since(started) > maxRestartDelay {
+			delay = minRestartDelay
+		}
+		s.logger.Warn("process failed, restarting", zap.Error(err), zap.Duration("delay", delay))
+		s.mutex.Lock()
+		s.state = processFailing
+		s.lastErr = err
+		s.restarts++
+		s.mutex.Unlock()
+
+		select {
+		case <-ctx.Done():
+			s.setState(processStopped, nil)
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRestartDelay)
+	}
+}
+
+func (s *supervisor) runOnce(ctx context.Context) error {
+	logs, err := os.OpenFile(s.logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("opening log file: %w", err)
+	}
+	defer logs.Close()
+
+	fmt.Fprintf(logs, "=== %s starting %s ===\n", time.Now().UTC().Format(time.RFC3339), s.name)
+
+	if s.prestart != nil {
+		if err := s.prestart(ctx, logs); err != nil {
+			return fmt.Errorf("before start: %w", err)
+		}
+	}
+
+	cmd := s.command()
+	cmd.Stdout = logs
+	cmd.Stderr = logs
+	setProcessGroup(cmd)
+
+	s.mutex.Lock()
+	if ctx.Err() != nil {
+		s.mutex.Unlock()
+		return ctx.Err()
+	}
+	if err := cmd.Start(); err != nil {
+		s.mutex.Unlock()
+		return fmt.Errorf("starting %q: %w", cmd.Path, err)
+	}
+	s.cmd = cmd
+	s.state = processRunning
+	s.mutex.Unlock()
+
+	err = cmd.Wait()
+
+	s.mutex.Lock()
+	s.cmd = nil
+	s.mutex.Unlock()
+
+	if err != nil {
+		fmt.Fprintf(logs, "=== %s %s exited: %s ===\n", time.Now().UTC().Format(time.RFC3339), s.name, err)
+	}
+	return err
+}
+
+func (s *supervisor) setState(state processState, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.state = state
+	s.lastErr = err
+}
+
+// Stop terminates the process and stops restarting it, killing it if it does not exit in time.
+func (s *supervisor) Stop() {
+	s.mutex.Lock()
+	if s.cancel == nil {
+		s.mutex.Unlock()
+		return
+	}
+	s.cancel()
+	done := s.done
+	cmd := s.cmd
+	if cmd != nil {
+		if err := terminateProcess(cmd); err != nil {
+			s.logger.Debug("cannot terminate process", zap.Error(err))
+		}
+	}
+	s.mutex.Unlock()
+
+	select {
+	case <-done:
+		return
+	case <-time.After(stopTimeout):
+	}
+
+	s.mutex.Lock()
+	if s.cmd != nil {
+		s.logger.Warn("process did not exit in time, killing it")
+		_ = killProcess(s.cmd)
+	}
+	s.mutex.Unlock()
+	<-done
+}
+
+// Pause suspends the running process.
+func (s *supervisor) Pause() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.state != processRunning || s.cmd == nil {
+		return errors.New("process is not running")
+	}
+	if err := pauseProcess(s.cmd); err != nil {
+		return fmt.Errorf("pausing process: %w", err)
+	}
+	s.state = processPaused
+	return nil
+}
+
+// Resume continues the paused process.
+func (s *supervisor) Resume() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.state != processPaused || s.cmd == nil {
+		return errors.New("process is not paused")
+	}
+	if err := resumeProcess(s.cmd); err != nil {
+		return fmt.Errorf("resuming process: %w", err)
+	}
+	s.state = processRunning
+	return nil
+}
+
+func (s *supervisor) State() (state processState, lastErr error, restarts int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.state, s.lastErr, s.restarts
+}
@@ -0,0 +1,56 @@
+package process
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSupervisor(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals are not supported on windows")
+	}
+	minRestartDelay = 10 * time.Millisecond
+
+	dir := t.TempDir()
+	counter := filepath.Join(dir, "counter")
+	logPath := filepath.Join(dir, "test.log")
+
+	// fails on the first two runs, then keeps running
+	svc := newSupervisor("test", logPath, func() *exec.Cmd {
+		return exec.Command("sh", "-c", `echo run >> `+counter+`; if [ $(wc -l < `+counter+`) -lt 3 ]; then echo failing; exit 1; fi; echo running; exec sleep 30`)
+	}, zap.NewNop())
+	svc.Start()
+	defer svc.Stop()
+
+	require.Eventually(t, func() bool {
+		state, _, _ := svc.State()
+		return state == processRunning
+	}, 5*time.Second, 10*time.Millisecond)
+	_, lastErr, restarts := svc.State()
+	assert.Equal(t, 2, restarts)
+	assert.ErrorContains(t, lastErr, "exit status 1")
+
+	require.Eventually(t, func() bool {
+		logs, _ := os.ReadFile(logPath)
+		return strings.Count(string(logs), "failing") == 2 && strings.Contains(string(logs), "running")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, svc.Pause())
+	state, _, _ := svc.State()
+	assert.Equal(t, processPaused, state)
+	assert.Error(t, svc.Pause())
+	require.NoError(t, svc.Resume())
+
+	svc.Stop()
+	state, _, _ = svc.State()
+	assert.Equal(t, processStopped, state)
+}
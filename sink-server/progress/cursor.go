@@ -0,0 +1,111 @@
+package progress
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	_ "github.com/lib/pq"
+)
+
+// Cursor is the position of a sink, as written in the cursors table of substreams-sink-sql.
+type Cursor struct {
+	ModuleHash string
+	Cursor     string
+	BlockNum   uint64
+}
+
+// ReadCursor reads the cursor of the module `outputModuleHash` from the cursors table of the database at
+// `dsn`, or the one at the highest block when none matches. It returns nil when the table is empty.
+func ReadCursor(ctx context.Context, dsn string, outputModuleHash string) (*Cursor, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dsn: %w", err)
+	}
+
+	switch u.Scheme {
+	case "postgres", "postgresql":
+		return readPostgresCursor(ctx, dsn, outputModuleHash)
+	case "http", "https":
+		return readClickhouseCursor(ctx, u, outputModuleHash)
+	}
+	return nil, fmt.Errorf("unsupported cursor dsn scheme %q", u.Scheme)
+}
+
+func readPostgresCursor(ctx context.Context, dsn string, outputModuleHash string) (*Cursor, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+	defer db.Close()
+
+	out := &Cursor{}
+	var blockNum int64
+	err = db.QueryRowContext(ctx, "SELECT id, cursor, block_num FROM cursors ORDER BY id = $1 DESC, block_num DESC LIMIT 1", outputModuleHash).Scan(&out.ModuleHash, &out.Cursor, &blockNum)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying cursors table: %w", err)
+	}
+	out.BlockNum = uint64(blockNum)
+	return out, nil
+}
+
+// readClickhouseCursor uses the ClickHouse HTTP interface at `u`, its path being the database name.
+func readClickhouseCursor(ctx context.Context, u *url.URL, outputModuleHash string) (*Cursor, error) {
+	database := strings.Trim(u.Path, "/")
+	if database == "" {
+		database = "default"
+	}
+
+	params := url.Values{}
+	params.Set("database", database)
+	params.Set("param_hash", outputModuleHash)
+	params.Set("query", "SELECT id, cursor, block_num FROM cursors FINAL ORDER BY id = {hash:String} DESC, block_num DESC LIMIT 1 FORMAT TabSeparated")
+
+	endpoint := url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/", RawQuery: params.Encode()}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if u.User != nil {
+		password, _ := u.User.Password()
+		req.Header.Set("X-ClickHouse-User", u.User.Username())
+		req.Header.Set("X-ClickHouse-Key", password)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("querying clickhouse: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading clickhouse response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("querying cursors table: clickhouse returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	line := strings.TrimSpace(string(body))
+	if line == "" {
+		return nil, nil
+	}
+	fields := strings.Split(line, "\t")
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("unexpected cursors row %q", line)
+	}
+	blockNum, err := strconv.ParseUint(fields[2], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid block_num %q: %w", fields[2], err)
+	}
+	return &Cursor{ModuleHash: fields[0], Cursor: fields[1], BlockNum: blockNum}, nil
+}
@@ -0,0 +1,39 @@
+package progress
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+type logLine struct {
+	Level string `json:"level"`
+	Msg   string `json:"msg"`
+	Error string `json:"error"`
+}
+
+// LastErrorFromLogs returns the last error logged by a substreams-sink-sql process in `lines`, either as
+// a JSON log line of level error or above, or as the 'Error: ...' line printed when it exits.
+func LastErrorFromLogs(lines []string) string {
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if msg, found := strings.CutPrefix(line, "Error: "); found {
+			return msg
+		}
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+
+		entry := &logLine{}
+		if err := json.Unmarshal([]byte(line), entry); err != nil {
+			continue
+		}
+		switch strings.ToLower(entry.Level) {
+		case "error", "dpanic", "panic", "fatal":
+			if entry.Error != "" {
+				return entry.Msg + ": " + entry.Error
+			}
+			return entry.Msg
+		}
+	}
+	return ""
+}
@@ -0,0 +1,106 @@
+package progress
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Metrics are the values read from the Prometheus metrics of a sink.
+type Metrics struct {
+	// HeadBlockNumber is the last block received by the sink
+	HeadBlockNumber uint64
+	// HeadBlockTimeDrift is the time elapsed since the production of that block
+	HeadBlockTimeDrift time.Duration
+}
+
+// ReadMetrics fetches the Prometheus metrics at `metricsURL`.
+func ReadMetrics(ctx context.Context, metricsURL string) (*Metrics, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metricsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching metrics: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching metrics: %s", resp.Status)
+	}
+	return parseMetrics(resp.Body)
+}
+
+// parseMetrics reads the head block metrics from the Prometheus text format, the highest value being
+// kept when they are reported by several services.
+func parseMetrics(r io.Reader) (*Metrics, error) {
+	out := &Metrics{}
+	found := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, value, ok := parseSample(line)
+		if !ok {
+			continue
+		}
+		switch name {
+		case "head_block_number":
+			if value > 0 && uint64(value) > out.HeadBlockNumber {
+				out.HeadBlockNumber = uint64(value)
+			}
+			found = true
+		case "head_block_time_drift":
+			if drift := time.Duration(value * float64(time.Second)); drift > out.HeadBlockTimeDrift {
+				out.HeadBlockTimeDrift = drift
+			}
+			found = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading metrics: %w", err)
+	}
+	if !found {
+		return nil, fmt.Errorf("no head block metrics found")
+	}
+	return out, nil
+}
+
+// parseSample splits a sample line like `name{labels} value [timestamp]`.
+func parseSample(line string) (name string, value float64, ok bool) {
+	nameEnd := strings.IndexAny(line, "{ ")
+	if nameEnd <= 0 {
+		return "", 0, false
+	}
+	name = line[:nameEnd]
+
+	rest := line[nameEnd:]
+	if strings.HasPrefix(rest, "{") {
+		end := strings.LastIndex(rest, "}")
+		if end < 0 {
+			return "", 0, false
+		}
+		rest = rest[end+1:]
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", 0, false
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return "", 0, false
+	}
+	return name, value, true
+}
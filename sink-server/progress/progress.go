@@ -0,0 +1,135 @@
+// Package progress reports the progress of the sinks of the deployments from the cursor they write to
+// their database and from the metrics they expose, instead of parsing their logs.
+package progress
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
+	"go.uber.org/zap"
+)
+
+// probeTimeout bounds each read of a sink progress, so that a busy or missing database does not slow down
+// the 'info' and 'list' requests.
+var probeTimeout = 2 * time.Second
+
+// Source tells where to read the progress of the sink of a deployment, any of its fields can be empty.
+type Source struct {
+	// CursorDSN is the database holding the cursors table of the sink: a 'postgres://' DSN, or the
+	// 'http://' URL of a ClickHouse HTTP interface
+	CursorDSN string
+	// OutputModuleHash selects the cursor of the deployed module, the cursor at the highest block is used
+	// when none matches
+	OutputModuleHash string
+	// MetricsURL is the URL of the Prometheus metrics of the sink, as served on '--metrics-listen-addr'
+	MetricsURL string
+	// Logs returns the last lines of the sink logs, only used to report its last error
+	Logs func(ctx context.Context) ([]string, error)
+}
+
+type observation struct {
+	blockNum        uint64
+	at              time.Time
+	blocksPerSecond float64
+}
+
+// Tracker reads the progress of the sinks, and computes their throughput between two reads.
+type Tracker struct {
+	mutex        sync.Mutex
+	observations map[string]*observation
+	now          func() time.Time
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{
+		observations: make(map[string]*observation),
+		now:          time.Now,
+	}
+}
+
+// Progress reads the progress of the sink of `deploymentID` from `source`, it returns nil when nothing
+// could be read.
+func (t *Tracker) Progress(ctx context.Context, deploymentID string, source *Source, zlog *zap.Logger) *pbsinksvc.SinkProgress {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	out := &pbsinksvc.SinkProgress{}
+	found := false
+
+	if source.CursorDSN != "" {
+		cursor, err := ReadCursor(ctx, source.CursorDSN, source.OutputModuleHash)
+		if err != nil {
+			zlog.Debug("cannot read sink cursor", zap.String("deployment_id", deploymentID), zap.Error(err))
+		} else if cursor != nil {
+			out.LastProcessedBlock = cursor.BlockNum
+			out.LastCursor = cursor.Cursor
+			found = true
+		}
+	}
+
+	if source.MetricsURL != "" {
+		metrics, err := ReadMetrics(ctx, source.MetricsURL)
+		if err != nil {
+			zlog.Debug("cannot read sink metrics", zap.String("deployment_id", deploymentID), zap.Error(err))
+		} else {
+			out.HeadLagSeconds = metrics.HeadBlockTimeDrift.Seconds()
+			if out.LastProcessedBlock == 0 && metrics.HeadBlockNumber != 0 {
+				// the sink has not flushed yet
+				out.LastProcessedBlock = metrics.HeadBlockNumber
+			}
+			found = true
+		}
+	}
+
+	if source.Logs != nil {
+		lines, err := source.Logs(ctx)
+		if err != nil {
+			zlog.Debug("cannot read sink logs", zap.String("deployment_id", deploymentID), zap.Error(err))
+		} else if lastErr := LastErrorFromLogs(lines); lastErr != "" {
+			out.LastError = lastErr
+			found = true
+		}
+	}
+
+	if !found {
+		return nil
+	}
+	if out.LastProcessedBlock != 0 {
+		out.BlocksPerSecond = t.observe(deploymentID, out.LastProcessedBlock)
+	}
+	return out
+}
+
+// observe records that the sink of `deploymentID` reached `blockNum`, and returns its throughput since the
+// previous observation.
+func (t *Tracker) observe(deploymentID string, blockNum uint64) float64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := t.now()
+	prev := t.observations[deploymentID]
+	if prev == nil || blockNum < prev.blockNum {
+		// first observation, or the deployment was reset
+		t.observations[deploymentID] = &observation{blockNum: blockNum, at: now}
+		return 0
+	}
+
+	elapsed := now.Sub(prev.at)
+	if elapsed < time.Second {
+		// too close to the previous observation to be meaningful
+		return prev.blocksPerSecond
+	}
+
+	rate := float64(blockNum-prev.blockNum) / elapsed.Seconds()
+	t.observations[deploymentID] = &observation{blockNum: blockNum, at: now, blocksPerSecond: rate}
+	return rate
+}
+
+// Forget drops what is known of the sink of `deploymentID`, to be called when it is removed or reset.
+func (t *Tracker) Forget(deploymentID string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.observations, deploymentID)
+}
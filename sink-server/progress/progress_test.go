@@ -0,0 +1,167 @@
+package progress
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTracker_Progress(t *testing.T) {
+	metrics := `# HELP head_block_number
+# TYPE head_block_number gauge
+head_block_number{app="substreams_sink"} 1200
+head_block_time_drift{app="substreams_sink"} 12.5
+substreams_sink_data_message 42
+`
+	var cursorRow string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/metrics":
+			w.Write([]byte(metrics))
+		case "/":
+			assert.Equal(t, "substreams", r.URL.Query().Get("database"))
+			assert.Equal(t, "abcdef", r.URL.Query().Get("param_hash"))
+			assert.Equal(t, "dev-node", r.Header.Get("X-ClickHouse-User"))
+			assert.Equal(t, "secret", r.Header.Get("X-ClickHouse-Key"))
+			w.Write([]byte(cursorRow))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	now := time.Unix(1700000000, 0)
+	tracker := NewTracker()
+	tracker.now = func() time.Time { return now }
+
+	logs := []string{
+		`{"level":"info","msg":"postgres sink stats","last_block":"#1000 (abc)"}`,
+		`{"level":"error","msg":"flushing failed","error":"connection refused"}`,
+		`{"level":"info","msg":"retrying"}`,
+	}
+	source := &Source{
+		CursorDSN:        "http://dev-node:secret@" + strings.TrimPrefix(srv.URL, "http://") + "/substreams",
+		OutputModuleHash: "abcdef",
+		MetricsURL:       srv.URL + "/metrics",
+		Logs:             func(ctx context.Context) ([]string, error) { return logs, nil },
+	}
+
+	cursorRow = "abcdef\tc1\t1000\n"
+	out := tracker.Progress(context.Background(), "dep", source, zap.NewNop())
+	require.NotNil(t, out)
+	assert.Equal(t, uint64(1000), out.LastProcessedBlock)
+	assert.Equal(t, "c1", out.LastCursor)
+	assert.Equal(t, 12.5, out.HeadLagSeconds)
+	assert.Equal(t, 0.0, out.BlocksPerSecond)
+	assert.Equal(t, "flushing failed: connection refused", out.LastError)
+
+	now = now.Add(10 * time.Second)
+	cursorRow = "abcdef\tc2\t1500\n"
+	out = tracker.Progress(context.Background(), "dep", source, zap.NewNop())
+	require.NotNil(t, out)
+	assert.Equal(t, uint64(1500), out.LastProcessedBlock)
+	assert.Equal(t, 50.0, out.BlocksPerSecond)
+
+	// not flushed yet after a reset, the block received by the sink is used
+	tracker.Forget("dep")
+	cursorRow = ""
+	out = tracker.Progress(context.Background(), "dep", source, zap.NewNop())
+	require.NotNil(t, out)
+	assert.Equal(t, uint64(1200), out.LastProcessedBlock)
+	assert.Empty(t, out.LastCursor)
+
+	assert.Nil(t, tracker.Progress(context.Background(), "dep", &Source{MetricsURL: srv.URL + "/unknown"}, zap.NewNop()))
+	assert.Nil(t, tracker.Progress(context.Background(), "dep", &Source{}, zap.NewNop()))
+}
+
+func TestTracker_observe(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tracker := NewTracker()
+	tracker.now = func() time.Time { return now }
+
+	assert.Equal(t, 0.0, tracker.observe("dep", 100))
+
+	now = now.Add(2 * time.Second)
+	assert.Equal(t, 10.0, tracker.observe("dep", 120))
+
+	// too close to the previous observation, the previous rate is kept
+	now = now.Add(100 * time.Millisecond)
+	assert.Equal(t, 10.0, tracker.observe("dep", 125))
+
+	// going backward starts over
+	now = now.Add(5 * time.Second)
+	assert.Equal(t, 0.0, tracker.observe("dep", 10))
+}
+
+func TestParseMetrics(t *testing.T) {
+	tests := []struct {
+		name          string
+		in            string
+		expected      *Metrics
+		expectedError bool
+	}{
+		{
+			name:     "labels and timestamp",
+			in:       "head_block_number{app=\"a\",x=\"{}\"} 42 1700000000\nhead_block_time_drift{app=\"a\"} 1.5\n",
+			expected: &Metrics{HeadBlockNumber: 42, HeadBlockTimeDrift: 1500 * time.Millisecond},
+		},
+		{
+			name:     "highest of several services",
+			in:       "head_block_number{app=\"a\"} 42\nhead_block_number{app=\"b\"} 40\nhead_block_time_drift 3\n",
+			expected: &Metrics{HeadBlockNumber: 42, HeadBlockTimeDrift: 3 * time.Second},
+		},
+		{
+			name:          "not a number",
+			in:            "head_block_number NaN\nhead_block_time_drift +Inf\n",
+			expectedError: true,
+		},
+		{
+			name:          "missing",
+			in:            "# comment\nother_metric 12\n",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out, err := parseMetrics(strings.NewReader(test.in))
+			if test.expectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, out)
+		})
+	}
+}
+
+func TestLastErrorFromLogs(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		expected string
+	}{
+		{"none", []string{`{"level":"info","msg":"ok"}`, "plain text"}, ""},
+		{"exit error", []string{`{"level":"error","msg":"first"}`, "Error: run: database unreachable"}, "run: database unreachable"},
+		{"last json error", []string{`{"level":"error","msg":"first"}`, `{"level":"fatal","msg":"second"}`, `{"level":"info","msg":"ok"}`}, "second"},
+		{"invalid json", []string{`{"level":"error"`}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, LastErrorFromLogs(test.lines))
+		})
+	}
+}
+
+func TestReadCursor_UnsupportedScheme(t *testing.T) {
+	_, err := ReadCursor(context.Background(), "clickhouse://dev-node@localhost:9000/substreams", "abc")
+	require.Error(t, err)
+}
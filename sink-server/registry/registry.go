@@ -0,0 +1,247 @@
+// Package registry keeps a persistent record of the deployments of the sink server: who created them, with
+// which package and parameters, and every change of their status.
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	deploymentsBucket = []byte("deployments")
+	eventsBucket      = []byte("events")
+)
+
+var ErrNotFound = errors.New("deployment not found")
+
+// Registry stores the deployments in a bbolt database, each record is a `DeploymentRecord` and each of its
+// events a `HistoryEvent`, kept in a bucket per deployment under their sequence number.
+type Registry struct {
+	db  *bolt.DB
+	now func() time.Time
+}
+
+// Open opens the registry at `path`, creating it if it does not exist.
+func Open(path string) (*Registry, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening registry %q: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{deploymentsBucket, eventsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating registry buckets: %w", err)
+	}
+
+	return &Registry{db: db, now: time.Now}, nil
+}
+
+func (r *Registry) Close() error {
+	return r.db.Close()
+}
+
+// PackageHash returns the sha256 of the serialized package, identifying what was deployed.
+func PackageHash(pkg *pbsubstreams.Package) string {
+	cnt, err := proto.MarshalOptions{Deterministic: true}.Marshal(pkg)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(cnt)
+	return hex.EncodeToString(sum[:])
+}
+
+// RecordDeploy creates the record of a new deployment along with its "deploy" event.
+func (r *Registry) RecordDeploy(record *pbsinksvc.DeploymentRecord, reason string) error {
+	now := timestamppb.New(r.now())
+	record.CreatedAt = now
+
+	return r.db.Update(func(tx *bolt.Tx) error {
+		if err := putRecord(tx, record); err != nil {
+			return err
+		}
+		return addEvent(tx, record.Id, &pbsinksvc.HistoryEvent{
+			Timestamp:   now,
+			Action:      "deploy",
+			User:        record.Owner,
+			NewStatus:   record.Status,
+			Reason:      reason,
+			PackageHash: record.PackageHash,
+		})
+	})
+}
+
+// RecordAction adds the event of a request on the deployment, its timestamp and previous status being set
+// here. `packageInfo` is only set when a new package was deployed. A successful "remove" is recorded with
+// the REMOVING status. The record is created if the deployment predates the registry.
+func (r *Registry) RecordAction(deploymentID string, event *pbsinksvc.HistoryEvent, packageInfo *pbsinksvc.PackageInfo) error {
+	now := r.now()
+	return r.db.Update(func(tx *bolt.Tx) error {
+		record, err := getOrCreateRecord(tx, deploymentID, now)
+		if err != nil {
+			return err
+		}
+
+		event.Timestamp = timestamppb.New(now)
+		event.PreviousStatus = record.Status
+
+		record.Status = event.NewStatus
+		if event.PackageHash != "" {
+			record.PackageHash = event.PackageHash
+		}
+		if packageInfo != nil {
+			record.PackageInfo = packageInfo
+		}
+		if event.Action == "remove" && event.NewStatus == pbsinksvc.DeploymentStatus_REMOVING {
+			record.Removed = true
+		}
+
+		if err := putRecord(tx, record); err != nil {
+			return err
+		}
+		return addEvent(tx, deploymentID, event)
+	})
+}
+
+// ObserveStatus adds a "status" event when `status` differs from the last one seen for the deployment.
+func (r *Registry) ObserveStatus(deploymentID string, status pbsinksvc.DeploymentStatus, reason string) error {
+	now := r.now()
+	return r.db.Update(func(tx *bolt.Tx) error {
+		record, err := getOrCreateRecord(tx, deploymentID, now)
+		if err != nil {
+			return err
+		}
+		if record.Status == status || record.Removed {
+			return nil
+		}
+
+		event := &pbsinksvc.HistoryEvent{
+			Timestamp:      timestamppb.New(now),
+			Action:         "status",
+			PreviousStatus: record.Status,
+			NewStatus:      status,
+			Reason:         reason,
+		}
+		record.Status = status
+		if err := putRecord(tx, record); err != nil {
+			return err
+		}
+		return addEvent(tx, deploymentID, event)
+	})
+}
+
+// Record returns the record of the deployment, or ErrNotFound.
+func (r *Registry) Record(deploymentID string) (*pbsinksvc.DeploymentRecord, error) {
+	var out *pbsinksvc.DeploymentRecord
+	err := r.db.View(func(tx *bolt.Tx) error {
+		record, err := getRecord(tx, deploymentID)
+		if err != nil {
+			return err
+		}
+		if record == nil {
+			return ErrNotFound
+		}
+		out = record
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// History returns the record of the deployment and its events, oldest first, or ErrNotFound.
+func (r *Registry) History(deploymentID string) (*pbsinksvc.HistoryResponse, error) {
+	out := &pbsinksvc.HistoryResponse{}
+	err := r.db.View(func(tx *bolt.Tx) error {
+		record, err := getRecord(tx, deploymentID)
+		if err != nil {
+			return err
+		}
+		if record == nil {
+			return ErrNotFound
+		}
+		out.Deployment = record
+
+		events := tx.Bucket(eventsBucket).Bucket([]byte(deploymentID))
+		if events == nil {
+			return nil
+		}
+		return events.ForEach(func(_, v []byte) error {
+			event := &pbsinksvc.HistoryEvent{}
+			if err := proto.Unmarshal(v, event); err != nil {
+				return fmt.Errorf("unmarshalling event: %w", err)
+			}
+			out.Events = append(out.Events, event)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func getRecord(tx *bolt.Tx, deploymentID string) (*pbsinksvc.DeploymentRecord, error) {
+	cnt := tx.Bucket(deploymentsBucket).Get([]byte(deploymentID))
+	if cnt == nil {
+		return nil, nil
+	}
+	record := &pbsinksvc.DeploymentRecord{}
+	if err := proto.Unmarshal(cnt, record); err != nil {
+		return nil, fmt.Errorf("unmarshalling deployment %q: %w", deploymentID, err)
+	}
+	return record, nil
+}
+
+func getOrCreateRecord(tx *bolt.Tx, deploymentID string, now time.Time) (*pbsinksvc.DeploymentRecord, error) {
+	record, err := getRecord(tx, deploymentID)
+	if err != nil || record != nil {
+		return record, err
+	}
+	return &pbsinksvc.DeploymentRecord{Id: deploymentID, CreatedAt: timestamppb.New(now)}, nil
+}
+
+func putRecord(tx *bolt.Tx, record *pbsinksvc.DeploymentRecord) error {
+	cnt, err := proto.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("marshalling deployment %q: %w", record.Id, err)
+	}
+	return tx.Bucket(deploymentsBucket).Put([]byte(record.Id), cnt)
+}
+
+func addEvent(tx *bolt.Tx, deploymentID string, event *pbsinksvc.HistoryEvent) error {
+	events, err := tx.Bucket(eventsBucket).CreateBucketIfNotExists([]byte(deploymentID))
+	if err != nil {
+		return fmt.Errorf("creating events bucket of %q: %w", deploymentID, err)
+	}
+	seq, err := events.NextSequence()
+	if err != nil {
+		return err
+	}
+	cnt, err := proto.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshalling event: %w", err)
+	}
+
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return events.Put(key, cnt)
+}
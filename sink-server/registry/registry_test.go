@@ -0,0 +1,108 @@
+package registry
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_History(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "registry.db")
+	reg, err := Open(path)
+	require.NoError(t, err)
+
+	now := time.Unix(1700000000, 0)
+	reg.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	require.NoError(t, reg.RecordDeploy(&pbsinksvc.DeploymentRecord{
+		Id:          "dep",
+		Owner:       "alice",
+		PackageHash: "h1",
+		Parameters:  []*pbsinksvc.Parameter{{Key: "SF_PGWEB", Value: "true"}},
+		Status:      pbsinksvc.DeploymentStatus_RUNNING,
+	}, ""))
+
+	// same status, nothing recorded
+	require.NoError(t, reg.ObserveStatus("dep", pbsinksvc.DeploymentStatus_RUNNING, ""))
+	require.NoError(t, reg.ObserveStatus("dep", pbsinksvc.DeploymentStatus_FAILING, "sink: exited"))
+	require.NoError(t, reg.RecordAction("dep", &pbsinksvc.HistoryEvent{Action: "update", User: "bob", NewStatus: pbsinksvc.DeploymentStatus_RUNNING, PackageHash: "h2"}, &pbsinksvc.PackageInfo{Name: "pkg"}))
+	require.NoError(t, reg.RecordAction("dep", &pbsinksvc.HistoryEvent{Action: "remove", User: "bob", NewStatus: pbsinksvc.DeploymentStatus_REMOVING}, nil))
+	// removed deployments do not change anymore
+	require.NoError(t, reg.ObserveStatus("dep", pbsinksvc.DeploymentStatus_STOPPED, ""))
+	require.NoError(t, reg.Close())
+
+	// reopened from disk
+	reg, err = Open(path)
+	require.NoError(t, err)
+	defer reg.Close()
+
+	out, err := reg.History("dep")
+	require.NoError(t, err)
+
+	assert.Equal(t, "alice", out.Deployment.Owner)
+	assert.Equal(t, "h2", out.Deployment.PackageHash)
+	assert.Equal(t, "pkg", out.Deployment.PackageInfo.Name)
+	assert.Equal(t, "SF_PGWEB", out.Deployment.Parameters[0].Key)
+	assert.Equal(t, pbsinksvc.DeploymentStatus_REMOVING, out.Deployment.Status)
+	assert.True(t, out.Deployment.Removed)
+	assert.Equal(t, int64(1700000001), out.Deployment.CreatedAt.Seconds)
+
+	type event struct {
+		action   string
+		user     string
+		prev     pbsinksvc.DeploymentStatus
+		new      pbsinksvc.DeploymentStatus
+		reason   string
+		pkgHash  string
+		unixTime int64
+	}
+	var events []event
+	for _, ev := range out.Events {
+		events = append(events, event{ev.Action, ev.User, ev.PreviousStatus, ev.NewStatus, ev.Reason, ev.PackageHash, ev.Timestamp.Seconds})
+	}
+	assert.Equal(t, []event{
+		{"deploy", "alice", pbsinksvc.DeploymentStatus_UNKNOWN, pbsinksvc.DeploymentStatus_RUNNING, "", "h1", 1700000001},
+		{"status", "", pbsinksvc.DeploymentStatus_RUNNING, pbsinksvc.DeploymentStatus_FAILING, "sink: exited", "", 1700000003},
+		{"update", "bob", pbsinksvc.DeploymentStatus_FAILING, pbsinksvc.DeploymentStatus_RUNNING, "", "h2", 1700000004},
+		{"remove", "bob", pbsinksvc.DeploymentStatus_RUNNING, pbsinksvc.DeploymentStatus_REMOVING, "", "", 1700000005},
+	}, events)
+
+	_, err = reg.History("unknown")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestRegistry_ObserveStatusCreatesRecord(t *testing.T) {
+	reg, err := Open(filepath.Join(t.TempDir(), "registry.db"))
+	require.NoError(t, err)
+	defer reg.Close()
+
+	require.NoError(t, reg.ObserveStatus("old", pbsinksvc.DeploymentStatus_PAUSED, ""))
+
+	out, err := reg.History("old")
+	require.NoError(t, err)
+	assert.Equal(t, pbsinksvc.DeploymentStatus_PAUSED, out.Deployment.Status)
+	require.Len(t, out.Events, 1)
+	assert.Equal(t, "status", out.Events[0].Action)
+
+	record, err := reg.Record("old")
+	require.NoError(t, err)
+	assert.Equal(t, "", record.Owner)
+
+	_, err = reg.Record("unknown")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestPackageHash(t *testing.T) {
+	a := PackageHash(&pbsubstreams.Package{SinkModule: "a"})
+	assert.Len(t, a, 64)
+	assert.Equal(t, a, PackageHash(&pbsubstreams.Package{SinkModule: "a"}))
+	assert.NotEqual(t, a, PackageHash(&pbsubstreams.Package{SinkModule: "b"}))
+}
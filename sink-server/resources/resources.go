@@ -0,0 +1,149 @@
+// Package resources defines the size tiers of the deployments of the sink server, each one limiting the
+// CPU and memory of the services of a deployment.
+package resources
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// SizeParameter is the deployment parameter requesting a size tier, ex: 'SF_SIZE=large'
+const SizeParameter = "SF_SIZE"
+
+// Limits of a service, zero meaning no limit.
+type Limits struct {
+	// CPUs is the number of CPUs the service can use, it can be fractional
+	CPUs        float64
+	MemoryBytes int64
+}
+
+func (l Limits) IsZero() bool {
+	return l.CPUs == 0 && l.MemoryBytes == 0
+}
+
+// Size is a tier of resource limits, given to each service of a deployment depending on its role.
+type Size struct {
+	Name     string
+	Database Limits
+	Sink     Limits
+	// Other limits the frontends and jobs of the deployment (pgweb, postgraphile, dbt, ...)
+	Other Limits
+}
+
+const (
+	mebi = int64(1) << 20
+	gibi = int64(1) << 30
+)
+
+// DefaultSizes are the tiers used when the server is not given any.
+var DefaultSizes = Sizes{
+	"small": {
+		Name:     "small",
+		Database: Limits{CPUs: 1, MemoryBytes: 1 * gibi},
+		Sink:     Limits{CPUs: 1, MemoryBytes: 512 * mebi},
+		Other:    Limits{CPUs: 0.5, MemoryBytes: 256 * mebi},
+	},
+	"medium": {
+		Name:     "medium",
+		Database: Limits{CPUs: 2, MemoryBytes: 4 * gibi},
+		Sink:     Limits{CPUs: 2, MemoryBytes: 2 * gibi},
+		Other:    Limits{CPUs: 1, MemoryBytes: 1 * gibi},
+	},
+	"large": {
+		Name:     "large",
+		Database: Limits{CPUs: 4, MemoryBytes: 16 * gibi},
+		Sink:     Limits{CPUs: 4, MemoryBytes: 4 * gibi},
+		Other:    Limits{CPUs: 2, MemoryBytes: 2 * gibi},
+	},
+}
+
+// Sizes are the tiers a deployment can request, by name.
+type Sizes map[string]*Size
+
+// Get returns the size named `name`, or an error listing the available ones.
+func (s Sizes) Get(name string) (*Size, error) {
+	if size, found := s[name]; found {
+		return size, nil
+	}
+	return nil, fmt.Errorf("unknown size %q, must be one of: %s", name, strings.Join(s.Names(), ", "))
+}
+
+// Names returns the names of the sizes, sorted.
+func (s Sizes) Names() []string {
+	out := make([]string, 0, len(s))
+	for name := range s {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+type limitsConfig struct {
+	CPUs   float64 `yaml:"cpus"`
+	Memory string  `yaml:"memory"`
+}
+
+// LoadSizes reads the tiers from a YAML file mapping each size name to the limits of its roles, with the
+// memory as a quantity (ex: '512Mi', '4Gi'):
+//
+//	small:
+//	  database: {cpus: 1, memory: 1Gi}
+//	  sink: {cpus: 1, memory: 512Mi}
+//	  other: {cpus: 0.5, memory: 256Mi}
+func LoadSizes(path string) (Sizes, error) {
+	cnt, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading sizes: %w", err)
+	}
+
+	config := map[string]map[string]limitsConfig{}
+	if err := yaml.Unmarshal(cnt, &config); err != nil {
+		return nil, fmt.Errorf("unmarshalling sizes %q: %w", path, err)
+	}
+	if len(config) == 0 {
+		return nil, fmt.Errorf("no sizes defined in %q", path)
+	}
+
+	out := make(Sizes, len(config))
+	for name, roles := range config {
+		size := &Size{Name: name}
+		for role, cfg := range roles {
+			limits, err := cfg.limits()
+			if err != nil {
+				return nil, fmt.Errorf("size %q, role %q: %w", name, role, err)
+			}
+			switch role {
+			case "database":
+				size.Database = limits
+			case "sink":
+				size.Sink = limits
+			case "other":
+				size.Other = limits
+			default:
+				return nil, fmt.Errorf("size %q: unknown role %q, must be one of: database, sink, other", name, role)
+			}
+		}
+		out[name] = size
+	}
+	return out, nil
+}
+
+func (c limitsConfig) limits() (Limits, error) {
+	if c.CPUs < 0 {
+		return Limits{}, fmt.Errorf("invalid cpus %v", c.CPUs)
+	}
+	out := Limits{CPUs: c.CPUs}
+	if c.Memory != "" {
+		memory, err := resource.ParseQuantity(c.Memory)
+		if err != nil {
+			return Limits{}, fmt.Errorf("invalid memory %q: %w", c.Memory, err)
+		}
+		out.MemoryBytes = memory.Value()
+	}
+	return out, nil
+}
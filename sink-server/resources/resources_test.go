@@ -0,0 +1,42 @@
+package resources
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSizes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sizes.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+tiny:
+  database: {cpus: 0.5, memory: 512Mi}
+  sink: {cpus: 0.25, memory: 128Mi}
+huge:
+  database: {cpus: 16, memory: 64Gi}
+`), 0644))
+
+	sizes, err := LoadSizes(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"huge", "tiny"}, sizes.Names())
+
+	tiny, err := sizes.Get("tiny")
+	require.NoError(t, err)
+	assert.Equal(t, Limits{CPUs: 0.5, MemoryBytes: 512 << 20}, tiny.Database)
+	assert.Equal(t, Limits{CPUs: 0.25, MemoryBytes: 128 << 20}, tiny.Sink)
+	assert.True(t, tiny.Other.IsZero())
+
+	_, err = sizes.Get("small")
+	assert.EqualError(t, err, `unknown size "small", must be one of: huge, tiny`)
+
+	require.NoError(t, os.WriteFile(path, []byte(`tiny: {frontend: {cpus: 1}}`), 0644))
+	_, err = LoadSizes(path)
+	assert.ErrorContains(t, err, `unknown role "frontend"`)
+
+	require.NoError(t, os.WriteFile(path, []byte(`tiny: {sink: {memory: lots}}`), 0644))
+	_, err = LoadSizes(path)
+	assert.ErrorContains(t, err, `invalid memory "lots"`)
+}
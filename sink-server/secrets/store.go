@@ -0,0 +1,173 @@
+// Package secrets keeps the credentials generated for the deployments of the sink server, encrypted at rest
+// with a key given to the server, or generated next to the secrets when none is.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const keyFile = "master.key"
+
+var ErrNotFound = errors.New("secrets not found")
+
+// Store holds the secrets of each deployment in its own file, a JSON map of secret name to value sealed
+// with AES-GCM. Without a key given to Open, the key is generated on first use in the 'master.key' file of
+// the store folder, where it protects nothing from someone able to read the folder.
+type Store struct {
+	dir  string
+	aead cipher.AEAD
+}
+
+// Open opens the store in `dir`, creating the folder if it does not exist. The secrets are encrypted with
+// `key`, 32 bytes as returned by LoadKey, or with the key of the 'master.key' file of the folder when nil,
+// generated if it does not exist.
+func Open(dir string, key []byte) (*Store, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("creating secrets folder %q: %w", dir, err)
+	}
+
+	if key == nil {
+		var err error
+		if key, err = readOrCreateKey(filepath.Join(dir, keyFile)); err != nil {
+			return nil, err
+		}
+	} else if len(key) != 32 {
+		return nil, fmt.Errorf("invalid secrets key: expected 32 bytes, got %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("creating cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("creating cipher: %w", err)
+	}
+
+	return &Store{dir: dir, aead: aead}, nil
+}
+
+// LoadKey returns the key given as 64 hex characters in `hexKey`, or read from `path`, a file of 32 bytes
+// like the 'master.key' generated by Open. It returns nil when both are empty.
+func LoadKey(hexKey, path string) ([]byte, error) {
+	switch {
+	case hexKey != "" && path != "":
+		return nil, fmt.Errorf("the secrets key cannot be given both as a value and as a file")
+	case hexKey != "":
+		key, err := hex.DecodeString(hexKey)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("invalid secrets key: expected 64 hex characters")
+		}
+		return key, nil
+	case path != "":
+		key, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading secrets key: %w", err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("invalid secrets key %q: expected 32 bytes, got %d", path, len(key))
+		}
+		return key, nil
+	}
+	return nil, nil
+}
+
+func readOrCreateKey(path string) ([]byte, error) {
+	key, err := os.ReadFile(path)
+	if err == nil {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("invalid secrets key %q: expected 32 bytes, got %d", path, len(key))
+		}
+		return key, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("reading secrets key: %w", err)
+	}
+
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("generating secrets key: %w", err)
+	}
+	if err := os.WriteFile(path, key, 0600); err != nil {
+		return nil, fmt.Errorf("writing secrets key: %w", err)
+	}
+	return key, nil
+}
+
+// Generate returns a new random secret, safe to use as a password in a DSN.
+func Generate() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generating secret: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Get returns the secrets of the deployment, or ErrNotFound.
+func (s *Store) Get(deploymentID string) (map[string]string, error) {
+	cnt, err := os.ReadFile(s.path(deploymentID))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("reading secrets of %q: %w", deploymentID, err)
+	}
+
+	nonceSize := s.aead.NonceSize()
+	if len(cnt) < nonceSize {
+		return nil, fmt.Errorf("reading secrets of %q: file is truncated", deploymentID)
+	}
+	plain, err := s.aead.Open(nil, cnt[:nonceSize], cnt[nonceSize:], []byte(deploymentID))
+	if err != nil {
+		return nil, fmt.Errorf("decrypting secrets of %q: %w", deploymentID, err)
+	}
+
+	out := make(map[string]string)
+	if err := json.Unmarshal(plain, &out); err != nil {
+		return nil, fmt.Errorf("unmarshalling secrets of %q: %w", deploymentID, err)
+	}
+	return out, nil
+}
+
+// Put replaces the secrets of the deployment.
+func (s *Store) Put(deploymentID string, secrets map[string]string) error {
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return fmt.Errorf("marshalling secrets of %q: %w", deploymentID, err)
+	}
+
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return fmt.Errorf("generating nonce: %w", err)
+	}
+	// the deployment ID is authenticated with the content, so that a file cannot be swapped with another one
+	sealed := s.aead.Seal(nonce, nonce, plain, []byte(deploymentID))
+
+	tmp := s.path(deploymentID) + ".tmp"
+	if err := os.WriteFile(tmp, sealed, 0600); err != nil {
+		return fmt.Errorf("writing secrets of %q: %w", deploymentID, err)
+	}
+	return os.Rename(tmp, s.path(deploymentID))
+}
+
+// Delete removes the secrets of the deployment, if any.
+func (s *Store) Delete(deploymentID string) error {
+	if err := os.Remove(s.path(deploymentID)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("deleting secrets of %q: %w", deploymentID, err)
+	}
+	return nil
+}
+
+func (s *Store) path(deploymentID string) string {
+	return filepath.Join(s.dir, deploymentID+".enc")
+}
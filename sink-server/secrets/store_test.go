@@ -0,0 +1,89 @@
+package secrets
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := Open(dir, nil)
+	require.NoError(t, err)
+
+	_, err = store.Get("dep")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	password, err := Generate()
+	require.NoError(t, err)
+	assert.Len(t, password, 32)
+
+	require.NoError(t, store.Put("dep", map[string]string{"db_password": password}))
+
+	cnt, err := os.ReadFile(filepath.Join(dir, "dep.enc"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(cnt), password)
+
+	// reopening uses the same key
+	store, err = Open(dir, nil)
+	require.NoError(t, err)
+	secrets, err := store.Get("dep")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"db_password": password}, secrets)
+
+	// the content is bound to its deployment
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other.enc"), cnt, 0600))
+	_, err = store.Get("other")
+	assert.ErrorContains(t, err, "decrypting secrets")
+
+	require.NoError(t, store.Delete("dep"))
+	require.NoError(t, store.Delete("dep"))
+	_, err = store.Get("dep")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestStore_Key(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "secrets.key")
+	require.NoError(t, os.WriteFile(keyPath, bytes.Repeat([]byte{0x01}, 32), 0600))
+
+	key, err := LoadKey("", keyPath)
+	require.NoError(t, err)
+	hexKey, err := LoadKey(strings.Repeat("01", 32), "")
+	require.NoError(t, err)
+	assert.Equal(t, key, hexKey)
+
+	// the key is not written next to the secrets
+	dir := t.TempDir()
+	store, err := Open(dir, key)
+	require.NoError(t, err)
+	require.NoError(t, store.Put("dep", map[string]string{"db_password": "secret"}))
+	assert.NoFileExists(t, filepath.Join(dir, keyFile))
+
+	// the secrets can only be read with the same key
+	other, err := Open(dir, nil)
+	require.NoError(t, err)
+	_, err = other.Get("dep")
+	assert.ErrorContains(t, err, "decrypting secrets")
+
+	store, err = Open(dir, hexKey)
+	require.NoError(t, err)
+	secrets, err := store.Get("dep")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"db_password": "secret"}, secrets)
+
+	key, err = LoadKey("", "")
+	require.NoError(t, err)
+	assert.Nil(t, key)
+
+	_, err = LoadKey("0102", "")
+	assert.EqualError(t, err, "invalid secrets key: expected 64 hex characters")
+	_, err = LoadKey(strings.Repeat("01", 32), keyPath)
+	assert.EqualError(t, err, "the secrets key cannot be given both as a value and as a file")
+	_, err = Open(dir, []byte("short"))
+	assert.EqualError(t, err, "invalid secrets key: expected 32 bytes, got 5")
+}
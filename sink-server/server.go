@@ -2,9 +2,13 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -19,6 +23,8 @@ import (
 	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
 	"github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1/pbsinksvcconnect"
 	sinkcontext "github.com/streamingfast/substreams/sink-server/context"
+	"github.com/streamingfast/substreams/sink-server/notify"
+	"github.com/streamingfast/substreams/sink-server/registry"
 	"go.uber.org/zap"
 )
 
@@ -34,10 +40,24 @@ type server struct {
 	authenticator dauth.Authenticator
 	logger        *zap.Logger
 	engine        Engine
+	registry      *registry.Registry
+	limits        Limits
+
+	notifications Notifications
+	monitor       *notify.Monitor
+	broker        *notify.Broker
+	webhooks      []*notify.Webhook
+
+	// quotaLock serializes the requests starting deployments, between their quota check and the start
+	quotaLock sync.Mutex
 
 	shutdownLock sync.RWMutex
 }
 
+// registryFolder holds the deployment registry in the data dir, hidden so that the engines
+// sharing the data dir do not take it for a deployment.
+const registryFolder = ".registry"
+
 func New(
 	ctx context.Context,
 	engine Engine,
@@ -45,8 +65,25 @@ func New(
 	httpListenAddr string,
 	corsHostRegexAllow *regexp.Regexp,
 	authenticator dauth.Authenticator,
+	limits Limits,
+	notifications Notifications,
 	logger *zap.Logger,
 ) (*server, error) {
+	if err := limits.validate(); err != nil {
+		return nil, err
+	}
+	if err := notifications.validate(); err != nil {
+		return nil, err
+	}
+	registryDir := filepath.Join(dataDir, registryFolder)
+	if err := os.MkdirAll(registryDir, 0755); err != nil {
+		return nil, fmt.Errorf("creating registry dir %q: %w", registryDir, err)
+	}
+	reg, err := registry.Open(filepath.Join(registryDir, "registry.db"))
+	if err != nil {
+		return nil, err
+	}
+
 	srv := &server{
 		Shutter:            shutter.New(),
 		httpListenAddr:     httpListenAddr,
@@ -54,6 +91,12 @@ func New(
 		authenticator:      authenticator,
 		logger:             logger,
 		engine:             engine,
+		registry:           reg,
+		limits:             limits,
+		notifications:      notifications,
+		monitor:            notify.NewMonitor(notifications.StallTimeout),
+		broker:             notify.NewBroker(),
+		webhooks:           newWebhooks(notifications, logger),
 	}
 
 	return srv, nil
@@ -85,7 +128,11 @@ func (s *server) Run(ctx context.Context) {
 	srv := connectweb.New([]connectweb.HandlerGetter{streamHandlerGetter}, options...)
 	addr := strings.ReplaceAll(s.httpListenAddr, "*", "")
 
+	watchCtx, stopWatching := context.WithCancel(ctx)
+	go s.watchDeployments(watchCtx)
+
 	s.OnTerminating(func(err error) {
+		stopWatching()
 		s.shutdownLock.Lock()
 		s.logger.Warn("shutting down connect web server")
 
@@ -101,6 +148,9 @@ func (s *server) Run(ctx context.Context) {
 	})
 
 	s.OnTerminated(func(err error) {
+		if err := s.registry.Close(); err != nil {
+			s.logger.Warn("failed to close registry", zap.Error(err))
+		}
 		s.shutdownLock.Unlock()
 	})
 
@@ -121,18 +171,37 @@ func (s *server) Deploy(ctx context.Context, req *connect_go.Request[pbsinksvc.D
 
 	ctx = sinkcontext.SetHeader(ctx, req.Header())
 	ctx = sinkcontext.SetProductionMode(ctx, !req.Msg.GetDevelopmentMode())
-	paramMap := map[string]string{}
-	for _, param := range req.Msg.GetParameters() {
-		paramMap[param.Key] = param.Value
+	ctx = sinkcontext.SetParameterMap(ctx, parameterMap(req.Msg.GetParameters()))
+	ctx, err := s.withSize(ctx)
+	if err != nil {
+		return nil, err
 	}
-	ctx = sinkcontext.SetParameterMap(ctx, paramMap)
 
 	s.logger.Info("deployment request", zap.String("deployment_id", id))
 
+	record := &pbsinksvc.DeploymentRecord{
+		Id:              id,
+		Owner:           dauth.FromContext(ctx).UserID(),
+		PackageHash:     registry.PackageHash(req.Msg.SubstreamsPackage),
+		Parameters:      req.Msg.GetParameters(),
+		DevelopmentMode: req.Msg.GetDevelopmentMode(),
+	}
+
+	s.quotaLock.Lock()
+	defer s.quotaLock.Unlock()
+	if err := s.checkQuota(ctx, id); err != nil {
+		return nil, err
+	}
+
 	info, err := s.engine.Create(ctx, id, req.Msg.SubstreamsPackage, s.logger)
 	if err != nil {
+		record.Status = pbsinksvc.DeploymentStatus_FAILING
+		s.recordDeploy(record, err.Error())
 		return nil, err
 	}
+	record.Status = info.Status
+	record.PackageInfo = info.PackageInfo
+	s.recordDeploy(record, info.Reason)
 
 	return connect_go.NewResponse(&pbsinksvc.DeployResponse{
 		Status:       info.Status,
@@ -146,15 +215,38 @@ func (s *server) Deploy(ctx context.Context, req *connect_go.Request[pbsinksvc.D
 func (s *server) Update(ctx context.Context, req *connect_go.Request[pbsinksvc.UpdateRequest]) (*connect_go.Response[pbsinksvc.UpdateResponse], error) {
 	ctx = sinkcontext.SetHeader(ctx, req.Header())
 	id := req.Msg.DeploymentId
-	_, err := s.engine.Info(ctx, id, s.logger) // only checking if it exists
+	prev, err := s.engine.Info(ctx, id, s.logger)
 	if err != nil {
 		return nil, fmt.Errorf("looking up deployment %q: %w", id, err)
 	}
 
-	s.logger.Info("update request", zap.String("deployment_id", id))
+	s.logger.Info("update request", zap.String("deployment_id", id), zap.Bool("blue_green", req.Msg.BlueGreen))
 
-	err = s.engine.Update(ctx, id, req.Msg.SubstreamsPackage, req.Msg.Reset_, s.logger)
+	ctx, err = s.withDeploymentParameters(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Msg.BlueGreen && req.Msg.Reset_ {
+		return nil, connect_go.NewError(connect_go.CodeInvalidArgument, fmt.Errorf("a blue/green update cannot reset the deployment"))
+	}
+
+	event := &pbsinksvc.HistoryEvent{
+		Action:      "update",
+		PackageHash: registry.PackageHash(req.Msg.SubstreamsPackage),
+	}
+	if req.Msg.BlueGreen {
+		err = s.engine.UpdateBlueGreen(ctx, id, req.Msg.SubstreamsPackage, s.logger)
+	} else {
+		err = s.engine.Update(ctx, id, req.Msg.SubstreamsPackage, req.Msg.Reset_, s.logger)
+	}
 	if err != nil {
+		event.NewStatus = prev.Status
+		event.Reason = err.Error()
+		s.recordAction(ctx, id, event, nil)
+		if errors.Is(err, errors.ErrUnsupported) {
+			return nil, connect_go.NewError(connect_go.CodeUnimplemented, err)
+		}
 		return nil, err
 	}
 
@@ -162,6 +254,15 @@ func (s *server) Update(ctx context.Context, req *connect_go.Request[pbsinksvc.U
 	if err != nil {
 		return nil, err
 	}
+	event.NewStatus = info.Status
+	event.Reason = info.Reason
+	if req.Msg.BlueGreen {
+		// the package of the deployment only changes once the update has caught up
+		event.Reason = "blue/green update pending"
+		s.recordAction(ctx, id, event, nil)
+	} else {
+		s.recordAction(ctx, id, event, info.PackageInfo)
+	}
 
 	return connect_go.NewResponse(&pbsinksvc.UpdateResponse{
 		Status:   info.Status,
@@ -177,6 +278,7 @@ func (s *server) Info(ctx context.Context, req *connect_go.Request[pbsinksvc.Inf
 	if err != nil {
 		return nil, err
 	}
+	s.observeStatus(req.Msg.DeploymentId, info.Status, info.Reason)
 
 	return connect_go.NewResponse(info), nil
 }
@@ -192,6 +294,7 @@ func (s *server) List(ctx context.Context, req *connect_go.Request[pbsinksvc.Lis
 
 	out := &pbsinksvc.ListResponse{}
 	for _, d := range list {
+		s.observeStatus(d.Id, d.Status, d.Reason)
 		out.Deployments = append(out.Deployments, &pbsinksvc.DeploymentWithStatus{
 			Id:          d.Id,
 			Status:      d.Status,
@@ -215,6 +318,7 @@ func (s *server) Pause(ctx context.Context, req *connect_go.Request[pbsinksvc.Pa
 
 	_, err = s.engine.Pause(ctx, req.Msg.DeploymentId, s.logger)
 	if err != nil {
+		s.recordAction(ctx, req.Msg.DeploymentId, &pbsinksvc.HistoryEvent{Action: "pause", NewStatus: prevState, Reason: err.Error()}, nil)
 		return nil, fmt.Errorf("pausing %q: %w", req.Msg.DeploymentId, err)
 	}
 
@@ -226,6 +330,7 @@ func (s *server) Pause(ctx context.Context, req *connect_go.Request[pbsinksvc.Pa
 	if newState == pbsinksvc.DeploymentStatus_UNKNOWN || newState == pbsinksvc.DeploymentStatus_RUNNING {
 		newState = pbsinksvc.DeploymentStatus_PAUSING
 	}
+	s.recordAction(ctx, req.Msg.DeploymentId, &pbsinksvc.HistoryEvent{Action: "pause", NewStatus: newState}, nil)
 
 	out := &pbsinksvc.PauseResponse{
 		PreviousStatus: prevState,
@@ -246,6 +351,7 @@ func (s *server) Stop(ctx context.Context, req *connect_go.Request[pbsinksvc.Sto
 
 	_, err = s.engine.Stop(ctx, req.Msg.DeploymentId, s.logger)
 	if err != nil {
+		s.recordAction(ctx, req.Msg.DeploymentId, &pbsinksvc.HistoryEvent{Action: "stop", NewStatus: prevState, Reason: err.Error()}, nil)
 		return nil, fmt.Errorf("stopping %q: %w", req.Msg.DeploymentId, err)
 	}
 
@@ -257,6 +363,7 @@ func (s *server) Stop(ctx context.Context, req *connect_go.Request[pbsinksvc.Sto
 	if newState == pbsinksvc.DeploymentStatus_UNKNOWN || newState == pbsinksvc.DeploymentStatus_RUNNING {
 		newState = pbsinksvc.DeploymentStatus_STOPPING
 	}
+	s.recordAction(ctx, req.Msg.DeploymentId, &pbsinksvc.HistoryEvent{Action: "stop", NewStatus: newState}, nil)
 
 	out := &pbsinksvc.StopResponse{
 		PreviousStatus: prevState,
@@ -275,8 +382,16 @@ func (s *server) Resume(ctx context.Context, req *connect_go.Request[pbsinksvc.R
 	}
 	prevState := info.Status
 
+	s.quotaLock.Lock()
+	defer s.quotaLock.Unlock()
+	if err := s.checkQuota(ctx, req.Msg.DeploymentId); err != nil {
+		s.recordAction(ctx, req.Msg.DeploymentId, &pbsinksvc.HistoryEvent{Action: "resume", NewStatus: prevState, Reason: err.Error()}, nil)
+		return nil, err
+	}
+
 	_, err = s.engine.Resume(ctx, req.Msg.DeploymentId, prevState, s.logger)
 	if err != nil {
+		s.recordAction(ctx, req.Msg.DeploymentId, &pbsinksvc.HistoryEvent{Action: "resume", NewStatus: prevState, Reason: err.Error()}, nil)
 		return nil, fmt.Errorf("resuming %q: %w", req.Msg.DeploymentId, err)
 	}
 
@@ -288,6 +403,7 @@ func (s *server) Resume(ctx context.Context, req *connect_go.Request[pbsinksvc.R
 	if newState == pbsinksvc.DeploymentStatus_UNKNOWN || newState == pbsinksvc.DeploymentStatus_PAUSED {
 		newState = pbsinksvc.DeploymentStatus_RESUMING
 	}
+	s.recordAction(ctx, req.Msg.DeploymentId, &pbsinksvc.HistoryEvent{Action: "resume", NewStatus: newState}, nil)
 
 	out := &pbsinksvc.ResumeResponse{
 		PreviousStatus: prevState,
@@ -308,11 +424,183 @@ func (s *server) Remove(ctx context.Context, req *connect_go.Request[pbsinksvc.R
 
 	_, err = s.engine.Remove(ctx, req.Msg.DeploymentId, s.logger)
 	if err != nil {
+		s.recordAction(ctx, req.Msg.DeploymentId, &pbsinksvc.HistoryEvent{Action: "remove", NewStatus: prevState, Reason: err.Error()}, nil)
 		return nil, fmt.Errorf("removing %q: %w", req.Msg.DeploymentId, err)
 	}
+	s.recordAction(ctx, req.Msg.DeploymentId, &pbsinksvc.HistoryEvent{Action: "remove", NewStatus: pbsinksvc.DeploymentStatus_REMOVING}, nil)
 
 	out := &pbsinksvc.RemoveResponse{
 		PreviousStatus: prevState,
 	}
 	return connect_go.NewResponse(out), nil
 }
+
+func (s *server) Rollback(ctx context.Context, req *connect_go.Request[pbsinksvc.RollbackRequest]) (*connect_go.Response[pbsinksvc.RollbackResponse], error) {
+	ctx = sinkcontext.SetHeader(ctx, req.Header())
+	id := req.Msg.DeploymentId
+	s.logger.Info("rollback request", zap.String("deployment_id", id))
+
+	info, err := s.engine.Info(ctx, id, s.logger)
+	if err != nil {
+		return nil, fmt.Errorf("looking up deployment %q: %w", id, err)
+	}
+
+	out, err := s.engine.Rollback(ctx, id, s.logger)
+	if err != nil {
+		s.recordAction(ctx, id, &pbsinksvc.HistoryEvent{Action: "rollback", NewStatus: info.Status, Reason: err.Error()}, nil)
+		if errors.Is(err, errors.ErrUnsupported) {
+			return nil, connect_go.NewError(connect_go.CodeUnimplemented, err)
+		}
+		return nil, fmt.Errorf("rolling back %q: %w", id, err)
+	}
+
+	info, err = s.engine.Info(ctx, id, s.logger)
+	if err != nil {
+		return nil, err
+	}
+	event := &pbsinksvc.HistoryEvent{Action: "rollback", NewStatus: info.Status, Reason: info.Reason}
+	if out.CancelledPendingUpdate {
+		event.Reason = "pending update cancelled"
+		s.recordAction(ctx, id, event, nil)
+	} else {
+		s.recordAction(ctx, id, event, out.PackageInfo)
+	}
+
+	return connect_go.NewResponse(out), nil
+}
+
+func (s *server) History(ctx context.Context, req *connect_go.Request[pbsinksvc.HistoryRequest]) (*connect_go.Response[pbsinksvc.HistoryResponse], error) {
+	ctx = sinkcontext.SetHeader(ctx, req.Header())
+	s.logger.Info("history request", zap.String("deployment_id", req.Msg.DeploymentId))
+
+	if err := s.checkOwner(ctx, req.Msg.DeploymentId); err != nil {
+		return nil, err
+	}
+
+	out, err := s.registry.History(req.Msg.DeploymentId)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotFound) {
+			return nil, connect_go.NewError(connect_go.CodeNotFound, fmt.Errorf("deployment %q: %w", req.Msg.DeploymentId, err))
+		}
+		return nil, fmt.Errorf("reading history of %q: %w", req.Msg.DeploymentId, err)
+	}
+	return connect_go.NewResponse(out), nil
+}
+
+func (s *server) Logs(ctx context.Context, req *connect_go.Request[pbsinksvc.LogsRequest], stream *connect_go.ServerStream[pbsinksvc.LogsResponse]) error {
+	ctx = sinkcontext.SetHeader(ctx, req.Header())
+	id := req.Msg.DeploymentId
+	s.logger.Info("logs request", zap.String("deployment_id", id), zap.String("service", req.Msg.Service), zap.Bool("follow", req.Msg.Follow))
+
+	if err := s.checkOwner(ctx, id); err != nil {
+		return err
+	}
+
+	service := req.Msg.Service
+	if service != "" {
+		info, err := s.engine.Info(ctx, id, s.logger)
+		if err != nil {
+			return fmt.Errorf("looking up deployment %q: %w", id, err)
+		}
+		if service, err = serviceName(id, service, info.Services); err != nil {
+			return err
+		}
+	}
+
+	var since time.Time
+	if req.Msg.Since != nil {
+		since = req.Msg.Since.AsTime()
+	}
+
+	var sendLock sync.Mutex
+	send := func(resp *pbsinksvc.LogsResponse) error {
+		sendLock.Lock()
+		defer sendLock.Unlock()
+		return stream.Send(resp)
+	}
+	if err := s.engine.Logs(ctx, id, service, req.Msg.Follow, since, send, s.logger); err != nil {
+		return fmt.Errorf("reading logs of %q: %w", id, err)
+	}
+	return nil
+}
+
+func (s *server) WatchDeployments(ctx context.Context, req *connect_go.Request[pbsinksvc.WatchDeploymentsRequest], stream *connect_go.ServerStream[pbsinksvc.WatchDeploymentsResponse]) error {
+	ctx = sinkcontext.SetHeader(ctx, req.Header())
+	s.logger.Info("watch deployments request", zap.Strings("deployment_ids", req.Msg.DeploymentIds))
+
+	for _, id := range req.Msg.DeploymentIds {
+		if err := s.checkOwner(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	events, unsubscribe := s.broker.Subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-events:
+			if !s.watches(ctx, req.Msg.DeploymentIds, event) {
+				continue
+			}
+			if err := stream.Send(&pbsinksvc.WatchDeploymentsResponse{Event: event}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// checkOwner refuses the requests of the users other than the one that created the deployment. The
+// deployments predating the registry, or created without authentication, have no owner.
+func (s *server) checkOwner(ctx context.Context, deploymentID string) error {
+	record, err := s.registry.Record(deploymentID)
+	switch {
+	case errors.Is(err, registry.ErrNotFound):
+		return nil
+	case err != nil:
+		return fmt.Errorf("reading deployment %q: %w", deploymentID, err)
+	}
+	if record.Owner != "" && record.Owner != dauth.FromContext(ctx).UserID() {
+		return connect_go.NewError(connect_go.CodePermissionDenied, fmt.Errorf("deployment %q belongs to another user", deploymentID))
+	}
+	return nil
+}
+
+// serviceName returns the name of `service` in the services of the deployment, in which it can be given
+// without the deployment ID prefix.
+func serviceName(deploymentID, service string, services map[string]string) (string, error) {
+	for _, name := range []string{service, deploymentID + "-" + service} {
+		if _, found := services[name]; found {
+			return name, nil
+		}
+	}
+
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, strings.TrimPrefix(name, deploymentID+"-"))
+	}
+	sort.Strings(names)
+	return "", connect_go.NewError(connect_go.CodeInvalidArgument, fmt.Errorf("deployment %q has no service %q, available: %s", deploymentID, service, strings.Join(names, ", ")))
+}
+
+// recordDeploy, recordAction and observeStatus keep the history of the deployments, failing to do so is
+// only logged since the request itself went through.
+func (s *server) recordDeploy(record *pbsinksvc.DeploymentRecord, reason string) {
+	if err := s.registry.RecordDeploy(record, reason); err != nil {
+		s.logger.Warn("cannot record deployment", zap.String("deployment_id", record.Id), zap.Error(err))
+	}
+}
+
+func (s *server) recordAction(ctx context.Context, deploymentID string, event *pbsinksvc.HistoryEvent, packageInfo *pbsinksvc.PackageInfo) {
+	event.User = dauth.FromContext(ctx).UserID()
+	if err := s.registry.RecordAction(deploymentID, event, packageInfo); err != nil {
+		s.logger.Warn("cannot record deployment event", zap.String("deployment_id", deploymentID), zap.String("action", event.Action), zap.Error(err))
+	}
+}
+
+func (s *server) observeStatus(deploymentID string, status pbsinksvc.DeploymentStatus, reason string) {
+	if err := s.registry.ObserveStatus(deploymentID, status, reason); err != nil {
+		s.logger.Warn("cannot record deployment status", zap.String("deployment_id", deploymentID), zap.Error(err))
+	}
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	connect_go "connectrpc.com/connect"
+	pbsinksvc "github.com/streamingfast/substreams/pb/sf/substreams/sink/service/v1"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestCheckOwner(t *testing.T) {
+	s := newTestServer(t, nil, Limits{})
+	require.NoError(t, s.registry.RecordDeploy(&pbsinksvc.DeploymentRecord{Id: "alice1", Owner: "alice"}, ""))
+	require.NoError(t, s.registry.RecordDeploy(&pbsinksvc.DeploymentRecord{Id: "anon1"}, ""))
+
+	assert.NoError(t, s.checkOwner(withUser("alice"), "alice1"))
+	assert.Equal(t, connect_go.CodePermissionDenied, connect_go.CodeOf(s.checkOwner(withUser("bob"), "alice1")))
+	assert.Equal(t, connect_go.CodePermissionDenied, connect_go.CodeOf(s.checkOwner(context.Background(), "alice1")))
+
+	// without a known owner
+	assert.NoError(t, s.checkOwner(withUser("bob"), "anon1"))
+	assert.NoError(t, s.checkOwner(withUser("bob"), "unknown"))
+}
+
+func TestHistory(t *testing.T) {
+	s := newTestServer(t, nil, Limits{})
+	require.NoError(t, s.registry.RecordDeploy(&pbsinksvc.DeploymentRecord{Id: "alice1", Owner: "alice"}, ""))
+
+	resp, err := s.History(withUser("alice"), connect_go.NewRequest(&pbsinksvc.HistoryRequest{DeploymentId: "alice1"}))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", resp.Msg.Deployment.Owner)
+
+	_, err = s.History(withUser("bob"), connect_go.NewRequest(&pbsinksvc.HistoryRequest{DeploymentId: "alice1"}))
+	assert.Equal(t, connect_go.CodePermissionDenied, connect_go.CodeOf(err))
+	_, err = s.History(withUser("bob"), connect_go.NewRequest(&pbsinksvc.HistoryRequest{DeploymentId: "unknown"}))
+	assert.Equal(t, connect_go.CodeNotFound, connect_go.CodeOf(err))
+}
+
+type updateEngine struct {
+	Engine
+	status pbsinksvc.DeploymentStatus
+}
+
+func (e *updateEngine) Info(context.Context, string, *zap.Logger) (*pbsinksvc.InfoResponse, error) {
+	return &pbsinksvc.InfoResponse{Status: e.status}, nil
+}
+
+func (e *updateEngine) UpdateBlueGreen(context.Context, string, *pbsubstreams.Package, *zap.Logger) error {
+	return fmt.Errorf("blue/green updates: %w", errors.ErrUnsupported)
+}
+
+func TestUpdate_Error(t *testing.T) {
+	s := newTestServer(t, &updateEngine{status: pbsinksvc.DeploymentStatus_RUNNING}, Limits{})
+	require.NoError(t, s.registry.RecordDeploy(&pbsinksvc.DeploymentRecord{Id: "alice1", Owner: "alice", Status: pbsinksvc.DeploymentStatus_RUNNING}, ""))
+
+	_, err := s.Update(withUser("alice"), connect_go.NewRequest(&pbsinksvc.UpdateRequest{DeploymentId: "alice1", BlueGreen: true, SubstreamsPackage: &pbsubstreams.Package{}}))
+	assert.Equal(t, connect_go.CodeUnimplemented, connect_go.CodeOf(err))
+
+	// the deployment keeps running, the error is only the reason of the event
+	s.observeStatus("alice1", pbsinksvc.DeploymentStatus_RUNNING, "")
+	history, err := s.registry.History("alice1")
+	require.NoError(t, err)
+	assert.Equal(t, pbsinksvc.DeploymentStatus_RUNNING, history.Deployment.Status)
+	last := history.Events[len(history.Events)-1]
+	assert.Equal(t, "update", last.Action)
+	assert.Equal(t, pbsinksvc.DeploymentStatus_RUNNING, last.NewStatus)
+	assert.Equal(t, "blue/green updates: unsupported operation", last.Reason)
+}
+
+func TestServiceName(t *testing.T) {
+	services := map[string]string{"abcd1234-sink": "", "abcd1234-postgres": "", "dbt": ""}
+
+	name, err := serviceName("abcd1234", "sink", services)
+	require.NoError(t, err)
+	assert.Equal(t, "abcd1234-sink", name)
+
+	name, err = serviceName("abcd1234", "abcd1234-postgres", services)
+	require.NoError(t, err)
+	assert.Equal(t, "abcd1234-postgres", name)
+
+	name, err = serviceName("abcd1234", "dbt", services)
+	require.NoError(t, err)
+	assert.Equal(t, "dbt", name)
+
+	_, err = serviceName("abcd1234", "pgweb", services)
+	assert.Equal(t, connect_go.CodeInvalidArgument, connect_go.CodeOf(err))
+	assert.ErrorContains(t, err, "available: dbt, postgres, sink")
+}
+
+func TestCheckDeployments(t *testing.T) {
+	engine := &listEngine{deployments: []*pbsinksvc.DeploymentWithStatus{{Id: "alice1", Status: pbsinksvc.DeploymentStatus_RUNNING}}}
+	s := newTestServer(t, engine, Limits{})
+	require.NoError(t, s.registry.RecordDeploy(&pbsinksvc.DeploymentRecord{Id: "alice1", Owner: "alice"}, ""))
+	events, unsubscribe := s.broker.Subscribe()
+	defer unsubscribe()
+
+	now := time.Now()
+	s.checkDeployments(context.Background(), now)
+	engine.deployments[0].Status = pbsinksvc.DeploymentStatus_FAILING
+	engine.deployments[0].Reason = "sink: exited"
+	s.checkDeployments(context.Background(), now.Add(time.Minute))
+
+	require.Len(t, events, 1)
+	event := <-events
+	assert.Equal(t, "alice1", event.DeploymentId)
+	assert.Equal(t, pbsinksvc.DeploymentStatus_RUNNING, event.PreviousStatus)
+	assert.Equal(t, pbsinksvc.DeploymentStatus_FAILING, event.NewStatus)
+
+	history, err := s.registry.History("alice1")
+	require.NoError(t, err)
+	assert.Equal(t, pbsinksvc.DeploymentStatus_FAILING, history.Deployment.Status)
+
+	assert.True(t, s.watches(withUser("alice"), nil, event))
+	assert.False(t, s.watches(withUser("bob"), nil, event))
+	assert.True(t, s.watches(withUser("alice"), []string{"alice1"}, event))
+	assert.False(t, s.watches(withUser("alice"), []string{"alice2"}, event))
+}
+
+func TestNotificationsValidate(t *testing.T) {
+	assert.NoError(t, Notifications{Webhooks: []string{"https://example.com/hook", "http://localhost:8080"}}.validate())
+	assert.Error(t, Notifications{Webhooks: []string{"example.com/hook"}}.validate())
+}
@@ -0,0 +1,189 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/streamingfast/cli"
+	"github.com/streamingfast/dstore"
+	"go.uber.org/zap"
+
+	"github.com/streamingfast/substreams/manifest"
+	"github.com/streamingfast/substreams/metrics"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"github.com/streamingfast/substreams/reqctx"
+	"github.com/streamingfast/substreams/storage/store"
+	"github.com/streamingfast/substreams/wasm"
+	_ "github.com/streamingfast/substreams/wasm/native"
+	_ "github.com/streamingfast/substreams/wasm/wazero"
+)
+
+var replayTraceCmd = &cobra.Command{
+	Use:   "replay-trace <trace_file> [<manifest|spkg_path>]",
+	Short: "Re-run a module against an execution trace recorded by a Substreams server",
+	Long: cli.Dedent(`
+		Re-runs a single module against an execution trace recorded by a Substreams server started with an
+		execution trace directory. Inputs, reads of stores in 'get' mode and WASM extension responses are all
+		taken from the trace, so no chain data nor endpoint is needed.
+
+		The output, logs and state calls of each replayed block are compared with the recorded ones and any
+		divergence is reported, in which case the command exits with a non-zero code.
+
+		The manifest is optional as it will try to find a file named 'substreams.yaml' in current working
+		directory if nothing entered. It must contain the same code as the one that produced the trace.
+	`),
+	Example: ExamplePrefixed("substreams tools replay-trace", `
+		./traces/tier2-map_pools-12000000-12010000-1701264332000000000.jsonl
+		./traces/tier1-map_pools-12000000-0-1701264332000000000.jsonl substreams-v0.1.0.spkg --module map_pools --block 12000042
+	`),
+	Args: cobra.RangeArgs(1, 2),
+	RunE: replayTraceE,
+}
+
+func init() {
+	replayTraceCmd.Flags().String("module", "", "Module to replay, required when the trace file contains executions of multiple modules")
+	replayTraceCmd.Flags().Uint64("block", 0, "Only replay the execution at this block number")
+
+	Cmd.AddCommand(replayTraceCmd)
+}
+
+func replayTraceE(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	manifestPath := ""
+	if len(args) == 2 {
+		manifestPath = args[1]
+	}
+
+	traces, err := readTraceFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	moduleName := mustGetString(cmd, "module")
+	if moduleName == "" {
+		moduleName, err = singleTracedModule(traces)
+		if err != nil {
+			return err
+		}
+	}
+
+	blockNum := mustGetUint64(cmd, "block")
+	var selected []*wasm.CallTrace
+	for _, trace := range traces {
+		if trace.Module != moduleName {
+			continue
+		}
+		if cmd.Flags().Changed("block") && trace.BlockNum != blockNum {
+			continue
+		}
+		selected = append(selected, trace)
+	}
+	if len(selected) == 0 {
+		return fmt.Errorf("no execution of module %q found in trace file %q", moduleName, args[0])
+	}
+
+	manifestReader, err := manifest.NewReader(manifestPath)
+	if err != nil {
+		return fmt.Errorf("manifest reader: %w", err)
+	}
+
+	pkg, graph, err := manifestReader.Read()
+	if err != nil {
+		return fmt.Errorf("read manifest %q: %w", manifestPath, err)
+	}
+
+	module, err := graph.Module(moduleName)
+	if err != nil {
+		return fmt.Errorf("module %q: %w", moduleName, err)
+	}
+	binary := pkg.Modules.Binaries[module.BinaryIndex]
+
+	stats := metrics.NewReqStats(&metrics.Config{}, zlog)
+	ctx = reqctx.WithReqStats(ctx, stats)
+	ctx = reqctx.WithRequest(ctx, &reqctx.RequestDetails{OutputModule: moduleName})
+
+	var outputStore store.Store
+	var updatePolicy pbsubstreams.Module_KindStore_UpdatePolicy
+	var valueType string
+	if kindStore := module.GetKindStore(); kindStore != nil {
+		updatePolicy = kindStore.UpdatePolicy
+		valueType = kindStore.ValueType
+
+		storeConfig, err := store.NewConfig(module.Name, module.InitialBlock, "", updatePolicy, valueType, dstore.NewMockStore(nil))
+		if err != nil {
+			return fmt.Errorf("creating output store: %w", err)
+		}
+		outputStore = storeConfig.NewFullKV(zlog)
+	}
+
+	replayer, err := wasm.NewTraceReplayer(ctx, binary.Content, binary.Type, selected, outputStore, updatePolicy, valueType, stats)
+	if err != nil {
+		return fmt.Errorf("setting up replay of module %q: %w", moduleName, err)
+	}
+	defer replayer.Close(ctx)
+
+	divergences := 0
+	for _, trace := range selected {
+		replayed, err := replayer.Replay(ctx, trace)
+		if err != nil {
+			return fmt.Errorf("replaying block %d: %w", trace.BlockNum, err)
+		}
+
+		diffs := wasm.CompareTraces(trace, replayed)
+		switch {
+		case len(diffs) != 0:
+			divergences++
+			fmt.Printf("Block #%d (%s): DIVERGED\n", trace.BlockNum, trace.BlockID)
+			for _, diff := range diffs {
+				fmt.Printf("    - %s\n", diff)
+			}
+		case replayed.Error != "":
+			fmt.Printf("Block #%d (%s): reproduced error: %s\n", trace.BlockNum, trace.BlockID, replayed.Error)
+		default:
+			fmt.Printf("Block #%d (%s): OK\n", trace.BlockNum, trace.BlockID)
+		}
+
+		for _, line := range replayed.Logs {
+			zlog.Debug("module log", zap.Uint64("block_num", trace.BlockNum), zap.String("log", line))
+		}
+	}
+
+	fmt.Printf("\nReplayed %d execution(s) of module %q, %d diverged\n", len(selected), moduleName, divergences)
+	if divergences != 0 {
+		return fmt.Errorf("replay diverged from trace on %d block(s)", divergences)
+	}
+	return nil
+}
+
+func readTraceFile(path string) ([]*wasm.CallTrace, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening trace file: %w", err)
+	}
+	defer f.Close()
+
+	traces, err := wasm.ReadTraces(f)
+	if err != nil {
+		return nil, fmt.Errorf("trace file %q: %w", path, err)
+	}
+	return traces, nil
+}
+
+func singleTracedModule(traces []*wasm.CallTrace) (string, error) {
+	seen := map[string]bool{}
+	var names []string
+	for _, trace := range traces {
+		if !seen[trace.Module] {
+			seen[trace.Module] = true
+			names = append(names, trace.Module)
+		}
+	}
+
+	if len(names) != 1 {
+		return "", fmt.Errorf("trace file contains executions of modules %s, use --module to select one", strings.Join(names, ", "))
+	}
+	return names[0], nil
+}
@@ -27,6 +27,8 @@ func NewComparable(expect string, op string, args string) (cmp Comparable, err e
 		cmp, err = newInt(expect, params)
 	case "float":
 		cmp, err = newFloat(expect, params)
+	default:
+		return nil, fmt.Errorf("unknown op %q", op)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("unknown op %q", op)
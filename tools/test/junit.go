@@ -0,0 +1,102 @@
+package test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+	SystemErr string          `xml:"system-err,omitempty"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteJUnit writes the results as a JUnit XML report at `path`, with one test suite per
+// module and one test case per test of the spec. Tests that never matched are reported as skipped.
+func (r *Runner) WriteJUnit(path string) error {
+	report := r.junitReport()
+
+	cnt, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshalling junit report: %w", err)
+	}
+
+	if err := os.WriteFile(path, append([]byte(xml.Header), append(cnt, '\n')...), 0644); err != nil {
+		return fmt.Errorf("writing junit report to %q: %w", path, err)
+	}
+	return nil
+}
+
+func (r *Runner) junitReport() *junitTestSuites {
+	report := &junitTestSuites{}
+
+	suiteIndex := map[string]int{}
+	for _, test := range r.tests {
+		idx, found := suiteIndex[test.moduleName]
+		if !found {
+			idx = len(report.Suites)
+			suiteIndex[test.moduleName] = idx
+			report.Suites = append(report.Suites, junitTestSuite{Name: test.moduleName})
+		}
+		suite := &report.Suites[idx]
+
+		testCase := junitTestCase{
+			Name:      test.name,
+			ClassName: test.moduleName,
+		}
+		switch {
+		case test.failed():
+			testCase.Failure = &junitFailure{
+				Message: test.failures[0],
+				Text:    strings.Join(test.failures, "\n"),
+			}
+			suite.Failures++
+		case !test.matched():
+			testCase.Skipped = &junitSkipped{Message: "no output matched the test"}
+			suite.Skipped++
+		}
+
+		suite.Tests++
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	for i := range report.Suites {
+		if r.streamErr != nil {
+			report.Suites[i].SystemErr = r.streamErr.Error()
+		}
+		report.Tests += report.Suites[i].Tests
+		report.Failures += report.Suites[i].Failures
+		report.Skipped += report.Suites[i].Skipped
+	}
+
+	return report
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/jhump/protoreflect/dynamic"
@@ -15,18 +16,17 @@ import (
 )
 
 type Runner struct {
-	tests          map[uint64]map[string][]*Test
+	tests          []*Test
 	descs          map[string]*manifest.ModuleDescriptor
 	messageFactory *dynamic.MessageFactory
 
+	// stores holds the state of the store modules received in debug outputs, rebuilt from their deltas
+	stores map[string]map[string][]byte
+
 	logger *zap.Logger
 
-	configured uint64
-	passed     uint64
-	failed     uint64
-	notfound   uint64
-	verbose    bool
-	results    []*Result
+	verbose   bool
+	streamErr error
 }
 
 var successStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Bold(true)
@@ -38,32 +38,35 @@ func NewRunner(path string, descs map[string]*manifest.ModuleDescriptor, verbose
 		return nil, fmt.Errorf("reading spec: %w", err)
 	}
 
+	return newRunnerFromSpec(spec, descs, verbose, logger)
+}
+
+func newRunnerFromSpec(spec *Spec, descs map[string]*manifest.ModuleDescriptor, verbose bool, logger *zap.Logger) (*Runner, error) {
 	r := &Runner{
-		tests:          map[uint64]map[string][]*Test{},
 		descs:          descs,
 		messageFactory: dynamic.NewMessageFactoryWithDefaults(),
+		stores:         map[string]map[string][]byte{},
 		logger:         logger.Named("substreams_test"),
 		verbose:        verbose,
-		configured:     uint64(len(spec.Tests)),
 	}
 
 	for idx, testConfig := range spec.Tests {
-		if _, found := r.tests[testConfig.Block]; !found {
-			r.tests[testConfig.Block] = map[string][]*Test{}
-		}
-
-		blockTests := r.tests[testConfig.Block]
-
 		test, err := testConfig.Test(idx)
 		if err != nil {
 			return nil, fmt.Errorf("failed to setup test number %d: %w", idx, err)
 		}
-		blockTests[testConfig.Module] = append(blockTests[testConfig.Module], test)
+		r.tests = append(r.tests, test)
 	}
 
 	return r, nil
 }
 
+// Snapshot loads the initial state of a store module, as received when the module is part of
+// `--debug-modules-initial-snapshot`, so store assertions see keys written before the start block.
+func (r *Runner) Snapshot(data *pbsubstreamsrpc.InitialSnapshotData) {
+	r.applyDeltas(data.ModuleName, data.Deltas)
+}
+
 func (r *Runner) Test(
 	ctx context.Context,
 	output *pbsubstreamsrpc.MapModuleOutput,
@@ -73,41 +76,117 @@ func (r *Runner) Test(
 ) error {
 	logger := r.logger.With(zap.Uint64("block_num", clock.Number))
 
-	blockTests, found := r.tests[clock.Number]
-	if !found {
+	for _, out := range debugStoreOutputs {
+		r.applyDeltas(out.Name, out.DebugStoreDeltas)
+	}
+
+	var blockTests []*Test
+	for _, test := range r.tests {
+		if test.appliesTo(clock.Number) {
+			blockTests = append(blockTests, test)
+		}
+	}
+	if len(blockTests) == 0 {
 		logger.Debug("skip block test not test found")
 		return nil
 	}
 
+	mapOutputs := map[string]*pbsubstreamsrpc.MapModuleOutput{}
 	for _, out := range append([]*pbsubstreamsrpc.MapModuleOutput{output}, debugMapOutputs...) {
-		moduleName := out.Name
-		moduleTests, found := blockTests[moduleName]
-		if !found {
-			logger.Debug("skipping module test no test found", zap.String("module", out.Name))
-			continue
+		if out != nil {
+			mapOutputs[out.Name] = out
 		}
+	}
+	storeOutputs := map[string]*pbsubstreamsrpc.StoreModuleOutput{}
+	for _, out := range debugStoreOutputs {
+		storeOutputs[out.Name] = out
+	}
+
+	for _, test := range blockTests {
+		mapOut, isMap := mapOutputs[test.moduleName]
+		storeOut, isStore := storeOutputs[test.moduleName]
 
-		if err := r.testMapModule(ctx, out, moduleTests, logger); err != nil {
-			return fmt.Errorf("failed to run test on module  %q - %d: %w", out.Name, clock.Number, err)
+		if test.store != nil {
+			if err := r.testStoreState(test, storeOut, logger); err != nil {
+				return fmt.Errorf("failed to run test %q on block %d: %w", test.name, clock.Number, err)
+			}
+		} else if test.code != nil {
+			var err error
+			switch {
+			case isMap:
+				err = r.testMapModule(ctx, mapOut, test, logger)
+			case isStore:
+				err = r.testStoreModule(ctx, storeOut, test, logger)
+			default:
+				logger.Debug("skipping module test no output found", zap.String("module", test.moduleName))
+			}
+			if err != nil {
+				return fmt.Errorf("failed to run test %q on block %d: %w", test.name, clock.Number, err)
+			}
+		}
+
+		if len(test.logs) != 0 {
+			var debugInfo *pbsubstreamsrpc.OutputDebugInfo
+			switch {
+			case isMap:
+				debugInfo = mapOut.DebugInfo
+			case isStore:
+				debugInfo = storeOut.DebugInfo
+			default:
+				continue
+			}
+			r.testLogs(test, debugInfo, clock.Number)
 		}
 	}
 
-	for _, out := range debugStoreOutputs {
-		moduleName := out.Name
-		moduleTests, found := blockTests[moduleName]
-		if !found {
-			logger.Debug("skipping module test no test found", zap.String("module", out.Name))
+	return nil
+}
+
+// StreamEnded is called once the stream is over, with the error it failed with if any, to evaluate
+// the expected failures.
+func (r *Runner) StreamEnded(err error) {
+	r.streamErr = err
+
+	for _, test := range r.tests {
+		if test.expectError == "" {
 			continue
 		}
-		if err := r.testStoreModule(ctx, out, moduleTests, logger); err != nil {
-			return fmt.Errorf("failed to run test on module  %q - %d: %w", out.Name, clock.Number, err)
+
+		switch {
+		case err == nil:
+			test.record(false, fmt.Sprintf("expected stream to fail with an error containing %q, but it completed successfully", test.expectError))
+		case !strings.Contains(err.Error(), test.expectError):
+			test.record(false, fmt.Sprintf("expected stream to fail with an error containing %q, got %q", test.expectError, err.Error()))
+		default:
+			test.record(true, "")
 		}
 	}
+}
 
-	return nil
+// ErrorExpected returns true when the error the stream ended with was expected by the spec and all tests passed.
+func (r *Runner) ErrorExpected() bool {
+	if r.streamErr == nil || r.Failed() {
+		return false
+	}
+	for _, test := range r.tests {
+		if test.expectError != "" {
+			return true
+		}
+	}
+	return false
 }
 
-func (r *Runner) testMapModule(ctx context.Context, module *pbsubstreamsrpc.MapModuleOutput, tests []*Test, logger *zap.Logger) error {
+// Failed returns true when at least one test failed.
+func (r *Runner) Failed() bool {
+	for _, test := range r.tests {
+		if test.failed() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Runner) testMapModule(ctx context.Context, module *pbsubstreamsrpc.MapModuleOutput, test *Test, logger *zap.Logger) error {
 	logger = logger.With(zap.String("module", module.Name), zap.String("module_type", "map"))
 	moduleName := module.Name
 
@@ -144,7 +223,7 @@ func (r *Runner) testMapModule(ctx context.Context, module *pbsubstreamsrpc.MapM
 		return nil
 	}
 
-	return r.runTests(ctx, input, tests, logger)
+	return r.runTest(ctx, input, test, logger)
 }
 
 type StorageDelta struct {
@@ -155,25 +234,14 @@ type StorageDelta struct {
 	NewValue  *json.RawMessage `json:"new,omitempty"`
 }
 
-func (r *Runner) testStoreModule(ctx context.Context, module *pbsubstreamsrpc.StoreModuleOutput, tests []*Test, logger *zap.Logger) error {
+func (r *Runner) testStoreModule(ctx context.Context, module *pbsubstreamsrpc.StoreModuleOutput, test *Test, logger *zap.Logger) error {
 	logger = logger.With(zap.String("module", module.Name), zap.String("module_type", "store"))
-	moduleName := module.Name
 
-	msgDesc, ok := r.descs[moduleName]
+	dynMsg, ok := r.storeValueMessage(module.Name, logger)
 	if !ok {
-		logger.Debug("skipping store module: unable to get store delta message descriptor")
 		return nil
 	}
 
-	var dynMsg *dynamic.Message
-	if msgDesc.ProtoMessageType != "" {
-		dynMsg = r.messageFactory.NewDynamicMessage(msgDesc.MessageDescriptor)
-		if dynMsg == nil {
-			logger.Warn("skipping store module: unable to create dynamic message for store delta decoding")
-			return nil
-		}
-	}
-
 	logger.Debug("running test on store deltas", zap.Int("delta_count", len(module.DebugStoreDeltas)))
 	for _, delta := range module.DebugStoreDeltas {
 		temp := &StorageDelta{
@@ -206,70 +274,200 @@ func (r *Runner) testStoreModule(ctx context.Context, module *pbsubstreamsrpc.St
 			return nil
 		}
 
-		if err := r.runTests(ctx, input, tests, logger); err != nil {
+		if err := r.runTest(ctx, input, test, logger); err != nil {
 			return fmt.Errorf("failed to run tests: %w", err)
 		}
-
 	}
 
 	return nil
 }
 
-func (r *Runner) runTests(ctx context.Context, input interface{}, tests []*Test, logger *zap.Logger) error {
+// storeValueMessage returns the message used to decode the store's values, nil when values are
+// plain strings, and false when the store cannot be decoded.
+func (r *Runner) storeValueMessage(moduleName string, logger *zap.Logger) (*dynamic.Message, bool) {
+	msgDesc, ok := r.descs[moduleName]
+	if !ok {
+		logger.Debug("skipping store module: unable to get store delta message descriptor")
+		return nil, false
+	}
+
+	if msgDesc.ProtoMessageType == "" {
+		return nil, true
+	}
 
-	for _, test := range tests {
-		logger.Debug("running test", zap.String("path", test.path))
-		iter := test.code.RunWithContext(ctx, input) // or query.RunWithContext
-		// we will assume there should be only 1 result
-		v, ok := iter.Next()
-		if !ok {
-			break
-		}
-		if err, ok := v.(error); ok {
-			logger.Debug("failed get path ", zap.Error(err))
+	dynMsg := r.messageFactory.NewDynamicMessage(msgDesc.MessageDescriptor)
+	if dynMsg == nil {
+		logger.Warn("skipping store module: unable to create dynamic message for store delta decoding")
+		return nil, false
+	}
+	return dynMsg, true
+}
+
+func (r *Runner) applyDeltas(moduleName string, deltas []*pbsubstreamsrpc.StoreDelta) {
+	kv, found := r.stores[moduleName]
+	if !found {
+		kv = map[string][]byte{}
+		r.stores[moduleName] = kv
+	}
+
+	for _, delta := range deltas {
+		if delta.Operation == pbsubstreamsrpc.StoreDelta_DELETE {
+			delete(kv, delta.Key)
 			continue
 		}
+		kv[delta.Key] = delta.NewValue
+	}
+}
 
-		actual, ok := v.(string)
-		if !ok {
-			r.notfound++
-			continue
+func (r *Runner) testStoreState(test *Test, module *pbsubstreamsrpc.StoreModuleOutput, logger *zap.Logger) error {
+	kv, tracked := r.stores[test.moduleName]
+	if !tracked {
+		test.record(false, fmt.Sprintf("no deltas received for store %q, add it to --debug-modules-output", test.moduleName))
+		return nil
+	}
+
+	key := test.store.Key
+	value, exists := kv[key]
+
+	if test.store.Exists != nil {
+		switch {
+		case *test.store.Exists && !exists:
+			test.record(false, fmt.Sprintf("[store] expected key %q to exist", key))
+		case !*test.store.Exists && exists:
+			test.record(false, fmt.Sprintf("[store] expected key %q to not exist", key))
+		default:
+			test.record(true, "")
 		}
+	}
 
-		valid, msg, err := test.comparable.Cmp(actual)
-		if err != nil {
-			return fmt.Errorf("failed to run test %d - %s: %w", test.fileIndex, test.path, err)
+	if test.store.Delta != "" {
+		found := false
+		if module != nil {
+			for _, delta := range module.DebugStoreDeltas {
+				if delta.Key == key && delta.Operation.String() == test.store.Delta {
+					found = true
+					break
+				}
+			}
+		}
+		if found {
+			test.record(true, "")
+		} else {
+			test.record(false, fmt.Sprintf("[store] expected a %s delta on key %q", test.store.Delta, key))
 		}
+	}
+
+	if test.code == nil {
+		return nil
+	}
+
+	if !exists {
+		test.record(false, fmt.Sprintf("[store] expected key %q to exist to compare its value", key))
+		return nil
+	}
+
+	dynMsg, ok := r.storeValueMessage(test.moduleName, logger)
+	if !ok {
+		return nil
+	}
 
-		result := &Result{
-			test:  test,
-			Valid: valid,
-			Msg:   msg,
+	var raw *json.RawMessage
+	if dynMsg == nil {
+		raw = r.decodeString(value)
+	} else if raw, ok = r.decodeDynamicStoreDeltas(dynMsg, value, logger); !ok {
+		return fmt.Errorf("failed to decode value of key %q", key)
+	} else if raw == nil {
+		// An empty value is a message with all default fields
+		empty := json.RawMessage("{}")
+		raw = &empty
+	}
+
+	var input interface{}
+	if err := json.Unmarshal(*raw, &input); err != nil {
+		return fmt.Errorf("json unmarshalling value of key %q: %w", key, err)
+	}
+
+	return r.runTest(context.Background(), input, test, logger)
+}
+
+func (r *Runner) testLogs(test *Test, debugInfo *pbsubstreamsrpc.OutputDebugInfo, blockNum uint64) {
+	for _, expected := range test.logs {
+		found := false
+		for _, line := range debugInfo.GetLogs() {
+			if strings.Contains(line, expected) {
+				found = true
+				break
+			}
 		}
-		r.results = append(r.results, result)
-		if result.Valid {
-			r.passed++
+
+		if found {
+			test.record(true, "")
 		} else {
-			r.failed++
+			test.record(false, fmt.Sprintf("[logs] expected a log line containing %q at block %d", expected, blockNum))
 		}
 	}
+}
+
+func (r *Runner) runTest(ctx context.Context, input interface{}, test *Test, logger *zap.Logger) error {
+	logger.Debug("running test", zap.String("path", test.path))
+	iter := test.code.RunWithContext(ctx, input) // or query.RunWithContext
+	// we will assume there should be only 1 result
+	v, ok := iter.Next()
+	if !ok {
+		return nil
+	}
+	if err, ok := v.(error); ok {
+		logger.Debug("failed get path ", zap.Error(err))
+		return nil
+	}
+
+	actual, ok := v.(string)
+	if !ok {
+		return nil
+	}
+
+	valid, msg, err := test.comparable.Cmp(actual)
+	if err != nil {
+		return fmt.Errorf("failed to run test %d - %s: %w", test.fileIndex, test.path, err)
+	}
+
+	test.record(valid, msg)
 	return nil
 }
 
 func (r *Runner) LogResults() {
+	var passed, failed, notMatched int
+	for _, test := range r.tests {
+		switch {
+		case test.failed():
+			failed++
+		case test.passed():
+			passed++
+		default:
+			notMatched++
+		}
+	}
+
 	if r.verbose {
 		fmt.Println()
-		for _, result := range r.results {
+		for _, test := range r.tests {
 			status := successStyle.Render("ok")
-			if !result.Valid {
-				status = fmt.Sprintf("%s > %s", failedStyle.Render("failed"), result.Msg)
+			if test.failed() {
+				status = fmt.Sprintf("%s > %s", failedStyle.Render("failed"), strings.Join(test.failures, "; "))
+			} else if !test.matched() {
+				status = "not matched"
 			}
-			fmt.Printf("test %s:%d:%d ... %s\n", result.test.moduleName, result.test.block, result.test.fileIndex, status)
+			fmt.Printf("test %s ... %s\n", test.name, status)
 		}
 	}
 
+	result := "ok"
+	if failed > 0 {
+		result = "FAILED"
+	}
+
 	fmt.Println()
-	fmt.Printf("test result: ok. %d configured; %d passed; %d failed; %d not matched\n", r.configured, r.passed, r.failed, int(r.configured)-len(r.results))
+	fmt.Printf("test result: %s. %d configured; %d passed; %d failed; %d not matched\n", result, len(r.tests), passed, failed, notMatched)
 	fmt.Println()
 }
 
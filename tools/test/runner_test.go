@@ -0,0 +1,92 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/streamingfast/substreams/manifest"
+	pbsubstreamsrpc "github.com/streamingfast/substreams/pb/sf/substreams/rpc/v2"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestRunner_Scenarios(t *testing.T) {
+	spec := &Spec{Tests: []*TestConfig{
+		{Name: "created", Module: "store_count", Block: 10, Store: &StoreAssertion{Key: "a", Exists: ptr(true), Delta: "CREATE"}},
+		{Name: "deleted", Module: "store_count", Block: 12, Store: &StoreAssertion{Key: "a", Exists: ptr(false)}},
+		{Name: "value", Module: "store_count", Range: "10-12", Store: &StoreAssertion{Key: "a"}, Expect: "5", Op: "int"},
+		{Name: "snapshot", Module: "store_count", Block: 10, Store: &StoreAssertion{Key: "from_snapshot", Exists: ptr(true)}},
+		{Name: "logs", Module: "map_events", Range: "10-13", Logs: []string{"processing"}},
+		{Name: "missing logs", Module: "map_events", Block: 11, Logs: []string{"never logged"}},
+		{Name: "not matched", Module: "map_events", Block: 100, Logs: []string{"processing"}},
+		{Name: "failure", Module: "map_events", ExpectError: "division by zero"},
+	}}
+
+	descs := map[string]*manifest.ModuleDescriptor{
+		"store_count": {StoreValueType: "int64"},
+	}
+	runner, err := newRunnerFromSpec(spec, descs, false, zap.NewNop())
+	require.NoError(t, err)
+
+	runner.Snapshot(&pbsubstreamsrpc.InitialSnapshotData{
+		ModuleName: "store_count",
+		Deltas:     []*pbsubstreamsrpc.StoreDelta{{Operation: pbsubstreamsrpc.StoreDelta_CREATE, Key: "from_snapshot", NewValue: []byte("1")}},
+	})
+
+	block := func(num uint64, log string, deltas ...*pbsubstreamsrpc.StoreDelta) {
+		t.Helper()
+		output := &pbsubstreamsrpc.MapModuleOutput{Name: "map_events", DebugInfo: &pbsubstreamsrpc.OutputDebugInfo{Logs: []string{log}}}
+		stores := []*pbsubstreamsrpc.StoreModuleOutput{{Name: "store_count", DebugStoreDeltas: deltas}}
+		require.NoError(t, runner.Test(context.Background(), output, nil, stores, &pbsubstreams.Clock{Number: num}))
+	}
+
+	block(10, "processing block 10", &pbsubstreamsrpc.StoreDelta{Operation: pbsubstreamsrpc.StoreDelta_CREATE, Key: "a", NewValue: []byte("5")})
+	block(11, "processing block 11")
+	block(12, "processing block 12", &pbsubstreamsrpc.StoreDelta{Operation: pbsubstreamsrpc.StoreDelta_DELETE, Key: "a", OldValue: []byte("5")})
+
+	runner.StreamEnded(errors.New("rpc error: module \"map_events\": division by zero"))
+
+	results := map[string][]string{}
+	for _, test := range runner.tests {
+		if test.matched() {
+			results[test.name] = test.failures
+		}
+	}
+	assert.Equal(t, map[string][]string{
+		"created":      nil,
+		"deleted":      nil,
+		"value":        nil,
+		"snapshot":     nil,
+		"logs":         nil,
+		"missing logs": {`[logs] expected a log line containing "never logged" at block 11`},
+		"failure":      nil,
+	}, results)
+	assert.True(t, runner.Failed())
+	assert.False(t, runner.ErrorExpected())
+
+	junitPath := filepath.Join(t.TempDir(), "junit.xml")
+	require.NoError(t, runner.WriteJUnit(junitPath))
+
+	cnt, err := os.ReadFile(junitPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(cnt), `<testsuites tests="8" failures="1" skipped="1">`)
+	assert.Contains(t, string(cnt), `<testcase name="missing logs" classname="map_events">`)
+	assert.Contains(t, string(cnt), `<skipped message="no output matched the test"></skipped>`)
+}
+
+func TestRunner_ExpectedErrorNotRaised(t *testing.T) {
+	runner, err := newRunnerFromSpec(&Spec{Tests: []*TestConfig{
+		{Module: "map_events", ExpectError: "division by zero"},
+	}}, nil, false, zap.NewNop())
+	require.NoError(t, err)
+
+	runner.StreamEnded(nil)
+	assert.True(t, runner.Failed())
+	assert.Equal(t, []string{`expected stream to fail with an error containing "division by zero", but it completed successfully`}, runner.tests[0].failures)
+}
@@ -12,6 +12,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 )
 
 type Spec struct {
@@ -19,16 +20,98 @@ type Spec struct {
 }
 
 type TestConfig struct {
+	// Name identifies the test in the results and JUnit report, defaults to `<module>:<block>:<index>`
+	Name   string `json:"name,omitempty" yaml:"name"`
 	Module string `json:"module" yaml:"module"`
 	Block  uint64 `json:"block" yaml:"block"`
+	// Range runs the test on every block received in `<start>-<end>` (end exclusive) instead of a single Block.
+	Range  string `json:"range,omitempty" yaml:"range"`
 	Path   string `json:"path" yaml:"path"`
 	Expect string `json:"expect" yaml:"expect"`
 	Op     string `json:"op,omitempty" yaml:"op"`
 	Args   string `json:"args,omitempty" yaml:"args"`
+
+	// Store asserts on the state of a store module, built from its deltas, instead of its output.
+	Store *StoreAssertion `json:"store,omitempty" yaml:"store"`
+	// Logs are substrings that must each be found in the module's logs.
+	Logs []string `json:"logs,omitempty" yaml:"logs"`
+	// ExpectError is a substring of the error the stream is expected to fail with.
+	ExpectError string `json:"expect_error,omitempty" yaml:"expect_error"`
+}
+
+type StoreAssertion struct {
+	Key string `json:"key" yaml:"key"`
+	// Exists asserts the key is present (true) or absent (false) once the block is applied.
+	Exists *bool `json:"exists,omitempty" yaml:"exists"`
+	// Delta asserts the block has a delta on the key with this operation (CREATE, UPDATE or DELETE).
+	Delta string `json:"delta,omitempty" yaml:"delta"`
 }
 
 func (t *TestConfig) Test(idx int) (*Test, error) {
-	query, err := gojq.Parse(t.Path)
+	test := &Test{
+		name:        t.Name,
+		path:        t.Path,
+		block:       t.Block,
+		moduleName:  t.Module,
+		fileIndex:   idx,
+		store:       t.Store,
+		logs:        t.Logs,
+		expectError: t.ExpectError,
+	}
+
+	if t.Range != "" {
+		startBlock, endBlock, err := parseBlockRange(t.Range)
+		if err != nil {
+			return nil, fmt.Errorf("invalid range %q: %w", t.Range, err)
+		}
+		test.startBlock, test.endBlock = startBlock, endBlock
+	} else {
+		test.startBlock, test.endBlock = t.Block, t.Block+1
+	}
+
+	if test.name == "" {
+		where := strconv.FormatUint(t.Block, 10)
+		if t.Range != "" {
+			where = t.Range
+		}
+		test.name = fmt.Sprintf("%s:%s:%d", t.Module, where, idx)
+	}
+
+	if t.ExpectError != "" {
+		if t.Path != "" || t.Store != nil || len(t.Logs) != 0 {
+			return nil, fmt.Errorf("'expect_error' cannot be combined with other assertions")
+		}
+		return test, nil
+	}
+
+	if t.Store != nil {
+		if t.Store.Key == "" {
+			return nil, fmt.Errorf("store assertion requires a 'key'")
+		}
+		switch t.Store.Delta {
+		case "", "CREATE", "UPDATE", "DELETE":
+		default:
+			return nil, fmt.Errorf("invalid store delta operation %q, must be one of CREATE, UPDATE or DELETE", t.Store.Delta)
+		}
+		if t.Store.Exists == nil && t.Store.Delta == "" && t.Expect == "" {
+			return nil, fmt.Errorf("store assertion on key %q asserts nothing, set 'exists', 'delta' or 'expect'", t.Store.Key)
+		}
+	}
+
+	path := t.Path
+	if t.Store != nil && path == "" && t.Expect != "" {
+		// The value of the key itself
+		path = "."
+	}
+
+	if path == "" {
+		if t.Store == nil && len(t.Logs) == 0 {
+			return nil, fmt.Errorf("test asserts nothing, set one of 'path', 'store', 'logs' or 'expect_error'")
+		}
+		return test, nil
+	}
+
+	query, err := gojq.Parse(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse jq path: %w", err)
 	}
@@ -42,14 +125,27 @@ func (t *TestConfig) Test(idx int) (*Test, error) {
 		return nil, fmt.Errorf("failed to setup comparator: %w", err)
 	}
 
-	return &Test{
-		code:       code,
-		path:       t.Path,
-		block:      t.Block,
-		moduleName: t.Module,
-		fileIndex:  idx,
-		comparable: cmp,
-	}, nil
+	test.path = path
+	test.code = code
+	test.comparable = cmp
+	return test, nil
+}
+
+func parseBlockRange(in string) (start, end uint64, err error) {
+	parts := strings.Split(in, "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("expected format <start>-<end>")
+	}
+	if start, err = strconv.ParseUint(strings.TrimSpace(parts[0]), 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("start block: %w", err)
+	}
+	if end, err = strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("end block: %w", err)
+	}
+	if end <= start {
+		return 0, 0, fmt.Errorf("end block %d must be greater than start block %d", end, start)
+	}
+	return start, end, nil
 }
 
 func readSpecFromFile(path string) (*Spec, error) {
@@ -152,16 +248,37 @@ func readSpecFromYAML(reader io.Reader) (*Spec, error) {
 }
 
 type Test struct {
+	name       string
 	code       *gojq.Code
 	path       string
 	comparable comparator.Comparable
 	moduleName string
 	block      uint64
 	fileIndex  int
+
+	// [startBlock, endBlock)
+	startBlock uint64
+	endBlock   uint64
+
+	store       *StoreAssertion
+	logs        []string
+	expectError string
+
+	evaluations int
+	failures    []string
 }
 
-type Result struct {
-	test  *Test
-	Valid bool
-	Msg   string
+func (t *Test) appliesTo(blockNum uint64) bool {
+	return t.expectError == "" && blockNum >= t.startBlock && blockNum < t.endBlock
 }
+
+func (t *Test) record(valid bool, msg string) {
+	t.evaluations++
+	if !valid {
+		t.failures = append(t.failures, msg)
+	}
+}
+
+func (t *Test) passed() bool  { return t.evaluations > 0 && len(t.failures) == 0 }
+func (t *Test) failed() bool  { return len(t.failures) > 0 }
+func (t *Test) matched() bool { return t.evaluations > 0 }
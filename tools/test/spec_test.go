@@ -102,6 +102,39 @@ func Test_readSpecFromFile(t *testing.T) {
 				},
 			},
 		},
+		{
+			filepath: "./testdata/test_spec_scenarios.yaml",
+			expect: &Spec{
+				Tests: []*TestConfig{
+					{
+						Name:   "pool created",
+						Module: "store_pools",
+						Block:  12370078,
+						Store: &StoreAssertion{
+							Key:    "pool:6f48eca74b38d2936b02ab603ff4e36a6c0e3a77",
+							Exists: ptr(true),
+							Delta:  "CREATE",
+						},
+					},
+					{
+						Module: "store_pool_count",
+						Range:  "12370000-12370100",
+						Store:  &StoreAssertion{Key: "count"},
+						Expect: "10",
+						Op:     "int",
+					},
+					{
+						Module: "map_pools",
+						Range:  "12370000-12370100",
+						Logs:   []string{"processing block"},
+					},
+					{
+						Module:      "map_pools",
+						ExpectError: "division by zero",
+					},
+				},
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -112,3 +145,36 @@ func Test_readSpecFromFile(t *testing.T) {
 		})
 	}
 }
+
+func TestTestConfig_Test(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    *TestConfig
+		expectErr string
+	}{
+		{"path", &TestConfig{Module: "map", Block: 10, Path: ".a", Expect: "1"}, ""},
+		{"range", &TestConfig{Module: "map", Range: "10-20", Path: ".a", Expect: "1"}, ""},
+		{"invalid range", &TestConfig{Module: "map", Range: "20-10", Path: ".a", Expect: "1"}, "invalid range \"20-10\": end block 10 must be greater than start block 20"},
+		{"unknown op", &TestConfig{Module: "map", Block: 10, Path: ".a", Expect: "1", Op: "bool"}, "failed to setup comparator: unknown op \"bool\""},
+		{"nothing asserted", &TestConfig{Module: "map", Block: 10}, "test asserts nothing, set one of 'path', 'store', 'logs' or 'expect_error'"},
+		{"store without key", &TestConfig{Module: "store", Block: 10, Store: &StoreAssertion{Exists: ptr(true)}}, "store assertion requires a 'key'"},
+		{"store asserts nothing", &TestConfig{Module: "store", Block: 10, Store: &StoreAssertion{Key: "a"}}, "store assertion on key \"a\" asserts nothing, set 'exists', 'delta' or 'expect'"},
+		{"invalid delta", &TestConfig{Module: "store", Block: 10, Store: &StoreAssertion{Key: "a", Delta: "SET"}}, "invalid store delta operation \"SET\", must be one of CREATE, UPDATE or DELETE"},
+		{"expect error combined", &TestConfig{Module: "map", ExpectError: "boom", Logs: []string{"a"}}, "'expect_error' cannot be combined with other assertions"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.config.Test(0)
+			if test.expectErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, test.expectErr)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,269 @@
+package tui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+
+	pbsubstreamsrpc "github.com/streamingfast/substreams/pb/sf/substreams/rpc/v2"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+// maxGoldenDiffLines caps the number of differences printed for a single golden file
+const maxGoldenDiffLines = 20
+
+// Golden compares the decoded output of each module at each block with a golden file
+// `<dir>/<module>/<block>.json` holding its canonical JSON, or (re)writes those files when
+// `update` is set.
+type Golden struct {
+	dir    string
+	update bool
+
+	matched    int
+	written    int
+	mismatches []string
+}
+
+func NewGolden(dir string, update bool) *Golden {
+	return &Golden{
+		dir:    dir,
+		update: update,
+	}
+}
+
+// GoldenError is returned by IncomingMessage when a golden file cannot be compared or written, the
+// run cannot be trusted anymore and must stop.
+type GoldenError struct {
+	Err error
+}
+
+func (e *GoldenError) Error() string { return e.Err.Error() }
+func (e *GoldenError) Unwrap() error { return e.Err }
+
+// SetGolden enables golden files comparison (or update) for every block received.
+func (ui *TUI) SetGolden(golden *Golden) {
+	ui.golden = golden
+}
+
+func (ui *TUI) checkGolden(data *pbsubstreamsrpc.BlockScopedData) error {
+	clock := data.Clock
+
+	for _, out := range append([]*pbsubstreamsrpc.MapModuleOutput{data.Output}, data.DebugMapOutputs...) {
+		if _, ok := ui.msgTypes[out.Name]; !ok {
+			continue
+		}
+
+		var cnt []byte
+		if len(out.MapOutput.GetValue()) != 0 {
+			cnt = ui.decodeDynamicMessage(ui.msgTypes[out.Name], ui.msgDescs[out.Name], clock.Number, out.Name, out.MapOutput)
+		}
+		if err := ui.golden.check(out.Name, clock, cnt); err != nil {
+			return err
+		}
+	}
+
+	for _, out := range data.DebugStoreOutputs {
+		if _, ok := ui.msgTypes[out.Name]; !ok {
+			continue
+		}
+
+		var cnt []byte
+		if len(out.DebugStoreDeltas) != 0 {
+			var err error
+			if cnt, err = ui.jsonBlockDeltas(out.Name, clock.Number, out.DebugStoreDeltas); err != nil {
+				return fmt.Errorf("golden: %w", err)
+			}
+		}
+		if err := ui.golden.check(out.Name, clock, cnt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// check compares `cnt`, the JSON output of `module` at `clock` (nil when the module produced
+// nothing), with its golden file.
+func (g *Golden) check(module string, clock *pbsubstreams.Clock, cnt []byte) error {
+	path := filepath.Join(g.dir, module, fmt.Sprintf("%d.json", clock.Number))
+
+	var actual interface{}
+	var canonical []byte
+	if cnt != nil {
+		if err := json.Unmarshal(cnt, &actual); err != nil {
+			return fmt.Errorf("golden: decoding output of %q at block %d: %w", module, clock.Number, err)
+		}
+
+		// Marshalling a decoded value sorts object keys, giving a stable representation
+		var err error
+		if canonical, err = json.MarshalIndent(actual, "", "  "); err != nil {
+			return fmt.Errorf("golden: encoding output of %q at block %d: %w", module, clock.Number, err)
+		}
+		canonical = append(canonical, '\n')
+	}
+
+	if g.update {
+		return g.write(path, canonical)
+	}
+
+	expectedCnt, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("golden: reading %q: %w", path, err)
+	}
+
+	switch {
+	case os.IsNotExist(err) && canonical == nil:
+		return nil
+	case os.IsNotExist(err):
+		g.mismatch(path, []string{"golden file does not exist, run with --update-golden to create it"})
+		return nil
+	case canonical == nil:
+		g.mismatch(path, []string{"module produced no output, but a golden file exists"})
+		return nil
+	case bytes.Equal(expectedCnt, canonical):
+		g.matched++
+		return nil
+	}
+
+	var expected interface{}
+	if err := json.Unmarshal(expectedCnt, &expected); err != nil {
+		return fmt.Errorf("golden: decoding %q: %w", path, err)
+	}
+
+	var diffs []string
+	diffJSON("", expected, actual, &diffs)
+	if len(diffs) == 0 {
+		// Only formatting differs
+		g.matched++
+		return nil
+	}
+	g.mismatch(path, diffs)
+	return nil
+}
+
+func (g *Golden) write(path string, canonical []byte) error {
+	if canonical == nil {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("golden: removing %q: %w", path, err)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("golden: creating directory: %w", err)
+	}
+	if err := os.WriteFile(path, canonical, 0644); err != nil {
+		return fmt.Errorf("golden: writing %q: %w", path, err)
+	}
+	g.written++
+	return nil
+}
+
+func (g *Golden) mismatch(path string, diffs []string) {
+	g.mismatches = append(g.mismatches, path)
+
+	fmt.Fprintf(os.Stderr, "golden mismatch: %s\n", path)
+	for i, diff := range diffs {
+		if i == maxGoldenDiffLines {
+			fmt.Fprintf(os.Stderr, "  ... %d more difference(s)\n", len(diffs)-maxGoldenDiffLines)
+			break
+		}
+		fmt.Fprintf(os.Stderr, "  %s\n", diff)
+	}
+}
+
+// Report prints a summary and returns an error if any output did not match its golden file.
+func (g *Golden) Report() error {
+	if g.update {
+		fmt.Printf("golden files: %d written to %s\n", g.written, g.dir)
+		return nil
+	}
+
+	fmt.Printf("golden files: %d matched; %d mismatched\n", g.matched, len(g.mismatches))
+	if len(g.mismatches) != 0 {
+		return fmt.Errorf("%d output(s) do not match their golden file in %s", len(g.mismatches), g.dir)
+	}
+	return nil
+}
+
+// diffJSON appends to `diffs` a line for every difference between two decoded JSON values,
+// identified by its jq-like path.
+func diffJSON(path string, expected, actual interface{}, diffs *[]string) {
+	displayPath := path
+	if displayPath == "" {
+		displayPath = "."
+	}
+
+	switch exp := expected.(type) {
+	case map[string]interface{}:
+		act, ok := actual.(map[string]interface{})
+		if !ok {
+			*diffs = append(*diffs, fmt.Sprintf("%s: expected %s, got %s", displayPath, formatJSONValue(expected), formatJSONValue(actual)))
+			return
+		}
+
+		keys := make([]string, 0, len(exp)+len(act))
+		for key := range exp {
+			keys = append(keys, key)
+		}
+		for key := range act {
+			if _, found := exp[key]; !found {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			expValue, inExpected := exp[key]
+			actValue, inActual := act[key]
+			keyPath := path + "." + key
+			switch {
+			case !inActual:
+				*diffs = append(*diffs, fmt.Sprintf("%s: missing, expected %s", keyPath, formatJSONValue(expValue)))
+			case !inExpected:
+				*diffs = append(*diffs, fmt.Sprintf("%s: unexpected, got %s", keyPath, formatJSONValue(actValue)))
+			default:
+				diffJSON(keyPath, expValue, actValue, diffs)
+			}
+		}
+
+	case []interface{}:
+		act, ok := actual.([]interface{})
+		if !ok {
+			*diffs = append(*diffs, fmt.Sprintf("%s: expected %s, got %s", displayPath, formatJSONValue(expected), formatJSONValue(actual)))
+			return
+		}
+
+		if len(exp) != len(act) {
+			*diffs = append(*diffs, fmt.Sprintf("%s: expected %d element(s), got %d", displayPath, len(exp), len(act)))
+		}
+		for i := 0; i < len(exp) && i < len(act); i++ {
+			diffJSON(path+"["+strconv.Itoa(i)+"]", exp[i], act[i], diffs)
+		}
+
+	default:
+		if !reflect.DeepEqual(expected, actual) {
+			*diffs = append(*diffs, fmt.Sprintf("%s: expected %s, got %s", displayPath, formatJSONValue(expected), formatJSONValue(actual)))
+		}
+	}
+}
+
+func formatJSONValue(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "an array"
+	}
+
+	cnt, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(cnt)
+}
@@ -0,0 +1,118 @@
+package tui
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pbsubstreamsrpc "github.com/streamingfast/substreams/pb/sf/substreams/rpc/v2"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+)
+
+func TestGolden(t *testing.T) {
+	dir := t.TempDir()
+
+	newUI := func(update bool) (*TUI, *Golden) {
+		ui := New(nil, nil, nil)
+		ui.msgTypes["store_count"] = "int64"
+		golden := NewGolden(dir, update)
+		ui.SetGolden(golden)
+		return ui, golden
+	}
+
+	block := func(num uint64, value string) *pbsubstreamsrpc.BlockScopedData {
+		return &pbsubstreamsrpc.BlockScopedData{
+			Clock:  &pbsubstreams.Clock{Number: num},
+			Output: &pbsubstreamsrpc.MapModuleOutput{Name: "map_unprinted"},
+			DebugStoreOutputs: []*pbsubstreamsrpc.StoreModuleOutput{{
+				Name: "store_count",
+				DebugStoreDeltas: []*pbsubstreamsrpc.StoreDelta{
+					{Operation: pbsubstreamsrpc.StoreDelta_UPDATE, Key: "count", NewValue: []byte(value)},
+				},
+			}},
+		}
+	}
+
+	ui, golden := newUI(true)
+	require.NoError(t, ui.checkGolden(block(10, "1")))
+	require.NoError(t, ui.checkGolden(block(11, "2")))
+	require.NoError(t, golden.Report())
+	assert.Equal(t, 2, golden.written)
+
+	cnt, err := os.ReadFile(filepath.Join(dir, "store_count", "10.json"))
+	require.NoError(t, err)
+	assert.Equal(t, `{
+  "@block": 10,
+  "@deltas": [
+    {
+      "key": "count",
+      "new": "1",
+      "old": null,
+      "op": "UPDATE",
+      "ordinal": 0
+    }
+  ],
+  "@module": "store_count"
+}
+`, string(cnt))
+
+	ui, golden = newUI(false)
+	require.NoError(t, ui.checkGolden(block(10, "1")))
+	require.NoError(t, ui.checkGolden(block(11, "3")))
+	require.NoError(t, ui.checkGolden(block(12, "4")))
+	assert.Equal(t, 1, golden.matched)
+	assert.Equal(t, []string{filepath.Join(dir, "store_count", "11.json"), filepath.Join(dir, "store_count", "12.json")}, golden.mismatches)
+	assert.EqualError(t, golden.Report(), "2 output(s) do not match their golden file in "+dir)
+}
+
+func TestGolden_WriteError(t *testing.T) {
+	// the golden directory cannot be created under a file
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, nil, 0644))
+
+	ui := New(nil, nil, nil)
+	ui.outputMode = OutputModeJSONL
+	ui.msgTypes["store_count"] = "int64"
+	ui.SetGolden(NewGolden(file, true))
+
+	err := ui.IncomingMessage(context.Background(), &pbsubstreamsrpc.Response{Message: &pbsubstreamsrpc.Response_BlockScopedData{BlockScopedData: &pbsubstreamsrpc.BlockScopedData{
+		Clock:  &pbsubstreams.Clock{Number: 10},
+		Output: &pbsubstreamsrpc.MapModuleOutput{Name: "map_unprinted"},
+		DebugStoreOutputs: []*pbsubstreamsrpc.StoreModuleOutput{{
+			Name:             "store_count",
+			DebugStoreDeltas: []*pbsubstreamsrpc.StoreDelta{{Operation: pbsubstreamsrpc.StoreDelta_UPDATE, Key: "count", NewValue: []byte("1")}},
+		}},
+	}}}, nil)
+
+	var goldenErr *GoldenError
+	require.ErrorAs(t, err, &goldenErr)
+	assert.ErrorContains(t, err, "golden: creating directory")
+}
+
+func TestDiffJSON(t *testing.T) {
+	decode := func(in string) (out interface{}) {
+		require.NoError(t, json.Unmarshal([]byte(in), &out))
+		return
+	}
+
+	var diffs []string
+	diffJSON("",
+		decode(`{"a": 1, "b": {"c": [1, 2, 3], "d": "x"}, "e": true, "f": {"g": 1}}`),
+		decode(`{"a": 2, "b": {"c": [1, 5], "d": "x"}, "f": [], "h": null}`),
+		&diffs,
+	)
+
+	assert.Equal(t, []string{
+		".a: expected 1, got 2",
+		".b.c: expected 3 element(s), got 2",
+		".b.c[1]: expected 2, got 5",
+		".e: missing, expected true",
+		".f: expected an object, got an array",
+		".h: unexpected, got null",
+	}, diffs)
+}
@@ -95,6 +95,15 @@ func (ui *TUI) renderDecoratedDeltas(modName string, blockNum uint64, deltas []*
 }
 
 func (ui *TUI) printJSONBlockDeltas(modName string, blockNum uint64, deltas []*pbsubstreamsrpc.StoreDelta) error {
+	cnt, err := ui.jsonBlockDeltas(modName, blockNum, deltas)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(ui.prettyFormat(cnt, false)))
+	return nil
+}
+
+func (ui *TUI) jsonBlockDeltas(modName string, blockNum uint64, deltas []*pbsubstreamsrpc.StoreDelta) ([]byte, error) {
 	wrap := DeltasWrap{
 		Module:   modName,
 		BlockNum: blockNum,
@@ -115,10 +124,9 @@ func (ui *TUI) printJSONBlockDeltas(modName string, blockNum uint64, deltas []*p
 	}
 	cnt, err := json.Marshal(wrap)
 	if err != nil {
-		return fmt.Errorf("marshal wrap: %w", err)
+		return nil, fmt.Errorf("marshal wrap: %w", err)
 	}
-	fmt.Println(string(ui.prettyFormat(cnt, false)))
-	return nil
+	return cnt, nil
 }
 
 func indent(in []byte) []byte {
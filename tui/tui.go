@@ -41,6 +41,8 @@ type TUI struct {
 	msgDescs       map[string]*desc.MessageDescriptor
 	decodeMsgTypes map[string]func(in []byte) string
 	msgTypes       map[string]string // Replace by calls to GetFullyQualifiedName() on the `msgDescs`
+
+	golden *Golden
 }
 
 func New(req *pbsubstreamsrpc.Request, pkg *pbsubstreams.Package, outputStreamNames []string) *TUI {
@@ -164,13 +166,25 @@ func (ui *TUI) IncomingMessage(ctx context.Context, resp *pbsubstreamsrpc.Respon
 		if m.BlockScopedData == nil {
 			return nil
 		}
+		var goldenErr error
+		if ui.golden != nil {
+			if err := ui.checkGolden(m.BlockScopedData); err != nil {
+				goldenErr = &GoldenError{Err: err}
+			}
+		}
 		ui.seenFirstData = true
+		var err error
 		if ui.outputMode == OutputModeTUI {
 			ui.ensureTerminalUnlocked()
-			return ui.decoratedBlockScopedData(m.BlockScopedData.Output, m.BlockScopedData.DebugMapOutputs, m.BlockScopedData.DebugStoreOutputs, m.BlockScopedData.Clock)
+			err = ui.decoratedBlockScopedData(m.BlockScopedData.Output, m.BlockScopedData.DebugMapOutputs, m.BlockScopedData.DebugStoreOutputs, m.BlockScopedData.Clock)
 		} else {
-			return ui.jsonBlockScopedData(m.BlockScopedData.Output, m.BlockScopedData.DebugMapOutputs, m.BlockScopedData.DebugStoreOutputs, m.BlockScopedData.Clock)
+			err = ui.jsonBlockScopedData(m.BlockScopedData.Output, m.BlockScopedData.DebugMapOutputs, m.BlockScopedData.DebugStoreOutputs, m.BlockScopedData.Clock)
 		}
+		// the block is printed before failing on the golden files
+		if goldenErr != nil {
+			return goldenErr
+		}
+		return err
 	case *pbsubstreamsrpc.Response_Progress:
 		if m.Progress.ProcessedBytes != nil {
 			ui.TotalReadBytes = m.Progress.ProcessedBytes.TotalBytesRead
@@ -183,6 +197,9 @@ func (ui *TUI) IncomingMessage(ctx context.Context, resp *pbsubstreamsrpc.Respon
 			}
 		}
 	case *pbsubstreamsrpc.Response_DebugSnapshotData:
+		if testRunner != nil {
+			testRunner.Snapshot(m.DebugSnapshotData)
+		}
 		if ui.outputMode == OutputModeTUI {
 			ui.ensureTerminalUnlocked()
 			return ui.decoratedSnapshotData(m.DebugSnapshotData)
@@ -77,10 +77,10 @@ func NewStoreDeltaInput(name string) *StoreDeltaInput {
 
 type StoreReaderInput struct {
 	BaseArgument
-	Store store.Store
+	Store store.Reader
 }
 
-func NewStoreReaderInput(name string, store store.Store) *StoreReaderInput {
+func NewStoreReaderInput(name string, store store.Reader) *StoreReaderInput {
 	return &StoreReaderInput{
 		BaseArgument: BaseArgument{
 			name: name,
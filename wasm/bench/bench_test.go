@@ -66,7 +66,7 @@ func BenchmarkExecution(b *testing.B) {
 
 				wasmRuntime := wasm.NewRegistryWithRuntime(config.name, nil, 0)
 
-				module, err := wasmRuntime.NewModule(ctx, config.code)
+				module, err := wasmRuntime.NewModule(ctx, config.code, wasm.DefaultBinaryType)
 				require.NoError(b, err)
 
 				cachedInstance, err := module.NewInstance(ctx)
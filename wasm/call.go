@@ -3,6 +3,7 @@ package wasm
 import (
 	"fmt"
 	"math/big"
+	"strconv"
 	"time"
 
 	"github.com/dustin/go-humanize"
@@ -31,6 +32,8 @@ type Call struct {
 	LogsByteCount  uint64
 	ExecutionStack []string
 	stats          *metrics.Stats
+
+	trace *CallTrace
 }
 
 func NewCall(clock *pbsubstreams.Clock, moduleName string, entrypoint string, stats *metrics.Stats, arguments []Argument) *Call {
@@ -119,16 +122,19 @@ func (c *Call) ReachedLogsMaxByteCount() bool {
 func (c *Call) DoSet(ord uint64, key string, value []byte) {
 	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
 	c.validateSimple("set", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, key)
+	c.recordStateWrite("set", ord, key, value)
 	c.outputStore.SetBytes(ord, key, value)
 }
 func (c *Call) DoSetIfNotExists(ord uint64, key string, value []byte) {
 	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
 	c.validateSimple("set_if_not_exists", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET_IF_NOT_EXISTS, key)
+	c.recordStateWrite("set_if_not_exists", ord, key, value)
 	c.outputStore.SetBytesIfNotExists(ord, key, value)
 }
 func (c *Call) DoAppend(ord uint64, key string, value []byte) {
 	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
 	c.validateSimple("append", pbsubstreams.Module_KindStore_UPDATE_POLICY_APPEND, key)
+	c.recordStateWrite("append", ord, key, value)
 	if err := c.outputStore.Append(ord, key, value); err != nil {
 		c.ReturnError(fmt.Errorf("appending to store: %w", err))
 	}
@@ -136,11 +142,13 @@ func (c *Call) DoAppend(ord uint64, key string, value []byte) {
 func (c *Call) DoDeletePrefix(ord uint64, prefix string) {
 	defer c.stats.RecordModuleWasmStoreDeletePrefix(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
 	c.traceStateWrites("delete_prefix", prefix)
+	c.recordStateWrite("delete_prefix", ord, prefix, nil)
 	c.outputStore.DeletePrefix(ord, prefix)
 }
 func (c *Call) DoAddBigInt(ord uint64, key string, value string) {
 	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
 	c.validateWithValueType("add_bigint", pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, "bigint", key)
+	c.recordStateWrite("add_bigint", ord, key, []byte(value))
 
 	toAdd, _ := new(big.Int).SetString(value, 10)
 	c.outputStore.SumBigInt(ord, key, toAdd)
@@ -148,6 +156,7 @@ func (c *Call) DoAddBigInt(ord uint64, key string, value string) {
 func (c *Call) DoAddBigDecimal(ord uint64, key string, value string) {
 	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
 	c.validateWithTwoValueTypes("add_bigdecimal", pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, "bigdecimal", "bigfloat", key)
+	c.recordStateWrite("add_bigdecimal", ord, key, []byte(value))
 
 	toAdd, err := decimal.NewFromString(string(value))
 	if err != nil {
@@ -158,32 +167,38 @@ func (c *Call) DoAddBigDecimal(ord uint64, key string, value string) {
 func (c *Call) DoAddInt64(ord uint64, key string, value int64) {
 	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
 	c.validateWithValueType("add_int64", pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, "int64", key)
+	c.recordStateWrite("add_int64", ord, key, []byte(strconv.FormatInt(value, 10)))
 	c.outputStore.SumInt64(ord, key, value)
 }
 func (c *Call) DoAddFloat64(ord uint64, key string, value float64) {
 	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
 	c.validateWithValueType("add_float64", pbsubstreams.Module_KindStore_UPDATE_POLICY_ADD, "float64", key)
+	c.recordStateWrite("add_float64", ord, key, []byte(strconv.FormatFloat(value, 'g', -1, 64)))
 	c.outputStore.SumFloat64(ord, key, value)
 }
 func (c *Call) DoSetMinInt64(ord uint64, key string, value int64) {
 	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
 	c.validateWithValueType("set_min_int64", pbsubstreams.Module_KindStore_UPDATE_POLICY_MIN, "int64", key)
+	c.recordStateWrite("set_min_int64", ord, key, []byte(strconv.FormatInt(value, 10)))
 	c.outputStore.SetMinInt64(ord, key, value)
 }
 func (c *Call) DoSetMinBigInt(ord uint64, key string, value string) {
 	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
 	c.validateWithValueType("set_min_bigint", pbsubstreams.Module_KindStore_UPDATE_POLICY_MIN, "bigint", key)
+	c.recordStateWrite("set_min_bigint", ord, key, []byte(value))
 	toSet, _ := new(big.Int).SetString(value, 10)
 	c.outputStore.SetMinBigInt(ord, key, toSet)
 }
 func (c *Call) DoSetMinFloat64(ord uint64, key string, value float64) {
 	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
 	c.validateWithValueType("set_min_float64", pbsubstreams.Module_KindStore_UPDATE_POLICY_MIN, "float64", key)
+	c.recordStateWrite("set_min_float64", ord, key, []byte(strconv.FormatFloat(value, 'g', -1, 64)))
 	c.outputStore.SetMinFloat64(ord, key, value)
 }
 func (c *Call) DoSetMinBigDecimal(ord uint64, key string, value string) {
 	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
 	c.validateWithTwoValueTypes("set_min_bigdecimal", pbsubstreams.Module_KindStore_UPDATE_POLICY_MIN, "bigdecimal", "bigfloat", key)
+	c.recordStateWrite("set_min_bigdecimal", ord, key, []byte(value))
 	toAdd, err := decimal.NewFromString(value)
 	if err != nil {
 		c.ReturnError(fmt.Errorf("parsing bigdecimal: %w", err))
@@ -193,11 +208,13 @@ func (c *Call) DoSetMinBigDecimal(ord uint64, key string, value string) {
 func (c *Call) DoSetMaxInt64(ord uint64, key string, value int64) {
 	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
 	c.validateWithValueType("set_max_int64", pbsubstreams.Module_KindStore_UPDATE_POLICY_MAX, "int64", key)
+	c.recordStateWrite("set_max_int64", ord, key, []byte(strconv.FormatInt(value, 10)))
 	c.outputStore.SetMaxInt64(ord, key, value)
 }
 func (c *Call) DoSetMaxBigInt(ord uint64, key string, value string) {
 	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
 	c.validateWithValueType("set_max_bigint", pbsubstreams.Module_KindStore_UPDATE_POLICY_MAX, "bigint", key)
+	c.recordStateWrite("set_max_bigint", ord, key, []byte(value))
 	toSet, _ := new(big.Int).SetString(value, 10)
 	c.outputStore.SetMaxBigInt(ord, key, toSet)
 
@@ -205,11 +222,13 @@ func (c *Call) DoSetMaxBigInt(ord uint64, key string, value string) {
 func (c *Call) DoSetMaxFloat64(ord uint64, key string, value float64) {
 	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
 	c.validateWithValueType("set_max_float64", pbsubstreams.Module_KindStore_UPDATE_POLICY_MAX, "float64", key)
+	c.recordStateWrite("set_max_float64", ord, key, []byte(strconv.FormatFloat(value, 'g', -1, 64)))
 	c.outputStore.SetMaxFloat64(ord, key, value)
 }
 func (c *Call) DoSetMaxBigDecimal(ord uint64, key string, value string) {
 	defer c.stats.RecordModuleWasmStoreWrite(c.ModuleName, c.outputStore.SizeBytes(), time.Since(time.Now()))
 	c.validateWithTwoValueTypes("set_max_bigdecimal", pbsubstreams.Module_KindStore_UPDATE_POLICY_MAX, "bigdecimal", "bigfloat", key)
+	c.recordStateWrite("set_max_bigdecimal", ord, key, []byte(value))
 	toAdd, err := decimal.NewFromString(value)
 	if err != nil {
 		c.ReturnError(fmt.Errorf("parsing bigdecimal: %w", err))
@@ -220,49 +239,49 @@ func (c *Call) DoSetMaxBigDecimal(ord uint64, key string, value string) {
 func (c *Call) DoGetAt(storeIndex int, ord uint64, key string) (value []byte, found bool) {
 	defer c.stats.RecordModuleWasmStoreRead(c.ModuleName, time.Since(time.Now()))
 	c.validateStoreIndex(storeIndex, "get_at")
-	readStore := c.inputStores[storeIndex]
-	c.traceStateReads("get_at", storeIndex, found, key)
-	return readStore.GetAt(ord, key)
+	value, found = c.inputStores[storeIndex].GetAt(ord, key)
+	c.traceStateReads("get_at", storeIndex, ord, key, value, found)
+	return
 }
 
 func (c *Call) DoHasAt(storeIndex int, ord uint64, key string) (found bool) {
 	defer c.stats.RecordModuleWasmStoreRead(c.ModuleName, time.Since(time.Now()))
 	c.validateStoreIndex(storeIndex, "has_at")
-	readStore := c.inputStores[storeIndex]
-	c.traceStateReads("has_at", storeIndex, found, key)
-	return readStore.HasAt(ord, key)
+	found = c.inputStores[storeIndex].HasAt(ord, key)
+	c.traceStateReads("has_at", storeIndex, ord, key, nil, found)
+	return
 }
 
 func (c *Call) DoGetFirst(storeIndex int, key string) (value []byte, found bool) {
 	defer c.stats.RecordModuleWasmStoreRead(c.ModuleName, time.Since(time.Now()))
 	c.validateStoreIndex(storeIndex, "get_first")
-	readStore := c.inputStores[storeIndex]
-	c.traceStateReads("get_first", storeIndex, found, key)
-	return readStore.GetFirst(key)
+	value, found = c.inputStores[storeIndex].GetFirst(key)
+	c.traceStateReads("get_first", storeIndex, 0, key, value, found)
+	return
 }
 
 func (c *Call) DoHasFirst(storeIndex int, key string) (found bool) {
 	defer c.stats.RecordModuleWasmStoreRead(c.ModuleName, time.Since(time.Now()))
 	c.validateStoreIndex(storeIndex, "has_first")
-	readStore := c.inputStores[storeIndex]
-	c.traceStateReads("has_first", storeIndex, found, key)
-	return readStore.HasFirst(key)
+	found = c.inputStores[storeIndex].HasFirst(key)
+	c.traceStateReads("has_first", storeIndex, 0, key, nil, found)
+	return
 }
 
 func (c *Call) DoGetLast(storeIndex int, key string) (value []byte, found bool) {
 	defer c.stats.RecordModuleWasmStoreRead(c.ModuleName, time.Since(time.Now()))
 	c.validateStoreIndex(storeIndex, "get_last")
-	readStore := c.inputStores[storeIndex]
-	c.traceStateReads("get_last", storeIndex, found, key)
-	return readStore.GetLast(key)
+	value, found = c.inputStores[storeIndex].GetLast(key)
+	c.traceStateReads("get_last", storeIndex, 0, key, value, found)
+	return
 }
 
 func (c *Call) DoHasLast(storeIndex int, key string) (found bool) {
 	defer c.stats.RecordModuleWasmStoreRead(c.ModuleName, time.Since(time.Now()))
 	c.validateStoreIndex(storeIndex, "has_last")
-	readStore := c.inputStores[storeIndex]
-	c.traceStateReads("has_last", storeIndex, found, key)
-	return readStore.HasLast(key)
+	found = c.inputStores[storeIndex].HasLast(key)
+	c.traceStateReads("has_last", storeIndex, 0, key, nil, found)
+	return
 }
 
 func (c *Call) validateStoreIndex(storeIndex int, stateFunc string) {
@@ -303,10 +322,11 @@ func (c *Call) traceStateWrites(stateFunc, key string) {
 	c.ExecutionStack = append(c.ExecutionStack, line)
 }
 
-func (c *Call) traceStateReads(stateFunc string, storeIndex int, found bool, key string) {
+func (c *Call) traceStateReads(stateFunc string, storeIndex int, ord uint64, key string, value []byte, found bool) {
 	store := c.inputStores[storeIndex]
 	line := fmt.Sprintf("%s::%s key: %q, found: %v, store details: %s", store.Name(), stateFunc, key, found, store.String())
 	c.ExecutionStack = append(c.ExecutionStack, line)
+	c.recordStateCall(stateFunc, storeIndex, ord, key, value, found)
 }
 
 func (c *Call) returnInvalidPolicy(stateFunc, policy string) {
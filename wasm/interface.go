@@ -2,6 +2,10 @@ package wasm
 
 import (
 	"context"
+	"fmt"
+	"sort"
+
+	"golang.org/x/exp/maps"
 
 	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
 )
@@ -70,3 +74,29 @@ var runtimes = map[string]ModuleFactory{}
 func RegisterModuleFactory(name string, factory ModuleFactory) {
 	runtimes[name] = factory
 }
+
+// DefaultBinaryType is the `Binary.Type` of modules compiled against the
+// Substreams-specific ABI (`alloc`/`dealloc` exports, pointer/length arguments).
+// Those are always loaded through the runtime selected on the Registry.
+const DefaultBinaryType = "wasm/rust-v1"
+
+var binaryTypes = map[string]ModuleFactory{}
+
+// RegisterBinaryTypeFactory registers the ModuleFactory used to load modules
+// whose `Binary.Type` is `binaryType` (ex: `wasm/wasi`), regardless of the
+// runtime selected for DefaultBinaryType modules.
+func RegisterBinaryTypeFactory(binaryType string, factory ModuleFactory) {
+	if binaryType == DefaultBinaryType {
+		panic(fmt.Sprintf("cannot override binary type %q, use RegisterModuleFactory instead", binaryType))
+	}
+	binaryTypes[binaryType] = factory
+}
+
+// SupportedBinaryTypes returns the list of `Binary.Type` values that can be loaded
+// by a Registry, DefaultBinaryType first.
+func SupportedBinaryTypes() []string {
+	out := []string{DefaultBinaryType}
+	keys := maps.Keys(binaryTypes)
+	sort.Strings(keys)
+	return append(out, keys...)
+}
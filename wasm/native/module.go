@@ -0,0 +1,108 @@
+package native
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"golang.org/x/exp/maps"
+
+	"github.com/streamingfast/substreams/wasm"
+)
+
+const BinaryType = "native"
+
+// Func is the Go implementation of a module, called once per block like its WASM counterpart.
+//
+// The `args` are the module's inputs, in order, as built by the pipeline: sources, maps, params
+// and store deltas are `wasm.ValueArgument`, stores in `get` mode are `*wasm.StoreReaderInput`
+// whose `Store` can be read directly, and a store module also receives its `*wasm.StoreWriterOutput`
+// as its last argument.
+//
+// The returned output is used as the module's output; it is ignored for store modules.
+type Func func(ctx context.Context, call *wasm.Call, args []wasm.Argument) (output []byte, err error)
+
+var funcsLock sync.RWMutex
+var funcs = map[string]Func{}
+
+func init() {
+	wasm.RegisterBinaryTypeFactory(BinaryType, wasm.ModuleFactoryFunc(newModule))
+}
+
+// Register makes `f` available to modules of binary type `native` whose entrypoint is `entrypoint`.
+// It is meant to be called by operators at startup, before any request is served.
+func Register(entrypoint string, f Func) {
+	funcsLock.Lock()
+	defer funcsLock.Unlock()
+
+	if _, found := funcs[entrypoint]; found {
+		panic(fmt.Sprintf("native module entrypoint %q already registered", entrypoint))
+	}
+	funcs[entrypoint] = f
+}
+
+// Entrypoints returns the sorted list of registered entrypoints.
+func Entrypoints() []string {
+	funcsLock.RLock()
+	defer funcsLock.RUnlock()
+
+	out := maps.Keys(funcs)
+	sort.Strings(out)
+	return out
+}
+
+func lookup(entrypoint string) (Func, bool) {
+	funcsLock.RLock()
+	defer funcsLock.RUnlock()
+
+	f, found := funcs[entrypoint]
+	return f, found
+}
+
+// Module runs registered Go functions in-process. Its binary content is the `native` identifier
+// declared in the manifest, only used to make module hashes change when the implementation does.
+type Module struct {
+	identifier string
+}
+
+func newModule(ctx context.Context, code []byte, registry *wasm.Registry) (wasm.Module, error) {
+	return &Module{identifier: string(code)}, nil
+}
+
+func (m *Module) NewInstance(ctx context.Context) (wasm.Instance, error) {
+	return &instance{}, nil
+}
+
+func (m *Module) ExecuteNewCall(ctx context.Context, call *wasm.Call, cachedInstance wasm.Instance, arguments []wasm.Argument) (wasm.Instance, error) {
+	inst := cachedInstance
+	if inst == nil {
+		inst = &instance{}
+	}
+
+	f, found := lookup(call.Entrypoint)
+	if !found {
+		return inst, fmt.Errorf("native module %q: entrypoint %q not registered on this server", m.identifier, call.Entrypoint)
+	}
+
+	output, err := f(ctx, call, arguments)
+	if err != nil {
+		return inst, fmt.Errorf("native module %q: %w", m.identifier, err)
+	}
+	if output != nil {
+		call.SetReturnValue(output)
+	}
+
+	return inst, nil
+}
+
+func (m *Module) Close(ctx context.Context) error {
+	return nil
+}
+
+// instance is stateless: native functions must not keep state across calls
+// to stay deterministic.
+type instance struct{}
+
+func (i *instance) Cleanup(ctx context.Context) error { return nil }
+func (i *instance) Close(ctx context.Context) error   { return nil }
@@ -0,0 +1,85 @@
+package native
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/streamingfast/substreams/metrics"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"github.com/streamingfast/substreams/storage/store"
+	"github.com/streamingfast/substreams/wasm"
+	_ "github.com/streamingfast/substreams/wasm/wazero"
+)
+
+func init() {
+	Register("test_map_echo", func(ctx context.Context, call *wasm.Call, args []wasm.Argument) ([]byte, error) {
+		return append([]byte("echo:"), args[0].(wasm.ValueArgument).Value()...), nil
+	})
+	Register("test_store_set", func(ctx context.Context, call *wasm.Call, args []wasm.Argument) ([]byte, error) {
+		reader := args[0].(*wasm.StoreReaderInput).Store
+		value, found := reader.GetLast("in")
+		if !found {
+			return nil, fmt.Errorf("key %q not found", "in")
+		}
+		args[1].(*wasm.StoreWriterOutput).Store.SetBytes(0, "out", value)
+		return nil, nil
+	})
+}
+
+func TestModule_ExecuteNewCall(t *testing.T) {
+	ctx := context.Background()
+	registry := wasm.NewRegistry(nil, 0)
+	stats := metrics.NewReqStats(&metrics.Config{}, zap.NewNop())
+
+	mod, err := registry.NewModule(ctx, []byte("test@v1"), BinaryType)
+	require.NoError(t, err)
+
+	t.Run("map", func(t *testing.T) {
+		args := []wasm.Argument{wasm.NewParamsInput("hello")}
+		call := wasm.NewCall(&pbsubstreams.Clock{Number: 1}, "map_echo", "test_map_echo", stats, args)
+
+		_, err := mod.ExecuteNewCall(ctx, call, nil, args)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("echo:hello"), call.Output())
+	})
+
+	t.Run("store", func(t *testing.T) {
+		in := newTestStore(t, "store_in")
+		in.SetBytes(0, "in", []byte("value"))
+		out := newTestStore(t, "store_out")
+
+		args := []wasm.Argument{
+			wasm.NewStoreReaderInput("store_in", in),
+			wasm.NewStoreWriterOutput("store_out", out, pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "bytes"),
+		}
+		call := wasm.NewCall(&pbsubstreams.Clock{Number: 1}, "store_out", "test_store_set", stats, args)
+
+		_, err := mod.ExecuteNewCall(ctx, call, nil, args)
+		require.NoError(t, err)
+
+		value, found := out.GetLast("out")
+		require.True(t, found)
+		assert.Equal(t, []byte("value"), value)
+	})
+
+	t.Run("unknown entrypoint", func(t *testing.T) {
+		call := wasm.NewCall(&pbsubstreams.Clock{Number: 1}, "map_unknown", "map_unknown", stats, nil)
+
+		_, err := mod.ExecuteNewCall(ctx, call, nil, nil)
+		assert.EqualError(t, err, `native module "test@v1": entrypoint "map_unknown" not registered on this server`)
+	})
+}
+
+func newTestStore(t *testing.T, name string) store.Store {
+	t.Helper()
+
+	conf, err := store.NewConfig(name, 0, "", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "bytes", dstore.NewMockStore(nil))
+	require.NoError(t, err)
+	return conf.NewFullKV(zap.NewNop())
+}
@@ -45,8 +45,18 @@ func (r *Registry) registerWASMExtension(namespace string, importName string, ex
 func (r *Registry) MaxFuel() uint64            { return r.maxFuel }
 func (r *Registry) InstanceCacheEnabled() bool { return r.instanceCacheEnabled }
 
-func (r *Registry) NewModule(ctx context.Context, wasmCode []byte) (Module, error) {
-	return r.runtimeStack.NewModule(ctx, wasmCode, r)
+// NewModule loads `wasmCode` using the runtime matching its `Binary.Type`. An empty
+// `wasmCodeType` is treated as DefaultBinaryType.
+func (r *Registry) NewModule(ctx context.Context, wasmCode []byte, wasmCodeType string) (Module, error) {
+	if wasmCodeType == "" || wasmCodeType == DefaultBinaryType {
+		return r.runtimeStack.NewModule(ctx, wasmCode, r)
+	}
+
+	factory, found := binaryTypes[wasmCodeType]
+	if !found {
+		return nil, fmt.Errorf("unsupported binary type %q (valid values are %q)", wasmCodeType, strings.Join(SupportedBinaryTypes(), ", "))
+	}
+	return factory.NewModule(ctx, wasmCode, r)
 }
 
 func NewRegistry(extensions map[string]map[string]WASMExtension, maxFuel uint64) *Registry {
@@ -0,0 +1,259 @@
+package wasm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/streamingfast/substreams/metrics"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"github.com/streamingfast/substreams/storage/store"
+)
+
+// TraceReplayer re-executes a module against traces recorded by a TraceRecorder. Inputs
+// are taken from the trace, reads of stores in `get` mode are served from the recorded
+// state calls and WASM extensions answer with their recorded responses, so no chain data
+// nor endpoint is required.
+type TraceReplayer struct {
+	module Module
+	stats  *metrics.Stats
+
+	outputStore  store.Store
+	updatePolicy pbsubstreams.Module_KindStore_UpdatePolicy
+	valueType    string
+
+	current           *CallTrace
+	nextExtensionCall int
+}
+
+// NewTraceReplayer loads `code` for replaying `traces`. For store modules, `outputStore` receives
+// the writes and `updatePolicy` and `valueType` must be those of the module; it is nil for maps.
+func NewTraceReplayer(ctx context.Context, code []byte, binaryType string, traces []*CallTrace, outputStore store.Store, updatePolicy pbsubstreams.Module_KindStore_UpdatePolicy, valueType string, stats *metrics.Stats) (*TraceReplayer, error) {
+	r := &TraceReplayer{
+		stats:        stats,
+		outputStore:  outputStore,
+		updatePolicy: updatePolicy,
+		valueType:    valueType,
+	}
+
+	extensions := map[string]map[string]WASMExtension{}
+	for _, trace := range traces {
+		for _, extCall := range trace.ExtensionCalls {
+			if extensions[extCall.Namespace] == nil {
+				extensions[extCall.Namespace] = map[string]WASMExtension{}
+			}
+			extensions[extCall.Namespace][extCall.Function] = r.replayExtension(extCall.Namespace, extCall.Function)
+		}
+	}
+
+	module, err := NewRegistry(extensions, 0).NewModule(ctx, code, binaryType)
+	if err != nil {
+		return nil, fmt.Errorf("loading module: %w", err)
+	}
+	r.module = module
+
+	return r, nil
+}
+
+// Replay executes the module on the block of `trace` and returns the trace of the new execution.
+func (r *TraceReplayer) Replay(ctx context.Context, trace *CallTrace) (*CallTrace, error) {
+	arguments, err := r.arguments(trace)
+	if err != nil {
+		return nil, err
+	}
+
+	r.current = trace
+	r.nextExtensionCall = 0
+
+	clock := &pbsubstreams.Clock{
+		Id:        trace.BlockID,
+		Number:    trace.BlockNum,
+		Timestamp: timestamppb.New(trace.Timestamp),
+	}
+	call := NewCall(clock, trace.Module, trace.Entrypoint, r.stats, arguments)
+	call.StartTrace(arguments)
+
+	inst, err := r.module.ExecuteNewCall(ctx, call, nil, arguments)
+	if inst != nil {
+		if closeErr := inst.Close(ctx); closeErr != nil && err == nil {
+			err = fmt.Errorf("closing instance: %w", closeErr)
+		}
+	}
+
+	return call.FinishTrace(err), nil
+}
+
+func (r *TraceReplayer) Close(ctx context.Context) error {
+	return r.module.Close(ctx)
+}
+
+func (r *TraceReplayer) arguments(trace *CallTrace) (out []Argument, err error) {
+	storeIndex := 0
+	for _, in := range trace.Inputs {
+		switch in.Kind {
+		case TracedInputSource:
+			arg := NewSourceInput(in.Name)
+			arg.SetValue(in.Value)
+			out = append(out, arg)
+		case TracedInputMap:
+			arg := NewMapInput(in.Name)
+			arg.SetValue(in.Value)
+			out = append(out, arg)
+		case TracedInputStoreDeltas:
+			arg := NewStoreDeltaInput(in.Name)
+			arg.SetValue(in.Value)
+			out = append(out, arg)
+		case TracedInputParams:
+			out = append(out, NewParamsInput(string(in.Value)))
+		case TracedInputStoreReader:
+			out = append(out, NewStoreReaderInput(in.Name, newReplayStoreReader(in.Name, storeIndex, trace)))
+			storeIndex++
+		case TracedInputStoreWriter:
+			if r.outputStore == nil {
+				return nil, fmt.Errorf("trace of module %q at block %d writes to a store but no output store was provided", trace.Module, trace.BlockNum)
+			}
+			out = append(out, NewStoreWriterOutput(in.Name, r.outputStore, r.updatePolicy, r.valueType))
+		default:
+			return nil, fmt.Errorf("trace of module %q at block %d: unknown input kind %q", trace.Module, trace.BlockNum, in.Kind)
+		}
+	}
+	return out, nil
+}
+
+func (r *TraceReplayer) replayExtension(namespace, function string) WASMExtension {
+	return func(ctx context.Context, requestID string, clock *pbsubstreams.Clock, in []byte) (out []byte, err error) {
+		calls := r.current.ExtensionCalls
+		if r.nextExtensionCall >= len(calls) {
+			return nil, fmt.Errorf("unexpected call to extension %s::%s, only %d calls were recorded", namespace, function, len(calls))
+		}
+
+		recorded := calls[r.nextExtensionCall]
+		r.nextExtensionCall++
+
+		if recorded.Namespace != namespace || recorded.Function != function {
+			return nil, fmt.Errorf("call #%d to extension %s::%s was recorded as a call to %s::%s", r.nextExtensionCall, namespace, function, recorded.Namespace, recorded.Function)
+		}
+		if !bytes.Equal(recorded.Input, in) {
+			return nil, fmt.Errorf("call #%d to extension %s::%s has a different input than the recorded one", r.nextExtensionCall, namespace, function)
+		}
+		if recorded.Error != "" {
+			return nil, errors.New(recorded.Error)
+		}
+		return recorded.Output, nil
+	}
+}
+
+// replayStoreReader serves the reads recorded for a store in `get` mode. A read that was
+// not recorded returns not found, and is reported as a divergence by CompareTraces.
+type replayStoreReader struct {
+	name  string
+	reads map[string]*TracedStateCall
+}
+
+func newReplayStoreReader(name string, storeIndex int, trace *CallTrace) *replayStoreReader {
+	r := &replayStoreReader{
+		name:  name,
+		reads: map[string]*TracedStateCall{},
+	}
+	for _, stateCall := range trace.StateCalls {
+		if stateCall.StoreIndex == storeIndex {
+			r.reads[replayReadKey(stateCall.Func, stateCall.Ordinal, stateCall.Key)] = stateCall
+		}
+	}
+	return r
+}
+
+func replayReadKey(stateFunc string, ord uint64, key string) string {
+	return fmt.Sprintf("%s:%d:%s", stateFunc, ord, key)
+}
+
+func (r *replayStoreReader) lookup(stateFunc string, ord uint64, key string) ([]byte, bool) {
+	if read, found := r.reads[replayReadKey(stateFunc, ord, key)]; found {
+		return read.Value, read.Found
+	}
+	return nil, false
+}
+
+func (r *replayStoreReader) Name() string   { return r.name }
+func (r *replayStoreReader) String() string { return fmt.Sprintf("replay(%s)", r.name) }
+
+func (r *replayStoreReader) GetFirst(key string) ([]byte, bool) { return r.lookup("get_first", 0, key) }
+func (r *replayStoreReader) GetLast(key string) ([]byte, bool)  { return r.lookup("get_last", 0, key) }
+func (r *replayStoreReader) GetAt(ord uint64, key string) ([]byte, bool) {
+	return r.lookup("get_at", ord, key)
+}
+
+func (r *replayStoreReader) HasFirst(key string) bool {
+	_, found := r.lookup("has_first", 0, key)
+	return found
+}
+func (r *replayStoreReader) HasLast(key string) bool {
+	_, found := r.lookup("has_last", 0, key)
+	return found
+}
+func (r *replayStoreReader) HasAt(ord uint64, key string) bool {
+	_, found := r.lookup("has_at", ord, key)
+	return found
+}
+
+// CompareTraces returns a description of every difference between a recorded and a replayed
+// execution, or nil when the replay is identical.
+func CompareTraces(recorded, replayed *CallTrace) (diffs []string) {
+	if !bytes.Equal(recorded.Output, replayed.Output) {
+		diffs = append(diffs, fmt.Sprintf("output differs: recorded %d bytes, replayed %d bytes", len(recorded.Output), len(replayed.Output)))
+	}
+	if recorded.Error != replayed.Error {
+		diffs = append(diffs, fmt.Sprintf("error differs: recorded %q, replayed %q", recorded.Error, replayed.Error))
+	}
+
+	if len(recorded.Logs) != len(replayed.Logs) {
+		diffs = append(diffs, fmt.Sprintf("log count differs: recorded %d, replayed %d", len(recorded.Logs), len(replayed.Logs)))
+	} else {
+		for i := range recorded.Logs {
+			if recorded.Logs[i] != replayed.Logs[i] {
+				diffs = append(diffs, fmt.Sprintf("log #%d differs: recorded %q, replayed %q", i, recorded.Logs[i], replayed.Logs[i]))
+			}
+		}
+	}
+
+	if len(recorded.StateCalls) != len(replayed.StateCalls) {
+		diffs = append(diffs, fmt.Sprintf("state call count differs: recorded %d, replayed %d", len(recorded.StateCalls), len(replayed.StateCalls)))
+	}
+	for i := 0; i < len(recorded.StateCalls) && i < len(replayed.StateCalls); i++ {
+		rec, rep := recorded.StateCalls[i], replayed.StateCalls[i]
+		if rec.Func != rep.Func || rec.StoreIndex != rep.StoreIndex || rec.Ordinal != rep.Ordinal || rec.Key != rep.Key || !bytes.Equal(rec.Value, rep.Value) || rec.Found != rep.Found {
+			diffs = append(diffs, fmt.Sprintf("state call #%d differs: recorded %s, replayed %s", i, rec, rep))
+			break
+		}
+	}
+
+	if len(recorded.ExtensionCalls) != len(replayed.ExtensionCalls) {
+		diffs = append(diffs, fmt.Sprintf("extension call count differs: recorded %d, replayed %d", len(recorded.ExtensionCalls), len(replayed.ExtensionCalls)))
+	}
+	for i := 0; i < len(recorded.ExtensionCalls) && i < len(replayed.ExtensionCalls); i++ {
+		rec, rep := recorded.ExtensionCalls[i], replayed.ExtensionCalls[i]
+		if rec.Namespace != rep.Namespace || rec.Function != rep.Function || !bytes.Equal(rec.Input, rep.Input) || !bytes.Equal(rec.Output, rep.Output) || rec.Error != rep.Error {
+			diffs = append(diffs, fmt.Sprintf("extension call #%d differs: recorded %s, replayed %s", i, rec, rep))
+			break
+		}
+	}
+
+	return diffs
+}
+
+func (c *TracedExtensionCall) String() string {
+	if c.Error != "" {
+		return fmt.Sprintf("%s::%s(input: %x) -> (error: %q)", c.Namespace, c.Function, c.Input, c.Error)
+	}
+	return fmt.Sprintf("%s::%s(input: %x) -> (output: %x)", c.Namespace, c.Function, c.Input, c.Output)
+}
+
+func (c *TracedStateCall) String() string {
+	if c.StoreIndex < 0 {
+		return fmt.Sprintf("%s(ord: %d, key: %q, value: %x)", c.Func, c.Ordinal, c.Key, c.Value)
+	}
+	return fmt.Sprintf("%s(store: %d, ord: %d, key: %q) -> (value: %x, found: %v)", c.Func, c.StoreIndex, c.Ordinal, c.Key, c.Value, c.Found)
+}
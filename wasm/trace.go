@@ -0,0 +1,246 @@
+package wasm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// CallTrace is the complete record of a single module execution on a single block:
+// the input bytes it received, every `state` host call it issued, the WASM extension
+// calls with their responses and the output it produced. It contains everything
+// needed to re-run the module deterministically without chain data nor endpoints.
+type CallTrace struct {
+	Module     string    `json:"module"`
+	Entrypoint string    `json:"entrypoint"`
+	BlockNum   uint64    `json:"block_num"`
+	BlockID    string    `json:"block_id"`
+	Timestamp  time.Time `json:"timestamp"`
+
+	Inputs         []*TracedInput         `json:"inputs"`
+	StateCalls     []*TracedStateCall     `json:"state_calls,omitempty"`
+	ExtensionCalls []*TracedExtensionCall `json:"extension_calls,omitempty"`
+
+	Output []byte   `json:"output,omitempty"`
+	Logs   []string `json:"logs,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+const (
+	TracedInputSource      = "source"
+	TracedInputMap         = "map"
+	TracedInputStoreDeltas = "store_deltas"
+	TracedInputStoreReader = "store_reader"
+	TracedInputStoreWriter = "store_writer"
+	TracedInputParams      = "params"
+)
+
+type TracedInput struct {
+	Name  string `json:"name"`
+	Kind  string `json:"kind"`
+	Value []byte `json:"value,omitempty"`
+}
+
+// TracedStateCall is a `state` host call. StoreIndex is the index of the store in `get`
+// mode for reads, and -1 for writes to the module's own store. For `has_*` reads, Value is empty.
+type TracedStateCall struct {
+	Func       string `json:"func"`
+	StoreIndex int    `json:"store_index"`
+	Ordinal    uint64 `json:"ordinal"`
+	Key        string `json:"key"`
+	Value      []byte `json:"value,omitempty"`
+	Found      bool   `json:"found,omitempty"`
+}
+
+type TracedExtensionCall struct {
+	Namespace string `json:"namespace"`
+	Function  string `json:"function"`
+	Input     []byte `json:"input,omitempty"`
+	Output    []byte `json:"output,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func tracedInputKind(arg Argument) string {
+	switch arg.(type) {
+	case *SourceInput:
+		return TracedInputSource
+	case *MapInput:
+		return TracedInputMap
+	case *StoreDeltaInput:
+		return TracedInputStoreDeltas
+	case *StoreReaderInput:
+		return TracedInputStoreReader
+	case *StoreWriterOutput:
+		return TracedInputStoreWriter
+	case *ParamsInput:
+		return TracedInputParams
+	default:
+		panic(fmt.Sprintf("unknown wasm argument type %T", arg))
+	}
+}
+
+// StartTrace enables recording on the call. It must be called before the call is
+// executed, with the arguments' values already set.
+func (c *Call) StartTrace(arguments []Argument) {
+	c.trace = &CallTrace{
+		Module:     c.ModuleName,
+		Entrypoint: c.Entrypoint,
+	}
+	if c.Clock != nil {
+		c.trace.BlockNum = c.Clock.Number
+		c.trace.BlockID = c.Clock.Id
+		c.trace.Timestamp = c.Clock.Timestamp.AsTime()
+	}
+
+	for _, arg := range arguments {
+		in := &TracedInput{
+			Name: arg.Name(),
+			Kind: tracedInputKind(arg),
+		}
+		if v, ok := arg.(ValueArgument); ok {
+			in.Value = bytes.Clone(v.Value())
+		}
+		c.trace.Inputs = append(c.trace.Inputs, in)
+	}
+}
+
+// FinishTrace completes the trace with the call's output, logs and error, `execErr` being
+// the error returned by the runtime, if any. It returns nil when tracing was not started.
+func (c *Call) FinishTrace(execErr error) *CallTrace {
+	if c.trace == nil {
+		return nil
+	}
+
+	c.trace.Output = c.returnValue
+	c.trace.Logs = c.Logs
+	if err := c.Err(); err != nil {
+		c.trace.Error = err.Error()
+	} else if execErr != nil {
+		c.trace.Error = execErr.Error()
+	}
+	return c.trace
+}
+
+// TraceExtensionCall is called by the runtimes after each WASM extension call. Byte slices
+// are copied, they usually point into the instance's memory.
+func (c *Call) TraceExtensionCall(namespace, function string, input, output []byte, err error) {
+	if c.trace == nil {
+		return
+	}
+
+	extCall := &TracedExtensionCall{
+		Namespace: namespace,
+		Function:  function,
+		Input:     bytes.Clone(input),
+		Output:    bytes.Clone(output),
+	}
+	if err != nil {
+		extCall.Error = err.Error()
+	}
+	c.trace.ExtensionCalls = append(c.trace.ExtensionCalls, extCall)
+}
+
+func (c *Call) recordStateCall(stateFunc string, storeIndex int, ord uint64, key string, value []byte, found bool) {
+	if c.trace == nil {
+		return
+	}
+
+	c.trace.StateCalls = append(c.trace.StateCalls, &TracedStateCall{
+		Func:       stateFunc,
+		StoreIndex: storeIndex,
+		Ordinal:    ord,
+		Key:        key,
+		Value:      bytes.Clone(value),
+		Found:      found,
+	})
+}
+
+func (c *Call) recordStateWrite(stateFunc string, ord uint64, key string, value []byte) {
+	c.recordStateCall(stateFunc, -1, ord, key, value, false)
+}
+
+// TraceRecorder receives the trace of every module execution of a request.
+type TraceRecorder interface {
+	Record(trace *CallTrace) error
+}
+
+// FileTraceRecorder writes traces to a file, one JSON object per line.
+type FileTraceRecorder struct {
+	mu      sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+}
+
+func NewFileTraceRecorder(path string) (*FileTraceRecorder, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("opening trace file %q: %w", path, err)
+	}
+
+	return &FileTraceRecorder{
+		file:    f,
+		encoder: json.NewEncoder(f),
+	}, nil
+}
+
+func (r *FileTraceRecorder) Record(trace *CallTrace) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.encoder.Encode(trace); err != nil {
+		return fmt.Errorf("writing trace of module %q at block %d: %w", trace.Module, trace.BlockNum, err)
+	}
+	return nil
+}
+
+func (r *FileTraceRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.file.Close()
+}
+
+// ReadTraces decodes all traces written by a FileTraceRecorder.
+func ReadTraces(reader io.Reader) (out []*CallTrace, err error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 1024*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		trace := &CallTrace{}
+		if err := json.Unmarshal(scanner.Bytes(), trace); err != nil {
+			return nil, fmt.Errorf("decoding trace on line %d: %w", line, err)
+		}
+		out = append(out, trace)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading traces: %w", err)
+	}
+	return out, nil
+}
+
+type traceRecorderKeyType int
+
+var traceRecorderKey = traceRecorderKeyType(0)
+
+func WithTraceRecorder(ctx context.Context, recorder TraceRecorder) context.Context {
+	return context.WithValue(ctx, traceRecorderKey, recorder)
+}
+
+// TraceRecorderFromContext returns the recorder set on the context, or nil when execution
+// tracing is disabled.
+func TraceRecorderFromContext(ctx context.Context) TraceRecorder {
+	if recorder, ok := ctx.Value(traceRecorderKey).(TraceRecorder); ok {
+		return recorder
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package wasm_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/streamingfast/substreams/metrics"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"github.com/streamingfast/substreams/storage/store"
+	"github.com/streamingfast/substreams/wasm"
+	"github.com/streamingfast/substreams/wasm/native"
+	_ "github.com/streamingfast/substreams/wasm/wazero"
+)
+
+func init() {
+	native.Register("test_store_copy_traced", func(ctx context.Context, call *wasm.Call, args []wasm.Argument) ([]byte, error) {
+		value, found := call.DoGetLast(0, "in")
+		call.AppendLog("read in")
+		if found {
+			call.DoSet(1, "out", value)
+		}
+		return value, nil
+	})
+}
+
+func TestTraceRecordAndReplay(t *testing.T) {
+	ctx := context.Background()
+	stats := metrics.NewReqStats(&metrics.Config{}, zap.NewNop())
+
+	mod, err := wasm.NewRegistry(nil, 0).NewModule(ctx, []byte("test@v1"), native.BinaryType)
+	require.NoError(t, err)
+
+	in := newTestStore(t, "store_in")
+	in.SetBytes(0, "in", []byte("value"))
+
+	clock := &pbsubstreams.Clock{Id: "00a", Number: 10, Timestamp: timestamppb.Now()}
+	args := []wasm.Argument{
+		wasm.NewStoreReaderInput("store_in", in),
+		wasm.NewStoreWriterOutput("store_out", newTestStore(t, "store_out"), pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "bytes"),
+	}
+	call := wasm.NewCall(clock, "store_out", "test_store_copy_traced", stats, args)
+	call.StartTrace(args)
+
+	_, err = mod.ExecuteNewCall(ctx, call, nil, args)
+	require.NoError(t, err)
+	trace := call.FinishTrace(err)
+
+	require.Len(t, trace.StateCalls, 2)
+	assert.Equal(t, &wasm.TracedStateCall{Func: "get_last", StoreIndex: 0, Key: "in", Value: []byte("value"), Found: true}, trace.StateCalls[0])
+	assert.Equal(t, &wasm.TracedStateCall{Func: "set", StoreIndex: -1, Ordinal: 1, Key: "out", Value: []byte("value")}, trace.StateCalls[1])
+	assert.Equal(t, []string{"read in"}, trace.Logs)
+	assert.Equal(t, []byte("value"), trace.Output)
+
+	// Round-trip through the file format
+	buf := bytes.NewBuffer(nil)
+	recorder := &bufferRecorder{buf: buf}
+	require.NoError(t, recorder.Record(trace))
+	traces, err := wasm.ReadTraces(buf)
+	require.NoError(t, err)
+	require.Len(t, traces, 1)
+
+	replay := func(trace *wasm.CallTrace) *wasm.CallTrace {
+		replayer, err := wasm.NewTraceReplayer(ctx, []byte("test@v1"), native.BinaryType, []*wasm.CallTrace{trace}, newTestStore(t, "store_out"), pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "bytes", stats)
+		require.NoError(t, err)
+		defer replayer.Close(ctx)
+
+		replayed, err := replayer.Replay(ctx, trace)
+		require.NoError(t, err)
+		return replayed
+	}
+
+	assert.Empty(t, wasm.CompareTraces(traces[0], replay(traces[0])))
+
+	traces[0].StateCalls[0].Value = []byte("other")
+	assert.Equal(t, []string{
+		"output differs: recorded 5 bytes, replayed 5 bytes",
+		`state call #0 differs: recorded get_last(store: 0, ord: 0, key: "in") -> (value: 76616c7565, found: true), replayed get_last(store: 0, ord: 0, key: "in") -> (value: 6f74686572, found: true)`,
+	}, wasm.CompareTraces(trace, replay(traces[0])))
+}
+
+func TestCompareTraces_ExtensionCalls(t *testing.T) {
+	newTrace := func(output string) *wasm.CallTrace {
+		return &wasm.CallTrace{
+			ExtensionCalls: []*wasm.TracedExtensionCall{
+				{Namespace: "rpc", Function: "eth_call", Input: []byte{0x01}, Output: []byte{0x02}},
+				{Namespace: "rpc", Function: "eth_call", Input: []byte{0x03}, Output: []byte(output)},
+			},
+		}
+	}
+
+	assert.Empty(t, wasm.CompareTraces(newTrace("a"), newTrace("a")))
+
+	// the same number of calls with a different response is a divergence
+	assert.Equal(t, []string{
+		"extension call #1 differs: recorded rpc::eth_call(input: 03) -> (output: 61), replayed rpc::eth_call(input: 03) -> (output: 62)",
+	}, wasm.CompareTraces(newTrace("a"), newTrace("b")))
+
+	failed := newTrace("a")
+	failed.ExtensionCalls[0].Output = nil
+	failed.ExtensionCalls[0].Error = "timeout"
+	assert.Equal(t, []string{
+		`extension call #0 differs: recorded rpc::eth_call(input: 01) -> (output: 02), replayed rpc::eth_call(input: 01) -> (error: "timeout")`,
+	}, wasm.CompareTraces(newTrace("a"), failed))
+}
+
+func newTestStore(t *testing.T, name string) store.Store {
+	t.Helper()
+
+	conf, err := store.NewConfig(name, 0, "", pbsubstreams.Module_KindStore_UPDATE_POLICY_SET, "bytes", dstore.NewMockStore(nil))
+	require.NoError(t, err)
+	return conf.NewFullKV(zap.NewNop())
+}
+
+type bufferRecorder struct {
+	buf *bytes.Buffer
+}
+
+func (r *bufferRecorder) Record(trace *wasm.CallTrace) error {
+	return json.NewEncoder(r.buf).Encode(trace)
+}
@@ -44,6 +44,7 @@ func (i *instance) newExtensionFunction(ctx context.Context, namespace, name str
 		data := i.Heap.ReadBytes(ptr, length)
 
 		out, err := f(ctx, reqctx.Details(ctx).UniqueIDString(), i.CurrentCall.Clock, data)
+		i.CurrentCall.TraceExtensionCall(namespace, name, data, out, err)
 		if err != nil {
 			panic(fmt.Errorf(`running wasm extension "%s::%s": %w`, namespace, name, err))
 		}
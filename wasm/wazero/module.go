@@ -177,6 +177,7 @@ func addExtensionFunctions(ctx context.Context, runtime wazero.Runtime, registry
 					metricID := reqctx.ReqStats(ctx).RecordModuleWasmExternalCallBegin(call.ModuleName, fmt.Sprintf("%s:%s", namespace, importName))
 
 					out, err := f(ctx, reqctx.Details(ctx).UniqueIDString(), call.Clock, data)
+					call.TraceExtensionCall(namespace, importName, data, out, err)
 					if err != nil {
 						panic(fmt.Errorf(`running wasm extension "%s::%s": %w`, namespace, importName, err))
 					}
@@ -0,0 +1,200 @@
+package wazero
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+
+	"github.com/streamingfast/substreams/wasm"
+)
+
+const WASIBinaryType = "wasm/wasi"
+
+func init() {
+	wasm.RegisterBinaryTypeFactory(WASIBinaryType, wasm.ModuleFactoryFunc(newWASIModule))
+}
+
+// A WASIModule runs WASI preview1 command modules. Contrary to Module, it does not rely on
+// the `alloc`/`dealloc` exports for its inputs and output: inputs are framed on stdin and the
+// output is read from stdout. The host functions returning data (the `state` get_* functions and
+// the extensions) still write it with `alloc`, which the modules importing them must export.
+//
+// Each execution gets a fresh instance, as a command module cannot be re-entered once its
+// `_start` function has returned.
+type WASIModule struct {
+	*Module
+}
+
+func newWASIModule(ctx context.Context, wasmCode []byte, registry *wasm.Registry) (wasm.Module, error) {
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfigCompiler())
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		return nil, fmt.Errorf("instantiating wasi host module: %w", err)
+	}
+
+	hostModules, err := addExtensionFunctions(ctx, runtime, registry)
+	if err != nil {
+		return nil, err
+	}
+	stateModule, err := addHostFunctions(ctx, runtime, "state", stateFuncs)
+	if err != nil {
+		return nil, err
+	}
+	hostModules = append(hostModules, stateModule)
+
+	mod, err := runtime.CompileModule(ctx, wasmCode)
+	if err != nil {
+		return nil, fmt.Errorf("creating new module: %w", err)
+	}
+
+	if mod.ExportedFunctions()["_start"] == nil {
+		return nil, fmt.Errorf("missing required functions: _start (is it a wasi command module?)")
+	}
+	if mod.ExportedFunctions()["alloc"] == nil {
+		for _, f := range mod.ImportedFunctions() {
+			moduleName, name, _ := f.Import()
+			if writesToHeap(registry, moduleName, name) {
+				return nil, fmt.Errorf("import %s::%s returns its result in the module memory, which requires an exported alloc function", moduleName, name)
+			}
+		}
+	}
+
+	return &WASIModule{
+		Module: &Module{
+			wazModuleConfig: wazero.NewModuleConfig().WithStartFunctions(),
+			wazRuntime:      runtime,
+			userModule:      mod,
+			hostModules:     hostModules,
+		},
+	}, nil
+}
+
+func (m *WASIModule) NewInstance(ctx context.Context) (wasm.Instance, error) {
+	mod, err := m.instantiateModuleWithConfig(ctx, m.wazModuleConfig)
+	if err != nil {
+		return nil, fmt.Errorf("could not instantiate wasm module: %w", err)
+	}
+	return &instance{Module: mod}, nil
+}
+
+func (m *WASIModule) ExecuteNewCall(ctx context.Context, call *wasm.Call, cachedInstance wasm.Instance, arguments []wasm.Argument) (out wasm.Instance, err error) {
+	if cachedInstance != nil {
+		// stdio is bound at instantiation, so a command module is never reused across calls
+		if err := cachedInstance.Close(ctx); err != nil {
+			return nil, fmt.Errorf("closing previous instance: %w", err)
+		}
+	}
+
+	stdin, err := frameWASIArguments(arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	config := m.wazModuleConfig.
+		WithArgs("substreams", call.Entrypoint).
+		WithStdin(bytes.NewReader(stdin)).
+		WithStdout(stdout).
+		WithStderr(stderr)
+
+	mod, err := m.instantiateModuleWithConfig(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("could not instantiate wasm module: %w", err)
+	}
+	inst := &instance{Module: mod}
+
+	_, err = mod.ExportedFunction("_start").Call(wasm.WithContext(withInstanceContext(ctx, inst), call))
+	appendWASILogs(call, stderr)
+
+	var exitErr *sys.ExitError
+	if errors.As(err, &exitErr) {
+		if exitErr.ExitCode() != 0 {
+			return inst, fmt.Errorf("module exited with code %d", exitErr.ExitCode())
+		}
+		err = nil
+	}
+	if err != nil {
+		return inst, fmt.Errorf("call: %w", err)
+	}
+
+	if stdout.Len() != 0 {
+		call.SetReturnValue(stdout.Bytes())
+	}
+
+	return inst, nil
+}
+
+func (m *WASIModule) instantiateModuleWithConfig(ctx context.Context, config wazero.ModuleConfig) (api.Module, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, hostMod := range m.hostModules {
+		if m.wazRuntime.Module(hostMod.Name()) != nil {
+			continue
+		}
+		_, err := m.wazRuntime.InstantiateModule(ctx, hostMod, m.wazModuleConfig.WithName(hostMod.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("instantiating host module %q: %w", hostMod.Name(), err)
+		}
+	}
+	return m.wazRuntime.InstantiateModule(ctx, m.userModule, config.WithName(""))
+}
+
+// writesToHeap tells if the host function writes its result with the `alloc` export of the module.
+func writesToHeap(registry *wasm.Registry, moduleName, name string) bool {
+	if moduleName == "state" {
+		return strings.HasPrefix(name, "get_")
+	}
+	_, isExtension := registry.Extensions[moduleName]
+	return isExtension
+}
+
+// frameWASIArguments writes each input as a 4-byte little-endian length followed by
+// its content. Store readers are represented by their 4-byte little-endian store index,
+// to be used with the `state` host functions.
+func frameWASIArguments(arguments []wasm.Argument) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	var inputStoreCount uint32
+	for _, input := range arguments {
+		switch v := input.(type) {
+		case *wasm.StoreWriterOutput:
+		case *wasm.StoreReaderInput:
+			idx := make([]byte, 4)
+			binary.LittleEndian.PutUint32(idx, inputStoreCount)
+			writeWASIFrame(buf, idx)
+			inputStoreCount++
+		case wasm.ValueArgument:
+			writeWASIFrame(buf, v.Value())
+		default:
+			return nil, fmt.Errorf("unknown wasm argument type %T", input)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func writeWASIFrame(buf *bytes.Buffer, data []byte) {
+	length := make([]byte, 4)
+	binary.LittleEndian.PutUint32(length, uint32(len(data)))
+	buf.Write(length)
+	buf.Write(data)
+}
+
+func appendWASILogs(call *wasm.Call, stderr *bytes.Buffer) {
+	scanner := bufio.NewScanner(stderr)
+	scanner.Buffer(make([]byte, 0, 4096), wasm.MaxLogByteCount)
+	for scanner.Scan() {
+		if call.ReachedLogsMaxByteCount() {
+			return
+		}
+		call.AppendLog(scanner.Text())
+	}
+}
@@ -0,0 +1,92 @@
+package wazero
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/streamingfast/substreams/metrics"
+	pbsubstreams "github.com/streamingfast/substreams/pb/sf/substreams/v1"
+	"github.com/streamingfast/substreams/wasm"
+)
+
+// echoWASIModule returns a WASI command module copying its stdin to its stdout, which also imports
+// `extraImports` (module and name pairs) as functions taking four i32 and returning one.
+func echoWASIModule(extraImports ...[2]string) []byte {
+	name := func(s string) []byte { return append([]byte{byte(len(s))}, s...) }
+	section := func(id byte, entries int, content ...[]byte) []byte {
+		out := []byte{byte(entries)}
+		for _, c := range content {
+			out = append(out, c...)
+		}
+		return append([]byte{id, byte(len(out))}, out...)
+	}
+
+	// type 0: (i32, i32, i32, i32) -> i32, type 1: () -> ()
+	types := section(1, 2, []byte{0x60, 0x04, 0x7f, 0x7f, 0x7f, 0x7f, 0x01, 0x7f}, []byte{0x60, 0x00, 0x00})
+
+	var imports [][]byte
+	for _, imp := range append([][2]string{{"wasi_snapshot_preview1", "fd_read"}, {"wasi_snapshot_preview1", "fd_write"}}, extraImports...) {
+		imports = append(imports, append(append(name(imp[0]), name(imp[1])...), 0x00, 0x00))
+	}
+	startIndex := byte(len(imports))
+
+	body := []byte{
+		0x00,                                           // no locals
+		0x41, 0x00, 0x41, 0xc0, 0x00, 0x36, 0x02, 0x00, // iovec buffer at 64
+		0x41, 0x04, 0x41, 0x80, 0x08, 0x36, 0x02, 0x00, // of 1024 bytes
+		0x41, 0x00, 0x41, 0x00, 0x41, 0x01, 0x41, 0x10, 0x10, 0x00, 0x1a, // fd_read(stdin, iovec, 1, &read)
+		0x41, 0x04, 0x41, 0x10, 0x28, 0x02, 0x00, 0x36, 0x02, 0x00, // iovec length = read
+		0x41, 0x01, 0x41, 0x00, 0x41, 0x01, 0x41, 0x14, 0x10, 0x01, 0x1a, // fd_write(stdout, iovec, 1, &written)
+		0x0b,
+	}
+
+	out := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	out = append(out, types...)
+	out = append(out, section(2, len(imports), imports...)...)
+	out = append(out, section(3, 1, []byte{0x01})...)
+	out = append(out, section(5, 1, []byte{0x00, 0x01})...)
+	out = append(out, section(7, 2, append(name("memory"), 0x02, 0x00), append(name("_start"), 0x00, startIndex))...)
+	out = append(out, section(10, 1, append([]byte{byte(len(body))}, body...))...)
+	return out
+}
+
+func TestWASIModule_ExecuteNewCall(t *testing.T) {
+	ctx := context.Background()
+	registry := wasm.NewRegistry(nil, 0)
+	stats := metrics.NewReqStats(&metrics.Config{}, zap.NewNop())
+
+	mod, err := registry.NewModule(ctx, echoWASIModule(), WASIBinaryType)
+	require.NoError(t, err)
+
+	args := []wasm.Argument{wasm.NewParamsInput("hello")}
+	call := wasm.NewCall(&pbsubstreams.Clock{Number: 1}, "map_echo", "map_echo", stats, args)
+	inst, err := mod.ExecuteNewCall(ctx, call, nil, args)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x05, 0x00, 0x00, 0x00, 'h', 'e', 'l', 'l', 'o'}, call.Output())
+
+	// a new instance reads the inputs of each call
+	args = []wasm.Argument{wasm.NewParamsInput("a"), wasm.NewParamsInput("bc")}
+	call = wasm.NewCall(&pbsubstreams.Clock{Number: 2}, "map_echo", "map_echo", stats, args)
+	_, err = mod.ExecuteNewCall(ctx, call, inst, args)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x01, 0x00, 0x00, 0x00, 'a', 0x02, 0x00, 0x00, 0x00, 'b', 'c'}, call.Output())
+}
+
+func TestNewWASIModule_RequiresAlloc(t *testing.T) {
+	ctx := context.Background()
+	registry := wasm.NewRegistry(map[string]map[string]wasm.WASMExtension{"rpc": {"eth_call": nil}}, 0)
+
+	_, err := registry.NewModule(ctx, echoWASIModule([2]string{"state", "get_last"}), WASIBinaryType)
+	assert.EqualError(t, err, "import state::get_last returns its result in the module memory, which requires an exported alloc function")
+
+	_, err = registry.NewModule(ctx, echoWASIModule([2]string{"rpc", "eth_call"}), WASIBinaryType)
+	assert.EqualError(t, err, "import rpc::eth_call returns its result in the module memory, which requires an exported alloc function")
+
+	// the state functions that do not return data can be used
+	_, err = registry.NewModule(ctx, echoWASIModule([2]string{"state", "has_last"}), WASIBinaryType)
+	assert.NoError(t, err)
+}